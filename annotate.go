@@ -0,0 +1,296 @@
+package vidio
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"strings"
+)
+
+// Draws annotations such as detection boxes, tracks and labels directly onto a frame buffer, e.g. to
+// visualize results of a detection pipeline before the frame is written. All drawing is clipped to the
+// clip rectangle and blended with the alpha of the color.
+type Canvas struct {
+	pix    []byte          // Frame buffer drawn on.
+	width  int             // Frame width.
+	height int             // Frame height.
+	format PixelFormat     // Layout of the frame buffer.
+	clip   image.Rectangle // Drawing is restricted to this rectangle.
+}
+
+// Style of a label drawn with Canvas.Label.
+type LabelStyle struct {
+	Color      color.Color // Text color. Default white.
+	Background color.Color // Background color. Default translucent black.
+	Scale      int         // Size of the 5x7 pixel font glyphs is multiplied by this. Default 2.
+	Padding    int         // Pixels between the text and the edge of the background. Default Scale.
+}
+
+// Returns a canvas drawing onto the given frame buffer in the given pixel format.
+func NewCanvas(frame []byte, width, height int, format PixelFormat) (*Canvas, error) {
+	depth := format.Depth()
+	if depth == 0 {
+		return nil, fmt.Errorf("vidio: unsupported pixel format: %s", format)
+	}
+	if width <= 0 || height <= 0 || len(frame) < width*height*depth {
+		return nil, fmt.Errorf("vidio: buffer size %d is smaller than frame size %d", len(frame), width*height*depth)
+	}
+	return &Canvas{
+		pix:    frame,
+		width:  width,
+		height: height,
+		format: format,
+		clip:   image.Rect(0, 0, width, height),
+	}, nil
+}
+
+// Restricts drawing to the given rectangle within the frame.
+func (canvas *Canvas) SetClip(rect image.Rectangle) {
+	canvas.clip = rect.Intersect(image.Rect(0, 0, canvas.width, canvas.height))
+}
+
+// Blends the color into the pixel at (x, y) if it is inside the clip rectangle.
+func (canvas *Canvas) plot(x, y int, c color.RGBA) {
+	if !(image.Point{x, y}.In(canvas.clip)) || c.A == 0 {
+		return
+	}
+	depth := canvas.format.Depth()
+	i := (y*canvas.width + x) * depth
+	pix := canvas.pix[i : i+depth]
+	// Colors are premultiplied, so blending is "source over".
+	over := func(dst *byte, src uint8) {
+		*dst = src + uint8(uint32(*dst)*uint32(255-c.A)/255)
+	}
+	switch canvas.format {
+	case PixelRGBA:
+		over(&pix[0], c.R)
+		over(&pix[1], c.G)
+		over(&pix[2], c.B)
+		over(&pix[3], c.A)
+	case PixelBGRA:
+		over(&pix[0], c.B)
+		over(&pix[1], c.G)
+		over(&pix[2], c.R)
+		over(&pix[3], c.A)
+	case PixelRGB24:
+		over(&pix[0], c.R)
+		over(&pix[1], c.G)
+		over(&pix[2], c.B)
+	case PixelBGR24:
+		over(&pix[0], c.B)
+		over(&pix[1], c.G)
+		over(&pix[2], c.R)
+	case PixelGray:
+		over(&pix[0], color.GrayModel.Convert(c).(color.Gray).Y)
+	}
+}
+
+// Fills the rectangle with the color.
+func (canvas *Canvas) FillRect(rect image.Rectangle, c color.Color) {
+	rgba := color.RGBAModel.Convert(c).(color.RGBA)
+	rect = rect.Canon().Intersect(canvas.clip)
+	for y := rect.Min.Y; y < rect.Max.Y; y++ {
+		for x := rect.Min.X; x < rect.Max.X; x++ {
+			canvas.plot(x, y, rgba)
+		}
+	}
+}
+
+// Draws the outline of the rectangle, "thickness" pixels wide on the inside of the rectangle.
+func (canvas *Canvas) Rect(rect image.Rectangle, c color.Color, thickness int) {
+	rect = rect.Canon()
+	thickness = max(1, min(thickness, (min(rect.Dx(), rect.Dy())+1)/2))
+	inner := rect.Inset(thickness)
+	canvas.FillRect(image.Rect(rect.Min.X, rect.Min.Y, rect.Max.X, inner.Min.Y), c)
+	canvas.FillRect(image.Rect(rect.Min.X, inner.Max.Y, rect.Max.X, rect.Max.Y), c)
+	canvas.FillRect(image.Rect(rect.Min.X, inner.Min.Y, inner.Min.X, inner.Max.Y), c)
+	canvas.FillRect(image.Rect(inner.Max.X, inner.Min.Y, rect.Max.X, inner.Max.Y), c)
+}
+
+// Draws a line "thickness" pixels wide from "from" to "to".
+func (canvas *Canvas) Line(from, to image.Point, c color.Color, thickness int) {
+	canvas.Polyline([]image.Point{from, to}, c, thickness, false)
+}
+
+// Draws lines "thickness" pixels wide through the points, e.g. the track of an object. If "closed" is true,
+// the last point is connected to the first, e.g. to outline a polygon.
+func (canvas *Canvas) Polyline(points []image.Point, c color.Color, thickness int, closed bool) {
+	rgba := color.RGBAModel.Convert(c).(color.RGBA)
+	thickness = max(1, thickness)
+	// Pixels are covered by the square brush of several points; drawing them once keeps translucent lines even.
+	covered := map[image.Point]bool{}
+	brush := func(center image.Point) {
+		for y := center.Y - (thickness-1)/2; y <= center.Y+thickness/2; y++ {
+			for x := center.X - (thickness-1)/2; x <= center.X+thickness/2; x++ {
+				if point := (image.Point{x, y}); !covered[point] {
+					covered[point] = true
+					canvas.plot(x, y, rgba)
+				}
+			}
+		}
+	}
+
+	segments := len(points) - 1
+	if closed && len(points) > 2 {
+		segments++
+	}
+	if len(points) == 1 {
+		brush(points[0])
+	}
+	for i := 0; i < segments; i++ {
+		// Bresenham's line algorithm.
+		from, to := points[i], points[(i+1)%len(points)]
+		dx, dy := abs(to.X-from.X), -abs(to.Y-from.Y)
+		sx, sy := sign(to.X-from.X), sign(to.Y-from.Y)
+		err := dx + dy
+		for point := from; ; {
+			brush(point)
+			if point == to {
+				break
+			}
+			e2 := 2 * err
+			if e2 >= dy {
+				err += dy
+				point.X += sx
+			}
+			if e2 <= dx {
+				err += dx
+				point.Y += sy
+			}
+		}
+	}
+}
+
+// Draws the text on a background with its top left corner at "at" and returns the rectangle of the label.
+// Text is drawn with a built-in 5x7 pixel font of digits, letters shown as capitals and common punctuation.
+// Use LabelSize to place labels, e.g. above a detection box.
+func (canvas *Canvas) Label(at image.Point, text string, style LabelStyle) image.Rectangle {
+	scale, padding := labelScale(style)
+	foreground, background := style.Color, style.Background
+	if foreground == nil {
+		foreground = color.White
+	}
+	if background == nil {
+		background = color.RGBA{0, 0, 0, 160}
+	}
+
+	rect := image.Rectangle{Min: at, Max: at.Add(LabelSize(text, style))}
+	canvas.FillRect(rect, background)
+	rgba := color.RGBAModel.Convert(foreground).(color.RGBA)
+	x := at.X + padding
+	for _, r := range strings.ToUpper(text) {
+		glyph, ok := font5x7[r]
+		if !ok {
+			glyph = font5x7['?']
+		}
+		for row, bits := range glyph {
+			for column := 0; column < 5; column++ {
+				if bits&(1<<(4-column)) == 0 {
+					continue
+				}
+				for y := 0; y < scale; y++ {
+					for dx := 0; dx < scale; dx++ {
+						canvas.plot(x+column*scale+dx, at.Y+padding+row*scale+y, rgba)
+					}
+				}
+			}
+		}
+		x += 6 * scale
+	}
+	return rect
+}
+
+// Returns the size of a label with the given text and style, including the padding.
+func LabelSize(text string, style LabelStyle) image.Point {
+	scale, padding := labelScale(style)
+	characters := len([]rune(text))
+	width := max(0, characters*6-1) * scale
+	return image.Point{width + 2*padding, 7*scale + 2*padding}
+}
+
+// Returns the glyph scale and padding of the style, with defaults applied.
+func labelScale(style LabelStyle) (int, int) {
+	scale := style.Scale
+	if scale <= 0 {
+		scale = 2
+	}
+	padding := style.Padding
+	if padding <= 0 {
+		padding = scale
+	}
+	return scale, padding
+}
+
+func abs(value int) int {
+	if value < 0 {
+		return -value
+	}
+	return value
+}
+
+func sign(value int) int {
+	switch {
+	case value < 0:
+		return -1
+	case value > 0:
+		return 1
+	default:
+		return 0
+	}
+}
+
+// 5x7 pixel glyphs, one byte per row with the leftmost pixel in bit 4.
+var font5x7 = map[rune][7]byte{
+	' ':  {},
+	'0':  {0x0E, 0x11, 0x13, 0x15, 0x19, 0x11, 0x0E},
+	'1':  {0x04, 0x0C, 0x04, 0x04, 0x04, 0x04, 0x0E},
+	'2':  {0x0E, 0x11, 0x01, 0x02, 0x04, 0x08, 0x1F},
+	'3':  {0x1F, 0x02, 0x04, 0x02, 0x01, 0x11, 0x0E},
+	'4':  {0x02, 0x06, 0x0A, 0x12, 0x1F, 0x02, 0x02},
+	'5':  {0x1F, 0x10, 0x1E, 0x01, 0x01, 0x11, 0x0E},
+	'6':  {0x06, 0x08, 0x10, 0x1E, 0x11, 0x11, 0x0E},
+	'7':  {0x1F, 0x01, 0x02, 0x04, 0x08, 0x08, 0x08},
+	'8':  {0x0E, 0x11, 0x11, 0x0E, 0x11, 0x11, 0x0E},
+	'9':  {0x0E, 0x11, 0x11, 0x0F, 0x01, 0x02, 0x0C},
+	'A':  {0x0E, 0x11, 0x11, 0x1F, 0x11, 0x11, 0x11},
+	'B':  {0x1E, 0x11, 0x11, 0x1E, 0x11, 0x11, 0x1E},
+	'C':  {0x0E, 0x11, 0x10, 0x10, 0x10, 0x11, 0x0E},
+	'D':  {0x1C, 0x12, 0x11, 0x11, 0x11, 0x12, 0x1C},
+	'E':  {0x1F, 0x10, 0x10, 0x1E, 0x10, 0x10, 0x1F},
+	'F':  {0x1F, 0x10, 0x10, 0x1E, 0x10, 0x10, 0x10},
+	'G':  {0x0E, 0x11, 0x10, 0x17, 0x11, 0x11, 0x0F},
+	'H':  {0x11, 0x11, 0x11, 0x1F, 0x11, 0x11, 0x11},
+	'I':  {0x0E, 0x04, 0x04, 0x04, 0x04, 0x04, 0x0E},
+	'J':  {0x07, 0x02, 0x02, 0x02, 0x02, 0x12, 0x0C},
+	'K':  {0x11, 0x12, 0x14, 0x18, 0x14, 0x12, 0x11},
+	'L':  {0x10, 0x10, 0x10, 0x10, 0x10, 0x10, 0x1F},
+	'M':  {0x11, 0x1B, 0x15, 0x15, 0x11, 0x11, 0x11},
+	'N':  {0x11, 0x11, 0x19, 0x15, 0x13, 0x11, 0x11},
+	'O':  {0x0E, 0x11, 0x11, 0x11, 0x11, 0x11, 0x0E},
+	'P':  {0x1E, 0x11, 0x11, 0x1E, 0x10, 0x10, 0x10},
+	'Q':  {0x0E, 0x11, 0x11, 0x11, 0x15, 0x12, 0x0D},
+	'R':  {0x1E, 0x11, 0x11, 0x1E, 0x14, 0x12, 0x11},
+	'S':  {0x0F, 0x10, 0x10, 0x0E, 0x01, 0x01, 0x1E},
+	'T':  {0x1F, 0x04, 0x04, 0x04, 0x04, 0x04, 0x04},
+	'U':  {0x11, 0x11, 0x11, 0x11, 0x11, 0x11, 0x0E},
+	'V':  {0x11, 0x11, 0x11, 0x11, 0x11, 0x0A, 0x04},
+	'W':  {0x11, 0x11, 0x11, 0x15, 0x15, 0x15, 0x0A},
+	'X':  {0x11, 0x11, 0x0A, 0x04, 0x0A, 0x11, 0x11},
+	'Y':  {0x11, 0x11, 0x11, 0x0A, 0x04, 0x04, 0x04},
+	'Z':  {0x1F, 0x01, 0x02, 0x04, 0x08, 0x10, 0x1F},
+	'.':  {0x00, 0x00, 0x00, 0x00, 0x00, 0x0C, 0x0C},
+	',':  {0x00, 0x00, 0x00, 0x00, 0x0C, 0x04, 0x08},
+	':':  {0x00, 0x0C, 0x0C, 0x00, 0x0C, 0x0C, 0x00},
+	'-':  {0x00, 0x00, 0x00, 0x1F, 0x00, 0x00, 0x00},
+	'_':  {0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x1F},
+	'+':  {0x00, 0x04, 0x04, 0x1F, 0x04, 0x04, 0x00},
+	'=':  {0x00, 0x00, 0x1F, 0x00, 0x1F, 0x00, 0x00},
+	'/':  {0x00, 0x01, 0x02, 0x04, 0x08, 0x10, 0x00},
+	'%':  {0x18, 0x19, 0x02, 0x04, 0x08, 0x13, 0x03},
+	'(':  {0x02, 0x04, 0x08, 0x08, 0x08, 0x04, 0x02},
+	')':  {0x08, 0x04, 0x02, 0x02, 0x02, 0x04, 0x08},
+	'#':  {0x0A, 0x0A, 0x1F, 0x0A, 0x1F, 0x0A, 0x0A},
+	'!':  {0x04, 0x04, 0x04, 0x04, 0x04, 0x00, 0x04},
+	'?':  {0x0E, 0x11, 0x01, 0x02, 0x04, 0x00, 0x04},
+	'\'': {0x04, 0x04, 0x08, 0x00, 0x00, 0x00, 0x00},
+}
@@ -0,0 +1,215 @@
+package vidio
+
+import (
+	"encoding/json"
+	"fmt"
+	"image"
+	"image/color"
+	"os"
+)
+
+// An object drawn onto a frame by an AnnotatedWriter, such as a detection or a tracked object.
+type Annotation struct {
+	ID    int             // Track ID. Annotations with the same ID share a color. 0 if not tracked.
+	Label string          // Text shown above the box, e.g. the class.
+	Score float64         // Confidence between 0 and 1, shown as a percentage after the label. 0 hides it.
+	Box   image.Rectangle // Bounding box. Empty boxes are not drawn.
+	Track []image.Point   // Previous positions, drawn as a line. Optional.
+	Color color.Color     // Color of the box, label and track. Default chosen by ID.
+}
+
+// Annotation as written to the sidecar file.
+type annotationRecord struct {
+	ID    int      `json:"id,omitempty"`
+	Label string   `json:"label,omitempty"`
+	Score float64  `json:"score,omitempty"`
+	Box   [4]int   `json:"box"` // x0, y0, x1, y1.
+	Track [][2]int `json:"track,omitempty"`
+}
+
+// Annotations of a frame as written to the sidecar file.
+type annotatedFrame struct {
+	Frame       int                `json:"frame"` // Index of the frame, counted from 0.
+	Time        float64            `json:"time"`  // Timestamp of the frame in seconds.
+	Annotations []annotationRecord `json:"annotations"`
+}
+
+// Optional parameters for AnnotatedWriter.
+type AnnotatedOptions struct {
+	Writer    *Options   // Options of the underlying VideoWriter.
+	Sidecar   string     // If not empty, the annotations of every frame are also written to this JSON file.
+	Thickness int        // Line width of boxes and tracks in pixels. Default 2.
+	Label     LabelStyle // Style of the labels. The background defaults to the annotation color.
+}
+
+// Writes a video with annotations, such as the results of an object detector or tracker, drawn onto the
+// frames while encoding. The sidecar JSON file holds an array with the annotations of every frame,
+// identified by frame index and timestamp, so they can be processed further in sync with the video.
+type AnnotatedWriter struct {
+	writer    *VideoWriter // Video the annotated frames are written to.
+	canvas    *Canvas      // Canvas over "frame".
+	frame     []byte       // Copy of the current frame, annotations are drawn onto it.
+	index     int          // Index of the next frame.
+	sidecar   *os.File     // Sidecar file, if any.
+	thickness int          // Line width of boxes and tracks.
+	label     LabelStyle   // Style of the labels.
+}
+
+// Colors of annotations without a color, chosen by ID.
+var annotationPalette = []color.RGBA{
+	{230, 25, 75, 255},
+	{60, 180, 75, 255},
+	{255, 225, 25, 255},
+	{0, 130, 200, 255},
+	{245, 130, 48, 255},
+	{145, 30, 180, 255},
+	{70, 240, 240, 255},
+	{240, 50, 230, 255},
+}
+
+// Creates a writer drawing annotations onto RGBA frames of the given size and encoding them to "filename".
+func NewAnnotatedWriter(filename string, width, height int, options *AnnotatedOptions) (*AnnotatedWriter, error) {
+	if options == nil {
+		options = &AnnotatedOptions{}
+	}
+	writer := &AnnotatedWriter{
+		frame:     make([]byte, width*height*4),
+		thickness: options.Thickness,
+		label:     options.Label,
+	}
+	if writer.thickness <= 0 {
+		writer.thickness = 2
+	}
+	canvas, err := NewCanvas(writer.frame, width, height, PixelRGBA)
+	if err != nil {
+		return nil, err
+	}
+	writer.canvas = canvas
+
+	video, err := NewVideoWriter(filename, width, height, options.Writer)
+	if err != nil {
+		return nil, err
+	}
+	writer.writer = video
+
+	if options.Sidecar != "" {
+		sidecar, err := os.Create(options.Sidecar)
+		if err != nil {
+			video.Close()
+			return nil, err
+		}
+		if _, err := sidecar.WriteString("["); err != nil {
+			sidecar.Close()
+			video.Close()
+			return nil, err
+		}
+		writer.sidecar = sidecar
+	}
+	return writer, nil
+}
+
+// The VideoWriter the annotated frames are written to.
+func (writer *AnnotatedWriter) VideoWriter() *VideoWriter {
+	return writer.writer
+}
+
+// Draws the annotations onto a copy of the frame, so the given frame is not changed, and writes it.
+func (writer *AnnotatedWriter) Write(frame []byte, annotations []Annotation) error {
+	if len(frame) < len(writer.frame) {
+		return fmt.Errorf("vidio: buffer size %d is smaller than frame size %d", len(frame), len(writer.frame))
+	}
+	copy(writer.frame, frame)
+	renderAnnotations(writer.canvas, annotations, writer.thickness, writer.label)
+	if err := writer.writer.Write(writer.frame); err != nil {
+		return err
+	}
+
+	if writer.sidecar != nil {
+		data, err := json.Marshal(annotationFrame(writer.index, writer.writer.FPS(), annotations))
+		if err != nil {
+			return err
+		}
+		separator := "\n"
+		if writer.index > 0 {
+			separator = ",\n"
+		}
+		if _, err := writer.sidecar.WriteString(separator + string(data)); err != nil {
+			return err
+		}
+	}
+	writer.index++
+	return nil
+}
+
+// Finishes the video and the sidecar file.
+func (writer *AnnotatedWriter) Close() error {
+	writer.writer.Close()
+	err := writer.writer.Err()
+	if writer.sidecar != nil {
+		if _, sidecarErr := writer.sidecar.WriteString("\n]\n"); err == nil {
+			err = sidecarErr
+		}
+		if sidecarErr := writer.sidecar.Close(); err == nil {
+			err = sidecarErr
+		}
+		writer.sidecar = nil
+	}
+	return err
+}
+
+// Draws the tracks, boxes and labels of the annotations.
+func renderAnnotations(canvas *Canvas, annotations []Annotation, thickness int, style LabelStyle) {
+	for _, annotation := range annotations {
+		c := annotation.Color
+		if c == nil {
+			c = annotationPalette[(annotation.ID%len(annotationPalette)+len(annotationPalette))%len(annotationPalette)]
+		}
+		if len(annotation.Track) > 1 {
+			canvas.Polyline(annotation.Track, c, max(1, thickness/2), false)
+		}
+		if annotation.Box.Empty() {
+			continue
+		}
+		canvas.Rect(annotation.Box, c, thickness)
+
+		text := annotation.Label
+		if annotation.Score > 0 {
+			text = fmt.Sprintf("%s %.0f%%", text, annotation.Score*100)
+		}
+		if text == "" {
+			continue
+		}
+		label := style
+		if label.Background == nil {
+			label.Background = c
+		}
+		// Labels go above the box, or inside it at the top edge of the frame.
+		at := annotation.Box.Min.Sub(image.Point{0, LabelSize(text, label).Y})
+		if at.Y < 0 {
+			at.Y = annotation.Box.Min.Y
+		}
+		canvas.Label(at, text, label)
+	}
+}
+
+// Returns the sidecar entry of the frame with the given index.
+func annotationFrame(index int, fps float64, annotations []Annotation) annotatedFrame {
+	entry := annotatedFrame{Frame: index, Annotations: make([]annotationRecord, len(annotations))}
+	if fps > 0 {
+		entry.Time = float64(index) / fps
+	}
+	for i, annotation := range annotations {
+		box := annotation.Box.Canon()
+		record := annotationRecord{
+			ID:    annotation.ID,
+			Label: annotation.Label,
+			Score: annotation.Score,
+			Box:   [4]int{box.Min.X, box.Min.Y, box.Max.X, box.Max.Y},
+		}
+		for _, point := range annotation.Track {
+			record.Track = append(record.Track, [2]int{point.X, point.Y})
+		}
+		entry.Annotations[i] = record
+	}
+	return entry
+}
@@ -0,0 +1,136 @@
+package vidio
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"fmt"
+	"image/jpeg"
+	"image/png"
+	"io"
+	"time"
+)
+
+// Container of the frames written by ExportFramesArchive.
+type ArchiveFormat int
+
+const (
+	ArchiveTar ArchiveFormat = iota // Uncompressed tar, readable while it is being streamed.
+	ArchiveZip                      // Zip with stored entries, as the frames are already compressed.
+)
+
+// Optional parameters for ExportFramesArchive.
+type ArchiveOptions struct {
+	Image   string // Image format of the frames, "jpeg" or "png". Default "jpeg".
+	Quality int    // JPEG quality between 1 and 100. Default 90.
+	Every   int    // Export every n-th frame. Default 1, every frame.
+	Prefix  string // Name of the entries before the frame index, may contain a directory. Default "frame-".
+}
+
+// Writes the frames of a video added with add to a tar or zip stream.
+type frameArchive struct {
+	tar      *tar.Writer // Writer of tar archives.
+	zip      *zip.Writer // Writer of zip archives.
+	modified time.Time   // Modification time of the entries.
+}
+
+// Decodes "filename" and streams its frames, encoded as JPEG or PNG images, into a tar or zip archive written
+// to "w", e.g. the response of a dataset download endpoint. No files are written to disk. Entries are named by
+// the prefix and the index of the frame in the video, such as "frame-000042.jpg".
+func ExportFramesArchive(filename string, w io.Writer, format ArchiveFormat, archive *ArchiveOptions, options ...Option) error {
+	settings := ArchiveOptions{}
+	if archive != nil {
+		settings = *archive
+	}
+	if settings.Image == "" {
+		settings.Image = "jpeg"
+	}
+	if settings.Image != "jpeg" && settings.Image != "png" {
+		return fmt.Errorf("vidio: unsupported image format: %s", settings.Image)
+	}
+	if settings.Quality <= 0 || settings.Quality > 100 {
+		settings.Quality = 90
+	}
+	settings.Every = max(1, settings.Every)
+	if settings.Prefix == "" {
+		settings.Prefix = "frame-"
+	}
+	extension := ".jpg"
+	if settings.Image == "png" {
+		extension = ".png"
+	}
+
+	video, err := NewVideo(filename, options...)
+	if err != nil {
+		return err
+	}
+	defer video.Close()
+
+	writer, err := newFrameArchive(w, format)
+	if err != nil {
+		return err
+	}
+	data := &bytes.Buffer{}
+	for index := 0; video.Read(); index++ {
+		if index%settings.Every != 0 {
+			continue
+		}
+		frame, err := video.Image()
+		if err != nil {
+			return err
+		}
+		data.Reset()
+		if settings.Image == "png" {
+			err = png.Encode(data, frame)
+		} else {
+			err = jpeg.Encode(data, frame, &jpeg.Options{Quality: settings.Quality})
+		}
+		if err != nil {
+			return err
+		}
+		if err := writer.add(fmt.Sprintf("%s%06d%s", settings.Prefix, index, extension), data.Bytes()); err != nil {
+			return err
+		}
+	}
+	return writer.Close()
+}
+
+// Creates an archive of the given format writing to "w".
+func newFrameArchive(w io.Writer, format ArchiveFormat) (*frameArchive, error) {
+	archive := &frameArchive{modified: time.Now()}
+	switch format {
+	case ArchiveTar:
+		archive.tar = tar.NewWriter(w)
+	case ArchiveZip:
+		archive.zip = zip.NewWriter(w)
+	default:
+		return nil, fmt.Errorf("vidio: unknown archive format: %d", format)
+	}
+	return archive, nil
+}
+
+// Adds an entry holding the given data.
+func (archive *frameArchive) add(name string, data []byte) error {
+	if archive.tar != nil {
+		header := &tar.Header{Name: name, Mode: 0644, Size: int64(len(data)), ModTime: archive.modified}
+		if err := archive.tar.WriteHeader(header); err != nil {
+			return err
+		}
+		_, err := archive.tar.Write(data)
+		return err
+	}
+	entry, err := archive.zip.CreateHeader(&zip.FileHeader{Name: name, Method: zip.Store, Modified: archive.modified})
+	if err != nil {
+		return err
+	}
+	_, err = entry.Write(data)
+	return err
+}
+
+// Finishes the archive. Does not close the underlying writer.
+func (archive *frameArchive) Close() error {
+	if archive.tar != nil {
+		return archive.tar.Close()
+	}
+	return archive.zip.Close()
+}
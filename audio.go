@@ -0,0 +1,94 @@
+package vidio
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math"
+	"os/exec"
+)
+
+// Decodes the first audio stream of the given file into mono 32-bit float samples at the given sample rate.
+func readPCM(filename string, rate int) ([]float32, error) {
+	if err := installed("ffmpeg"); err != nil {
+		return nil, err
+	}
+
+	cmd := exec.Command(
+		"ffmpeg",
+		"-i", filename,
+		"-vn",
+		"-loglevel", logLevel(),
+		"-map", "0:a:0",
+		"-f", "f32le",
+		"-ac", "1",
+		"-ar", fmt.Sprintf("%d", rate),
+		"-",
+	)
+	logOutput(cmd, filename)
+
+	pipe, err := cmd.StdoutPipe()
+	if err != nil {
+		return nil, err
+	}
+
+	if err := cmd.Start(); err != nil {
+		return nil, err
+	}
+
+	builder := bytes.Buffer{}
+	if _, err := io.Copy(&builder, pipe); err != nil {
+		waitOutput(cmd)
+		return nil, err
+	}
+
+	if err := waitOutput(cmd); err != nil {
+		return nil, fmt.Errorf("vidio: failed to decode audio from %s: %w", filename, err)
+	}
+
+	data := builder.Bytes()
+	samples := make([]float32, len(data)/4)
+	for i := range samples {
+		samples[i] = math.Float32frombits(binary.LittleEndian.Uint32(data[i*4:]))
+	}
+
+	return samples, nil
+}
+
+// Computes the RMS energy of consecutive windows of "window" samples.
+func energy(samples []float32, window int) []float64 {
+	if window <= 0 {
+		return nil
+	}
+	rms := make([]float64, len(samples)/window)
+	for i := range rms {
+		sum := 0.0
+		for _, s := range samples[i*window : (i+1)*window] {
+			sum += float64(s) * float64(s)
+		}
+		rms[i] = math.Sqrt(sum / float64(window))
+	}
+	return rms
+}
+
+// Returns the times in seconds where the audio energy rises sharply above the previous level.
+// Onsets closer together than "gap" seconds are merged.
+func detectOnsets(samples []float32, rate int, gap float64) []float64 {
+	window := rate / 100 // 10ms analysis windows.
+	rms := energy(samples, window)
+
+	onsets := []float64{}
+	last := math.Inf(-1)
+	floor := 0.0
+	for i, e := range rms {
+		t := float64(i*window) / float64(rate)
+		if e > 0.05 && e > 4*floor && t-last >= gap {
+			onsets = append(onsets, t)
+			last = t
+		}
+		// Slowly adapting noise floor.
+		floor = 0.9*floor + 0.1*e
+	}
+	return onsets
+}
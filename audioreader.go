@@ -0,0 +1,239 @@
+package vidio
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"os/exec"
+	"strconv"
+	"strings"
+)
+
+// Reads the first audio stream of a file as interleaved 16-bit PCM chunks, e.g. to play mp3, flac or wav files.
+type AudioReader struct {
+	filename string        // Audio filename.
+	rate     int           // Sample rate in Hz.
+	channels int           // Number of interleaved channels.
+	duration float64       // Duration in seconds.
+	codec    string        // Codec of the audio stream.
+	chunk    []byte        // Samples of the last chunk read. Shorter than the buffer at the end of the file.
+	buffer   []byte        // Chunk buffer.
+	samples  int64         // Number of samples per channel read since decoding started at "start".
+	start    int64         // Number of samples per channel skipped when decoding starts, set by Seek.
+	tempo    float64       // Playback speed factor applied with atempo. 0 is normal speed.
+	sandbox  *Sandbox      // Reduced privileges for the decoding process. nil runs it normally.
+	pipe     io.ReadCloser // Stdout pipe of ffmpeg.
+	cmd      *exec.Cmd     // ffmpeg command.
+}
+
+// Length of the chunks returned by AudioReader.Read in seconds.
+const audioChunk = 0.1
+
+// Opens the first audio stream of the given file. Samples keep the sample rate and channels of the stream.
+func NewAudioReader(filename string, options ...Option) (*AudioReader, error) {
+	c := newConfig(options)
+	if err := c.checkSources(filename); err != nil {
+		return nil, err
+	}
+	if !isURL(filename) && !exists(filename) {
+		return nil, fmt.Errorf("vidio: audio file %s does not exist", filename)
+	}
+	if err := installed("ffprobe"); err != nil {
+		return nil, err
+	}
+	data, err := probeStreams(c.sandbox, filename, "a")
+	if err != nil {
+		return nil, err
+	}
+	if len(data) == 0 {
+		return nil, fmt.Errorf("vidio: no audio data found in %s", filename)
+	}
+
+	audio := &AudioReader{
+		filename: filename,
+		rate:     int(parse(data[0]["sample_rate"])),
+		channels: int(parse(data[0]["channels"])),
+		duration: parse(data[0]["duration"]),
+		codec:    data[0]["codec_name"],
+		sandbox:  c.sandbox,
+	}
+	if audio.rate <= 0 || audio.channels <= 0 {
+		return nil, fmt.Errorf("vidio: audio stream of %s has no sample rate or channels", filename)
+	}
+	audio.buffer = make([]byte, int(audioChunk*float64(audio.rate))*audio.channels*2)
+	return audio, nil
+}
+
+func (audio *AudioReader) FileName() string {
+	return audio.filename
+}
+
+// Sample rate in Hz.
+func (audio *AudioReader) SampleRate() int {
+	return audio.rate
+}
+
+// Number of interleaved channels.
+func (audio *AudioReader) Channels() int {
+	return audio.channels
+}
+
+// Duration in seconds.
+func (audio *AudioReader) Duration() float64 {
+	return audio.duration
+}
+
+func (audio *AudioReader) Codec() string {
+	return audio.codec
+}
+
+// Interleaved signed 16-bit little-endian samples of the last chunk read, a tenth of a second of audio.
+// The last chunk of a file may be shorter.
+func (audio *AudioReader) Chunk() []byte {
+	return audio.chunk
+}
+
+// Position in seconds of the end of the last chunk read.
+func (audio *AudioReader) Position() float64 {
+	return (float64(audio.start) + float64(audio.samples)*audio.speed()) / float64(audio.rate)
+}
+
+// Returns the playback speed factor.
+func (audio *AudioReader) speed() float64 {
+	if audio.tempo == 0 {
+		return 1
+	}
+	return audio.tempo
+}
+
+// Makes the next Read continue at the current position with the audio sped up or slowed down by the given
+// factor, keeping its pitch. Stops the current decode.
+func (audio *AudioReader) setTempo(factor float64) {
+	position := audio.Position()
+	audio.Close()
+	audio.start = int64(math.Round(position * float64(audio.rate)))
+	audio.samples = 0
+	audio.tempo = factor
+}
+
+// Returns the atempo filters changing the speed by the given factor. A single atempo filter
+// only supports factors from 0.5 to 2 in older ffmpeg versions, so larger changes are chained.
+func tempoFilter(factor float64) string {
+	filters := []string{}
+	for ; factor > 2; factor /= 2 {
+		filters = append(filters, "atempo=2")
+	}
+	for ; factor < 0.5; factor /= 0.5 {
+		filters = append(filters, "atempo=0.5")
+	}
+	filters = append(filters, "atempo="+strconv.FormatFloat(factor, 'f', -1, 64))
+	return strings.Join(filters, ",")
+}
+
+// Starts the ffmpeg process decoding the audio.
+func (audio *AudioReader) init() error {
+	args := []string{"-loglevel", logLevel()}
+	if audio.start > 0 {
+		args = append(args, "-ss", strconv.FormatFloat(float64(audio.start)/float64(audio.rate), 'f', 6, 64))
+	}
+	args = append(args, protocolArgs(audio.filename)...)
+	args = append(args,
+		"-i", audio.filename,
+		"-map", "0:a:0",
+		"-vn",
+	)
+	if audio.speed() != 1 {
+		args = append(args, "-af", tempoFilter(audio.speed()))
+	}
+	args = append(args,
+		"-f", "s16le",
+		"-acodec", "pcm_s16le",
+		"-ar", strconv.Itoa(audio.rate),
+		"-ac", strconv.Itoa(audio.channels),
+		"-",
+	)
+	cmd, err := audio.sandbox.command(nil, "ffmpeg", args...)
+	if err != nil {
+		return err
+	}
+	logOutput(cmd, audio.filename)
+	pipe, err := cmd.StdoutPipe()
+	if err != nil {
+		return err
+	}
+	if err := cmd.Start(); err != nil {
+		return err
+	}
+	audio.cmd, audio.pipe = cmd, pipe
+	audio.samples = 0
+	return nil
+}
+
+// Reads the next chunk of samples. If the end of the audio has been reached, returns false, otherwise true.
+func (audio *AudioReader) Read() bool {
+	if audio.cmd == nil {
+		if err := audio.init(); err != nil {
+			return false
+		}
+	}
+	n, _ := io.ReadFull(audio.pipe, audio.buffer)
+	// Only whole samples of all channels are returned.
+	n -= n % (2 * audio.channels)
+	if n == 0 {
+		audio.Close()
+		return false
+	}
+	audio.chunk = audio.buffer[:n]
+	audio.samples += int64(n / (2 * audio.channels))
+	return true
+}
+
+// Makes the next Read continue at the given time in seconds. Stops the current decode.
+func (audio *AudioReader) Seek(t float64) error {
+	if t < 0 || audio.duration > 0 && t > audio.duration {
+		return fmt.Errorf("vidio: time %g is not within the audio duration %g", t, audio.duration)
+	}
+	audio.Close()
+	audio.start = int64(t * float64(audio.rate))
+	audio.samples = 0
+	return nil
+}
+
+// Stops the ffmpeg process. The next Read starts decoding again at the last seek position.
+func (audio *AudioReader) Close() {
+	if audio.pipe != nil {
+		audio.pipe.Close()
+	}
+	if audio.cmd != nil {
+		waitOutput(audio.cmd)
+	}
+	audio.cmd, audio.pipe = nil, nil
+}
+
+// Makes the next Read start again at the beginning of the audio.
+func (audio *AudioReader) Reset() {
+	audio.Seek(0)
+}
+
+// Returns true if none of the given video streams holds moving pictures. Cover art of music files is stored
+// as an attached picture video stream.
+func audioOnly(streams []map[string]string) bool {
+	for _, stream := range streams {
+		if stream["disposition:attached_pic"] != "1" {
+			return false
+		}
+	}
+	return true
+}
+
+// Cuts the last chunk at the given time in seconds, if it extends beyond it.
+func (audio *AudioReader) truncate(t float64) {
+	// Samples read beyond "t", in samples of the chunk.
+	excess := audio.samples - int64(math.Round((t*float64(audio.rate)-float64(audio.start))/audio.speed()))
+	if excess <= 0 {
+		return
+	}
+	excess = min(excess, int64(len(audio.chunk)/(2*audio.channels)))
+	audio.chunk = audio.chunk[:len(audio.chunk)-int(excess)*2*audio.channels]
+	audio.samples -= excess
+}
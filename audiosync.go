@@ -0,0 +1,147 @@
+package vidio
+
+import (
+	"fmt"
+	"math"
+	"math/cmplx"
+	"time"
+)
+
+// Sample rate and onset envelope windows per second of the audio compared by AlignByAudio.
+// Offsets are found to the nearest 10ms.
+const (
+	alignRate = 8000
+	alignFPS  = 100
+)
+
+// Finds the offsets of recordings of the same event, e.g. camera angles without synchronized clocks, from
+// the cross-correlation of their audio onset envelopes. Returns the start of every file relative to the file
+// started first, in the order given, as expected by MultiVideo.SetOffsets. Every file must have audio.
+func AlignByAudio(files []string) ([]time.Duration, error) {
+	if len(files) == 0 {
+		return nil, fmt.Errorf("vidio: no files to align given")
+	}
+	tracks := make([][]float32, len(files))
+	for i, filename := range files {
+		if !isURL(filename) && !exists(filename) {
+			return nil, fmt.Errorf("vidio: video file %s does not exist", filename)
+		}
+		samples, err := readPCM(filename, alignRate)
+		if err != nil {
+			return nil, err
+		}
+		if len(samples) < alignRate/alignFPS*2 {
+			return nil, fmt.Errorf("vidio: %s has no audio to align", filename)
+		}
+		tracks[i] = samples
+	}
+	return alignAudio(tracks, alignRate), nil
+}
+
+// Returns the start of every track relative to the track started first.
+func alignAudio(tracks [][]float32, rate int) []time.Duration {
+	hop := rate / alignFPS
+	reference := normalizeEnvelope(onsetEnvelope(tracks[0], hop, alignFPS))
+	starts := make([]int, len(tracks))
+	for i := 1; i < len(tracks); i++ {
+		starts[i] = correlationLag(reference, normalizeEnvelope(onsetEnvelope(tracks[i], hop, alignFPS)))
+	}
+
+	first := starts[0]
+	for _, start := range starts[1:] {
+		first = min(first, start)
+	}
+	offsets := make([]time.Duration, len(tracks))
+	for i, start := range starts {
+		offsets[i] = time.Duration(start-first) * time.Second / alignFPS
+	}
+	return offsets
+}
+
+// Subtracts the mean of the envelope and scales it to unit variance, so loud and quiet recordings compare alike.
+func normalizeEnvelope(envelope []float64) []float64 {
+	mean := 0.0
+	for _, value := range envelope {
+		mean += value
+	}
+	mean /= float64(max(1, len(envelope)))
+	variance := 0.0
+	for _, value := range envelope {
+		variance += (value - mean) * (value - mean)
+	}
+	deviation := math.Sqrt(variance / float64(max(1, len(envelope))))
+	if deviation == 0 {
+		deviation = 1
+	}
+	normalized := make([]float64, len(envelope))
+	for i, value := range envelope {
+		normalized[i] = (value - mean) / deviation
+	}
+	return normalized
+}
+
+// Returns the lag maximizing the cross-correlation of the signals, i.e. the index of "reference" matching the
+// start of "signal". Negative if "signal" started before "reference". Computed with FFTs, as recordings are long.
+func correlationLag(reference, signal []float64) int {
+	n := 1
+	for n < len(reference)+len(signal) {
+		n *= 2
+	}
+	a, b := make([]complex128, n), make([]complex128, n)
+	for i, value := range reference {
+		a[i] = complex(value, 0)
+	}
+	for i, value := range signal {
+		b[i] = complex(value, 0)
+	}
+	fft(a, false)
+	fft(b, false)
+	for i := range a {
+		a[i] *= cmplx.Conj(b[i])
+	}
+	fft(a, true)
+
+	// Index k holds lag k, index n-k holds lag -k.
+	best, lag := math.Inf(-1), 0
+	for k := -(len(signal) - 1); k < len(reference); k++ {
+		index := k
+		if k < 0 {
+			index += n
+		}
+		if value := real(a[index]); value > best {
+			best, lag = value, k
+		}
+	}
+	return lag
+}
+
+// Computes the discrete Fourier transform of "values" in place, or its inverse without the 1/n scaling.
+// The length must be a power of two.
+func fft(values []complex128, inverse bool) {
+	n := len(values)
+	for i, j := 1, 0; i < n; i++ {
+		bit := n >> 1
+		for ; j&bit != 0; bit >>= 1 {
+			j ^= bit
+		}
+		j ^= bit
+		if i < j {
+			values[i], values[j] = values[j], values[i]
+		}
+	}
+	for size := 2; size <= n; size <<= 1 {
+		angle := -2 * math.Pi / float64(size)
+		if inverse {
+			angle = -angle
+		}
+		step := cmplx.Rect(1, angle)
+		for start := 0; start < n; start += size {
+			w := complex(1, 0)
+			for k := 0; k < size/2; k++ {
+				even, odd := values[start+k], values[start+k+size/2]*w
+				values[start+k], values[start+k+size/2] = even+odd, even-odd
+				w *= step
+			}
+		}
+	}
+}
@@ -0,0 +1,281 @@
+package vidio
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+)
+
+// Sample format of PCM audio data.
+type SampleFormat string
+
+const (
+	SampleS16 SampleFormat = "s16le" // Signed 16-bit little-endian integers. The default.
+	SampleF32 SampleFormat = "f32le" // 32-bit little-endian floats between -1 and 1.
+)
+
+// Number of bytes per sample of the format, or 0 if the format is not supported.
+func (format SampleFormat) Size() int {
+	switch format {
+	case SampleS16:
+		return 2
+	case SampleF32:
+		return 4
+	default:
+		return 0
+	}
+}
+
+type AudioWriter struct {
+	filename string         // Output filename.
+	rate     int            // Sample rate in Hz.
+	channels int            // Number of interleaved channels.
+	format   SampleFormat   // Sample format of the written data.
+	codec    string         // Codec to encode audio with.
+	bitrate  int            // Output audio bitrate. 0 uses the codec default.
+	pipe     io.WriteCloser // Stdin pipe of ffmpeg, or the audio pipe of the VideoWriter the audio is muxed into.
+	cmd      *exec.Cmd      // ffmpeg command. nil if the audio is muxed into a VideoWriter.
+	stderr   *tailBuffer    // End of the ffmpeg error output.
+	written  int64          // Number of bytes of samples written.
+	closed   bool           // Whether the writer was closed.
+}
+
+// Optional parameters for AudioWriter.
+type AudioOptions struct {
+	SampleRate int          // Sample rate in Hz. Default 48000.
+	Channels   int          // Number of interleaved channels. Default 2.
+	Format     SampleFormat // Sample format of the written data. Default SampleS16.
+	Codec      string       // Codec for audio. Default chosen by the file extension: aac, libopus, flac, libmp3lame or pcm_s16le.
+	Bitrate    int          // Bitrate in bits/s. 0 uses the codec default.
+}
+
+func (writer *AudioWriter) FileName() string {
+	return writer.filename
+}
+
+// Sample rate in Hz.
+func (writer *AudioWriter) SampleRate() int {
+	return writer.rate
+}
+
+func (writer *AudioWriter) Channels() int {
+	return writer.channels
+}
+
+func (writer *AudioWriter) Format() SampleFormat {
+	return writer.format
+}
+
+func (writer *AudioWriter) Codec() string {
+	return writer.codec
+}
+
+// Bitrate of audio in bits/s. 0 means the codec default.
+func (writer *AudioWriter) Bitrate() int {
+	return writer.bitrate
+}
+
+// Duration in seconds of the audio written so far.
+func (writer *AudioWriter) Duration() float64 {
+	return float64(writer.written) / float64(writer.format.Size()*writer.channels*writer.rate)
+}
+
+// Returns the default audio codec for the container of the given filename.
+func audioCodec(filename string) string {
+	switch strings.ToLower(filepath.Ext(filename)) {
+	case ".opus", ".ogg", ".webm":
+		return "libopus"
+	case ".flac":
+		return "flac"
+	case ".mp3":
+		return "libmp3lame"
+	case ".wav":
+		return "pcm_s16le"
+	default:
+		return "aac"
+	}
+}
+
+// Fills in the defaults of the given options and returns an AudioWriter for "filename" without starting ffmpeg.
+func newAudioWriter(filename string, options *AudioOptions) (*AudioWriter, error) {
+	if options == nil {
+		options = &AudioOptions{}
+	}
+
+	writer := &AudioWriter{
+		filename: filename,
+		rate:     options.SampleRate,
+		channels: options.Channels,
+		format:   options.Format,
+		codec:    options.Codec,
+		bitrate:  options.Bitrate,
+	}
+
+	if writer.rate == 0 {
+		writer.rate = 48000
+	}
+	if writer.channels == 0 {
+		writer.channels = 2
+	}
+	if writer.format == "" {
+		writer.format = SampleS16
+	}
+	if writer.codec == "" {
+		writer.codec = audioCodec(filename)
+	}
+
+	if writer.rate < 0 || writer.channels < 0 || writer.bitrate < 0 {
+		return nil, fmt.Errorf("vidio: audio options must not be negative")
+	}
+	if writer.format.Size() == 0 {
+		return nil, fmt.Errorf("vidio: unsupported sample format: %s", writer.format)
+	}
+
+	return writer, nil
+}
+
+// Returns the ffmpeg input options describing the PCM data written to the writer.
+func (writer *AudioWriter) inputArgs() []string {
+	return []string{
+		"-f", string(writer.format),
+		"-ar", fmt.Sprintf("%d", writer.rate),
+		"-ac", fmt.Sprintf("%d", writer.channels),
+	}
+}
+
+// Returns the ffmpeg output options encoding the audio.
+func (writer *AudioWriter) outputArgs() []string {
+	args := []string{"-c:a", writer.codec}
+	if writer.bitrate > 0 {
+		args = append(args, "-b:a", fmt.Sprintf("%d", writer.bitrate))
+	}
+	return args
+}
+
+// Creates a new AudioWriter which encodes PCM samples to an audio file.
+func NewAudioWriter(filename string, options *AudioOptions) (*AudioWriter, error) {
+	// Check if ffmpeg is installed on the users machine.
+	if err := installed("ffmpeg"); err != nil {
+		return nil, err
+	}
+
+	writer, err := newAudioWriter(filename, options)
+	if err != nil {
+		return nil, err
+	}
+
+	command := []string{"-y", "-loglevel", "error"}
+	command = append(command, writer.inputArgs()...)
+	command = append(command, "-i", "-")
+	command = append(command, writer.outputArgs()...)
+	if format := outputFormat(filename); format != "" {
+		command = append(command, "-f", format)
+	}
+	command = append(command, filename)
+
+	cmd := exec.Command("ffmpeg", command...)
+	writer.cmd = cmd
+	writer.stderr = &tailBuffer{size: 4096}
+	cmd.Stderr = writer.stderr
+
+	pipe, err := cmd.StdinPipe()
+	if err != nil {
+		return nil, err
+	}
+	writer.pipe = pipe
+
+	if err := cmd.Start(); err != nil {
+		return nil, err
+	}
+
+	return writer, nil
+}
+
+// Creates an AudioWriter whose samples are read by ffmpeg from an extra pipe, for muxing into a VideoWriter.
+// Returns the read end of the pipe, which has to be passed to the ffmpeg process.
+func newMuxedAudioWriter(filename string, options *AudioOptions) (*AudioWriter, *os.File, error) {
+	// Extra file descriptors are not inherited by child processes on Windows.
+	if runtime.GOOS == "windows" {
+		return nil, nil, fmt.Errorf("vidio: muxing audio into a video is not supported on windows")
+	}
+	writer, err := newAudioWriter(filename, options)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	reader, pipe, err := os.Pipe()
+	if err != nil {
+		return nil, nil, err
+	}
+	writer.pipe = pipe
+	return writer, reader, nil
+}
+
+// Writes interleaved PCM samples in the format of the writer.
+func (writer *AudioWriter) Write(data []byte) error {
+	if writer.closed {
+		return fmt.Errorf("vidio: audio writer for %s is closed", writer.filename)
+	}
+	if len(data)%(writer.format.Size()*writer.channels) != 0 {
+		return fmt.Errorf("vidio: audio data size %d is not a multiple of the sample frame size %d", len(data), writer.format.Size()*writer.channels)
+	}
+
+	total := 0
+	for total < len(data) {
+		n, err := writer.pipe.Write(data[total:])
+		if err != nil {
+			return fmt.Errorf("vidio: failed to write audio to %s: %w", writer.filename, err)
+		}
+		total += n
+	}
+	writer.written += int64(total)
+
+	return nil
+}
+
+// Writes interleaved 16-bit samples. The writer format must be SampleS16.
+func (writer *AudioWriter) WriteInt16(samples []int16) error {
+	if writer.format != SampleS16 {
+		return fmt.Errorf("vidio: audio writer format is %s, not %s", writer.format, SampleS16)
+	}
+	data := make([]byte, len(samples)*2)
+	for i, sample := range samples {
+		binary.LittleEndian.PutUint16(data[i*2:], uint16(sample))
+	}
+	return writer.Write(data)
+}
+
+// Writes interleaved float samples between -1 and 1. The writer format must be SampleF32.
+func (writer *AudioWriter) WriteFloat32(samples []float32) error {
+	if writer.format != SampleF32 {
+		return fmt.Errorf("vidio: audio writer format is %s, not %s", writer.format, SampleF32)
+	}
+	data := make([]byte, len(samples)*4)
+	for i, sample := range samples {
+		binary.LittleEndian.PutUint32(data[i*4:], math.Float32bits(sample))
+	}
+	return writer.Write(data)
+}
+
+// Closes the pipe and, for standalone writers, waits for ffmpeg to finish the file.
+// Audio muxed into a VideoWriter is finished when the VideoWriter is closed.
+func (writer *AudioWriter) Close() error {
+	if writer.closed {
+		return nil
+	}
+	writer.closed = true
+	writer.pipe.Close()
+	if writer.cmd == nil {
+		return nil
+	}
+	if err := writer.cmd.Wait(); err != nil {
+		return fmt.Errorf("vidio: ffmpeg failed to write %s: %s", writer.filename, strings.TrimSpace(writer.stderr.String()))
+	}
+	return nil
+}
@@ -0,0 +1,108 @@
+package vidio
+
+import "math"
+
+const (
+	beatRate = 22050 // Sample rate audio is decoded at for beat detection.
+	beatHop  = 256   // Samples per onset envelope value, about 11.6ms.
+)
+
+// Detects the beats of the music in the first audio stream and returns their times in seconds, e.g. to cut
+// slideshows and montages on the beat. The tempo is estimated from the periodicity of note onsets, assuming
+// a steady tempo between 60 and 200 BPM, and each beat is aligned to the strongest onset near it.
+// Returns no beats for silence.
+func DetectBeats(filename string) ([]float64, error) {
+	samples, err := readPCM(filename, beatRate)
+	if err != nil {
+		return nil, err
+	}
+	return detectBeats(samples, beatRate), nil
+}
+
+// Returns the beat times in seconds of the given mono samples.
+func detectBeats(samples []float32, rate int) []float64 {
+	fps := float64(rate) / beatHop
+	envelope := onsetEnvelope(samples, beatHop, fps)
+	period := beatPeriod(envelope, fps)
+	if period == 0 {
+		return []float64{}
+	}
+
+	beats := []float64{}
+	for _, index := range trackBeats(envelope, period) {
+		beats = append(beats, float64(index)/fps)
+	}
+	return beats
+}
+
+// Returns the onset strength per window of "hop" samples, "fps" windows per second: the rise in log energy
+// over the previous window, minus its local average so steady loudness changes do not count as onsets.
+func onsetEnvelope(samples []float32, hop int, fps float64) []float64 {
+	rms := energy(samples, hop)
+	flux := make([]float64, len(rms))
+	for i := 1; i < len(rms); i++ {
+		flux[i] = max(0, math.Log1p(100*rms[i])-math.Log1p(100*rms[i-1]))
+	}
+
+	// Moving average over about a quarter second.
+	radius := max(1, int(0.125*fps))
+	envelope := make([]float64, len(flux))
+	for i := range flux {
+		sum, count := 0.0, 0
+		for j := max(0, i-radius); j <= min(len(flux)-1, i+radius); j++ {
+			sum += flux[j]
+			count++
+		}
+		envelope[i] = max(0, flux[i]-sum/float64(count))
+	}
+	return envelope
+}
+
+// Estimates the beat period in envelope values from the autocorrelation of the onset envelope,
+// weighted towards 120 BPM to prefer the natural tempo over its halves and doubles. 0 if there are no onsets.
+func beatPeriod(envelope []float64, fps float64) int {
+	shortest, longest := int(fps*60/200), int(fps*60/60)
+	preferred := fps * 60 / 120
+	best, score := 0, 0.0
+	for lag := max(1, shortest); lag <= longest && lag < len(envelope); lag++ {
+		sum := 0.0
+		for i := 0; i+lag < len(envelope); i++ {
+			sum += envelope[i] * envelope[i+lag]
+		}
+		octaves := math.Log2(float64(lag) / preferred)
+		weighted := sum / float64(len(envelope)-lag) * math.Exp(-0.5*octaves*octaves)
+		if weighted > score {
+			best, score = lag, weighted
+		}
+	}
+	return best
+}
+
+// Returns the envelope indexes of the beats. The phase is chosen so the beats fall on the strongest onsets,
+// then every beat is moved to the strongest onset within a tenth of a period of its expected position.
+func trackBeats(envelope []float64, period int) []int {
+	phase, score := 0, -1.0
+	for offset := 0; offset < period && offset < len(envelope); offset++ {
+		sum := 0.0
+		for i := offset; i < len(envelope); i += period {
+			sum += envelope[i]
+		}
+		if sum > score {
+			phase, score = offset, sum
+		}
+	}
+
+	tolerance := max(1, period/10)
+	beats := []int{}
+	for expected := phase; expected < len(envelope); {
+		best := expected
+		for i := max(0, expected-tolerance); i <= min(len(envelope)-1, expected+tolerance); i++ {
+			if envelope[i] > envelope[best] {
+				best = i
+			}
+		}
+		beats = append(beats, best)
+		expected = best + period
+	}
+	return beats
+}
@@ -0,0 +1,68 @@
+package vidio
+
+import (
+	"fmt"
+	"image"
+	"strconv"
+
+	"github.com/benitogf/Vidio/ffcmd"
+)
+
+// Returns "count" frames spaced "interval" seconds apart and centered on the time "t" in seconds,
+// e.g. to pick the best frame of a face around a moment. The frames are decoded with a single seek
+// and read instead of one ffmpeg run per frame. Bursts near the start of the video begin at 0,
+// and fewer frames are returned near the end of the video. Frames are rotated and stretched to their
+// display aspect ratio unless WithoutAutoOrient is given. Frame i is at time max(0, t-interval*(count-1)/2) + i*interval.
+func Burst(filename string, t float64, count int, interval float64, options ...Option) ([]*image.RGBA, error) {
+	if count <= 0 || interval <= 0 {
+		return nil, fmt.Errorf("vidio: burst needs a positive frame count and interval")
+	}
+
+	video, err := NewVideo(filename, options...)
+	if err != nil {
+		return nil, err
+	}
+	defer video.Close()
+
+	format := func(value float64) string {
+		return strconv.FormatFloat(value, 'f', -1, 64)
+	}
+	c := newConfig(options)
+	input, orient, width, height := video.orientation(c)
+	// The decoding filter sets the frame size the orientation is based on.
+	filter := "fps=fps=" + format(1/interval)
+	if video.filter != "" {
+		filter = video.filter + "," + filter
+	}
+	if orient != "" {
+		filter += "," + orient
+	}
+	builder := ffcmd.New().
+		Input(filename, append(input, "-ss", format(burstStart(t, count, interval)))...).
+		Map(fmt.Sprintf("0:v:%d", video.Stream())).
+		Output("-",
+			"-vf", filter,
+			"-frames:v", strconv.Itoa(count),
+			"-f", "rawvideo",
+			"-pix_fmt", "rgba",
+		)
+
+	data, err := c.output(builder)
+	if err != nil {
+		return nil, err
+	}
+
+	size := width * height * 4
+	frames := []*image.RGBA{}
+	for offset := 0; size > 0 && offset+size <= len(data); offset += size {
+		frame := image.NewRGBA(image.Rect(0, 0, width, height))
+		copy(frame.Pix, data[offset:offset+size])
+		frames = append(frames, frame)
+	}
+	return frames, nil
+}
+
+// Returns the time in seconds of the first frame of a burst centered on "t".
+func burstStart(t float64, count int, interval float64) float64 {
+	return max(0, t-interval*float64(count-1)/2)
+}
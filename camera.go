@@ -11,6 +11,7 @@ import (
 	"runtime"
 	"strings"
 	"syscall"
+	"time"
 )
 
 type Camera struct {
@@ -23,6 +24,10 @@ type Camera struct {
 	framebuffer []byte        // Raw frame data.
 	pipe        io.ReadCloser // Stdout pipe for ffmpeg process streaming webcam.
 	cmd         *exec.Cmd     // ffmpeg command.
+	clock       frameClock    // Frame delivery statistics.
+	preroll     *PreRoll      // Circular buffer of the most recent frames.
+	input       []string      // ffmpeg input options including "-i" for non device sources, e.g. IP cameras.
+	pixfmt      PixelFormat   // Pixel format of decoded frames. Default rgba.
 }
 
 // Camera device name.
@@ -52,10 +57,26 @@ func (camera *Camera) Codec() string {
 	return camera.codec
 }
 
+// Pixel format of decoded frames.
+func (camera *Camera) PixelFormat() PixelFormat {
+	return camera.pixfmt
+}
+
+// Number of bytes per row of a frame. Rows are tightly packed, so this is Width()*Depth().
+func (camera *Camera) Stride() int {
+	return camera.width * camera.depth
+}
+
 func (camera *Camera) FrameBuffer() []byte {
 	return camera.framebuffer
 }
 
+// Returns a snapshot of the frame delivery statistics, such as the estimated
+// capture to delivery latency and the number of frames queued in the pipe.
+func (camera *Camera) Stats() Stats {
+	return camera.clock.stats()
+}
+
 func (camera *Camera) SetFrameBuffer(buffer []byte) error {
 	size := camera.width * camera.height * camera.depth
 	if len(buffer) < size {
@@ -65,8 +86,31 @@ func (camera *Camera) SetFrameBuffer(buffer []byte) error {
 	return nil
 }
 
+// Keeps the last "seconds" of frames read from the camera in memory,
+// so they can be written out with DumpPreRoll when an event is triggered.
+func (camera *Camera) EnablePreRoll(seconds float64) error {
+	if camera.pixfmt != PixelRGBA {
+		return fmt.Errorf("vidio: pre-roll requires rgba frames, camera uses %s", camera.pixfmt)
+	}
+	preroll, err := NewPreRoll(camera.width, camera.height, camera.fps, seconds)
+	if err != nil {
+		return err
+	}
+	camera.preroll = preroll
+	return nil
+}
+
+// Writes the pre-roll frames to the given output file and returns the open VideoWriter,
+// so the frames following the trigger can be appended to the same recording.
+func (camera *Camera) DumpPreRoll(output string, options *Options) (*VideoWriter, error) {
+	if camera.preroll == nil {
+		return nil, fmt.Errorf("vidio: pre-roll is not enabled for camera %s", camera.name)
+	}
+	return camera.preroll.DumpPreRoll(output, options)
+}
+
 // Creates a new camera struct that can read from the device with the given stream index.
-func NewCamera(stream int) (*Camera, error) {
+func NewCamera(stream int, options ...Option) (*Camera, error) {
 	// Check if ffmpeg is installed on the users machine.
 	if err := installed("ffmpeg"); err != nil {
 		return nil, err
@@ -93,8 +137,13 @@ func NewCamera(stream int) (*Camera, error) {
 		return nil, fmt.Errorf("vidio: unsupported OS: %s", runtime.GOOS)
 	}
 
-	camera := &Camera{name: device, depth: 4}
-	if err := camera.getCameraData(device); err != nil {
+	pixfmt, err := newConfig(options).pixelFormat()
+	if err != nil {
+		return nil, err
+	}
+
+	camera := &Camera{name: device, depth: pixfmt.Depth(), pixfmt: pixfmt}
+	if _, err := camera.getCameraData(); err != nil {
 		return nil, err
 	}
 
@@ -143,32 +192,45 @@ func (camera *Camera) parseWebcamData(buffer string) {
 	}
 }
 
+// Returns the ffmpeg input options used to open the camera.
+func (camera *Camera) inputArgs() ([]string, error) {
+	if camera.input != nil {
+		return camera.input, nil
+	}
+
+	webcamDeviceName, err := webcam()
+	if err != nil {
+		return nil, err
+	}
+
+	return []string{"-f", webcamDeviceName, "-i", camera.name}, nil
+}
+
 // Get camera meta data such as width, height, fps and codec.
-func (camera *Camera) getCameraData(device string) error {
+// Returns the ffmpeg output the data was parsed from.
+func (camera *Camera) getCameraData() (string, error) {
 	// Run command to get camera data.
 	// Webcam will turn on and then off in quick succession.
-	webcamDeviceName, err := webcam()
+	input, err := camera.inputArgs()
 	if err != nil {
-		return err
+		return "", err
 	}
 
 	cmd := exec.Command(
 		"ffmpeg",
-		"-hide_banner",
-		"-f", webcamDeviceName,
-		"-i", device,
+		append([]string{"-hide_banner"}, input...)...,
 	)
 
 	// The command will fail since we do not give a file to write to, therefore
 	// it will write the meta data to Stderr.
 	pipe, err := cmd.StderrPipe()
 	if err != nil {
-		return err
+		return "", err
 	}
 
 	// Start the command.
 	if err := cmd.Start(); err != nil {
-		return err
+		return "", err
 	}
 
 	// Read ffmpeg output from Stdout.
@@ -186,7 +248,7 @@ func (camera *Camera) getCameraData(device string) error {
 	cmd.Wait()
 
 	camera.parseWebcamData(builder.String())
-	return nil
+	return builder.String(), nil
 }
 
 // Once the user calls Read() for the first time on a Camera struct,
@@ -195,23 +257,26 @@ func (camera *Camera) init() error {
 	// If user exits with Ctrl+C, stop ffmpeg process.
 	camera.cleanup()
 
-	webcamDeviceName, err := webcam()
+	input, err := camera.inputArgs()
 	if err != nil {
 		return err
 	}
 
 	// Use ffmpeg to pipe webcam to stdout.
-	cmd := exec.Command(
-		"ffmpeg",
+	command := []string{
 		"-hide_banner",
-		"-loglevel", "quiet",
-		"-f", webcamDeviceName,
-		"-i", camera.name,
+		"-loglevel", logLevel(),
+	}
+	command = append(command, input...)
+	command = append(
+		command,
 		"-f", "image2pipe",
-		"-pix_fmt", "rgba",
+		"-pix_fmt", string(camera.pixfmt),
 		"-vcodec", "rawvideo",
 		"-",
 	)
+	cmd := exec.Command("ffmpeg", command...)
+	logOutput(cmd, camera.name)
 
 	camera.cmd = cmd
 	pipe, err := cmd.StdoutPipe()
@@ -228,6 +293,10 @@ func (camera *Camera) init() error {
 		camera.framebuffer = make([]byte, camera.width*camera.height*camera.depth)
 	}
 
+	camera.clock = frameClock{fps: camera.fps}
+	// A larger pipe buffer lets ffmpeg run ahead by a few frames instead of blocking on every write.
+	setPipeSize(pipe, pipeFrames*camera.width*camera.height*camera.depth)
+
 	return nil
 }
 
@@ -240,10 +309,15 @@ func (camera *Camera) Read() bool {
 		}
 	}
 
+	start := time.Now()
 	if _, err := io.ReadFull(camera.pipe, camera.framebuffer); err != nil {
 		camera.Close()
 		return false
 	}
+	camera.clock.tick(camera.pipe, camera.width*camera.height*camera.depth, time.Since(start))
+	if camera.preroll != nil {
+		camera.preroll.Push(camera.framebuffer)
+	}
 
 	return true
 }
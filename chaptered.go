@@ -0,0 +1,182 @@
+package vidio
+
+import (
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/benitogf/Vidio/ffcmd"
+)
+
+var (
+	// GoPro HERO5 and later: "GH" (AVC), "GX" (HEVC) or "GL" (low resolution proxy),
+	// two digit chapter and four digit file number, e.g. GH010042.MP4, GH020042.MP4.
+	goproChapter = regexp.MustCompile(`(?i)^(G[HXL])(\d{2})(\d{4})(\.\w+)$`)
+	// GoPro HERO4 and earlier: the first chapter is GOPR0042.MP4, the following ones GP010042.MP4, GP020042.MP4.
+	goproLegacyFirst   = regexp.MustCompile(`(?i)^GOPR(\d{4})(\.\w+)$`)
+	goproLegacyChapter = regexp.MustCompile(`(?i)^GP(\d{2})(\d{4})(\.\w+)$`)
+	// DJI: consecutively numbered files, optionally with a per-file timestamp and suffix,
+	// e.g. DJI_0042.MP4 or DJI_20240501123000_0042_D.MP4.
+	djiChapter = regexp.MustCompile(`(?i)^(DJI_)(?:\d{14}_)?(\d{4})((?:_[A-Z]+)?\.\w+)$`)
+)
+
+// Size from which a DJI file is considered split by the camera because of the FAT32 file size limit.
+const djiSplitSize = 3_500_000_000
+
+// Returns the files of the chaptered recording "path" belongs to, in playback order. Cameras split long
+// recordings into chapters of a few gigabytes. GoPro chapters are recognized by their names. DJI files are
+// only numbered consecutively, so they are grouped while the earlier file reached the split size.
+// Any chapter of a set can be given. Files not matching a known scheme are returned on their own.
+func DetectChapteredSet(path string) ([]string, error) {
+	if _, err := os.Stat(path); err != nil {
+		return nil, err
+	}
+	dir, name := filepath.Dir(path), filepath.Base(path)
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	names := make([]string, 0, len(entries))
+	for _, entry := range entries {
+		if entry.Type().IsRegular() {
+			names = append(names, entry.Name())
+		}
+	}
+
+	var chapters []string
+	switch {
+	case goproChapter.MatchString(name):
+		chapters = goproChapters(name, names)
+	case goproLegacyFirst.MatchString(name) || goproLegacyChapter.MatchString(name):
+		chapters = goproLegacyChapters(name, names)
+	case djiChapter.MatchString(name):
+		chapters = djiChapters(dir, name, names)
+	default:
+		chapters = []string{name}
+	}
+
+	files := make([]string, len(chapters))
+	for i, chapter := range chapters {
+		files[i] = filepath.Join(dir, chapter)
+	}
+	return files, nil
+}
+
+// Returns the names of the GoPro chapters with the same prefix, file number and extension as "name".
+func goproChapters(name string, names []string) []string {
+	match := goproChapter.FindStringSubmatch(name)
+	chapters := map[int]string{}
+	for _, candidate := range names {
+		other := goproChapter.FindStringSubmatch(candidate)
+		if other != nil && strings.EqualFold(other[1], match[1]) && other[3] == match[3] && strings.EqualFold(other[4], match[4]) {
+			chapter, _ := strconv.Atoi(other[2])
+			chapters[chapter] = candidate
+		}
+	}
+	return sortedChapters(chapters)
+}
+
+// Returns the names of the legacy GoPro chapters with the same file number and extension as "name".
+func goproLegacyChapters(name string, names []string) []string {
+	number, extension := "", ""
+	if match := goproLegacyFirst.FindStringSubmatch(name); match != nil {
+		number, extension = match[1], match[2]
+	} else {
+		match := goproLegacyChapter.FindStringSubmatch(name)
+		number, extension = match[2], match[3]
+	}
+
+	chapters := map[int]string{}
+	for _, candidate := range names {
+		if match := goproLegacyFirst.FindStringSubmatch(candidate); match != nil && match[1] == number && strings.EqualFold(match[2], extension) {
+			chapters[0] = candidate
+		}
+		if match := goproLegacyChapter.FindStringSubmatch(candidate); match != nil && match[2] == number && strings.EqualFold(match[3], extension) {
+			chapter, _ := strconv.Atoi(match[1])
+			chapters[chapter] = candidate
+		}
+	}
+	return sortedChapters(chapters)
+}
+
+// Returns the names of the consecutively numbered DJI files recorded together with "name".
+func djiChapters(dir, name string, names []string) []string {
+	match := djiChapter.FindStringSubmatch(name)
+	files := map[int]string{}
+	for _, candidate := range names {
+		other := djiChapter.FindStringSubmatch(candidate)
+		if other != nil && strings.EqualFold(other[1], match[1]) && strings.EqualFold(other[3], match[3]) {
+			number, _ := strconv.Atoi(other[2])
+			files[number] = candidate
+		}
+	}
+	split := func(number int) bool {
+		info, err := os.Stat(filepath.Join(dir, files[number]))
+		return err == nil && info.Size() >= djiSplitSize
+	}
+
+	first, _ := strconv.Atoi(match[2])
+	for files[first-1] != "" && split(first-1) {
+		first--
+	}
+	chapters := []string{}
+	for number := first; files[number] != ""; number++ {
+		chapters = append(chapters, files[number])
+		if !split(number) {
+			break
+		}
+	}
+	return chapters
+}
+
+// Returns the chapter names ordered by chapter number.
+func sortedChapters(chapters map[int]string) []string {
+	numbers := make([]int, 0, len(chapters))
+	for number := range chapters {
+		numbers = append(numbers, number)
+	}
+	sort.Ints(numbers)
+	names := make([]string, len(numbers))
+	for i, number := range numbers {
+		names[i] = chapters[number]
+	}
+	return names
+}
+
+// Detects the chaptered recording "path" belongs to and joins its chapters into "output" without
+// re-encoding. Only video and audio are copied; data tracks such as GoPro telemetry are dropped.
+func JoinChapters(path, output string, options ...Option) error {
+	c := newConfig(options)
+	// The chapters are read through a generated concat list, so they are checked here.
+	if err := c.checkSources(path); err != nil {
+		return err
+	}
+	files, err := DetectChapteredSet(path)
+	if err != nil {
+		return err
+	}
+
+	entries := make([]concatEntry, len(files))
+	for i, filename := range files {
+		entries[i] = concatEntry{filename: filename}
+	}
+	list, err := writeConcatList(entries)
+	if err != nil {
+		return err
+	}
+	c.generated = append(c.generated, list)
+	if !c.dryRun() {
+		defer os.Remove(list)
+	}
+
+	builder := ffcmd.New().
+		Global("-y").
+		Input(list, concatArgs...).
+		Map("0:v", "0:a?").
+		Output(output, "-c", "copy")
+
+	return c.run(builder)
+}
@@ -0,0 +1,216 @@
+package vidio
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/benitogf/Vidio/ffcmd"
+)
+
+// Scene change score above which a frame counts as a shot boundary. Between 0 and 1.
+const sceneThreshold = 0.3
+
+// A chapter of a video.
+type Chapter struct {
+	Start float64 // Start time in seconds.
+	End   float64 // End time in seconds.
+	Title string  // Chapter title.
+}
+
+// A detected shot boundary.
+type sceneCut struct {
+	time  float64 // Time in seconds of the first frame of the new shot.
+	score float64 // Scene change score between 0 and 1.
+}
+
+// Detects shot boundaries in the first video stream with the ffmpeg scene change score.
+func detectScenes(filename string, threshold float64, options ...Option) ([]sceneCut, error) {
+	builder := ffcmd.New().
+		Input(filename).
+		Map("0:v:0").
+		Output("-", "-vf", fmt.Sprintf("select='gt(scene,%g)',metadata=print:file=-", threshold), "-an", "-f", "null")
+
+	output, err := newConfig(options).output(builder)
+	if err != nil {
+		return nil, err
+	}
+	return parseScenes(string(output)), nil
+}
+
+// Parses the output of the metadata filter, which prints lines like
+// "frame:3    pts:120     pts_time:4.8" followed by "lavfi.scene_score=0.52" for every selected frame.
+func parseScenes(output string) []sceneCut {
+	cuts := []sceneCut{}
+	for _, line := range strings.Split(output, "\n") {
+		line = strings.TrimSpace(line)
+		if index := strings.Index(line, "pts_time:"); index >= 0 {
+			fields := strings.Fields(line[index+len("pts_time:"):])
+			if len(fields) == 0 {
+				continue
+			}
+			if t, err := strconv.ParseFloat(fields[0], 64); err == nil {
+				cuts = append(cuts, sceneCut{time: t})
+			}
+		} else if value, ok := strings.CutPrefix(line, "lavfi.scene_score="); ok && len(cuts) > 0 {
+			if score, err := strconv.ParseFloat(value, 64); err == nil {
+				cuts[len(cuts)-1].score = score
+			}
+		}
+	}
+	return cuts
+}
+
+// Splits a video into chapters at shot boundaries. Chapters are at least "minLen" seconds long:
+// within each stretch of "minLen" seconds after a chapter starts, no cut is made, and the strongest
+// shot boundary in the following stretch starts the next chapter. Chapters are titled "Chapter 1", "Chapter 2", ...
+func AutoChapters(filename string, minLen float64, options ...Option) ([]Chapter, error) {
+	if minLen <= 0 {
+		return nil, fmt.Errorf("vidio: minimum chapter length must be positive")
+	}
+
+	video, err := NewVideo(filename, options...)
+	if err != nil {
+		return nil, err
+	}
+	video.Close()
+
+	cuts, err := detectScenes(filename, sceneThreshold, options...)
+	if err != nil {
+		return nil, err
+	}
+	// Filter timestamps include the start time of the video.
+	for i := range cuts {
+		cuts[i].time -= video.StartTime()
+	}
+	return chaptersFromCuts(cuts, video.Duration(), minLen), nil
+}
+
+// Groups shot boundaries into chapters of at least "minLen" seconds covering the whole duration.
+func chaptersFromCuts(cuts []sceneCut, duration, minLen float64) []Chapter {
+	sort.Slice(cuts, func(i, j int) bool { return cuts[i].time < cuts[j].time })
+
+	starts := []float64{0}
+	for i := 0; i < len(cuts); {
+		start := starts[len(starts)-1]
+		// Skips cuts which would make the current or the last chapter too short.
+		if cuts[i].time-start < minLen || duration-cuts[i].time < minLen {
+			i++
+			continue
+		}
+		// Picks the strongest cut within the next minLen seconds.
+		best := i
+		for j := i; j < len(cuts) && cuts[j].time-cuts[i].time < minLen; j++ {
+			if duration-cuts[j].time >= minLen && cuts[j].score > cuts[best].score {
+				best = j
+			}
+		}
+		starts = append(starts, cuts[best].time)
+		i = best + 1
+	}
+
+	chapters := make([]Chapter, len(starts))
+	for i, start := range starts {
+		end := duration
+		if i+1 < len(starts) {
+			end = starts[i+1]
+		}
+		chapters[i] = Chapter{Start: start, End: end, Title: fmt.Sprintf("Chapter %d", i+1)}
+	}
+	return chapters
+}
+
+// Escapes characters with a special meaning in ffmetadata files.
+func ffmetadataEscape(value string) string {
+	return strings.NewReplacer(
+		`\`, `\\`,
+		`=`, `\=`,
+		`;`, `\;`,
+		`#`, `\#`,
+		"\n", "\\\n",
+	).Replace(value)
+}
+
+// Writes the global tags and chapters to a temporary ffmetadata file and returns its path.
+// See https://ffmpeg.org/ffmpeg-formats.html#Metadata-2.
+func writeFFMetadata(tags map[string]string, chapters []Chapter) (string, error) {
+	builder := strings.Builder{}
+	builder.WriteString(";FFMETADATA1\n")
+	keys := make([]string, 0, len(tags))
+	for key := range tags {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		builder.WriteString(ffmetadataEscape(key) + "=" + ffmetadataEscape(tags[key]) + "\n")
+	}
+	for _, chapter := range chapters {
+		if chapter.End <= chapter.Start {
+			return "", fmt.Errorf("vidio: chapter %q ends before it starts", chapter.Title)
+		}
+		builder.WriteString("[CHAPTER]\nTIMEBASE=1/1000\n")
+		fmt.Fprintf(&builder, "START=%d\nEND=%d\n", int64(chapter.Start*1000), int64(chapter.End*1000))
+		if chapter.Title != "" {
+			builder.WriteString("title=" + ffmetadataEscape(chapter.Title) + "\n")
+		}
+	}
+
+	file, err := os.CreateTemp("", "vidio-*.ffmetadata")
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+	if _, err := file.WriteString(builder.String()); err != nil {
+		os.Remove(file.Name())
+		return "", err
+	}
+	return file.Name(), nil
+}
+
+// Copies the input to the output with the given chapters, replacing any existing chapters.
+// All streams are copied without re-encoding. The output container must support chapters, e.g. mp4 or mkv.
+func WriteChapters(input, output string, chapters []Chapter, options ...Option) error {
+	if chapters == nil {
+		chapters = []Chapter{}
+	}
+	return SetMetadata(input, output, nil, chapters, options...)
+}
+
+// Copies the input to the output with new global tags and chapters, written through an ffmetadata file.
+// All streams and their own tags are copied without re-encoding. "tags" replaces all global tags of the input
+// and "chapters" replaces all of its chapters. A nil map or slice keeps those of the input, an empty one removes them.
+// To update single tags, start from the tags of the input, e.g. from MetaData().
+func SetMetadata(input, output string, tags map[string]string, chapters []Chapter, options ...Option) error {
+	if !exists(input) {
+		return fmt.Errorf("vidio: video file %s does not exist", input)
+	}
+	metadata, err := writeFFMetadata(tags, chapters)
+	if err != nil {
+		return err
+	}
+	c := newConfig(options)
+	c.generated = append(c.generated, metadata)
+	if !c.dryRun() {
+		defer os.Remove(metadata)
+	}
+
+	args := []string{}
+	if tags != nil {
+		args = append(args, "-map_metadata", "1")
+	}
+	if chapters != nil {
+		args = append(args, "-map_chapters", "1")
+	}
+	args = append(args, "-c", "copy")
+
+	builder := ffcmd.New().
+		Global("-y").
+		Input(input).
+		Input(metadata, "-f", "ffmetadata").
+		Map("0").
+		Output(output, args...)
+
+	return c.run(builder)
+}
@@ -0,0 +1,84 @@
+package vidio
+
+import (
+	"fmt"
+	"image/color"
+	"math"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/benitogf/Vidio/ffcmd"
+)
+
+// Composites the green screen video "foreground" over "background" and writes the result to "output".
+// Pixels whose chroma is within "similarity" of "keyColor" become transparent, and "blend" softens the edge
+// over the following chroma distance. Both are between 0 and 1, e.g. 0.1 and 0.05 for an evenly lit screen.
+// The background is a video or an image, scaled to the size of the foreground. The output ends with the
+// foreground and has its audio.
+func ChromaKey(foreground, background, output string, keyColor color.Color, similarity, blend float64, options ...Option) error {
+	if similarity <= 0 || similarity > 1 || blend < 0 || blend > 1 {
+		return fmt.Errorf("vidio: chroma key similarity must be in (0, 1] and blend in [0, 1]")
+	}
+	for _, filename := range []string{foreground, background} {
+		if !isURL(filename) && !exists(filename) {
+			return fmt.Errorf("vidio: file %s does not exist", filename)
+		}
+	}
+
+	backgroundOptions := []string{}
+	switch strings.ToLower(filepath.Ext(background)) {
+	case ".png", ".jpg", ".jpeg", ".bmp", ".webp", ".tif", ".tiff":
+		backgroundOptions = append(backgroundOptions, "-loop", "1")
+	}
+
+	key := color.RGBAModel.Convert(keyColor).(color.RGBA)
+	format := func(value float64) string {
+		return strconv.FormatFloat(value, 'f', -1, 64)
+	}
+	builder := ffcmd.New().
+		Global("-y").
+		Input(foreground).
+		Input(background, backgroundOptions...).
+		Filter("[1:v][0:v]scale2ref[bg][fg]").
+		Filter(fmt.Sprintf("[fg]chromakey=0x%02X%02X%02X:%s:%s[keyed]", key.R, key.G, key.B, format(similarity), format(blend))).
+		Filter("[bg][keyed]overlay=shortest=1,format=yuv420p[v]").
+		Map("[v]", "0:a?").
+		Output(output, "-c:v", "libx264", "-crf", "18", "-c:a", "copy")
+
+	return newConfig(options).run(builder)
+}
+
+// Keys out "keyColor" from an RGBA frame in place, like the ffmpeg chromakey filter: the alpha of each pixel
+// is set from the distance of its chroma to the chroma of the key color, using "similarity" and "blend" as
+// in ChromaKey. Colors are premultiplied with the new alpha, so the frame can be composited over another
+// with FrameImage and image/draw.
+func KeyFrame(frame []byte, width, height int, keyColor color.Color, similarity, blend float64) error {
+	if len(frame) < width*height*4 {
+		return fmt.Errorf("vidio: buffer size %d is smaller than frame size %d", len(frame), width*height*4)
+	}
+	key := color.RGBAModel.Convert(keyColor).(color.RGBA)
+	_, keyU, keyV := color.RGBToYCbCr(key.R, key.G, key.B)
+
+	for i := 0; i < width*height*4; i += 4 {
+		_, u, v := color.RGBToYCbCr(frame[i], frame[i+1], frame[i+2])
+		du, dv := float64(u)-float64(keyU), float64(v)-float64(keyV)
+		alpha := keyAlpha(math.Sqrt((du*du+dv*dv)/(255*255*2)), similarity, blend)
+		frame[i+3] = uint8(float64(frame[i+3])*alpha + 0.5)
+		for channel := 0; channel < 3; channel++ {
+			frame[i+channel] = uint8(float64(frame[i+channel])*alpha + 0.5)
+		}
+	}
+	return nil
+}
+
+// Returns the opacity between 0 and 1 of a pixel with the given chroma distance to the key color.
+func keyAlpha(distance, similarity, blend float64) float64 {
+	if blend > 0.0001 {
+		return min(max((distance-similarity)/blend, 0), 1)
+	}
+	if distance > similarity {
+		return 1
+	}
+	return 0
+}
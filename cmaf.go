@@ -0,0 +1,92 @@
+package vidio
+
+import (
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+)
+
+// Optional parameters for CMAF writers.
+type CMAFOptions struct {
+	Segment float64  // Segment duration in seconds. Default 4.
+	Chunk   float64  // CMAF chunk (LL-HLS partial segment) duration in seconds. Default 0.5.
+	Window  int      // Number of segments kept in the playlists. 0 keeps all segments.
+	Writer  *Options // Encoding options.
+}
+
+// Creates a VideoWriter producing low latency fMP4/CMAF segments in "dir". The writer generates a
+// DASH manifest (manifest.mpd) and LL-HLS playlists (master.m3u8) with partial segments of the
+// configured chunk duration. Keyframes are aligned to segment boundaries.
+func NewCMAFWriter(dir string, width, height int, options *CMAFOptions) (*VideoWriter, error) {
+	if options == nil {
+		options = &CMAFOptions{}
+	}
+
+	segment, chunk, err := options.durations()
+	if err != nil {
+		return nil, err
+	}
+
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, err
+	}
+
+	writerOptions := Options{}
+	if options.Writer != nil {
+		writerOptions = *options.Writer
+	}
+	writerOptions.StreamFile = ""
+	if writerOptions.Codec == "" {
+		writerOptions.Codec = "libx264"
+	}
+
+	writer, err := NewVideoWriter(filepath.Join(dir, "manifest.mpd"), width, height, &writerOptions)
+	if err != nil {
+		return nil, err
+	}
+
+	writer.output = cmafArgs(segment, chunk, writer.fps, options.Window)
+
+	return writer, nil
+}
+
+// Returns the segment and chunk durations in seconds, applying the defaults.
+func (options *CMAFOptions) durations() (float64, float64, error) {
+	segment := options.Segment
+	if segment <= 0 {
+		segment = 4
+	}
+	chunk := options.Chunk
+	if chunk <= 0 {
+		chunk = 0.5
+	}
+	if chunk > segment {
+		return 0, 0, fmt.Errorf("vidio: chunk duration %f is longer than segment duration %f", chunk, segment)
+	}
+	return segment, chunk, nil
+}
+
+// Output options of the dash muxer writing "segment" second CMAF segments of "chunk" second chunks,
+// keeping "window" segments in the playlists.
+func cmafArgs(segment, chunk, fps float64, window int) []string {
+	gop := int(math.Round(segment * fps))
+	return []string{
+		"-g", fmt.Sprintf("%d", gop),
+		"-keyint_min", fmt.Sprintf("%d", gop),
+		"-sc_threshold", "0", // No extra keyframes on scene changes, so segments stay aligned.
+		"-f", "dash",
+		"-seg_duration", fmt.Sprintf("%f", segment),
+		"-frag_type", "duration",
+		"-frag_duration", fmt.Sprintf("%f", chunk),
+		"-streaming", "1",
+		"-ldash", "1",
+		"-use_template", "1",
+		"-use_timeline", "0",
+		"-hls_playlist", "1",
+		"-lhls", "1",
+		"-window_size", fmt.Sprintf("%d", window),
+		"-init_seg_name", "init-$RepresentationID$.m4s",
+		"-media_seg_name", "chunk-$RepresentationID$-$Number%05d$.m4s",
+	}
+}
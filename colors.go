@@ -0,0 +1,155 @@
+package vidio
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"math"
+	"math/rand"
+	"sort"
+)
+
+// Number of frames sampled by DominantColors.
+const colorFrames = 8
+
+// Number of pixels sampled per frame by DominantColors.
+const colorSamples = 4096
+
+// Returns the "k" dominant colors of a video, the most common first, for theming user interfaces
+// after the video content. Pixels are sampled from frames spread across the video and clustered with k-means.
+func DominantColors(filename string, k int) ([]color.RGBA, error) {
+	if k < 1 {
+		return nil, fmt.Errorf("vidio: number of colors must be at least 1")
+	}
+
+	video, err := NewVideo(filename)
+	if err != nil {
+		return nil, err
+	}
+	defer video.Close()
+
+	frames, _, err := sampleFrames(video, colorFrames, newConfig(nil))
+	if err != nil {
+		return nil, err
+	}
+
+	samples := [][3]float64{}
+	for _, frame := range frames {
+		samples = append(samples, samplePixels(frame, colorSamples)...)
+	}
+
+	return kmeans(samples, k, 20), nil
+}
+
+// Returns up to "count" pixels of the image taken on an even grid.
+func samplePixels(img *image.RGBA, count int) [][3]float64 {
+	pixels := len(img.Pix) / 4
+	step := pixels / count
+	if step < 1 {
+		step = 1
+	}
+	samples := make([][3]float64, 0, pixels/step)
+	for i := 0; i < pixels; i += step {
+		samples = append(samples, [3]float64{float64(img.Pix[i*4]), float64(img.Pix[i*4+1]), float64(img.Pix[i*4+2])})
+	}
+	return samples
+}
+
+// Clusters the colors into "k" groups with k-means++ and returns the cluster centers
+// ordered by the number of colors in each cluster. The seeding is deterministic.
+func kmeans(samples [][3]float64, k, iterations int) []color.RGBA {
+	if len(samples) == 0 {
+		return []color.RGBA{}
+	}
+	if k > len(samples) {
+		k = len(samples)
+	}
+
+	random := rand.New(rand.NewSource(1))
+	centers := [][3]float64{samples[random.Intn(len(samples))]}
+	distances := make([]float64, len(samples))
+	for len(centers) < k {
+		// Picks the next center with a probability proportional to the squared distance to the closest center.
+		total := 0.0
+		for i, sample := range samples {
+			_, distances[i] = nearest(centers, sample)
+			total += distances[i]
+		}
+		if total == 0 {
+			break
+		}
+		target := random.Float64() * total
+		next := len(samples) - 1
+		for i, distance := range distances {
+			target -= distance
+			if target <= 0 {
+				next = i
+				break
+			}
+		}
+		centers = append(centers, samples[next])
+	}
+
+	counts := make([]int, len(centers))
+	for iteration := 0; iteration < iterations; iteration++ {
+		sums := make([][3]float64, len(centers))
+		for i := range counts {
+			counts[i] = 0
+		}
+		for _, sample := range samples {
+			c, _ := nearest(centers, sample)
+			counts[c]++
+			for j := 0; j < 3; j++ {
+				sums[c][j] += sample[j]
+			}
+		}
+		moved := false
+		for c := range centers {
+			if counts[c] == 0 {
+				continue
+			}
+			for j := 0; j < 3; j++ {
+				mean := sums[c][j] / float64(counts[c])
+				if math.Abs(mean-centers[c][j]) > 0.5 {
+					moved = true
+				}
+				centers[c][j] = mean
+			}
+		}
+		if !moved {
+			break
+		}
+	}
+
+	order := make([]int, len(centers))
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(a, b int) bool { return counts[order[a]] > counts[order[b]] })
+
+	colors := make([]color.RGBA, len(centers))
+	for i, c := range order {
+		colors[i] = color.RGBA{
+			R: uint8(math.Round(centers[c][0])),
+			G: uint8(math.Round(centers[c][1])),
+			B: uint8(math.Round(centers[c][2])),
+			A: 255,
+		}
+	}
+	return colors
+}
+
+// Returns the index of the center closest to the sample and the squared distance to it.
+func nearest(centers [][3]float64, sample [3]float64) (int, float64) {
+	best, distance := 0, math.Inf(1)
+	for i, center := range centers {
+		d := 0.0
+		for j := 0; j < 3; j++ {
+			d += (center[j] - sample[j]) * (center[j] - sample[j])
+		}
+		if d < distance {
+			best, distance = i, d
+		}
+	}
+	return best, distance
+}
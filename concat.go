@@ -0,0 +1,128 @@
+package vidio
+
+import (
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+)
+
+// A single file entry in an ffmpeg concat demuxer list.
+type concatEntry struct {
+	filename string  // Path of the file.
+	inpoint  float64 // Start offset in seconds within the file. 0 means from the start.
+	outpoint float64 // End offset in seconds within the file. 0 means until the end.
+}
+
+// Quotes the given path for use in an ffmpeg concat demuxer list.
+// Single quotes are the only character that needs escaping inside a quoted string.
+func concatQuote(path string) string {
+	return "'" + strings.ReplaceAll(path, "'", `'\''`) + "'"
+}
+
+// Input options selecting the concat demuxer. Lists contain absolute paths, which the demuxer
+// only accepts with "-safe 0". Sources are still restricted to local files by the protocol whitelist.
+var concatArgs = []string{"-f", "concat", "-safe", "0"}
+
+// Opens "list", written by NewConcatVideo, with the concat demuxer. Other sources keep the
+// demuxer's default safe mode, so a list from an untrusted source can not reference arbitrary paths.
+func withConcatList(list string) Option {
+	return func(c *config) {
+		c.concatlist = list
+		c.generated = append(c.generated, list)
+	}
+}
+
+// Input options of the source. The list created by NewConcatVideo additionally selects the concat demuxer.
+func (video *Video) inputArgs() []string {
+	if video.concatlist != "" {
+		return append(append([]string{}, concatArgs...), protocolArgs(video.filename)...)
+	}
+	return protocolArgs(video.filename)
+}
+
+// Writes an ffmpeg concat demuxer list for the given entries to a temporary file
+// and returns its path. The caller is responsible for removing the file.
+func writeConcatList(entries []concatEntry) (string, error) {
+	f, err := os.CreateTemp("", "vidio-concat-*.ffconcat")
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	builder := strings.Builder{}
+	builder.WriteString("ffconcat version 1.0\n")
+	for _, entry := range entries {
+		path, err := filepath.Abs(entry.filename)
+		if err != nil {
+			os.Remove(f.Name())
+			return "", err
+		}
+		builder.WriteString(fmt.Sprintf("file %s\n", concatQuote(path)))
+		if entry.inpoint > 0 {
+			builder.WriteString(fmt.Sprintf("inpoint %f\n", entry.inpoint))
+		}
+		if entry.outpoint > 0 {
+			builder.WriteString(fmt.Sprintf("outpoint %f\n", entry.outpoint))
+		}
+	}
+
+	if _, err := f.WriteString(builder.String()); err != nil {
+		os.Remove(f.Name())
+		return "", err
+	}
+
+	return f.Name(), nil
+}
+
+// Opens the given files as one continuous video, decoded back to back through the concat demuxer with
+// timestamps continuing across files. Meant for segmented recordings such as dashcam or action camera
+// chapters, which share their codec parameters. Duration and frame count are the sums over all files.
+// The options are passed to NewVideo. The generated list file is kept after reading to the end, so the video
+// can be reset or seeked, and removed by Close. Videos which are never closed remove it once garbage collected.
+func NewConcatVideo(files []string, options ...Option) (*Video, error) {
+	if len(files) == 0 {
+		return nil, fmt.Errorf("vidio: no files to concatenate given")
+	}
+
+	entries := make([]concatEntry, len(files))
+	duration, frames := 0.0, 0
+	for i, filename := range files {
+		if isURL(filename) {
+			return nil, fmt.Errorf("vidio: only local files can be concatenated, got %s", filename)
+		}
+		video, err := NewVideo(filename, options...)
+		if err != nil {
+			return nil, err
+		}
+		duration += video.duration
+		frames += video.frames
+		if video.frames == 0 {
+			frames += int(math.Round(video.duration * video.fps))
+		}
+		entries[i] = concatEntry{filename: filename}
+	}
+
+	list, err := writeConcatList(entries)
+	if err != nil {
+		return nil, err
+	}
+	video, err := NewVideo(list, append(options[:len(options):len(options)], withConcatList(list))...)
+	if err != nil {
+		os.Remove(list)
+		return nil, err
+	}
+	video.concatlist = list
+	// The demuxer only knows the length of the file it is reading.
+	video.duration, video.frames = duration, frames
+	if err := newConfig(options).limits.apply(video); err != nil {
+		os.Remove(list)
+		return nil, err
+	}
+	runtime.SetFinalizer(video, func(video *Video) {
+		os.Remove(video.concatlist)
+	})
+	return video, nil
+}
@@ -0,0 +1,52 @@
+package vidio
+
+import (
+	"bytes"
+	"strings"
+)
+
+// A frame the decoder reported as damaged while reading with error concealment.
+type DamagedFrame struct {
+	Frame   int    // Approximate index of the frame: the number of frames delivered when the damage was reported.
+	Message string // ffmpeg decoder error message.
+}
+
+// Makes videos keep decoding past damaged or truncated data instead of stopping. Damaged macroblocks
+// are concealed from neighbouring frames, and every decoder error is reported to "onDamage", which may be nil.
+// "onDamage" is called from a separate goroutine.
+func WithErrorConcealment(onDamage func(DamagedFrame)) Option {
+	return func(c *config) {
+		c.conceal = true
+		c.ondamage = onDamage
+	}
+}
+
+// Input options making ffmpeg ignore bitstream errors and conceal damaged macroblocks.
+var concealInputArgs = []string{"-err_detect", "ignore_err", "-ec", "guess_mvs+deblock+favor_inter"}
+
+// Output options stopping ffmpeg from giving up when many frames fail to decode.
+var concealOutputArgs = []string{"-max_error_rate", "1"}
+
+// Reports the ffmpeg error output of a video line by line as damaged frames.
+type damageReporter struct {
+	video   *Video // Video being decoded.
+	report  func(DamagedFrame)
+	partial bytes.Buffer // Incomplete last line.
+}
+
+func (reporter *damageReporter) Write(p []byte) (int, error) {
+	reporter.partial.Write(p)
+	for {
+		line, err := reporter.partial.ReadString('\n')
+		if err != nil {
+			// Keeps the incomplete line for the next write.
+			reporter.partial.Reset()
+			reporter.partial.WriteString(line)
+			break
+		}
+		if message := strings.TrimSpace(line); message != "" && reporter.report != nil {
+			reporter.report(DamagedFrame{Frame: int(reporter.video.delivered.Load()), Message: message})
+		}
+	}
+	return len(p), nil
+}
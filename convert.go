@@ -0,0 +1,143 @@
+package vidio
+
+import (
+	"encoding/binary"
+	"fmt"
+)
+
+// Pixel conversion helpers. The loops are unrolled and work on whole 32-bit words where possible,
+// which lets the compiler keep pixels in registers and is several times faster than per-channel loops.
+
+// Converts between RGBA and BGRA by swapping the R and B channels. "dst" and "src" may be the same slice.
+func SwapRB(dst, src []byte) error {
+	if len(src)%4 != 0 {
+		return fmt.Errorf("vidio: source size %d is not a multiple of 4", len(src))
+	}
+	if len(dst) < len(src) {
+		return fmt.Errorf("vidio: destination size %d is smaller than source size %d", len(dst), len(src))
+	}
+
+	i := 0
+	for ; i+16 <= len(src); i += 16 {
+		a := binary.LittleEndian.Uint32(src[i:])
+		b := binary.LittleEndian.Uint32(src[i+4:])
+		c := binary.LittleEndian.Uint32(src[i+8:])
+		d := binary.LittleEndian.Uint32(src[i+12:])
+		binary.LittleEndian.PutUint32(dst[i:], swapRB(a))
+		binary.LittleEndian.PutUint32(dst[i+4:], swapRB(b))
+		binary.LittleEndian.PutUint32(dst[i+8:], swapRB(c))
+		binary.LittleEndian.PutUint32(dst[i+12:], swapRB(d))
+	}
+	for ; i < len(src); i += 4 {
+		binary.LittleEndian.PutUint32(dst[i:], swapRB(binary.LittleEndian.Uint32(src[i:])))
+	}
+	return nil
+}
+
+// Swaps the lowest and third byte of a little-endian pixel word.
+func swapRB(v uint32) uint32 {
+	return v&0xFF00FF00 | (v>>16)&0xFF | (v&0xFF)<<16
+}
+
+// Converts 24-bit RGB pixels to 32-bit BGRA pixels with opaque alpha.
+func RGBToBGRA(dst, src []byte) error {
+	if len(src)%3 != 0 {
+		return fmt.Errorf("vidio: source size %d is not a multiple of 3", len(src))
+	}
+	if len(dst) < len(src)/3*4 {
+		return fmt.Errorf("vidio: destination size %d is smaller than %d", len(dst), len(src)/3*4)
+	}
+
+	s, d := 0, 0
+	for ; s+12 <= len(src); s, d = s+12, d+16 {
+		binary.LittleEndian.PutUint32(dst[d:], 0xFF000000|uint32(src[s])<<16|uint32(src[s+1])<<8|uint32(src[s+2]))
+		binary.LittleEndian.PutUint32(dst[d+4:], 0xFF000000|uint32(src[s+3])<<16|uint32(src[s+4])<<8|uint32(src[s+5]))
+		binary.LittleEndian.PutUint32(dst[d+8:], 0xFF000000|uint32(src[s+6])<<16|uint32(src[s+7])<<8|uint32(src[s+8]))
+		binary.LittleEndian.PutUint32(dst[d+12:], 0xFF000000|uint32(src[s+9])<<16|uint32(src[s+10])<<8|uint32(src[s+11]))
+	}
+	for ; s < len(src); s, d = s+3, d+4 {
+		binary.LittleEndian.PutUint32(dst[d:], 0xFF000000|uint32(src[s])<<16|uint32(src[s+1])<<8|uint32(src[s+2]))
+	}
+	return nil
+}
+
+// Converts 32-bit BGRA pixels to 24-bit RGB pixels, dropping alpha.
+func BGRAToRGB(dst, src []byte) error {
+	if len(src)%4 != 0 {
+		return fmt.Errorf("vidio: source size %d is not a multiple of 4", len(src))
+	}
+	if len(dst) < len(src)/4*3 {
+		return fmt.Errorf("vidio: destination size %d is smaller than %d", len(dst), len(src)/4*3)
+	}
+
+	s, d := 0, 0
+	for ; s+16 <= len(src); s, d = s+16, d+12 {
+		_ = dst[d+11] // Bounds check elimination.
+		dst[d], dst[d+1], dst[d+2] = src[s+2], src[s+1], src[s]
+		dst[d+3], dst[d+4], dst[d+5] = src[s+6], src[s+5], src[s+4]
+		dst[d+6], dst[d+7], dst[d+8] = src[s+10], src[s+9], src[s+8]
+		dst[d+9], dst[d+10], dst[d+11] = src[s+14], src[s+13], src[s+12]
+	}
+	for ; s < len(src); s, d = s+4, d+3 {
+		dst[d], dst[d+1], dst[d+2] = src[s+2], src[s+1], src[s]
+	}
+	return nil
+}
+
+// Converts planar YUV 4:2:0 (BT.601, limited range) to RGBA. "y" has width*height samples,
+// "u" and "v" have ((width+1)/2)*((height+1)/2) samples each. Uses 16-bit fixed point arithmetic
+// and computes the chroma terms once per 2x2 block.
+func YUV420ToRGBA(dst, y, u, v []byte, width, height int) error {
+	cw, ch := (width+1)/2, (height+1)/2
+	if len(y) < width*height || len(u) < cw*ch || len(v) < cw*ch {
+		return fmt.Errorf("vidio: yuv planes are too small for %dx%d", width, height)
+	}
+	if len(dst) < width*height*4 {
+		return fmt.Errorf("vidio: destination size %d is smaller than frame size %d", len(dst), width*height*4)
+	}
+
+	for row := 0; row < height; row += 2 {
+		for col := 0; col < width; col += 2 {
+			ci := (row/2)*cw + col/2
+			cb := int32(u[ci]) - 128
+			cr := int32(v[ci]) - 128
+			// Coefficients scaled by 2^16.
+			rc := 104597 * cr
+			gc := -25675*cb - 53279*cr
+			bc := 132201 * cb
+
+			yuvPixel(dst, y, width, row, col, rc, gc, bc)
+			if col+1 < width {
+				yuvPixel(dst, y, width, row, col+1, rc, gc, bc)
+			}
+			if row+1 < height {
+				yuvPixel(dst, y, width, row+1, col, rc, gc, bc)
+				if col+1 < width {
+					yuvPixel(dst, y, width, row+1, col+1, rc, gc, bc)
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// Writes a single RGBA pixel from its luma sample and the precomputed chroma terms.
+func yuvPixel(dst, y []byte, width, row, col int, rc, gc, bc int32) {
+	index := row*width + col
+	l := (int32(y[index])-16)*76309 + 1<<15 // Rounds to nearest.
+	binary.LittleEndian.PutUint32(
+		dst[index*4:],
+		0xFF000000|uint32(clamp8((l+bc)>>16))<<16|uint32(clamp8((l+gc)>>16))<<8|uint32(clamp8((l+rc)>>16)),
+	)
+}
+
+// Clamps the given value to the 0-255 range.
+func clamp8(v int32) byte {
+	if v < 0 {
+		return 0
+	}
+	if v > 255 {
+		return 255
+	}
+	return byte(v)
+}
@@ -0,0 +1,61 @@
+package vidio
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Strength of the noise reduction applied with WithDenoise.
+type DenoiseLevel string
+
+const (
+	DenoiseLight  DenoiseLevel = "light"  // Removes fine sensor grain while keeping detail.
+	DenoiseMedium DenoiseLevel = "medium" // Cleans typical low-light footage.
+	DenoiseHeavy  DenoiseLevel = "heavy"  // Strong non-local means denoising for very noisy footage. Slow.
+)
+
+// ffmpeg filters of the denoise levels. hqdn3d takes the spatial and temporal strengths of luma and chroma,
+// nlmeans the strength, patch size and search window size.
+var denoiseFilters = map[DenoiseLevel]string{
+	DenoiseLight:  "hqdn3d=2:1.5:3:2.25",
+	DenoiseMedium: "hqdn3d=4:3:6:4.5",
+	DenoiseHeavy:  "nlmeans=s=6:p=7:r=15",
+}
+
+// Makes NewVideo and EncodeLadder reduce noise with the given strength, e.g. to clean low-light camera
+// footage before analysis or encoding.
+func WithDenoise(level DenoiseLevel) Option {
+	return func(c *config) {
+		c.denoise = level
+	}
+}
+
+// Returns the video filters to apply while decoding or before encoding, or an error if an option is invalid.
+func (c *config) videoFilter() (string, error) {
+	filters := []string{}
+	if c.ivtc {
+		filters = append(filters, ivtcFilter)
+	}
+	if c.denoise != "" {
+		filter, ok := denoiseFilters[c.denoise]
+		if !ok {
+			return "", fmt.Errorf("vidio: unknown denoise level: %s", c.denoise)
+		}
+		filters = append(filters, filter)
+	}
+	if c.lens != nil {
+		filter, err := c.lens.filter()
+		if err != nil {
+			return "", err
+		}
+		filters = append(filters, filter)
+	}
+	if c.view != nil {
+		filter, err := c.view.filter()
+		if err != nil {
+			return "", err
+		}
+		filters = append(filters, filter)
+	}
+	return strings.Join(filters, ","), nil
+}
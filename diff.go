@@ -0,0 +1,98 @@
+package vidio
+
+import (
+	"encoding/binary"
+	"fmt"
+	"image"
+)
+
+// Compares two RGBA frames of the given size and returns the number of pixels that differ
+// and the smallest rectangle containing all of them. The rectangle is empty if the frames are equal.
+func FrameDiff(a, b []byte, width, height int) (int, image.Rectangle, error) {
+	return FrameDiffThreshold(a, b, width, height, 0)
+}
+
+// Like FrameDiff, but a pixel only counts as changed if one of its R, G or B channels differs
+// by more than "threshold". A small threshold filters out sensor noise and compression artifacts
+// for motion detection.
+func FrameDiffThreshold(a, b []byte, width, height, threshold int) (int, image.Rectangle, error) {
+	size := width * height * 4
+	if width <= 0 || height <= 0 || len(a) < size || len(b) < size {
+		return 0, image.Rectangle{}, fmt.Errorf("vidio: buffers are smaller than frame size %d", size)
+	}
+
+	changed := 0
+	minX, minY, maxX, maxY := width, height, -1, -1
+	for y := 0; y < height; y++ {
+		row := y * width * 4
+		x := 0
+		for x < width {
+			i := row + x*4
+			// Skips two identical pixels at once.
+			if x+1 < width && binary.LittleEndian.Uint64(a[i:]) == binary.LittleEndian.Uint64(b[i:]) {
+				x += 2
+				continue
+			}
+			if pixelChanged(a[i:i+3], b[i:i+3], threshold) {
+				changed++
+				minX, maxX = min(minX, x), max(maxX, x)
+				minY, maxY = min(minY, y), max(maxY, y)
+			}
+			x++
+		}
+	}
+
+	if changed == 0 {
+		return 0, image.Rectangle{}, nil
+	}
+	return changed, image.Rect(minX, minY, maxX+1, maxY+1), nil
+}
+
+// Reports whether any of the R, G, B channels differ by more than "threshold".
+func pixelChanged(a, b []byte, threshold int) bool {
+	for c := 0; c < 3; c++ {
+		d := int(a[c]) - int(b[c])
+		if d > threshold || -d > threshold {
+			return true
+		}
+	}
+	return false
+}
+
+// Copies the pixels of "rect" out of an RGBA frame of the given width into a tightly packed buffer.
+// Together with FrameDiff and ApplyRegion this allows sending only the changed part of a frame.
+func ExtractRegion(frame []byte, width int, rect image.Rectangle) ([]byte, error) {
+	if err := checkRegion(frame, width, rect); err != nil {
+		return nil, err
+	}
+	data := make([]byte, 0, rect.Dx()*rect.Dy()*4)
+	for y := rect.Min.Y; y < rect.Max.Y; y++ {
+		start := (y*width + rect.Min.X) * 4
+		data = append(data, frame[start:start+rect.Dx()*4]...)
+	}
+	return data, nil
+}
+
+// Copies a tightly packed region produced by ExtractRegion back into an RGBA frame of the given width.
+func ApplyRegion(frame []byte, width int, rect image.Rectangle, data []byte) error {
+	if err := checkRegion(frame, width, rect); err != nil {
+		return err
+	}
+	if len(data) < rect.Dx()*rect.Dy()*4 {
+		return fmt.Errorf("vidio: region data size %d is smaller than region size %d", len(data), rect.Dx()*rect.Dy()*4)
+	}
+	rowsize := rect.Dx() * 4
+	for y := rect.Min.Y; y < rect.Max.Y; y++ {
+		start := (y*width + rect.Min.X) * 4
+		copy(frame[start:start+rowsize], data[(y-rect.Min.Y)*rowsize:])
+	}
+	return nil
+}
+
+// Checks that "rect" lies within an RGBA frame of the given width.
+func checkRegion(frame []byte, width int, rect image.Rectangle) error {
+	if width <= 0 || rect.Min.X < 0 || rect.Min.Y < 0 || rect.Max.X > width || rect.Max.Y*width*4 > len(frame) {
+		return fmt.Errorf("vidio: region %v is outside of the frame", rect)
+	}
+	return nil
+}
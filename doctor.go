@@ -0,0 +1,150 @@
+package vidio
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+)
+
+// Encoders and decoders checked by Doctor, the ones used by the operations of this package.
+var (
+	doctorEncoders = []string{"libx264", "libx265", "libvpx-vp9", "libaom-av1", "libsvtav1", "prores_ks", "aac", "libopus", "pcm_s16le", "png", "mjpeg"}
+	doctorDecoders = []string{"h264", "hevc", "vp9", "av1", "prores", "mjpeg", "aac", "mp3", "opus", "pcm_s16le"}
+)
+
+// An external program found by Doctor.
+type ProgramInfo struct {
+	Path    string `json:"path"`    // Absolute path. Empty if not found.
+	Version string `json:"version"` // Version reported by "-version", e.g. "6.1.1".
+}
+
+// Machine-readable report of the environment, e.g. to expose at /healthz.
+type Diagnostics struct {
+	Healthy  bool            `json:"healthy"`  // Whether ffmpeg and ffprobe run and the temporary directory is writable.
+	FFmpeg   ProgramInfo     `json:"ffmpeg"`   // The ffmpeg program.
+	FFprobe  ProgramInfo     `json:"ffprobe"`  // The ffprobe program.
+	Encoders map[string]bool `json:"encoders"` // Availability of the encoders used by this package.
+	Decoders map[string]bool `json:"decoders"` // Availability of common decoders.
+	HWAccels []string        `json:"hwaccels"` // Hardware acceleration methods of the ffmpeg build, e.g. "cuda" or "vaapi".
+	TempDir  string          `json:"temp_dir"` // Directory of temporary files.
+	Problems []string        `json:"problems"` // Reasons the environment is not healthy, and missing codecs.
+}
+
+// Checks that ffmpeg and ffprobe are installed and which versions, the availability of the encoders and
+// decoders used by this package, the hardware acceleration methods and whether the temporary directory is
+// writable. Missing codecs are listed as problems but do not make the environment unhealthy. Returns the
+// report together with an error naming the problems if the environment is not healthy.
+func Doctor() (*Diagnostics, error) {
+	report := &Diagnostics{
+		Encoders: map[string]bool{},
+		Decoders: map[string]bool{},
+		HWAccels: []string{},
+		TempDir:  os.TempDir(),
+		Problems: []string{},
+	}
+	healthy := true
+	fail := func(problem string) {
+		healthy = false
+		report.Problems = append(report.Problems, problem)
+	}
+
+	for _, program := range []struct {
+		name string
+		info *ProgramInfo
+	}{{"ffmpeg", &report.FFmpeg}, {"ffprobe", &report.FFprobe}} {
+		path, err := exec.LookPath(program.name)
+		if err != nil {
+			fail(program.name + " is not installed")
+			continue
+		}
+		program.info.Path = path
+		output, err := exec.Command(path, "-version").Output()
+		if err != nil {
+			fail(fmt.Sprintf("%s does not run: %v", program.name, err))
+			continue
+		}
+		program.info.Version = parseVersion(string(output))
+	}
+
+	if report.FFmpeg.Version != "" {
+		for _, list := range []struct {
+			kind   string
+			wanted []string
+			found  map[string]bool
+		}{{"encoder", doctorEncoders, report.Encoders}, {"decoder", doctorDecoders, report.Decoders}} {
+			output, err := exec.Command(report.FFmpeg.Path, "-hide_banner", "-"+list.kind+"s").Output()
+			if err != nil {
+				fail(fmt.Sprintf("listing ffmpeg %ss failed: %v", list.kind, err))
+				continue
+			}
+			available := map[string]bool{}
+			for _, codec := range parseCodecList(string(output)) {
+				available[codec] = true
+			}
+			for _, codec := range list.wanted {
+				list.found[codec] = available[codec]
+				if !available[codec] {
+					report.Problems = append(report.Problems, fmt.Sprintf("%s %s is not available", list.kind, codec))
+				}
+			}
+		}
+		if output, err := exec.Command(report.FFmpeg.Path, "-hide_banner", "-hwaccels").Output(); err == nil {
+			report.HWAccels = parseHWAccels(string(output))
+		}
+	}
+
+	file, err := os.CreateTemp("", "vidio-doctor-*")
+	if err != nil {
+		fail(fmt.Sprintf("temporary directory %s is not writable: %v", report.TempDir, err))
+	} else {
+		file.Close()
+		os.Remove(file.Name())
+	}
+
+	report.Healthy = healthy
+	if !healthy {
+		return report, fmt.Errorf("vidio: environment is not healthy: %s", strings.Join(report.Problems, "; "))
+	}
+	return report, nil
+}
+
+// Parses the version from the first line of "-version", e.g. "ffmpeg version 6.1.1-3ubuntu5 Copyright ...".
+func parseVersion(output string) string {
+	line, _, _ := strings.Cut(output, "\n")
+	fields := strings.Fields(line)
+	if len(fields) < 3 || fields[1] != "version" {
+		return "unknown"
+	}
+	return fields[2]
+}
+
+// Parses the codec names of all media types from the output of "ffmpeg -encoders" or "ffmpeg -decoders".
+func parseCodecList(output string) []string {
+	codecs := []string{}
+	listing := false
+	for _, line := range strings.Split(output, "\n") {
+		fields := strings.Fields(line)
+		// The legend ends with a separator line.
+		if len(fields) == 1 && fields[0] == "------" {
+			listing = true
+			continue
+		}
+		if listing && len(fields) >= 2 && len(fields[0]) == 6 {
+			codecs = append(codecs, fields[1])
+		}
+	}
+	return codecs
+}
+
+// Parses the method names from the output of "ffmpeg -hwaccels".
+func parseHWAccels(output string) []string {
+	methods := []string{}
+	for _, line := range strings.Split(output, "\n") {
+		line = strings.TrimSpace(line)
+		if line != "" && !strings.HasSuffix(line, ":") {
+			methods = append(methods, line)
+		}
+	}
+	return methods
+}
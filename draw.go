@@ -0,0 +1,94 @@
+package vidio
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+)
+
+// Wraps a frame buffer in the given pixel format as a draw.Image without copying, so overlays can be
+// composited with the image/draw package before the frame is passed to a writer.
+// RGBA frames are returned as *image.RGBA and gray frames as *image.Gray.
+func FrameImage(frame []byte, width, height int, format PixelFormat) (draw.Image, error) {
+	depth := format.Depth()
+	if depth == 0 {
+		return nil, fmt.Errorf("vidio: unsupported pixel format: %s", format)
+	}
+	if width <= 0 || height <= 0 || len(frame) < width*height*depth {
+		return nil, fmt.Errorf("vidio: buffer size %d is smaller than frame size %d", len(frame), width*height*depth)
+	}
+
+	rect := image.Rect(0, 0, width, height)
+	pix := frame[:width*height*depth]
+	switch format {
+	case PixelRGBA:
+		return &image.RGBA{Pix: pix, Stride: width * depth, Rect: rect}, nil
+	case PixelGray:
+		return &image.Gray{Pix: pix, Stride: width, Rect: rect}, nil
+	default:
+		return &packedImage{pix: pix, stride: width * depth, rect: rect, format: format}, nil
+	}
+}
+
+// Returns the current frame of the video as a draw.Image sharing the frame buffer.
+func (video *Video) Image() (draw.Image, error) {
+	return FrameImage(video.framebuffer, video.width, video.height, video.pixfmt)
+}
+
+// Returns the current frame of the camera as a draw.Image sharing the frame buffer.
+func (camera *Camera) Image() (draw.Image, error) {
+	return FrameImage(camera.framebuffer, camera.width, camera.height, camera.pixfmt)
+}
+
+// draw.Image over a BGRA, RGB24 or BGR24 buffer.
+type packedImage struct {
+	pix    []byte          // Pixel data.
+	stride int             // Bytes per row.
+	rect   image.Rectangle // Image bounds.
+	format PixelFormat     // Layout of the pixel data.
+}
+
+func (img *packedImage) ColorModel() color.Model {
+	return color.RGBAModel
+}
+
+func (img *packedImage) Bounds() image.Rectangle {
+	return img.rect
+}
+
+func (img *packedImage) At(x, y int) color.Color {
+	if !(image.Point{x, y}.In(img.rect)) {
+		return color.RGBA{}
+	}
+	i := img.offset(x, y)
+	switch img.format {
+	case PixelBGRA:
+		return color.RGBA{img.pix[i+2], img.pix[i+1], img.pix[i], img.pix[i+3]}
+	case PixelBGR24:
+		return color.RGBA{img.pix[i+2], img.pix[i+1], img.pix[i], 255}
+	default:
+		return color.RGBA{img.pix[i], img.pix[i+1], img.pix[i+2], 255}
+	}
+}
+
+func (img *packedImage) Set(x, y int, c color.Color) {
+	if !(image.Point{x, y}.In(img.rect)) {
+		return
+	}
+	i := img.offset(x, y)
+	rgba := color.RGBAModel.Convert(c).(color.RGBA)
+	switch img.format {
+	case PixelBGRA:
+		img.pix[i], img.pix[i+1], img.pix[i+2], img.pix[i+3] = rgba.B, rgba.G, rgba.R, rgba.A
+	case PixelBGR24:
+		img.pix[i], img.pix[i+1], img.pix[i+2] = rgba.B, rgba.G, rgba.R
+	default:
+		img.pix[i], img.pix[i+1], img.pix[i+2] = rgba.R, rgba.G, rgba.B
+	}
+}
+
+// Returns the index of the first byte of the pixel at (x, y).
+func (img *packedImage) offset(x, y int) int {
+	return (y-img.rect.Min.Y)*img.stride + (x-img.rect.Min.X)*img.format.Depth()
+}
@@ -0,0 +1,91 @@
+package vidio
+
+import (
+	"sync"
+	"time"
+
+	"github.com/benitogf/Vidio/ffcmd"
+)
+
+// Kind of an Event.
+type EventType string
+
+const (
+	EventProbe    EventType = "probe"    // A source was probed successfully.
+	EventProgress EventType = "progress" // Progress update of a running ffmpeg operation.
+	EventSegment  EventType = "segment"  // A Recorder finished writing a segment file.
+	EventError    EventType = "error"    // Probing a source or running an operation failed.
+	EventLog      EventType = "log"      // A message of a decoding ffmpeg process, see SetFFmpegLogLevel.
+)
+
+// A structured notification about a stage of an operation.
+type Event struct {
+	Type     EventType // Kind of event.
+	Time     time.Time // Time the event was emitted.
+	Source   string    // Input file or URL, if known.
+	Output   string    // Output file, if known.
+	Progress *Progress // Progress update, for EventProgress.
+	Segment  *Segment  // Finished segment, for EventSegment.
+	Err      error     // Failure, for EventError.
+	Message  string    // ffmpeg message, for EventLog.
+}
+
+// Registered event handlers, guarded by eventsMu.
+var (
+	eventsMu  sync.RWMutex
+	handlers  = map[int]func(Event){}
+	handlerID int
+)
+
+// Registers a handler receiving the events of all operations, e.g. to push notifications
+// to other services. Handlers are called synchronously from the goroutine running the operation,
+// so they should return quickly. Returns a function which removes the handler.
+func OnEvent(handler func(Event)) func() {
+	eventsMu.Lock()
+	defer eventsMu.Unlock()
+	handlerID++
+	id := handlerID
+	handlers[id] = handler
+	return func() {
+		eventsMu.Lock()
+		defer eventsMu.Unlock()
+		delete(handlers, id)
+	}
+}
+
+// Returns true if any event handlers are registered.
+func listening() bool {
+	eventsMu.RLock()
+	defer eventsMu.RUnlock()
+	return len(handlers) > 0
+}
+
+// Sends the event to all registered handlers.
+func emit(event Event) {
+	eventsMu.RLock()
+	registered := make([]func(Event), 0, len(handlers))
+	for _, handler := range handlers {
+		registered = append(registered, handler)
+	}
+	eventsMu.RUnlock()
+
+	if event.Time.IsZero() {
+		event.Time = time.Now()
+	}
+	// Handlers are called without holding the lock, so they may remove themselves.
+	for _, handler := range registered {
+		handler(event)
+	}
+}
+
+// Returns the first input and output of a command for events.
+func endpoints(builder *ffcmd.Builder) (string, string) {
+	source, output := "", ""
+	if inputs := builder.InputPaths(); len(inputs) > 0 {
+		source = inputs[0]
+	}
+	if outputs := builder.Outputs(); len(outputs) > 0 {
+		output = outputs[0]
+	}
+	return source, output
+}
@@ -0,0 +1,80 @@
+package vidio
+
+// What a Tee consumer does when its buffer is full.
+type DropPolicy int
+
+const (
+	DropNone   DropPolicy = iota // The decode waits until the consumer catches up.
+	DropOldest                   // The oldest buffered frame is dropped, so the consumer always gets the latest frames.
+	DropNewest                   // The new frame is dropped, so the consumer gets frames in bursts.
+)
+
+// Buffering of a single Tee consumer.
+type TeePolicy struct {
+	Buffer int        // Number of frames buffered for the consumer. Default 1.
+	Drop   DropPolicy // What happens when the buffer is full.
+}
+
+// Starts decoding the video in the background and returns "n" channels which all receive the decoded frames,
+// so one decode can feed e.g. a recorder, a motion detector and a preview. Every consumer receives its own
+// copy of each frame, buffered according to its policy; consumers without a policy block the decode
+// when they fall behind. All channels are closed when the video ends. A blocking consumer which stops
+// receiving stalls the others, so such consumers must keep receiving until their channel is closed.
+func (video *Video) Tee(n int, policies ...TeePolicy) []<-chan []byte {
+	consumers := make([]chan []byte, n)
+	settings := make([]TeePolicy, n)
+	outputs := make([]<-chan []byte, n)
+	for i := range consumers {
+		if i < len(policies) {
+			settings[i] = policies[i]
+		}
+		if settings[i].Buffer <= 0 {
+			settings[i].Buffer = 1
+		}
+		consumers[i] = make(chan []byte, settings[i].Buffer)
+		outputs[i] = consumers[i]
+	}
+
+	go func() {
+		defer func() {
+			for _, consumer := range consumers {
+				close(consumer)
+			}
+		}()
+		for video.Read() {
+			for i, consumer := range consumers {
+				frame := make([]byte, len(video.framebuffer))
+				copy(frame, video.framebuffer)
+				deliver(consumer, frame, settings[i].Drop)
+			}
+		}
+	}()
+
+	return outputs
+}
+
+// Sends the frame to the consumer, dropping a frame according to the policy if its buffer is full.
+func deliver(consumer chan []byte, frame []byte, drop DropPolicy) {
+	switch drop {
+	case DropNewest:
+		select {
+		case consumer <- frame:
+		default:
+		}
+	case DropOldest:
+		for {
+			select {
+			case consumer <- frame:
+				return
+			default:
+			}
+			// Makes room, unless the consumer received a frame in the meantime.
+			select {
+			case <-consumer:
+			default:
+			}
+		}
+	default:
+		consumer <- frame
+	}
+}
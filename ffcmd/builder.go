@@ -0,0 +1,114 @@
+// Package ffcmd builds ffmpeg command lines from inputs, filters, stream maps and outputs.
+//
+// The resulting arguments can be run with vidio.Run, which reuses the vidio process
+// management, error output capture and the execution options such as dry runs.
+package ffcmd
+
+import "strings"
+
+// An input file and the options placed before its "-i".
+type input struct {
+	path    string
+	options []string
+}
+
+// An output file with its stream maps and options.
+type output struct {
+	path    string
+	maps    []string
+	options []string
+}
+
+// Builder assembles an ffmpeg command line. All methods return the builder so calls can be chained.
+type Builder struct {
+	global  []string // Global options placed before the inputs.
+	inputs  []input  // Input files in order.
+	filters []string // Filtergraph chains joined into one -filter_complex.
+	maps    []string // Stream maps waiting for the next output.
+	outputs []output // Output files in order.
+}
+
+// Creates a new empty Builder.
+func New() *Builder {
+	return &Builder{}
+}
+
+// Adds global options such as "-y" or "-loglevel", "error".
+func (builder *Builder) Global(args ...string) *Builder {
+	builder.global = append(builder.global, args...)
+	return builder
+}
+
+// Adds an input file. The options are placed right before its "-i", e.g. "-ss", "10" to seek the input.
+func (builder *Builder) Input(path string, options ...string) *Builder {
+	builder.inputs = append(builder.inputs, input{path: path, options: options})
+	return builder
+}
+
+// Number of inputs added so far. The next input will have this index in stream specifiers.
+func (builder *Builder) Inputs() int {
+	return len(builder.inputs)
+}
+
+// Returns the paths of the inputs added so far.
+func (builder *Builder) InputPaths() []string {
+	paths := make([]string, len(builder.inputs))
+	for i, input := range builder.inputs {
+		paths[i] = input.path
+	}
+	return paths
+}
+
+// Adds a filtergraph chain, e.g. "[0:v]scale=640:-2[small]". All chains are joined into a single -filter_complex.
+func (builder *Builder) Filter(chain string) *Builder {
+	builder.filters = append(builder.filters, chain)
+	return builder
+}
+
+// Maps the given streams, e.g. "0:a:0" or "[small]", into the next output added with Output.
+func (builder *Builder) Map(streams ...string) *Builder {
+	builder.maps = append(builder.maps, streams...)
+	return builder
+}
+
+// Adds an output file with the given options, e.g. "-c:v", "libx264". The streams mapped with Map since the
+// previous output are mapped into this output.
+func (builder *Builder) Output(path string, options ...string) *Builder {
+	builder.outputs = append(builder.outputs, output{path: path, maps: builder.maps, options: options})
+	builder.maps = nil
+	return builder
+}
+
+// Returns the paths of the outputs added so far.
+func (builder *Builder) Outputs() []string {
+	paths := make([]string, len(builder.outputs))
+	for i, output := range builder.outputs {
+		paths[i] = output.path
+	}
+	return paths
+}
+
+// Returns the ffmpeg arguments, without the program name.
+func (builder *Builder) Args() []string {
+	args := append([]string{}, builder.global...)
+	for _, input := range builder.inputs {
+		args = append(args, input.options...)
+		args = append(args, "-i", input.path)
+	}
+	if len(builder.filters) > 0 {
+		args = append(args, "-filter_complex", strings.Join(builder.filters, ";"))
+	}
+	for _, output := range builder.outputs {
+		for _, stream := range output.maps {
+			args = append(args, "-map", stream)
+		}
+		args = append(args, output.options...)
+		args = append(args, output.path)
+	}
+	return args
+}
+
+// Returns the command line as a single string for logging. Arguments are not shell escaped.
+func (builder *Builder) String() string {
+	return strings.Join(append([]string{"ffmpeg"}, builder.Args()...), " ")
+}
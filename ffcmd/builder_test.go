@@ -0,0 +1,29 @@
+package ffcmd
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestBuilderArgs(t *testing.T) {
+	builder := New().
+		Global("-y").
+		Input("input.mp4", "-ss", "10").
+		Input("logo.png").
+		Filter("[0:v][1:v]overlay=10:10[out]").
+		Map("[out]", "0:a?").
+		Output("output.mp4", "-c:a", "copy")
+
+	expected := "-y -ss 10 -i input.mp4 -i logo.png -filter_complex [0:v][1:v]overlay=10:10[out] " +
+		"-map [out] -map 0:a? -c:a copy output.mp4"
+	if actual := strings.Join(builder.Args(), " "); actual != expected {
+		t.Errorf("Expected %v, got %v", expected, actual)
+	}
+
+	if builder.Inputs() != 2 {
+		t.Errorf("Expected 2 inputs, got %d", builder.Inputs())
+	}
+	if paths := strings.Join(builder.InputPaths(), " "); paths != "input.mp4 logo.png" {
+		t.Errorf("Expected input paths input.mp4 logo.png, got %v", paths)
+	}
+}
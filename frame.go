@@ -0,0 +1,37 @@
+package vidio
+
+import (
+	"fmt"
+	"image"
+)
+
+// Returns the mean luma (BT.601) of the given RGBA frame, between 0 and 255.
+func meanLuma(frame []byte) float64 {
+	if len(frame) < 4 {
+		return 0
+	}
+	total := 0.0
+	for i := 0; i+3 < len(frame); i += 4 {
+		total += 0.299*float64(frame[i]) + 0.587*float64(frame[i+1]) + 0.114*float64(frame[i+2])
+	}
+	return total / float64(len(frame)/4)
+}
+
+// Decodes "count" frames spread evenly across the video, skipping the very first and last frames,
+// which are often black. Returns fewer frames for short videos. Frames are oriented unless disabled in "c".
+func sampleFrames(video *Video, count int, c *config) ([]*image.RGBA, []int, error) {
+	total := video.Frames()
+	if total <= 0 {
+		return nil, nil, fmt.Errorf("vidio: video %s has no frames", video.FileName())
+	}
+	if count > total {
+		count = total
+	}
+	indexes := make([]int, count)
+	for i := range indexes {
+		indexes[i] = int((float64(i) + 0.5) * float64(total) / float64(count))
+	}
+	input, filter, width, height := video.orientation(c)
+	frames, err := video.readFrames(indexes, input, filter, width, height)
+	return frames, indexes, err
+}
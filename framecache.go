@@ -0,0 +1,204 @@
+package vidio
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// FrameCache is an on-disk cache of decoded frames keyed by file and frame index.
+// Entries are evicted least recently used first once the cache exceeds its size limit.
+// The cache survives process restarts, so repeated scrubbing or thumbnail requests
+// do not need to decode the same frames again.
+type FrameCache struct {
+	dir     string                 // Directory holding the cached frames.
+	limit   int64                  // Maximum total size of the cached frames in bytes.
+	size    int64                  // Current total size of the cached frames in bytes.
+	entries map[string]*cacheEntry // Cached frames by key.
+	mutex   sync.Mutex
+}
+
+// Size limit of a frame cache opened without one.
+const defaultFrameCacheLimit = 1 << 30
+
+// A single cached frame.
+type cacheEntry struct {
+	size int64     // Size of the frame file in bytes.
+	used time.Time // Last time the frame was read or written.
+}
+
+// Opens the frame cache stored in "dir", creating the directory if needed.
+// If "dir" is empty, a "vidio-frames" directory in the temporary directory is used.
+// "limit" is the maximum total size of the cached frames in bytes, 1 GiB if zero.
+func NewFrameCache(dir string, limit int64) (*FrameCache, error) {
+	if limit < 0 {
+		return nil, fmt.Errorf("vidio: frame cache size limit must not be negative")
+	}
+	if limit == 0 {
+		limit = defaultFrameCacheLimit
+	}
+	if dir == "" {
+		dir = filepath.Join(os.TempDir(), "vidio-frames")
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, err
+	}
+
+	cache := &FrameCache{dir: dir, limit: limit, entries: map[string]*cacheEntry{}}
+
+	// The modification time of a frame file is its last use.
+	files, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	for _, file := range files {
+		if file.IsDir() || !strings.HasSuffix(file.Name(), ".rgba") {
+			continue
+		}
+		info, err := file.Info()
+		if err != nil {
+			continue
+		}
+		key := strings.TrimSuffix(file.Name(), ".rgba")
+		cache.entries[key] = &cacheEntry{size: info.Size(), used: info.ModTime()}
+		cache.size += info.Size()
+	}
+	cache.evict()
+
+	return cache, nil
+}
+
+// Total size of the cached frames in bytes.
+func (cache *FrameCache) Size() int64 {
+	cache.mutex.Lock()
+	defer cache.mutex.Unlock()
+	return cache.size
+}
+
+// Returns the cache key of frame "n" of the given video stream. The key includes the file size and
+// modification time, so frames of files which changed on disk are not reused, and the decode filter
+// and output size, so frames decoded with other options are not mixed up.
+func (cache *FrameCache) key(video *Video, n int) string {
+	identity := video.filename
+	if path, err := filepath.Abs(video.filename); err == nil && !isURL(video.filename) {
+		identity = path
+	}
+	if info, err := os.Stat(video.filename); err == nil {
+		identity = fmt.Sprintf("%s|%d|%d", identity, info.Size(), info.ModTime().UnixNano())
+	}
+	identity = fmt.Sprintf("%s|%d|%q|%dx%dx%d|%s|%d", identity, video.stream, video.filter, video.width, video.height, video.depth, video.pixfmt, n)
+	sum := sha256.Sum256([]byte(identity))
+	return hex.EncodeToString(sum[:])
+}
+
+// Path of the file storing the frame with the given key.
+func (cache *FrameCache) path(key string) string {
+	return filepath.Join(cache.dir, key+".rgba")
+}
+
+// Reads the cached frame with the given key into "buffer". Returns false if the frame is not cached.
+func (cache *FrameCache) get(key string, buffer []byte) bool {
+	cache.mutex.Lock()
+	defer cache.mutex.Unlock()
+
+	entry, ok := cache.entries[key]
+	if !ok || entry.size != int64(len(buffer)) {
+		return false
+	}
+
+	f, err := os.Open(cache.path(key))
+	if err != nil {
+		cache.remove(key)
+		return false
+	}
+	defer f.Close()
+
+	if _, err := f.ReadAt(buffer, 0); err != nil {
+		cache.remove(key)
+		return false
+	}
+
+	entry.used = time.Now()
+	os.Chtimes(cache.path(key), entry.used, entry.used)
+	return true
+}
+
+// Stores the given frame under "key", evicting the least recently used frames if needed.
+func (cache *FrameCache) put(key string, frame []byte) error {
+	cache.mutex.Lock()
+	defer cache.mutex.Unlock()
+
+	if int64(len(frame)) > cache.limit {
+		return nil
+	}
+
+	// Write to a temporary file first so readers never see partial frames.
+	temp, err := os.CreateTemp(cache.dir, "tmp-*")
+	if err != nil {
+		return err
+	}
+	if _, err := temp.Write(frame); err != nil {
+		temp.Close()
+		os.Remove(temp.Name())
+		return err
+	}
+	temp.Close()
+	if err := os.Rename(temp.Name(), cache.path(key)); err != nil {
+		os.Remove(temp.Name())
+		return err
+	}
+
+	if entry, ok := cache.entries[key]; ok {
+		cache.size -= entry.size
+	}
+	cache.entries[key] = &cacheEntry{size: int64(len(frame)), used: time.Now()}
+	cache.size += int64(len(frame))
+	cache.evict()
+
+	return nil
+}
+
+// Removes the frame with the given key. Must be called with the mutex held.
+func (cache *FrameCache) remove(key string) {
+	if entry, ok := cache.entries[key]; ok {
+		cache.size -= entry.size
+		delete(cache.entries, key)
+	}
+	os.Remove(cache.path(key))
+}
+
+// Removes least recently used frames until the cache fits its limit. Must be called with the mutex held.
+func (cache *FrameCache) evict() {
+	if cache.size <= cache.limit {
+		return
+	}
+	keys := make([]string, 0, len(cache.entries))
+	for key := range cache.entries {
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		return cache.entries[keys[i]].used.Before(cache.entries[keys[j]].used)
+	})
+	for _, key := range keys {
+		if cache.size <= cache.limit {
+			break
+		}
+		cache.remove(key)
+	}
+}
+
+// Removes all cached frames.
+func (cache *FrameCache) Clear() error {
+	cache.mutex.Lock()
+	defer cache.mutex.Unlock()
+	for key := range cache.entries {
+		cache.remove(key)
+	}
+	return nil
+}
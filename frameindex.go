@@ -0,0 +1,127 @@
+package vidio
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/benitogf/Vidio/ffcmd"
+)
+
+// A frame of the first video stream as listed by ExportFrameIndex.
+type FrameRecord struct {
+	Index    int     // Index of the frame in presentation order.
+	PTS      float64 // Presentation time in seconds. NaN if unknown.
+	Keyframe bool    // Whether the frame is a keyframe.
+	Size     int     // Size of the compressed frame in bytes.
+	Luma     float64 // Mean luma in the sample range of the video, 0-255 for 8-bit video.
+}
+
+// Writes a table with one record per frame of the first video stream to "output", for analytics pipelines
+// working with frame-level tables: index, presentation time, keyframe flag, compressed size and mean luma.
+// "output" is a .csv file with a header row, or a .jsonl file with one JSON object per line. Tables for
+// columnar formats such as Parquet can be loaded from either. Requires decoding the video.
+func ExportFrameIndex(filename, output string, options ...Option) error {
+	extension := strings.ToLower(filepath.Ext(output))
+	if extension != ".csv" && extension != ".jsonl" {
+		return fmt.Errorf("vidio: output %s must be a .csv or .jsonl file", output)
+	}
+	if err := newConfig(options).checkSources(filename); err != nil {
+		return err
+	}
+	if !isURL(filename) && !exists(filename) {
+		return fmt.Errorf("vidio: video file %s does not exist", filename)
+	}
+
+	frames, err := ffprobeEntries(filename, "v:0", "frame", "pts_time,best_effort_timestamp_time,key_frame,pkt_size")
+	if err != nil {
+		return err
+	}
+	builder := ffcmd.New().
+		Input(filename).
+		Map("0:v:0").
+		Output("-", "-vf", "signalstats,metadata=print:key=lavfi.signalstats.YAVG:file=-", "-an", "-f", "null")
+	stats, err := newConfig(options).output(builder)
+	if err != nil {
+		return err
+	}
+
+	file, err := os.Create(output)
+	if err != nil {
+		return err
+	}
+	writer := bufio.NewWriter(file)
+	err = writeFrameIndex(writer, frameRecords(frames, parseLuma(string(stats))), extension == ".jsonl")
+	if flushErr := writer.Flush(); err == nil {
+		err = flushErr
+	}
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	return err
+}
+
+// Builds the records from the ffprobe frame entries and the mean luma of every frame.
+func frameRecords(frames []map[string]string, luma []float64) []FrameRecord {
+	records := make([]FrameRecord, len(frames))
+	for i, frame := range frames {
+		pts := probeTime(frame["pts_time"])
+		if math.IsNaN(pts) {
+			pts = probeTime(frame["best_effort_timestamp_time"])
+		}
+		records[i] = FrameRecord{
+			Index:    i,
+			PTS:      pts,
+			Keyframe: frame["key_frame"] == "1",
+			Size:     int(parse(frame["pkt_size"])),
+		}
+		if i < len(luma) {
+			records[i].Luma = luma[i]
+		}
+	}
+	return records
+}
+
+// Parses the mean luma of every frame from the signalstats metadata.
+func parseLuma(output string) []float64 {
+	luma := []float64{}
+	for _, line := range strings.Split(output, "\n") {
+		if value, ok := strings.CutPrefix(strings.TrimSpace(line), "lavfi.signalstats.YAVG="); ok {
+			luma = append(luma, parse(value))
+		}
+	}
+	return luma
+}
+
+// Writes the records as CSV with a header row, or as JSON lines. Unknown timestamps are empty in CSV and null in JSON.
+func writeFrameIndex(w io.Writer, records []FrameRecord, jsonl bool) error {
+	if !jsonl {
+		if _, err := io.WriteString(w, "frame,pts,keyframe,size,luma\n"); err != nil {
+			return err
+		}
+	}
+	format := func(v float64) string { return strconv.FormatFloat(v, 'f', -1, 64) }
+	for _, record := range records {
+		pts := format(record.PTS)
+		if math.IsNaN(record.PTS) {
+			pts = ""
+			if jsonl {
+				pts = "null"
+			}
+		}
+		line := fmt.Sprintf("%d,%s,%t,%d,%s\n", record.Index, pts, record.Keyframe, record.Size, format(record.Luma))
+		if jsonl {
+			line = fmt.Sprintf(`{"frame":%d,"pts":%s,"keyframe":%t,"size":%d,"luma":%s}`+"\n",
+				record.Index, pts, record.Keyframe, record.Size, format(record.Luma))
+		}
+		if _, err := io.WriteString(w, line); err != nil {
+			return err
+		}
+	}
+	return nil
+}
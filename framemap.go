@@ -0,0 +1,179 @@
+package vidio
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"sync"
+)
+
+// FrameMap decodes a video into a raw frame file which is memory-mapped for reading,
+// instead of streaming frames through a pipe. ffmpeg decodes at full speed without pipe
+// backpressure, and all frames decoded so far can be accessed randomly.
+type FrameMap struct {
+	video   *Video    // Video being decoded.
+	file    *os.File  // Raw frame file written by ffmpeg.
+	size    int       // Size of a single frame in bytes.
+	cmd     *exec.Cmd // ffmpeg command.
+	mapping []byte    // Current memory mapping of the frame file.
+	done    chan struct{}
+	err     error // ffmpeg exit error, valid once done is closed.
+	mutex   sync.Mutex
+}
+
+// Starts decoding the video into a raw frame file in "dir" and returns a FrameMap to access the frames.
+// If "dir" is empty, the temporary directory is used. Pass "/dev/shm" to keep the frames in shared memory
+// on Linux, which is RAM. The frame file needs width*height*depth bytes per frame for all frames, so the
+// frame count must be known: sources without one, such as live streams, need a frame limit set with WithLimits.
+func (video *Video) MapFrames(dir string) (*FrameMap, error) {
+	if dir == "" {
+		dir = os.TempDir()
+	}
+	if video.Frames() <= 0 {
+		return nil, fmt.Errorf("vidio: video %s has an unknown frame count, limit it with WithLimits to map its frames", video.filename)
+	}
+
+	file, err := os.CreateTemp(dir, "vidio-frames-*.rgba")
+	if err != nil {
+		return nil, err
+	}
+
+	args := append([]string{"-y", "-loglevel", logLevel()}, video.inputArgs()...)
+	args = append(args,
+		"-i", video.filename,
+		"-f", "rawvideo",
+		"-pix_fmt", string(video.pixfmt),
+		"-vcodec", "rawvideo",
+		"-map", fmt.Sprintf("0:v:%d", video.stream),
+	)
+	if video.filter != "" {
+		args = append(args, "-vf", video.filter)
+	}
+	args = append(args, video.limits.outputArgs()...)
+	cmd, err := video.sandbox.command(nil, "ffmpeg", append(args, file.Name())...)
+
+	if err != nil {
+		file.Close()
+		os.Remove(file.Name())
+		return nil, err
+	}
+	logOutput(cmd, video.filename)
+
+	if err := cmd.Start(); err != nil {
+		file.Close()
+		os.Remove(file.Name())
+		return nil, err
+	}
+
+	frames := &FrameMap{
+		video: video,
+		file:  file,
+		size:  video.width * video.height * video.depth,
+		cmd:   cmd,
+		done:  make(chan struct{}),
+	}
+	go func() {
+		frames.err = waitOutput(cmd)
+		close(frames.done)
+	}()
+
+	return frames, nil
+}
+
+// Size in bytes of the frame file once all frames are decoded.
+func (frames *FrameMap) Size() int64 {
+	return int64(frames.video.Frames()) * int64(frames.size)
+}
+
+// Number of frames fully decoded so far.
+func (frames *FrameMap) Frames() int {
+	info, err := frames.file.Stat()
+	if err != nil || frames.size == 0 {
+		return 0
+	}
+	return int(info.Size() / int64(frames.size))
+}
+
+// Returns true once ffmpeg has decoded all frames.
+func (frames *FrameMap) Done() bool {
+	select {
+	case <-frames.done:
+		return true
+	default:
+		return false
+	}
+}
+
+// Waits for ffmpeg to decode all frames.
+func (frames *FrameMap) Wait() error {
+	<-frames.done
+	return frames.err
+}
+
+// Returns frame "n" as a slice of the memory-mapped frame file. The slice must not be modified
+// and is only valid until the next call to Frame or Close. Returns an error if the frame has not been decoded yet.
+func (frames *FrameMap) Frame(n int) ([]byte, error) {
+	frames.mutex.Lock()
+	defer frames.mutex.Unlock()
+
+	available := frames.Frames()
+	if n < 0 || n >= available {
+		return nil, fmt.Errorf("vidio: frame %d is not decoded yet, %d frames available", n, available)
+	}
+
+	end := (n + 1) * frames.size
+	if len(frames.mapping) < end {
+		// The file grew since it was last mapped.
+		if err := frames.remap(available * frames.size); err != nil {
+			return nil, err
+		}
+	}
+
+	return frames.mapping[n*frames.size : end], nil
+}
+
+// Copies frame "n" into the video framebuffer.
+func (frames *FrameMap) ReadFrame(n int) error {
+	frame, err := frames.Frame(n)
+	if err != nil {
+		return err
+	}
+	if frames.video.framebuffer == nil {
+		frames.video.framebuffer = make([]byte, frames.size)
+	}
+	copy(frames.video.framebuffer, frame)
+	return nil
+}
+
+// Remaps the first "length" bytes of the frame file.
+func (frames *FrameMap) remap(length int) error {
+	if frames.mapping != nil {
+		if err := unmapFile(frames.mapping); err != nil {
+			return err
+		}
+		frames.mapping = nil
+	}
+	mapping, err := mapFile(frames.file, length)
+	if err != nil {
+		return fmt.Errorf("vidio: failed to map frame file: %w", err)
+	}
+	frames.mapping = mapping
+	return nil
+}
+
+// Stops ffmpeg, unmaps and removes the frame file.
+func (frames *FrameMap) Close() {
+	if !frames.Done() && frames.cmd.Process != nil {
+		frames.cmd.Process.Kill()
+	}
+	<-frames.done
+
+	frames.mutex.Lock()
+	defer frames.mutex.Unlock()
+	if frames.mapping != nil {
+		unmapFile(frames.mapping)
+		frames.mapping = nil
+	}
+	frames.file.Close()
+	os.Remove(frames.file.Name())
+}
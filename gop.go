@@ -0,0 +1,114 @@
+package vidio
+
+import (
+	"fmt"
+	"math"
+)
+
+// A group of pictures: a keyframe and the frames up to the next keyframe.
+type GOP struct {
+	Start    float64 // Presentation time in seconds of the keyframe.
+	Duration float64 // Duration in seconds until the next keyframe.
+	Frames   int     // Number of frames.
+	Open     bool    // Frames following the keyframe in decoding order are shown before it, referencing the previous GOP.
+}
+
+// GOP structure of a video.
+type GOPReport struct {
+	GOPs        []GOP   // All GOPs in order.
+	MinLength   int     // Smallest number of frames in a GOP, not counting the last GOP.
+	MaxLength   int     // Largest number of frames in a GOP.
+	AvgLength   float64 // Average number of frames in a GOP.
+	AvgDuration float64 // Average GOP duration in seconds.
+	OpenGOPs    int     // Number of open GOPs. Segmenters need closed GOPs to cut at every keyframe.
+	IFrames     int     // Number of intra coded frames.
+	PFrames     int     // Number of predicted frames.
+	BFrames     int     // Number of bidirectionally predicted frames.
+}
+
+// Inspects the GOP structure of the first video stream: GOP lengths, open or closed GOPs and the
+// distribution of frame types. Long or irregular GOPs slow down seeking, and open GOPs can not be
+// segmented at every keyframe. The frame types require decoding the video.
+func GOPStats(filename string) (*GOPReport, error) {
+	if !isURL(filename) && !exists(filename) {
+		return nil, fmt.Errorf("vidio: video file %s does not exist", filename)
+	}
+	packets, err := probePackets(filename, "v:0")
+	if err != nil {
+		return nil, err
+	}
+	frames, err := ffprobeEntries(filename, "v:0", "frame", "pict_type")
+	if err != nil {
+		return nil, err
+	}
+
+	types := make([]string, len(frames))
+	for i, frame := range frames {
+		types[i] = frame["pict_type"]
+	}
+	return gopReport(packets, types), nil
+}
+
+// Computes the GOP report from the packets in decoding order and the frame picture types.
+func gopReport(packets []packet, types []string) *GOPReport {
+	report := &GOPReport{GOPs: []GOP{}}
+	for _, t := range types {
+		switch t {
+		case "I":
+			report.IFrames++
+		case "P":
+			report.PFrames++
+		case "B":
+			report.BFrames++
+		}
+	}
+
+	// Average frame interval, for the duration of the last GOP.
+	first, last := math.Inf(1), math.Inf(-1)
+	for _, packet := range packets {
+		if !math.IsNaN(packet.pts) {
+			first, last = math.Min(first, packet.pts), math.Max(last, packet.pts)
+		}
+	}
+	interval := 0.0
+	if len(packets) > 1 && last > first {
+		interval = (last - first) / float64(len(packets)-1)
+	}
+
+	for _, packet := range packets {
+		if packet.keyframe || len(report.GOPs) == 0 {
+			report.GOPs = append(report.GOPs, GOP{Start: packet.pts})
+		}
+		gop := &report.GOPs[len(report.GOPs)-1]
+		if gop.Frames > 0 && packet.pts < gop.Start {
+			gop.Open = true
+		}
+		gop.Frames++
+	}
+
+	total := 0.0
+	for i := range report.GOPs {
+		gop := &report.GOPs[i]
+		if i+1 < len(report.GOPs) {
+			gop.Duration = report.GOPs[i+1].Start - gop.Start
+		} else {
+			gop.Duration = last - gop.Start + interval
+		}
+		total += gop.Duration
+		if gop.Open {
+			report.OpenGOPs++
+		}
+		if gop.Frames > report.MaxLength {
+			report.MaxLength = gop.Frames
+		}
+		// The last GOP is cut short by the end of the video.
+		if (i+1 < len(report.GOPs) || len(report.GOPs) == 1) && (report.MinLength == 0 || gop.Frames < report.MinLength) {
+			report.MinLength = gop.Frames
+		}
+	}
+	if len(report.GOPs) > 0 {
+		report.AvgLength = float64(len(packets)) / float64(len(report.GOPs))
+		report.AvgDuration = total / float64(len(report.GOPs))
+	}
+	return report
+}
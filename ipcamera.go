@@ -0,0 +1,94 @@
+package vidio
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// Optional parameters for IP cameras.
+type IPCameraOptions struct {
+	Transport    string        // RTSP transport protocol: "tcp", "udp", "udp_multicast" or "http". Default "tcp".
+	Timeout      time.Duration // Socket timeout for connecting and reading. Default 5 seconds.
+	ReorderQueue int           // Number of packets buffered to handle reordered packets. Default 500.
+	Username     string        // Username for camera authentication. Overrides credentials in the URL.
+	Password     string        // Password for camera authentication.
+	Retry        RetryPolicy   // Retry policy for transient connection failures. Default no retries.
+}
+
+// Creates a new camera struct that reads from the network camera stream at the given URL,
+// e.g. "rtsp://192.168.1.10:554/stream1". RTSP streams are opened with reliable defaults:
+// TCP transport, a socket timeout and a packet reorder buffer.
+func NewIPCamera(address string, options *IPCameraOptions) (*Camera, error) {
+	// Check if ffmpeg is installed on the users machine.
+	if err := installed("ffmpeg"); err != nil {
+		return nil, err
+	}
+
+	if options == nil {
+		options = &IPCameraOptions{}
+	}
+
+	parsed, err := url.Parse(address)
+	if err != nil {
+		return nil, fmt.Errorf("vidio: invalid camera url %s: %w", address, err)
+	}
+	if parsed.Scheme == "" || parsed.Host == "" {
+		return nil, fmt.Errorf("vidio: invalid camera url %s", address)
+	}
+
+	if options.Username != "" {
+		parsed.User = url.UserPassword(options.Username, options.Password)
+	}
+
+	timeout := options.Timeout
+	if timeout == 0 {
+		timeout = 5 * time.Second
+	}
+
+	input := []string{}
+	scheme := strings.ToLower(parsed.Scheme)
+	if scheme == "rtsp" || scheme == "rtsps" {
+		transport := options.Transport
+		if transport == "" {
+			transport = "tcp"
+		}
+		reorder := options.ReorderQueue
+		if reorder == 0 {
+			reorder = 500
+		}
+		input = append(
+			input,
+			"-rtsp_transport", transport,
+			"-reorder_queue_size", fmt.Sprintf("%d", reorder),
+		)
+	}
+	input = append(
+		input,
+		"-timeout", fmt.Sprintf("%d", timeout.Microseconds()),
+		"-i", parsed.String(),
+	)
+
+	// The camera name does not contain the password so it is safe to log.
+	camera := &Camera{name: parsed.Redacted(), depth: 4, pixfmt: PixelRGBA, input: input}
+	err = options.Retry.do(newConfig(nil), func() error {
+		output, err := camera.getCameraData()
+		if err != nil {
+			return err
+		}
+		if camera.width == 0 || camera.height == 0 {
+			return &OpenError{
+				Source: camera.name,
+				Output: strings.TrimSpace(strings.ReplaceAll(output, parsed.String(), camera.name)),
+				Err:    fmt.Errorf("no stream information found"),
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return camera, nil
+}
@@ -0,0 +1,109 @@
+package vidio
+
+import (
+	"fmt"
+	"image"
+	"image/draw"
+	"math"
+)
+
+// Generates a pan and zoom clip of "duration" seconds from a single image. The visible region of the image
+// moves from the "from" rectangle to the "to" rectangle with smooth acceleration and deceleration.
+// Frames have the size of "from", so both rectangles should have the same aspect ratio.
+// The options configure the writer; FPS defaults to 25.
+func KenBurns(img image.Image, duration float64, from, to image.Rectangle, output string, options *Options) error {
+	if duration <= 0 {
+		return fmt.Errorf("vidio: clip duration must be positive")
+	}
+	bounds := img.Bounds()
+	if from.Empty() || to.Empty() || !from.In(bounds) || !to.In(bounds) {
+		return fmt.Errorf("vidio: pan rectangles must be non-empty and inside the image bounds %v", bounds)
+	}
+
+	if options == nil {
+		options = &Options{}
+	}
+	writerOptions := *options
+	if writerOptions.FPS <= 0 {
+		writerOptions.FPS = 25
+	}
+
+	src, ok := img.(*image.RGBA)
+	if !ok {
+		src = image.NewRGBA(bounds)
+		draw.Draw(src, bounds, img, bounds.Min, draw.Src)
+	}
+
+	width, height := from.Dx(), from.Dy()
+	writer, err := NewVideoWriter(output, width, height, &writerOptions)
+	if err != nil {
+		return err
+	}
+	defer writer.Close()
+
+	frames := max(1, int(math.Round(duration*writerOptions.FPS)))
+	frame := image.NewRGBA(image.Rect(0, 0, width, height))
+	for i := 0; i < frames; i++ {
+		t := 0.0
+		if frames > 1 {
+			t = float64(i) / float64(frames-1)
+		}
+		panFrame(frame, src, panRect(from, to, t))
+		if err := writer.Write(frame.Pix); err != nil {
+			return err
+		}
+	}
+
+	writer.Close()
+	return writer.Err()
+}
+
+// Returns the region at time "t" between 0 and 1 of a pan from "from" to "to" as x, y, width, height.
+// The region is eased in and out and kept at sub-pixel precision so slow pans do not jitter.
+func panRect(from, to image.Rectangle, t float64) [4]float64 {
+	// Smoothstep easing.
+	t = t * t * (3 - 2*t)
+	lerp := func(a, b int) float64 {
+		return float64(a) + (float64(b)-float64(a))*t
+	}
+	return [4]float64{
+		lerp(from.Min.X, to.Min.X),
+		lerp(from.Min.Y, to.Min.Y),
+		lerp(from.Dx(), to.Dx()),
+		lerp(from.Dy(), to.Dy()),
+	}
+}
+
+// Fills "dst" with the given region of "src", scaled with bilinear interpolation.
+func panFrame(dst, src *image.RGBA, region [4]float64) {
+	width, height := dst.Rect.Dx(), dst.Rect.Dy()
+	bounds := src.Rect
+	scaleX, scaleY := region[2]/float64(width), region[3]/float64(height)
+	for y := 0; y < height; y++ {
+		// Samples at pixel centers.
+		sy := region[1] + (float64(y)+0.5)*scaleY - 0.5
+		y0 := int(math.Floor(sy))
+		fy := sy - float64(y0)
+		y0, y1 := clampInt(y0, bounds.Min.Y, bounds.Max.Y-1), clampInt(y0+1, bounds.Min.Y, bounds.Max.Y-1)
+		for x := 0; x < width; x++ {
+			sx := region[0] + (float64(x)+0.5)*scaleX - 0.5
+			x0 := int(math.Floor(sx))
+			fx := sx - float64(x0)
+			x0, x1 := clampInt(x0, bounds.Min.X, bounds.Max.X-1), clampInt(x0+1, bounds.Min.X, bounds.Max.X-1)
+
+			a, b := src.PixOffset(x0, y0), src.PixOffset(x1, y0)
+			c, d := src.PixOffset(x0, y1), src.PixOffset(x1, y1)
+			index := dst.PixOffset(x, y)
+			for channel := 0; channel < 4; channel++ {
+				top := float64(src.Pix[a+channel])*(1-fx) + float64(src.Pix[b+channel])*fx
+				bottom := float64(src.Pix[c+channel])*(1-fx) + float64(src.Pix[d+channel])*fx
+				dst.Pix[index+channel] = uint8(top*(1-fy) + bottom*fy + 0.5)
+			}
+		}
+	}
+}
+
+// Clamps "value" to the range [low, high].
+func clampInt(value, low, high int) int {
+	return min(max(value, low), high)
+}
@@ -0,0 +1,90 @@
+package vidio
+
+import (
+	"fmt"
+
+	"github.com/benitogf/Vidio/ffcmd"
+)
+
+// A single resolution and bitrate of an adaptive bitrate ladder.
+type Rendition struct {
+	Output       string // Output filename.
+	Width        int    // Frame width. 0 keeps the aspect ratio of the input for the given height.
+	Height       int    // Frame height. 0 keeps the aspect ratio of the input for the given width.
+	Bitrate      int    // Target video bitrate in bits/s, also used as the peak bitrate.
+	Codec        string // Codec for video. Default libx264.
+	AudioBitrate int    // Audio bitrate in bits/s. Default 128000.
+}
+
+// Encodes all renditions of a bitrate ladder from a single decode of the input. The decoded video is split
+// and scaled once per rendition, which avoids decoding the input once per output. Keyframes are forced every
+// 2 seconds in all renditions, so segments line up when the outputs are packaged for HLS or DASH.
+func EncodeLadder(filename string, renditions []Rendition, options ...Option) error {
+	if !isURL(filename) && !exists(filename) {
+		return fmt.Errorf("vidio: video file %s does not exist", filename)
+	}
+	if len(renditions) == 0 {
+		return fmt.Errorf("vidio: no renditions given")
+	}
+
+	c := newConfig(options)
+	filter, err := c.videoFilter()
+	if err != nil {
+		return err
+	}
+	builder := ffcmd.New().
+		Global("-y").
+		Input(filename)
+
+	split := fmt.Sprintf("[0:v]split=%d", len(renditions))
+	if filter != "" {
+		split = fmt.Sprintf("[0:v]%s,split=%d", filter, len(renditions))
+	}
+	for i := range renditions {
+		split += fmt.Sprintf("[s%d]", i)
+	}
+	builder.Filter(split)
+
+	for i, rendition := range renditions {
+		if rendition.Bitrate <= 0 {
+			return fmt.Errorf("vidio: rendition %s has no bitrate", rendition.Output)
+		}
+		width, height := rendition.Width, rendition.Height
+		if width == 0 && height != 0 {
+			width = -2
+		} else if height == 0 && width != 0 {
+			height = -2
+		}
+		if width == 0 && height == 0 {
+			builder.Filter(fmt.Sprintf("[s%d]null[v%d]", i, i))
+		} else {
+			builder.Filter(fmt.Sprintf("[s%d]scale=%d:%d[v%d]", i, width, height, i))
+		}
+
+		codec := rendition.Codec
+		if codec == "" {
+			codec = "libx264"
+		}
+		audio := rendition.AudioBitrate
+		if audio == 0 {
+			audio = 128000
+		}
+
+		output := []string{
+			"-c:v", codec,
+			"-b:v", fmt.Sprintf("%d", rendition.Bitrate),
+			"-maxrate", fmt.Sprintf("%d", rendition.Bitrate),
+			"-bufsize", fmt.Sprintf("%d", 2*rendition.Bitrate),
+			"-force_key_frames", "expr:gte(t,n_forced*2)",
+		}
+		if codec == "libx264" || codec == "libx265" {
+			// Scene cut keyframes would misalign the renditions.
+			output = append(output, "-sc_threshold", "0")
+		}
+		output = append(output, "-c:a", "aac", "-b:a", fmt.Sprintf("%d", audio))
+
+		builder.Map(fmt.Sprintf("[v%d]", i), "0:a:0?").Output(rendition.Output, output...)
+	}
+
+	return c.run(builder)
+}
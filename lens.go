@@ -0,0 +1,62 @@
+package vidio
+
+import (
+	"fmt"
+	"strconv"
+)
+
+// Lens distortion coefficients of a common action camera mode, for WithLensPreset.
+type LensPreset string
+
+const (
+	LensGoProWide      LensPreset = "gopro-wide"       // GoPro Wide field of view.
+	LensGoProMedium    LensPreset = "gopro-medium"     // GoPro Medium field of view.
+	LensDJIOsmoAction  LensPreset = "dji-osmo-action"  // DJI Osmo Action standard field of view.
+	LensInsta360GoWide LensPreset = "insta360-go-wide" // Insta360 GO wide field of view.
+)
+
+// Quadratic and quartic distortion coefficients of the presets. These are starting points measured on typical
+// footage, cameras and firmware versions differ slightly.
+var lensPresets = map[LensPreset][2]float64{
+	LensGoProWide:      {-0.32, 0.10},
+	LensGoProMedium:    {-0.18, 0.04},
+	LensDJIOsmoAction:  {-0.26, 0.07},
+	LensInsta360GoWide: {-0.30, 0.09},
+}
+
+// Lens correction set with WithLensCorrection or WithLensPreset.
+type lensCorrection struct {
+	preset LensPreset // Preset providing the coefficients. Empty if given directly.
+	k1     float64    // Quadratic distortion coefficient.
+	k2     float64    // Quartic distortion coefficient.
+}
+
+// Makes NewVideo and EncodeLadder correct radial lens distortion around the frame center with the ffmpeg
+// lenscorrection filter, e.g. to straighten lines of wide angle action camera footage before analysis.
+// Negative coefficients correct barrel distortion, positive ones pincushion distortion.
+func WithLensCorrection(k1, k2 float64) Option {
+	return func(c *config) {
+		c.lens = &lensCorrection{k1: k1, k2: k2}
+	}
+}
+
+// Makes NewVideo and EncodeLadder correct lens distortion with the coefficients of a common camera mode.
+func WithLensPreset(preset LensPreset) Option {
+	return func(c *config) {
+		c.lens = &lensCorrection{preset: preset}
+	}
+}
+
+// Returns the ffmpeg lenscorrection filter, or an error if the preset is unknown.
+func (lens *lensCorrection) filter() (string, error) {
+	k1, k2 := lens.k1, lens.k2
+	if lens.preset != "" {
+		coefficients, ok := lensPresets[lens.preset]
+		if !ok {
+			return "", fmt.Errorf("vidio: unknown lens preset: %s", lens.preset)
+		}
+		k1, k2 = coefficients[0], coefficients[1]
+	}
+	format := func(v float64) string { return strconv.FormatFloat(v, 'f', -1, 64) }
+	return "lenscorrection=k1=" + format(k1) + ":k2=" + format(k2), nil
+}
@@ -0,0 +1,58 @@
+package vidio
+
+import (
+	"fmt"
+	"strconv"
+)
+
+// Decoding limits protecting services from absurdly large inputs.
+type limits struct {
+	width    int     // Maximum frame width.
+	height   int     // Maximum frame height.
+	frames   int     // Maximum number of frames decoded.
+	duration float64 // Maximum duration decoded in seconds.
+}
+
+// Makes NewVideo refuse videos with frames larger than "maxW" x "maxH", and stop decoding
+// after "maxFrames" frames or "maxDuration" seconds, so decompression-bomb style inputs can not
+// exhaust memory or CPU. Frames and Duration report the truncated length. Zero values mean no limit.
+func WithLimits(maxW, maxH int, maxFrames int, maxDuration float64) Option {
+	return func(c *config) {
+		c.limits = &limits{width: maxW, height: maxH, frames: maxFrames, duration: maxDuration}
+	}
+}
+
+// Returns an error if the frame size of the video exceeds the limits, and truncates its length.
+func (l *limits) apply(video *Video) error {
+	if l == nil {
+		return nil
+	}
+	if (l.width > 0 || l.height > 0) && (video.width <= 0 || video.height <= 0) {
+		return fmt.Errorf("vidio: video %s has an unknown frame size", video.filename)
+	}
+	if l.width > 0 && video.width > l.width || l.height > 0 && video.height > l.height {
+		return fmt.Errorf("vidio: video %s frame size %dx%d exceeds the limit of %dx%d", video.filename, video.width, video.height, l.width, l.height)
+	}
+	if l.frames > 0 && video.frames > l.frames {
+		video.frames = l.frames
+	}
+	if l.duration > 0 && video.duration > l.duration {
+		video.duration = l.duration
+	}
+	return nil
+}
+
+// Returns the output options stopping decoding at the limits.
+func (l *limits) outputArgs() []string {
+	if l == nil {
+		return nil
+	}
+	args := []string{}
+	if l.frames > 0 {
+		args = append(args, "-frames:v", strconv.Itoa(l.frames))
+	}
+	if l.duration > 0 {
+		args = append(args, "-t", strconv.FormatFloat(l.duration, 'f', -1, 64))
+	}
+	return args
+}
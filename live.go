@@ -0,0 +1,150 @@
+package vidio
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// Default time a live file may stop growing before it is considered finished.
+const defaultLiveIdle = 5 * time.Second
+
+// Interval between checks whether a live file has grown.
+const livePoll = time.Second
+
+// Reads videos from files which are still being written, e.g. ongoing OBS recordings.
+// Reading waits at the end of the file for more data instead of stopping, and only ends once
+// the file has not grown for "idle". 0 uses 5 seconds. Duration and Frames are re-probed while the file grows.
+// Use a container which is readable while incomplete, such as mkv, fragmented mp4 or mpegts.
+func WithLive(idle time.Duration) Option {
+	return func(c *config) {
+		if idle <= 0 {
+			idle = defaultLiveIdle
+		}
+		c.live = idle
+	}
+}
+
+// State of a video file which is still being written.
+type liveFile struct {
+	idle     time.Duration // Time without growth after which the file is finished.
+	mu       sync.Mutex    // Guards the fields below, which are updated by the watcher.
+	size     int64         // Last seen file size.
+	grown    time.Time     // Last time the file size changed.
+	duration float64       // Last probed duration in seconds.
+	frames   int           // Last probed number of frames.
+	stop     chan struct{} // Stops the watcher.
+	stopped  bool
+}
+
+func newLiveFile(filename string, idle time.Duration, duration float64, frames int) *liveFile {
+	live := &liveFile{idle: idle, grown: time.Now(), duration: duration, frames: frames}
+	if info, err := os.Stat(filename); err == nil {
+		live.size = info.Size()
+	}
+	return live
+}
+
+// Input options making ffmpeg wait at the end of the file for more data,
+// giving up after reading nothing for the idle time.
+func (live *liveFile) inputArgs(filename string) []string {
+	return []string{
+		"-follow", "1",
+		"-rw_timeout", strconv.FormatInt(live.idle.Microseconds(), 10),
+		"-i", "file:" + filename,
+	}
+}
+
+// Checks whether the file has grown since the last poll. Returns true if it did.
+func (live *liveFile) poll(filename string, now time.Time) bool {
+	info, err := os.Stat(filename)
+	if err != nil {
+		return false
+	}
+	live.mu.Lock()
+	defer live.mu.Unlock()
+	if info.Size() == live.size {
+		return false
+	}
+	live.size = info.Size()
+	live.grown = now
+	return true
+}
+
+// Updates the duration and the number of frames from a new probe of the file.
+func (live *liveFile) update(duration, fps float64, frames int) {
+	live.mu.Lock()
+	defer live.mu.Unlock()
+	if duration > live.duration {
+		live.duration = duration
+	}
+	// Containers being written often do not store a frame count.
+	if frames == 0 {
+		frames = int(live.duration * fps)
+	}
+	if frames > live.frames {
+		live.frames = frames
+	}
+}
+
+// Returns true if the file has grown within the idle time before "now".
+func (live *liveFile) growing(now time.Time) bool {
+	live.mu.Lock()
+	defer live.mu.Unlock()
+	return !live.stopped && now.Sub(live.grown) < live.idle
+}
+
+// Polls the file until stopped, re-probing it whenever it has grown.
+func (live *liveFile) watch(video *Video) {
+	ticker := time.NewTicker(livePoll)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-live.stop:
+			return
+		case now := <-ticker.C:
+			if !live.poll(video.filename, now) {
+				continue
+			}
+			data, err := ffprobeEntries(video.filename, fmt.Sprintf("v:%d", video.stream), "format", "duration")
+			if err != nil || len(data) == 0 {
+				continue
+			}
+			live.update(parse(data[0]["duration"]), video.fps, 0)
+		}
+	}
+}
+
+// Starts watching the file, also after it was stopped by close.
+func (live *liveFile) start(video *Video) {
+	live.mu.Lock()
+	defer live.mu.Unlock()
+	if live.stop == nil || live.stopped {
+		live.stop = make(chan struct{})
+		live.stopped = false
+		go live.watch(video)
+	}
+}
+
+// Stops watching the file and marks it as no longer live.
+func (live *liveFile) close() {
+	live.mu.Lock()
+	defer live.mu.Unlock()
+	if live.stop != nil && !live.stopped {
+		close(live.stop)
+	}
+	live.stopped = true
+}
+
+// Returns true if the video was opened with WithLive and its file is still being written,
+// i.e. it has grown recently and reading it has not ended.
+func (video *Video) Live() bool {
+	if video.live == nil {
+		return false
+	}
+	now := time.Now()
+	video.live.poll(video.filename, now)
+	return video.live.growing(now)
+}
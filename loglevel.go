@@ -0,0 +1,89 @@
+package vidio
+
+import (
+	"bytes"
+	"fmt"
+	"os/exec"
+	"strings"
+	"sync/atomic"
+)
+
+// Log level of the ffmpeg processes decoding frames and audio, set with SetFFmpegLogLevel. Empty for "quiet".
+var decodeLogLevel atomic.Value
+
+// Log levels accepted by ffmpeg, from least to most verbose.
+var ffmpegLogLevels = []string{"quiet", "panic", "fatal", "error", "warning", "info", "verbose", "debug", "trace"}
+
+// Sets the log level of the ffmpeg processes decoding frames and audio, such as those of Video.Read,
+// ReadFrame, Camera and FrameMap. The default "quiet" hides all messages. With a higher level, e.g. "warning",
+// the messages, such as "corrupt frame" warnings, are emitted as EventLog events. Operations running ffmpeg
+// to completion always report errors in their returned error. Concealing decoders, see WithErrorConcealment,
+// report damage through their callback instead.
+func SetFFmpegLogLevel(level string) error {
+	for _, known := range ffmpegLogLevels {
+		if level == known {
+			decodeLogLevel.Store(level)
+			return nil
+		}
+	}
+	return fmt.Errorf("vidio: unknown ffmpeg log level: %s, expected one of %s", level, strings.Join(ffmpegLogLevels, ", "))
+}
+
+// Returns the log level of decoding processes.
+func logLevel() string {
+	if level, ok := decodeLogLevel.Load().(string); ok {
+		return level
+	}
+	return "quiet"
+}
+
+// Emits the messages of a decoding process as EventLog events, unless messages are disabled.
+func logOutput(cmd *exec.Cmd, source string) {
+	if logLevel() != "quiet" && cmd.Stderr == nil {
+		cmd.Stderr = &ffmpegLog{source: source}
+	}
+}
+
+// Waits for a process set up with logOutput to exit and emits its last message,
+// which ffmpeg may end without a newline.
+func waitOutput(cmd *exec.Cmd) error {
+	err := cmd.Wait()
+	if log, ok := cmd.Stderr.(*ffmpegLog); ok {
+		log.Close()
+	}
+	return err
+}
+
+// Emits every line written by ffmpeg as an event.
+type ffmpegLog struct {
+	source  string       // Input of the process.
+	partial bytes.Buffer // Incomplete last line.
+}
+
+func (log *ffmpegLog) Write(p []byte) (int, error) {
+	log.partial.Write(p)
+	for {
+		line, err := log.partial.ReadString('\n')
+		if err != nil {
+			// Keeps the incomplete line for the next write.
+			log.partial.Reset()
+			log.partial.WriteString(line)
+			break
+		}
+		log.emit(line)
+	}
+	return len(p), nil
+}
+
+// Emits the incomplete last line, once the process has exited.
+func (log *ffmpegLog) Close() error {
+	log.emit(log.partial.String())
+	log.partial.Reset()
+	return nil
+}
+
+func (log *ffmpegLog) emit(line string) {
+	if message := strings.TrimSpace(line); message != "" {
+		emit(Event{Type: EventLog, Source: log.source, Message: message})
+	}
+}
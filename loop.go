@@ -0,0 +1,114 @@
+package vidio
+
+import (
+	"fmt"
+
+	"github.com/benitogf/Vidio/ffcmd"
+)
+
+// Region of a Player set with SetLoopRegion.
+type loopRegion struct {
+	start float64 // Start in seconds.
+	end   float64 // End in seconds, exclusive.
+}
+
+// Constrains Player.Read and paced playback to the region from "start" to "end" in seconds, e.g. to study
+// a moment over and over. Reading continues at "start" once "end" or the end of the file is reached, and
+// the playback clock carries over, so the loop plays without a pause. If the position is outside the
+// region, the next Read jumps to "start".
+func (player *Player) SetLoopRegion(start, end float64) error {
+	if start < 0 || end <= start {
+		return fmt.Errorf("vidio: invalid loop region %g to %g", start, end)
+	}
+	if duration := player.duration(); duration > 0 && start >= duration {
+		return fmt.Errorf("vidio: loop region %g to %g is not within the duration %g", start, end, duration)
+	}
+	player.mu.Lock()
+	defer player.mu.Unlock()
+	player.loop = &loopRegion{start: start, end: end}
+	return nil
+}
+
+// Returns the loop region in seconds and true, or false if none is set.
+func (player *Player) LoopRegion() (float64, float64, bool) {
+	player.mu.Lock()
+	defer player.mu.Unlock()
+	if player.loop == nil {
+		return 0, 0, false
+	}
+	return player.loop.start, player.loop.end, true
+}
+
+// Removes the loop region, so reading continues to the end of the file.
+func (player *Player) ClearLoopRegion() {
+	player.mu.Lock()
+	defer player.mu.Unlock()
+	player.loop = nil
+}
+
+// Renders the loop region into "output", e.g. to share the moment studied in a review tool. Videos are
+// encoded with libx264 and their audio, if any, with AAC. Audio files are encoded with the default codec
+// of the output format, chosen by its extension.
+func (player *Player) ExportRegion(output string, options ...Option) error {
+	start, end, ok := player.LoopRegion()
+	if !ok {
+		return fmt.Errorf("vidio: player of %s has no loop region to export", player.FilePath)
+	}
+	region := clip{start: start, end: end}
+	if duration := player.duration(); duration > 0 {
+		region.end = min(region.end, duration)
+	}
+	if player.Audio == nil {
+		return encodeClips(player.FilePath, output, []clip{region}, player.Video.Stream(), true, options...)
+	}
+
+	builder := ffcmd.New().
+		Global("-y").
+		Input(player.FilePath, "-ss", fmt.Sprintf("%.3f", region.start), "-t", fmt.Sprintf("%.3f", region.end-region.start)).
+		Map("0:a:0").
+		Output(output, "-vn")
+	return newConfig(options).run(builder)
+}
+
+// Moves the position into the loop region before a read: wraps at the end of the region, or jumps to its
+// start if the position is before it.
+func (player *Player) enterLoop() error {
+	if player.loop == nil {
+		return nil
+	}
+	// Half a frame or sample of tolerance, so rounding of the position never reads past the end.
+	tolerance := 0.0
+	if rate := player.rate(); rate > 0 {
+		tolerance = 0.5 / rate
+	}
+	position := player.Position()
+	switch {
+	case position+tolerance >= player.loop.end:
+		return player.wrap()
+	case position+tolerance < player.loop.start:
+		if err := player.seek(player.loop.start); err != nil {
+			return err
+		}
+		player.restartClock()
+	}
+	return nil
+}
+
+// Continues reading at the start of the loop region. The end of the region stays due when it was,
+// so paced playback is not delayed.
+func (player *Player) wrap() error {
+	due := player.due()
+	if err := player.seek(player.loop.start); err != nil {
+		return err
+	}
+	player.clock, player.origin = due, player.Position()
+	return nil
+}
+
+// Returns the number of frames or samples per second.
+func (player *Player) rate() float64 {
+	if player.Audio != nil {
+		return float64(player.Audio.SampleRate())
+	}
+	return player.Video.FPS()
+}
@@ -0,0 +1,106 @@
+package vidio
+
+import (
+	"cmp"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"slices"
+)
+
+// A labelled moment of a Player, e.g. a note of a reviewer. Can be stored as JSON.
+type Marker struct {
+	ID    int     `json:"id"`    // Identifier, unique within the player.
+	Time  float64 `json:"time"`  // Position in seconds.
+	Label string  `json:"label"` // Description of the moment.
+}
+
+// Adds a marker at the given position in seconds and returns it. Markers are kept in order of time.
+// If a marker file is set, it is rewritten.
+func (player *Player) AddMarker(t float64, label string) (Marker, error) {
+	if duration := player.duration(); t < 0 || duration > 0 && t > duration {
+		return Marker{}, fmt.Errorf("vidio: marker time %g is not within the duration %g", t, duration)
+	}
+	player.markersMu.Lock()
+	defer player.markersMu.Unlock()
+	player.markerID++
+	marker := Marker{ID: player.markerID, Time: t, Label: label}
+	// Markers at the same time stay in the order they were added.
+	index := slices.IndexFunc(player.markers, func(marker Marker) bool { return marker.Time > t })
+	if index < 0 {
+		index = len(player.markers)
+	}
+	player.markers = slices.Insert(player.markers, index, marker)
+	return marker, player.saveMarkers()
+}
+
+// Returns the markers in order of time.
+func (player *Player) Markers() []Marker {
+	player.markersMu.Lock()
+	defer player.markersMu.Unlock()
+	return slices.Clone(player.markers)
+}
+
+// Removes the marker with the given ID. If a marker file is set, it is rewritten.
+func (player *Player) RemoveMarker(id int) error {
+	player.markersMu.Lock()
+	defer player.markersMu.Unlock()
+	index := slices.IndexFunc(player.markers, func(marker Marker) bool { return marker.ID == id })
+	if index < 0 {
+		return fmt.Errorf("vidio: marker %d does not exist", id)
+	}
+	player.markers = slices.Delete(player.markers, index, index+1)
+	return player.saveMarkers()
+}
+
+// Stores the markers of the player as a JSON array in the given file, rewritten whenever a marker is
+// added or removed. If the file exists, its markers replace the current ones, so markers survive restarts.
+func (player *Player) SetMarkerFile(filename string) error {
+	player.markersMu.Lock()
+	defer player.markersMu.Unlock()
+	data, err := os.ReadFile(filename)
+	if err == nil {
+		markers := []Marker{}
+		if err := json.Unmarshal(data, &markers); err != nil {
+			return fmt.Errorf("vidio: invalid marker file %s: %w", filename, err)
+		}
+		slices.SortStableFunc(markers, func(a, b Marker) int { return cmp.Compare(a.Time, b.Time) })
+		player.markers = markers
+		player.markerID = 0
+		for _, marker := range markers {
+			player.markerID = max(player.markerID, marker.ID)
+		}
+	} else if !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	player.markerFile = filename
+	return player.saveMarkers()
+}
+
+// Writes the markers to the marker file, if any. Must be called with markersMu held.
+func (player *Player) saveMarkers() error {
+	if player.markerFile == "" {
+		return nil
+	}
+	markers := player.markers
+	if markers == nil {
+		markers = []Marker{}
+	}
+	data, err := json.MarshalIndent(markers, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(player.markerFile, append(data, '\n'), 0644)
+}
+
+// Returns the duration of the file in seconds, or 0 if unknown.
+func (player *Player) duration() float64 {
+	if player.Audio != nil {
+		return player.Audio.Duration()
+	}
+	if player.Video != nil {
+		return player.Video.Duration()
+	}
+	return 0
+}
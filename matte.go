@@ -0,0 +1,63 @@
+package vidio
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/benitogf/Vidio/ffcmd"
+)
+
+// Codec options of the containers ApplyMatte can write with an alpha channel, by extension.
+var alphaCodecs = map[string][]string{
+	".mov":  {"-c:v", "prores_ks", "-profile:v", "4444", "-pix_fmt", "yuva444p10le", "-c:a", "copy"},
+	".webm": {"-c:v", "libvpx-vp9", "-pix_fmt", "yuva420p", "-c:a", "libopus"},
+	".mkv":  {"-c:v", "ffv1", "-pix_fmt", "yuva420p", "-c:a", "copy"},
+}
+
+// Writes the alpha channel of "input", which must have one, e.g. a ProRes 4444 or VP9 video with alpha,
+// as a grayscale matte video to "output": white where the source is opaque, black where it is transparent.
+func ExtractAlpha(input, output string, options ...Option) error {
+	if !isURL(input) && !exists(input) {
+		return fmt.Errorf("vidio: video file %s does not exist", input)
+	}
+
+	builder := ffcmd.New().
+		Global("-y").
+		Input(input).
+		Map("0:v:0").
+		Output(output,
+			"-vf", "alphaextract,format=gray",
+			"-c:v", "libx264",
+			"-crf", "12",
+			"-pix_fmt", "yuv420p",
+		)
+	return newConfig(options).run(builder)
+}
+
+// Combines "video" with the grayscale "matte" as its alpha channel and writes the result to "output",
+// e.g. a matte exported with ExtractAlpha or rendered by a segmentation model. The matte is scaled to the size
+// of the video. "output" must be a .mov (ProRes 4444), .webm (VP9) or .mkv (FFV1) file, which keep the alpha
+// channel. The audio of the video is kept.
+func ApplyMatte(video, matte, output string, options ...Option) error {
+	codec, ok := alphaCodecs[strings.ToLower(filepath.Ext(output))]
+	if !ok {
+		return fmt.Errorf("vidio: output %s must be a .mov, .webm or .mkv file to keep the alpha channel", output)
+	}
+	for _, filename := range []string{video, matte} {
+		if !isURL(filename) && !exists(filename) {
+			return fmt.Errorf("vidio: video file %s does not exist", filename)
+		}
+	}
+
+	builder := ffcmd.New().
+		Global("-y").
+		Input(video).
+		Input(matte).
+		Filter("[1:v][0:v]scale2ref[m][v]").
+		Filter("[m]format=gray[a]").
+		Filter("[v][a]alphamerge[out]").
+		Map("[out]", "0:a?").
+		Output(output, append(codec, "-shortest")...)
+	return newConfig(options).run(builder)
+}
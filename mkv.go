@@ -0,0 +1,139 @@
+package vidio
+
+import (
+	"encoding/binary"
+	"math"
+	"time"
+)
+
+// Minimal Matroska muxer for feeding raw RGBA frames with explicit timestamps to ffmpeg.
+// Raw video over a pipe carries no timing, so timestamped frames are wrapped in a
+// V_UNCOMPRESSED track with one cluster per frame instead.
+
+// Matroska element IDs, see https://www.matroska.org/technical/elements.html.
+var (
+	mkvEBML               = []byte{0x1A, 0x45, 0xDF, 0xA3}
+	mkvEBMLVersion        = []byte{0x42, 0x86}
+	mkvEBMLReadVersion    = []byte{0x42, 0xF7}
+	mkvEBMLMaxIDLength    = []byte{0x42, 0xF2}
+	mkvEBMLMaxSizeLength  = []byte{0x42, 0xF3}
+	mkvDocType            = []byte{0x42, 0x82}
+	mkvDocTypeVersion     = []byte{0x42, 0x87}
+	mkvDocTypeReadVersion = []byte{0x42, 0x85}
+	mkvSegment            = []byte{0x18, 0x53, 0x80, 0x67}
+	mkvInfo               = []byte{0x15, 0x49, 0xA9, 0x66}
+	mkvTimestampScale     = []byte{0x2A, 0xD7, 0xB1}
+	mkvMuxingApp          = []byte{0x4D, 0x80}
+	mkvWritingApp         = []byte{0x57, 0x41}
+	mkvTracks             = []byte{0x16, 0x54, 0xAE, 0x6B}
+	mkvTrackEntry         = []byte{0xAE}
+	mkvTrackNumber        = []byte{0xD7}
+	mkvTrackUID           = []byte{0x73, 0xC5}
+	mkvTrackType          = []byte{0x83}
+	mkvCodecID            = []byte{0x86}
+	mkvDefaultDuration    = []byte{0x23, 0xE3, 0x83}
+	mkvVideo              = []byte{0xE0}
+	mkvPixelWidth         = []byte{0xB0}
+	mkvPixelHeight        = []byte{0xBA}
+	mkvColourSpace        = []byte{0x2E, 0xB5, 0x24}
+	mkvCluster            = []byte{0x1F, 0x43, 0xB6, 0x75}
+	mkvTimestamp          = []byte{0xE7}
+	mkvSimpleBlock        = []byte{0xA3}
+)
+
+// Size marking an element whose size is not known in advance, used for the live segment.
+var mkvUnknownSize = []byte{0x01, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF}
+
+// Returns the stream header: the EBML header, the start of the segment, and a single
+// rgba video track with a timestamp scale of 1µs.
+func mkvHeader(width, height int, fps float64) []byte {
+	header := mkvElement(mkvEBML,
+		mkvUint(mkvEBMLVersion, 1),
+		mkvUint(mkvEBMLReadVersion, 1),
+		mkvUint(mkvEBMLMaxIDLength, 4),
+		mkvUint(mkvEBMLMaxSizeLength, 8),
+		mkvElement(mkvDocType, []byte("matroska")),
+		mkvUint(mkvDocTypeVersion, 4),
+		mkvUint(mkvDocTypeReadVersion, 2),
+	)
+	header = append(header, mkvSegment...)
+	header = append(header, mkvUnknownSize...)
+
+	header = append(header, mkvElement(mkvInfo,
+		mkvUint(mkvTimestampScale, uint64(time.Microsecond)),
+		mkvElement(mkvMuxingApp, []byte("vidio")),
+		mkvElement(mkvWritingApp, []byte("vidio")),
+	)...)
+
+	track := [][]byte{
+		mkvUint(mkvTrackNumber, 1),
+		mkvUint(mkvTrackUID, 1),
+		mkvUint(mkvTrackType, 1),
+		mkvElement(mkvCodecID, []byte("V_UNCOMPRESSED")),
+	}
+	if fps > 0 {
+		track = append(track, mkvUint(mkvDefaultDuration, uint64(math.Round(1e9/fps))))
+	}
+	track = append(track, mkvElement(mkvVideo,
+		mkvUint(mkvPixelWidth, uint64(width)),
+		mkvUint(mkvPixelHeight, uint64(height)),
+		mkvElement(mkvColourSpace, []byte("RGBA")),
+	))
+
+	return append(header, mkvElement(mkvTracks, mkvElement(mkvTrackEntry, track...))...)
+}
+
+// Returns the cluster and block header preceding a frame of "size" bytes with the given timestamp.
+// The frame data follows directly, so it does not need to be copied.
+func mkvFrameHeader(pts time.Duration, size int) []byte {
+	timestamp := mkvUint(mkvTimestamp, uint64(pts/time.Microsecond))
+	// Track number 1, relative timestamp 0, keyframe.
+	block := []byte{0x81, 0x00, 0x00, 0x80}
+
+	blocksize := len(block) + size
+	header := append([]byte{}, mkvSimpleBlock...)
+	header = append(header, mkvSize(uint64(blocksize))...)
+	header = append(header, block...)
+
+	clustersize := len(timestamp) + len(header) + size
+	cluster := append([]byte{}, mkvCluster...)
+	cluster = append(cluster, mkvSize(uint64(clustersize))...)
+	cluster = append(cluster, timestamp...)
+	return append(cluster, header...)
+}
+
+// Encodes an element with the given children as its data.
+func mkvElement(id []byte, children ...[]byte) []byte {
+	size := 0
+	for _, child := range children {
+		size += len(child)
+	}
+	element := append([]byte{}, id...)
+	element = append(element, mkvSize(uint64(size))...)
+	for _, child := range children {
+		element = append(element, child...)
+	}
+	return element
+}
+
+// Encodes an unsigned integer element using the fewest bytes.
+func mkvUint(id []byte, value uint64) []byte {
+	data := make([]byte, 8)
+	binary.BigEndian.PutUint64(data, value)
+	for len(data) > 1 && data[0] == 0 {
+		data = data[1:]
+	}
+	return mkvElement(id, data)
+}
+
+// Encodes an element size as an EBML variable length integer.
+func mkvSize(size uint64) []byte {
+	length := 1
+	// All ones is reserved for unknown sizes.
+	for length < 8 && size >= 1<<(7*length)-1 {
+		length++
+	}
+	data := make([]byte, 8)
+	binary.BigEndian.PutUint64(data, size|1<<(7*length))
+	return data[8-length:]
+}
@@ -0,0 +1,18 @@
+//go:build !windows
+
+package vidio
+
+import (
+	"os"
+	"syscall"
+)
+
+// Maps the first "length" bytes of the given file into memory, read only.
+func mapFile(file *os.File, length int) ([]byte, error) {
+	return syscall.Mmap(int(file.Fd()), 0, length, syscall.PROT_READ, syscall.MAP_SHARED)
+}
+
+// Unmaps memory returned by mapFile.
+func unmapFile(mapping []byte) error {
+	return syscall.Munmap(mapping)
+}
@@ -0,0 +1,17 @@
+package vidio
+
+import "os"
+
+// Memory mapping is not used on windows; the file is read into memory instead.
+func mapFile(file *os.File, length int) ([]byte, error) {
+	mapping := make([]byte, length)
+	if _, err := file.ReadAt(mapping, 0); err != nil {
+		return nil, err
+	}
+	return mapping, nil
+}
+
+// Releases memory returned by mapFile.
+func unmapFile(mapping []byte) error {
+	return nil
+}
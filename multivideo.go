@@ -0,0 +1,142 @@
+package vidio
+
+import (
+	"fmt"
+	"math"
+	"slices"
+	"strings"
+	"time"
+)
+
+// Reads several camera angles of the same scene in lockstep, e.g. for multicam review tools.
+// The angles are aligned so that the frames returned together were recorded at the same moment.
+type MultiVideo struct {
+	videos  []*Video        // Angles in the order given.
+	offsets []time.Duration // Start of every angle relative to the angle started first.
+	fps     float64         // Frames per second of all angles.
+	started bool            // Whether reading has started.
+}
+
+// Opens the given angles for reading in lockstep. Frames of all angles have the frame rate of the first file,
+// other angles are resampled. The angles are aligned by the timecode of their first frames if all of them have
+// one, otherwise by their creation times. Use SetOffsets with offsets from AlignByAudio if the cameras
+// were not synchronized. The options are passed to NewVideo for every file.
+func NewMultiVideo(files []string, options ...Option) (*MultiVideo, error) {
+	if len(files) == 0 {
+		return nil, fmt.Errorf("vidio: no video files given")
+	}
+
+	multi := &MultiVideo{}
+	for _, filename := range files {
+		video, err := NewVideo(filename, options...)
+		if err != nil {
+			multi.Close()
+			return nil, err
+		}
+		multi.videos = append(multi.videos, video)
+	}
+
+	multi.fps = multi.videos[0].fps
+	for _, video := range multi.videos[1:] {
+		if filter := normalizeFilter(video, video.width, video.height, multi.fps); filter != "" {
+			video.filter = strings.TrimPrefix(video.filter+","+filter, ",")
+		}
+	}
+
+	if err := multi.SetOffsets(captureOffsets(multi.videos)); err != nil {
+		multi.Close()
+		return nil, err
+	}
+	return multi, nil
+}
+
+// Returns the start of every video relative to the video started first, from the timecodes if all videos have one,
+// otherwise from the creation times. All offsets are 0 if neither is known for every video.
+func captureOffsets(videos []*Video) []time.Duration {
+	starts := make([]float64, len(videos))
+	timecodes, created := true, true
+	for i, video := range videos {
+		timecode, ok := video.Timecode()
+		if ok && video.fps > 0 {
+			starts[i] = float64(timecode.FrameNumber()) / video.fps
+		} else {
+			timecodes = false
+		}
+	}
+	if !timecodes {
+		for i, video := range videos {
+			creation, err := time.Parse(time.RFC3339Nano, video.metadata["tag:creation_time"])
+			if err != nil {
+				created = false
+				break
+			}
+			starts[i] = float64(creation.UnixNano()) / 1e9
+		}
+	}
+
+	offsets := make([]time.Duration, len(videos))
+	if !timecodes && !created {
+		return offsets
+	}
+	first := slices.Min(starts)
+	for i, start := range starts {
+		offsets[i] = time.Duration(math.Round((start - first) * 1e9))
+	}
+	return offsets
+}
+
+// Sets the start of every angle relative to the others, one offset per angle, such as the offsets returned
+// by AlignByAudio. Reading starts at the moment the angle started last began recording, earlier frames of the
+// other angles are skipped. Must be called before reading.
+func (multi *MultiVideo) SetOffsets(offsets []time.Duration) error {
+	if len(offsets) != len(multi.videos) {
+		return fmt.Errorf("vidio: %d offsets given for %d videos", len(offsets), len(multi.videos))
+	}
+	if multi.started {
+		return fmt.Errorf("vidio: offsets must be set before reading")
+	}
+
+	latest := slices.Max(offsets)
+	for i, video := range multi.videos {
+		video.start = int(math.Round((latest - offsets[i]).Seconds() * video.fps))
+		video.delivered.Store(int64(video.start))
+	}
+	multi.offsets = append([]time.Duration{}, offsets...)
+	return nil
+}
+
+// Start of every angle relative to the others.
+func (multi *MultiVideo) Offsets() []time.Duration {
+	return multi.offsets
+}
+
+// Frames per second of all angles, taken from the first file.
+func (multi *MultiVideo) FPS() float64 {
+	return multi.fps
+}
+
+// Angles in the order given. Each has its own frame size.
+func (multi *MultiVideo) Videos() []*Video {
+	return multi.videos
+}
+
+// Reads the next frame of every angle and returns their framebuffers, in the order of the files.
+// Returns nil once any of the angles has ended.
+func (multi *MultiVideo) ReadAll() [][]byte {
+	multi.started = true
+	frames := make([][]byte, len(multi.videos))
+	for i, video := range multi.videos {
+		if !video.Read() {
+			return nil
+		}
+		frames[i] = video.framebuffer
+	}
+	return frames
+}
+
+// Stops reading and closes all angles.
+func (multi *MultiVideo) Close() {
+	for _, video := range multi.videos {
+		video.Close()
+	}
+}
@@ -0,0 +1,74 @@
+//go:build ndi
+
+package vidio
+
+import (
+	"bytes"
+	"fmt"
+	"os/exec"
+	"regexp"
+	"strings"
+)
+
+// NDI support requires an ffmpeg build with the libndi_newtek input device.
+// Build with "-tags ndi" to enable it.
+
+// Returns the names of the NDI sources found on the local network.
+func NDISources() ([]string, error) {
+	if err := installed("ffmpeg"); err != nil {
+		return nil, err
+	}
+
+	cmd := exec.Command(
+		"ffmpeg",
+		"-hide_banner",
+		"-f", "libndi_newtek",
+		"-find_sources", "1",
+		"-i", "dummy",
+	)
+
+	// The command fails since there is no source named "dummy" and lists the sources on Stderr.
+	stderr := bytes.Buffer{}
+	cmd.Stderr = &stderr
+	cmd.Run()
+
+	output := stderr.String()
+	if strings.Contains(output, "Unknown input format") {
+		return nil, fmt.Errorf("vidio: ffmpeg was built without libndi_newtek support")
+	}
+
+	return parseNDISources(output), nil
+}
+
+// Parses the source names from the ffmpeg -find_sources output.
+// Sources are listed as: 'HOST (Source Name)'	'192.168.1.10:5961'.
+func parseNDISources(buffer string) []string {
+	regex := regexp.MustCompile(`'([^']+)'\s+'[^']+'`)
+	sources := []string{}
+	for _, match := range regex.FindAllStringSubmatch(buffer, -1) {
+		sources = append(sources, match[1])
+	}
+	return sources
+}
+
+// Creates a new camera struct that reads from the NDI source with the given name, as returned by NDISources.
+func NewNDICamera(source string) (*Camera, error) {
+	if err := installed("ffmpeg"); err != nil {
+		return nil, err
+	}
+
+	camera := &Camera{
+		name:   source,
+		depth:  4,
+		pixfmt: PixelRGBA,
+		input:  []string{"-f", "libndi_newtek", "-i", source},
+	}
+	if _, err := camera.getCameraData(); err != nil {
+		return nil, err
+	}
+	if camera.width == 0 || camera.height == 0 {
+		return nil, fmt.Errorf("vidio: could not read stream information from NDI source %s", source)
+	}
+
+	return camera, nil
+}
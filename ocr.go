@@ -0,0 +1,123 @@
+package vidio
+
+import (
+	"fmt"
+	"image"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Recognizes the text in an image, e.g. by calling Tesseract or a cloud OCR service. The image is only valid
+// during the call.
+type OCRFunc func(region image.Image) (string, error)
+
+// Text recognized in a region of a frame by ReadOverlayText.
+type OverlayText struct {
+	Frame int     // Index of the frame in the video.
+	Time  float64 // Timestamp of the frame in seconds.
+	Text  string  // Text returned by the OCR function.
+}
+
+// Crops the given region of the frames of "filename" and passes it to the OCR function, e.g. to read the
+// timestamps burnt into CCTV footage without metadata. A frame every "interval" seconds is read, every frame if
+// the interval is 0. The region is in pixels of the decoded frames. Parse recognized timestamps with
+// ParseBurnedTimestamp. Stops at the first error of the OCR function.
+func ReadOverlayText(filename string, region image.Rectangle, interval float64, ocr OCRFunc, options ...Option) ([]OverlayText, error) {
+	video, err := NewVideo(filename, options...)
+	if err != nil {
+		return nil, err
+	}
+	defer video.Close()
+
+	region = region.Canon()
+	if region.Empty() || !region.In(image.Rect(0, 0, video.width, video.height)) {
+		return nil, fmt.Errorf("vidio: region %v is not within the %dx%d frames", region, video.width, video.height)
+	}
+	video.filter = strings.TrimPrefix(video.filter+","+overlayFilter(region, interval), ",")
+	video.width, video.height = region.Dx(), region.Dy()
+
+	texts := []OverlayText{}
+	for i := 0; video.Read(); i++ {
+		frame, err := video.Image()
+		if err != nil {
+			return nil, err
+		}
+		text, err := ocr(frame)
+		if err != nil {
+			return texts, err
+		}
+		t := frameTime(i, video.fps)
+		if interval > 0 {
+			t = float64(i) * interval
+		}
+		texts = append(texts, OverlayText{
+			Frame: int(math.Round(t * video.fps)),
+			Time:  t,
+			Text:  strings.TrimSpace(text),
+		})
+	}
+	return texts, nil
+}
+
+// Returns the filter sampling a frame every "interval" seconds and cropping it to the region.
+func overlayFilter(region image.Rectangle, interval float64) string {
+	crop := fmt.Sprintf("crop=%d:%d:%d:%d", region.Dx(), region.Dy(), region.Min.X, region.Min.Y)
+	if interval <= 0 {
+		return crop
+	}
+	return "fps=" + strconv.FormatFloat(1/interval, 'f', -1, 64) + "," + crop
+}
+
+// Layouts of timestamps commonly burnt into CCTV and dashcam footage.
+var burnedLayouts = []string{
+	"2006-01-02 15:04:05",
+	"2006/01/02 15:04:05",
+	"2006.01.02 15:04:05",
+	"02-01-2006 15:04:05",
+	"02.01.2006 15:04:05",
+	"01/02/2006 15:04:05",
+	"01-02-2006 03:04:05 PM",
+	"01/02/2006 03:04:05 PM",
+	"2006-01-02 03:04:05 PM",
+	"Jan 02 2006 15:04:05",
+	"02 Jan 2006 15:04:05",
+}
+
+// Finds timestamps like "2024-05-01 13:45:07", "05/01/2024 01:45:07 PM" or "01 May 2024 13:45:07" in OCR output.
+var burnedPattern = regexp.MustCompile(`(?i)(\d{1,4}[-/.]\d{1,2}[-/.]\d{1,4}|[a-z]{3} \d{1,2},? \d{4}|\d{1,2} [a-z]{3} \d{4})(?: [a-z]{3})? \d{1,2}:\d{2}:\d{2}(?: ?[ap]m)?`)
+
+// Characters OCR commonly confuses with digits.
+var digitReplacer = strings.NewReplacer("O", "0", "o", "0", "D", "0", "I", "1", "l", "1", "|", "1", "S", "5", "B", "8", "Z", "2")
+
+// Weekdays some cameras show between date and time, and AM/PM markers with or without a space.
+var (
+	weekdayPattern  = regexp.MustCompile(` (MON|TUE|WED|THU|FRI|SAT|SUN) `)
+	meridiemPattern = regexp.MustCompile(` ?([AP]M)$`)
+)
+
+// Parses a timestamp burnt into frames, such as the text read by ReadOverlayText, in the given location,
+// UTC if nil. If the text does not parse, it is retried with letters OCR commonly confuses with digits
+// replaced, e.g. "2O24-O5-01 l3:45:07".
+func ParseBurnedTimestamp(text string, location *time.Location) (time.Time, error) {
+	if location == nil {
+		location = time.UTC
+	}
+	fields := strings.Join(strings.Fields(text), " ")
+	for _, candidate := range []string{fields, digitReplacer.Replace(fields)} {
+		for _, match := range burnedPattern.FindAllString(candidate, -1) {
+			// Month names are matched regardless of case.
+			match = strings.ToUpper(strings.Replace(match, ",", "", 1))
+			match = weekdayPattern.ReplaceAllString(match, " ")
+			match = meridiemPattern.ReplaceAllString(match, " $1")
+			for _, layout := range burnedLayouts {
+				if t, err := time.ParseInLocation(layout, match, location); err == nil {
+					return t, nil
+				}
+			}
+		}
+	}
+	return time.Time{}, fmt.Errorf("vidio: no timestamp found in %q", text)
+}
@@ -0,0 +1,65 @@
+package vidio
+
+import (
+	"math"
+	"strings"
+)
+
+// Makes thumbnail and preview operations, such as BestThumbnailFrame, Burst and PreviewClip, return frames
+// as they are stored. By default frames are rotated as given by the rotation metadata of phone clips,
+// and anamorphic frames with non-square pixels are stretched to their display aspect ratio.
+func WithoutAutoOrient() Option {
+	return func(c *config) {
+		c.noorient = true
+	}
+}
+
+// Filter stretching frames with non-square pixels to their display aspect ratio.
+const sarFilter = "scale=trunc(iw*sar/2)*2:ih,setsar=1"
+
+// Returns the rotation in degrees from the stream metadata, either the legacy rotate tag
+// or the rotation of the display matrix side data. 0 if the video is not rotated.
+func rotation(data map[string]string) int {
+	value, ok := data["tag:rotate"]
+	if !ok {
+		value, ok = data["rotation"]
+	}
+	if !ok {
+		return 0
+	}
+	degrees := int(parse(value)) % 360
+	if degrees < 0 {
+		degrees += 360
+	}
+	return degrees
+}
+
+// Parses a sample aspect ratio such as "4:3". Unknown or invalid ratios are 1.
+func parseRatio(value string) float64 {
+	num, den, ok := strings.Cut(value, ":")
+	if !ok || parse(num) <= 0 || parse(den) <= 0 {
+		return 1
+	}
+	return parse(num) / parse(den)
+}
+
+// Returns the input options and the filter orienting decoded frames, and the resulting frame size.
+// Frames are rotated by ffmpeg unless orientation is disabled, which also keeps non-square pixels.
+func (video *Video) orientation(c *config) ([]string, string, int, int) {
+	width, height := video.width, video.height
+	if c.noorient {
+		if video.rotation == 90 || video.rotation == 270 {
+			width, height = height, width
+		}
+		return []string{"-noautorotate"}, "", width, height
+	}
+	if video.sar == 0 || video.sar == 1 {
+		return nil, "", width, height
+	}
+	sar := video.sar
+	// Rotating frames by 90 degrees also inverts their sample aspect ratio.
+	if video.rotation == 90 || video.rotation == 270 {
+		sar = 1 / sar
+	}
+	return nil, sarFilter, int(2 * math.Trunc(float64(width)*sar/2)), height
+}
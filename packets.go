@@ -0,0 +1,142 @@
+package vidio
+
+import (
+	"bytes"
+	"fmt"
+	"math"
+	"os/exec"
+	"strconv"
+	"strings"
+)
+
+// A packet of a stream as reported by ffprobe.
+type packet struct {
+	pts      float64 // Presentation timestamp in seconds. NaN if unknown.
+	dts      float64 // Decoding timestamp in seconds. NaN if unknown.
+	size     int     // Size in bytes.
+	keyframe bool    // Whether the packet starts a keyframe.
+}
+
+// Runs ffprobe with "-show_entries section=entries" on the given stream and returns one map per entry.
+func ffprobeEntries(filename, stream, section, entries string) ([]map[string]string, error) {
+	if err := installed("ffprobe"); err != nil {
+		return nil, err
+	}
+
+	args := []string{"-loglevel", "error"}
+	if stream != "" {
+		args = append(args, "-select_streams", stream)
+	}
+	args = append(args,
+		"-show_entries", section+"="+entries,
+		"-print_format", "compact",
+	)
+	args = append(args, protocolArgs(filename)...)
+	cmd := exec.Command("ffprobe", append(args, filename)...)
+	stdout := bytes.Buffer{}
+	cmd.Stdout = &stdout
+	stderr := &tailBuffer{size: 4096}
+	cmd.Stderr = stderr
+
+	if err := cmd.Run(); err != nil {
+		return nil, &OpenError{Source: filename, Output: strings.TrimSpace(stderr.String()), Err: err}
+	}
+
+	data := []map[string]string{}
+	for _, line := range strings.Split(stdout.String(), "\n") {
+		fields := strings.Split(strings.TrimSpace(line), "|")
+		if fields[0] != section {
+			continue
+		}
+		entry := map[string]string{}
+		for _, field := range fields[1:] {
+			if key, value, ok := strings.Cut(field, "="); ok {
+				entry[key] = value
+			}
+		}
+		data = append(data, entry)
+	}
+	return data, nil
+}
+
+// Returns the packets of the given stream in decoding order.
+func probePackets(filename, stream string) ([]packet, error) {
+	data, err := ffprobeEntries(filename, stream, "packet", "pts_time,dts_time,size,flags")
+	if err != nil {
+		return nil, err
+	}
+	return parsePackets(data), nil
+}
+
+// Builds packets from the ffprobe packet entries.
+func parsePackets(data []map[string]string) []packet {
+	packets := make([]packet, len(data))
+	for i, entry := range data {
+		packets[i] = packet{
+			pts:      probeTime(entry["pts_time"]),
+			dts:      probeTime(entry["dts_time"]),
+			size:     int(parse(entry["size"])),
+			keyframe: strings.HasPrefix(entry["flags"], "K"),
+		}
+	}
+	return packets
+}
+
+// Parses an ffprobe timestamp, which is "N/A" if unknown.
+func probeTime(value string) float64 {
+	t, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		return math.NaN()
+	}
+	return t
+}
+
+// Bitrate of a stretch of a video.
+type BitratePoint struct {
+	Time      float64 // Start of the bucket in seconds.
+	Bitrate   float64 // Average bitrate during the bucket in bits/s.
+	Bytes     int     // Number of bytes of the packets in the bucket.
+	Keyframes int     // Number of keyframes in the bucket.
+}
+
+// Returns the bitrate of the first video stream over time, in buckets of "bucket" seconds, computed from the
+// packet sizes and timestamps without decoding the video. For plotting bitrate graphs.
+func BitrateTimeline(filename string, bucket float64) ([]BitratePoint, error) {
+	if bucket <= 0 {
+		return nil, fmt.Errorf("vidio: bitrate bucket size must be positive")
+	}
+	if !isURL(filename) && !exists(filename) {
+		return nil, fmt.Errorf("vidio: video file %s does not exist", filename)
+	}
+	packets, err := probePackets(filename, "v:0")
+	if err != nil {
+		return nil, err
+	}
+	return bitrateTimeline(packets, bucket), nil
+}
+
+// Groups the packets into buckets of "bucket" seconds by presentation time.
+func bitrateTimeline(packets []packet, bucket float64) []BitratePoint {
+	points := []BitratePoint{}
+	for _, packet := range packets {
+		t := packet.pts
+		if math.IsNaN(t) {
+			t = packet.dts
+		}
+		if math.IsNaN(t) || t < 0 {
+			continue
+		}
+		index := int(t / bucket)
+		for len(points) <= index {
+			points = append(points, BitratePoint{Time: float64(len(points)) * bucket})
+		}
+		points[index].Bytes += packet.size
+		if packet.keyframe {
+			points[index].Keyframes++
+		}
+	}
+	for i := range points {
+		points[i].Bitrate = float64(points[i].Bytes*8) / bucket
+	}
+	return points
+}
@@ -0,0 +1,105 @@
+package vidio
+
+import (
+	"fmt"
+	"math"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/benitogf/Vidio/ffcmd"
+)
+
+const (
+	peakRate   = 8000 // Sample rate of the audio analyzed by ClipAroundPeaks.
+	peakWindow = 0.1  // Length of the analysis windows in seconds.
+	peakSmooth = 5    // Windows averaged on either side, so single clicks are not peaks.
+)
+
+// Extracts clips around the "n" loudest moments of "input", such as crowd noise, a whistle or a goal call,
+// starting "pre" seconds before and ending "post" seconds after each peak. Peaks are at least the length of
+// a clip apart, so clips do not overlap. Clips are written next to the input as "<name>-peak-01.mp4",
+// "<name>-peak-02.mp4", ... in chronological order and encoded with libx264 and AAC. Returns the filenames.
+func ClipAroundPeaks(input string, pre, post float64, n int, options ...Option) ([]string, error) {
+	if n < 1 || pre < 0 || post < 0 || pre+post <= 0 {
+		return nil, fmt.Errorf("vidio: peak clips need at least one clip with a positive length")
+	}
+	if err := newConfig(options).checkSources(input); err != nil {
+		return nil, err
+	}
+	if !isURL(input) && !exists(input) {
+		return nil, fmt.Errorf("vidio: video file %s does not exist", input)
+	}
+	samples, err := readPCM(input, peakRate)
+	if err != nil {
+		return nil, err
+	}
+	duration := float64(len(samples)) / peakRate
+	peaks := audioPeaks(samples, peakRate, n, pre+post)
+	if len(peaks) == 0 {
+		return nil, fmt.Errorf("vidio: no audio peaks found in %s", input)
+	}
+
+	format := func(v float64) string { return strconv.FormatFloat(v, 'f', 3, 64) }
+	name := strings.TrimSuffix(input, filepath.Ext(input))
+	builder := ffcmd.New().Global("-y").Input(input)
+	files := make([]string, len(peaks))
+	for i, peak := range peaks {
+		files[i] = fmt.Sprintf("%s-peak-%02d.mp4", name, i+1)
+		builder.Map("0:v:0", "0:a:0").Output(files[i],
+			"-ss", format(math.Max(0, peak-pre)),
+			"-to", format(math.Min(duration, peak+post)),
+			"-c:v", "libx264",
+			"-crf", "20",
+			"-pix_fmt", "yuv420p",
+			"-c:a", "aac",
+			"-b:a", "160k",
+		)
+	}
+
+	if err := newConfig(options).run(builder); err != nil {
+		return nil, err
+	}
+	return files, nil
+}
+
+// Returns the times in seconds of the "n" loudest moments at least "spacing" seconds apart, in chronological order.
+func audioPeaks(samples []float32, rate, n int, spacing float64) []float64 {
+	rms := energy(samples, int(peakWindow*float64(rate)))
+	smooth := make([]float64, len(rms))
+	for i := range rms {
+		sum, count := 0.0, 0
+		for j := max(0, i-peakSmooth); j <= min(len(rms)-1, i+peakSmooth); j++ {
+			sum += rms[j]
+			count++
+		}
+		smooth[i] = sum / float64(count)
+	}
+
+	order := make([]int, len(smooth))
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(a, b int) bool { return smooth[order[a]] > smooth[order[b]] })
+
+	peaks := []float64{}
+	for _, i := range order {
+		if len(peaks) == n || smooth[i] == 0 {
+			break
+		}
+		t := (float64(i) + 0.5) * peakWindow
+		taken := false
+		for _, peak := range peaks {
+			if math.Abs(peak-t) < spacing {
+				taken = true
+				break
+			}
+		}
+		if !taken {
+			peaks = append(peaks, t)
+		}
+	}
+	sort.Float64s(peaks)
+	return peaks
+}
@@ -0,0 +1,113 @@
+package vidio
+
+import (
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"syscall"
+	"unsafe"
+)
+
+// Returns the number of bytes waiting to be read from the given pipe.
+func pipeBuffered(pipe io.Reader) int {
+	conn, ok := pipe.(syscall.Conn)
+	if !ok {
+		return 0
+	}
+	raw, err := conn.SyscallConn()
+	if err != nil {
+		return 0
+	}
+	var n int32
+	raw.Control(func(fd uintptr) {
+		_, _, errno := syscall.Syscall(syscall.SYS_IOCTL, fd, syscall.TIOCINQ, uintptr(unsafe.Pointer(&n)))
+		if errno != 0 {
+			n = 0
+		}
+	})
+	return int(n)
+}
+
+// Grows the kernel buffer of the given pipe to "size" bytes, capped at the system maximum.
+// Failures are ignored, the default 64KiB buffer still works, just with more context switches.
+func setPipeSize(pipe io.Reader, size int) {
+	conn, ok := pipe.(syscall.Conn)
+	if !ok {
+		return
+	}
+	raw, err := conn.SyscallConn()
+	if err != nil {
+		return
+	}
+
+	if data, err := os.ReadFile("/proc/sys/fs/pipe-max-size"); err == nil {
+		if max, err := strconv.Atoi(strings.TrimSpace(string(data))); err == nil && size > max {
+			size = max
+		}
+	}
+
+	raw.Control(func(fd uintptr) {
+		syscall.Syscall(syscall.SYS_FCNTL, fd, syscall.F_SETPIPE_SZ, uintptr(size))
+	})
+}
+
+// Fills the given buffers with consecutive data from the pipe using vectored reads (readv),
+// so several frames are read with a single system call when enough data is buffered.
+// Returns the number of buffers filled completely.
+func readBuffers(pipe io.Reader, buffers [][]byte) (int, error) {
+	conn, ok := pipe.(syscall.Conn)
+	if !ok {
+		return readBuffersFull(pipe, buffers)
+	}
+	raw, err := conn.SyscallConn()
+	if err != nil {
+		return readBuffersFull(pipe, buffers)
+	}
+
+	filled := 0
+	iovecs := make([]syscall.Iovec, 0, len(buffers))
+	for _, buffer := range buffers {
+		if len(buffer) == 0 {
+			filled++
+			continue
+		}
+		iovec := syscall.Iovec{Base: &buffer[0]}
+		iovec.SetLen(len(buffer))
+		iovecs = append(iovecs, iovec)
+	}
+
+	for len(iovecs) > 0 {
+		var n uintptr
+		var errno syscall.Errno
+		err := raw.Read(func(fd uintptr) bool {
+			n, _, errno = syscall.Syscall(syscall.SYS_READV, fd, uintptr(unsafe.Pointer(&iovecs[0])), uintptr(len(iovecs)))
+			// Returning false waits until the pipe is readable again.
+			return errno != syscall.EAGAIN
+		})
+		if err != nil {
+			return filled, err
+		}
+		if errno != 0 {
+			return filled, errno
+		}
+		if n == 0 {
+			return filled, io.ErrUnexpectedEOF
+		}
+
+		// Skip the fully read buffers and advance into the partially read one.
+		read := int(n)
+		for len(iovecs) > 0 && read >= int(iovecs[0].Len) {
+			read -= int(iovecs[0].Len)
+			iovecs = iovecs[1:]
+			filled++
+		}
+		if read > 0 {
+			base := unsafe.Slice(iovecs[0].Base, iovecs[0].Len)[read:]
+			iovecs[0].Base = &base[0]
+			iovecs[0].SetLen(len(base))
+		}
+	}
+
+	return filled, nil
+}
@@ -0,0 +1,20 @@
+//go:build !linux
+
+package vidio
+
+import "io"
+
+// Returns the number of bytes waiting to be read from the given pipe.
+// Only supported on Linux, returns 0 on other platforms.
+func pipeBuffered(pipe io.Reader) int {
+	return 0
+}
+
+// Grows the kernel buffer of the given pipe. Only supported on Linux.
+func setPipeSize(pipe io.Reader, size int) {}
+
+// Fills the given buffers with consecutive data from the pipe.
+// Returns the number of buffers filled completely.
+func readBuffers(pipe io.Reader, buffers [][]byte) (int, error) {
+	return readBuffersFull(pipe, buffers)
+}
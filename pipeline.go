@@ -0,0 +1,202 @@
+package vidio
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/benitogf/Vidio/ffcmd"
+)
+
+// A declarative processing pipeline, loaded from a JSON definition so processing can be changed
+// without recompiling. Paths may contain the placeholders "{input}", "{name}" and "{base}",
+// which are replaced with the input filename, its file name and its file name without extension.
+type Pipeline struct {
+	Sources []PipelineSource `json:"sources"` // Inputs in order. Input indexes in filters and maps refer to this order.
+	Filters []string         `json:"filters"` // Filtergraph chains, e.g. "[0:v]scale=1280:-2[hd]", joined into one -filter_complex.
+	Outputs []PipelineOutput `json:"outputs"` // Outputs written by the pipeline.
+	Limits  PipelineLimits   `json:"limits"`  // Limits applied to all outputs.
+}
+
+// An input of a Pipeline.
+type PipelineSource struct {
+	Path     string   `json:"path"`     // Input file or URL.
+	Start    float64  `json:"start"`    // Seconds skipped at the start of the input.
+	Duration float64  `json:"duration"` // Seconds read from the input. 0 reads everything.
+	Options  []string `json:"options"`  // Additional ffmpeg options placed before the input.
+}
+
+// An output of a Pipeline.
+type PipelineOutput struct {
+	Path         string   `json:"path"`          // Output filename.
+	Map          []string `json:"map"`           // Streams mapped into the output, e.g. "[hd]" or "0:a:0?". Empty uses the ffmpeg defaults.
+	Codec        string   `json:"codec"`         // Video codec, e.g. "libx264".
+	Bitrate      int      `json:"bitrate"`       // Video bitrate in bits/s.
+	AudioCodec   string   `json:"audio_codec"`   // Audio codec, e.g. "aac".
+	AudioBitrate int      `json:"audio_bitrate"` // Audio bitrate in bits/s.
+	Format       string   `json:"format"`        // Container format. Default derived from the extension.
+	Options      []string `json:"options"`       // Additional ffmpeg options for the output.
+}
+
+// Limits of a Pipeline.
+type PipelineLimits struct {
+	Duration    float64 `json:"duration"`      // Maximum duration of each output in seconds. 0 means no limit.
+	MaxFileSize int64   `json:"max_file_size"` // Maximum size of each output in bytes. 0 means no limit.
+	Timeout     string  `json:"timeout"`       // Maximum run time, e.g. "10m". Empty means no limit.
+}
+
+// Loads a pipeline definition from a JSON file.
+func LoadPipeline(filename string) (*Pipeline, error) {
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+	pipeline, err := ParsePipeline(data)
+	if err != nil {
+		return nil, fmt.Errorf("vidio: invalid pipeline %s: %w", filename, err)
+	}
+	return pipeline, nil
+}
+
+// Parses and validates a JSON pipeline definition. Unknown fields are rejected to catch typos.
+func ParsePipeline(data []byte) (*Pipeline, error) {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.DisallowUnknownFields()
+	pipeline := &Pipeline{}
+	if err := decoder.Decode(pipeline); err != nil {
+		return nil, err
+	}
+	if err := pipeline.validate(); err != nil {
+		return nil, err
+	}
+	return pipeline, nil
+}
+
+// Checks that the pipeline has sources and outputs and that its limits can be parsed.
+func (pipeline *Pipeline) validate() error {
+	if len(pipeline.Sources) == 0 {
+		return fmt.Errorf("vidio: pipeline has no sources")
+	}
+	if len(pipeline.Outputs) == 0 {
+		return fmt.Errorf("vidio: pipeline has no outputs")
+	}
+	for i, source := range pipeline.Sources {
+		if source.Path == "" {
+			return fmt.Errorf("vidio: pipeline source %d has no path", i)
+		}
+	}
+	for i, output := range pipeline.Outputs {
+		if output.Path == "" {
+			return fmt.Errorf("vidio: pipeline output %d has no path", i)
+		}
+	}
+	if _, err := pipeline.timeout(); err != nil {
+		return err
+	}
+	return nil
+}
+
+// Returns the run time limit, or 0 if there is none.
+func (pipeline *Pipeline) timeout() (time.Duration, error) {
+	if pipeline.Limits.Timeout == "" {
+		return 0, nil
+	}
+	timeout, err := time.ParseDuration(pipeline.Limits.Timeout)
+	if err != nil {
+		return 0, fmt.Errorf("vidio: invalid pipeline timeout %q", pipeline.Limits.Timeout)
+	}
+	return timeout, nil
+}
+
+// Replaces the placeholders in "path" for the given input filename.
+func expandPath(path, input string) string {
+	name := filepath.Base(input)
+	return strings.NewReplacer(
+		"{input}", input,
+		"{name}", name,
+		"{base}", strings.TrimSuffix(name, filepath.Ext(name)),
+	).Replace(path)
+}
+
+// Compiles the pipeline into an ffmpeg command, replacing the placeholders for the given input filename.
+func (pipeline *Pipeline) Builder(input string) (*ffcmd.Builder, error) {
+	if err := pipeline.validate(); err != nil {
+		return nil, err
+	}
+
+	builder := ffcmd.New().Global("-y")
+	for _, source := range pipeline.Sources {
+		options := []string{}
+		if source.Start > 0 {
+			options = append(options, "-ss", strconv.FormatFloat(source.Start, 'f', -1, 64))
+		}
+		if source.Duration > 0 {
+			options = append(options, "-t", strconv.FormatFloat(source.Duration, 'f', -1, 64))
+		}
+		options = append(options, source.Options...)
+		builder.Input(expandPath(source.Path, input), options...)
+	}
+	for _, filter := range pipeline.Filters {
+		builder.Filter(filter)
+	}
+
+	for _, output := range pipeline.Outputs {
+		options := []string{}
+		if output.Codec != "" {
+			options = append(options, "-c:v", output.Codec)
+		}
+		if output.Bitrate > 0 {
+			options = append(options, "-b:v", strconv.Itoa(output.Bitrate))
+		}
+		if output.AudioCodec != "" {
+			options = append(options, "-c:a", output.AudioCodec)
+		}
+		if output.AudioBitrate > 0 {
+			options = append(options, "-b:a", strconv.Itoa(output.AudioBitrate))
+		}
+		if pipeline.Limits.Duration > 0 {
+			options = append(options, "-t", strconv.FormatFloat(pipeline.Limits.Duration, 'f', -1, 64))
+		}
+		if pipeline.Limits.MaxFileSize > 0 {
+			options = append(options, "-fs", strconv.FormatInt(pipeline.Limits.MaxFileSize, 10))
+		}
+		options = append(options, output.Options...)
+		if output.Format != "" {
+			options = append(options, "-f", output.Format)
+		}
+		builder.Map(output.Map...).Output(expandPath(output.Path, input), options...)
+	}
+
+	return builder, nil
+}
+
+// Runs the pipeline for the given input filename, which replaces the placeholders in the paths.
+// The timeout limit of the pipeline is applied on top of the context passed with WithContext.
+func (pipeline *Pipeline) Run(input string, options ...Option) error {
+	builder, err := pipeline.Builder(input)
+	if err != nil {
+		return err
+	}
+	c := newConfig(options)
+	if timeout, _ := pipeline.timeout(); timeout > 0 {
+		ctx, cancel := context.WithTimeout(c.ctx, timeout)
+		defer cancel()
+		c.ctx = ctx
+	}
+	return c.run(builder)
+}
+
+// Returns a Watch pipeline step running the pipeline for the watched file.
+func (pipeline *Pipeline) Step(options ...Option) WatchStep {
+	return func(ctx context.Context, job *WatchJob) error {
+		// Copied, as steps may run concurrently.
+		stepOptions := append([]Option{}, options...)
+		return pipeline.Run(job.Filename, append(stepOptions, WithContext(ctx))...)
+	}
+}
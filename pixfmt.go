@@ -0,0 +1,53 @@
+package vidio
+
+import "fmt"
+
+// Pixel format of decoded frames.
+type PixelFormat string
+
+const (
+	PixelRGBA  PixelFormat = "rgba"  // 8-bit R, G, B, A. The default.
+	PixelBGRA  PixelFormat = "bgra"  // 8-bit B, G, R, A. Native layout of Win32 DIBs, X11 and most SDL/GPU surfaces.
+	PixelRGB24 PixelFormat = "rgb24" // 8-bit R, G, B without alpha.
+	PixelBGR24 PixelFormat = "bgr24" // 8-bit B, G, R without alpha. Native layout of 24-bit Win32 DIBs and OpenCV.
+	PixelGray  PixelFormat = "gray"  // 8-bit luma only.
+)
+
+// Number of bytes per pixel of the format, or 0 if the format is not supported.
+func (format PixelFormat) Depth() int {
+	switch format {
+	case PixelRGBA, PixelBGRA:
+		return 4
+	case PixelRGB24, PixelBGR24:
+		return 3
+	case PixelGray:
+		return 1
+	default:
+		return 0
+	}
+}
+
+// Returns the pixel format matching 32-bit display surfaces on all desktop platforms (little-endian ARGB words),
+// so frames can be blitted directly without swizzling channels in Go.
+func NativePixelFormat() PixelFormat {
+	return PixelBGRA
+}
+
+// Makes videos and cameras decode frames in the given pixel format instead of RGBA.
+// Frames are tightly packed: each row is Width()*Depth() bytes, see Stride().
+func WithPixelFormat(format PixelFormat) Option {
+	return func(c *config) {
+		c.pixfmt = format
+	}
+}
+
+// Returns the pixel format from the config, or an error if it is not supported.
+func (c *config) pixelFormat() (PixelFormat, error) {
+	if c.pixfmt == "" {
+		return PixelRGBA, nil
+	}
+	if c.pixfmt.Depth() == 0 {
+		return "", fmt.Errorf("vidio: unsupported pixel format: %s", c.pixfmt)
+	}
+	return c.pixfmt, nil
+}
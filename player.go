@@ -2,44 +2,341 @@ package vidio
 
 import (
 	"errors"
+	"fmt"
+	"regexp"
+	"sync"
+	"sync/atomic"
+	"time"
 )
 
+// A Video or audio file shared between sessions, created with GetPlayer. Copies of a player, such as the
+// entries of Players, share its state.
 type Player struct {
 	FilePath string
 	ID       string
 	Video    *Video
+	Audio    *AudioReader // Audio of audio-only files such as mp3, flac or wav. nil for videos.
+
+	*playerState
+}
+
+// State of a Player, shared by all copies of it.
+type playerState struct {
+	events      []*playerEvent    // Registered event triggers.
+	eventErr    error             // Last error raised by an event action.
+	subscribers []chan StateEvent // Channels returned by Subscribe, guarded by subscribersMu.
+	playing     bool              // Paced playback, see Play.
+	clock       time.Time         // Time the position "origin" was due.
+	origin      float64           // Position in seconds when the clock was last restarted.
+	speed       float64           // Playback speed factor, see SetSpeed. 0 is normal speed.
+	accessed    atomic.Int64      // Unix time in nanoseconds of the last access, see SetPlayerTTL.
+	markers     []Marker          // Markers in order of time, guarded by markersMu.
+	markerID    int               // ID of the last marker added.
+	markerFile  string            // JSON file the markers are stored in, if any.
+	markersMu   sync.Mutex        // Guards the markers.
+	loop        *loopRegion       // Region playback is constrained to, if any.
+	steps       *stepWindow       // Frames decoded for stepping, see StepForward.
+	mu          sync.Mutex        // Held while reading, seeking or closing, so players in use never expire.
+	closed      bool              // True once the player was closed or expired.
 }
 
-var Players []Player
+// Action taken by Player.OnEvent when the trigger fires.
+type SnapshotOrClip struct {
+	Output  string   // Output filename. A "%d" verb is replaced by the event number.
+	Clip    float64  // Clip length in seconds. 0 saves a single frame snapshot as png or jpeg.
+	Options *Options // Encoding options for clips.
+}
+
+// Registered Player event trigger.
+type playerEvent struct {
+	trigger   func() bool    // Returns true if the event should fire for the current frame.
+	action    SnapshotOrClip // Snapshot or clip to save.
+	cooldown  time.Duration  // Minimum time between two events.
+	last      time.Time      // Time the event last fired.
+	count     int            // Number of times the event fired.
+	writer    *VideoWriter   // Writer of the clip currently being recorded.
+	remaining int            // Number of frames left to write to the current clip.
+}
 
+// Registered players, guarded by playersMu. Entries are removed once they expire, see SetPlayerTTL,
+// so while a TTL is set, use ListPlayers to read them.
+var (
+	Players   []Player
+	playersMu sync.Mutex
+)
+
+// Returns a player without a Video or audio file.
+func newPlayer(filePath, id string) *Player {
+	return &Player{FilePath: filePath, ID: id, playerState: &playerState{}}
+}
+
+// Returns a copy of the registered player. Copies share the state of the player, so unlike a pointer
+// into Players, it stays valid when Players grows.
 func findPlayer(filePath string, id string) (*Player, error) {
-	for index, entry := range Players {
+	for _, entry := range Players {
 		if entry.FilePath == filePath && entry.ID == id {
-			return &Players[index], nil
+			return &entry, nil
 		}
 	}
 
-	return &Player{}, errors.New("failed to find player instance")
+	return newPlayer(filePath, id), errors.New("failed to find player instance")
 }
 
 func GetPlayer(filePath string, id string) (*Player, error) {
+	playersMu.Lock()
+	defer playersMu.Unlock()
+
 	player, err := findPlayer(filePath, id)
 	if err == nil {
-		player.Video.Reset()
-		return player, nil
+		player.mu.Lock()
+		closed := player.closed
+		if !closed {
+			player.touch()
+			if player.Audio != nil {
+				player.Audio.Reset()
+			} else {
+				player.Video.Reset()
+			}
+		}
+		player.mu.Unlock()
+		if !closed {
+			return player, nil
+		}
+		// The player expired while it was looked up, so a new one is opened.
+		removePlayer(player)
 	}
 
-	newVideo, err := NewVideo(filePath)
-	if err != nil {
-		return player, err
+	newPlayer := newPlayer(filePath, id)
+	if !isURL(filePath) && exists(filePath) && installed("ffprobe") == nil {
+		streams, err := ffprobe(filePath, "v")
+		if err != nil {
+			return player, err
+		}
+		if audioOnly(streams) {
+			newPlayer.Audio, err = NewAudioReader(filePath)
+			if err != nil {
+				return player, err
+			}
+		}
+	}
+	if newPlayer.Audio == nil {
+		newPlayer.Video, err = NewVideo(filePath)
+		if err != nil {
+			return player, err
+		}
+	}
+
+	newPlayer.touch()
+	Players = append(Players, *newPlayer)
+
+	return newPlayer, nil
+}
+
+// Registers an event which saves a snapshot or clip of the video whenever "trigger" returns true.
+// The trigger is evaluated after every frame read with Player.Read. Once fired, the event can not
+// fire again until "cooldown" has passed and the clip being recorded, if any, is finished.
+func (player *Player) OnEvent(trigger func() bool, action SnapshotOrClip, cooldown time.Duration) error {
+	if player.Audio != nil {
+		return fmt.Errorf("vidio: events are not supported for audio files")
+	}
+	if trigger == nil {
+		return fmt.Errorf("vidio: event trigger must not be nil")
 	}
-	newPlayer := Player{
-		FilePath: filePath,
-		ID:       id,
-		Video:    newVideo,
+	if action.Output == "" {
+		return fmt.Errorf("vidio: event output must not be empty")
 	}
+	player.mu.Lock()
+	defer player.mu.Unlock()
+	player.events = append(player.events, &playerEvent{
+		trigger:  trigger,
+		action:   action,
+		cooldown: cooldown,
+	})
+	return nil
+}
 
-	Players = append(Players, newPlayer)
+// Returns the last error raised while saving an event snapshot or clip.
+func (player *Player) Err() error {
+	player.mu.Lock()
+	defer player.mu.Unlock()
+	return player.eventErr
+}
 
-	return &newPlayer, nil
+// Reads the next frame of the video and runs the registered events on it, or the next chunk of samples
+// of an audio file. If the end has been reached, returns false, otherwise true.
+func (player *Player) Read() bool {
+	player.mu.Lock()
+	defer player.mu.Unlock()
+	if player.closed {
+		return false
+	}
+	player.touch()
+	if err := player.stopStepping(); err != nil {
+		player.publish(StateEvent{Type: StateError, Err: err})
+		return false
+	}
+	if err := player.enterLoop(); err != nil {
+		player.publish(StateEvent{Type: StateError, Err: err})
+		return false
+	}
+	if wait := player.wait(); wait > 0 {
+		// Sleeps without the lock, so the player can be seeked or looked up in the meantime.
+		player.mu.Unlock()
+		time.Sleep(wait)
+		player.mu.Lock()
+		if player.closed {
+			return false
+		}
+	}
+	if !player.next() {
+		// The end of the file inside a loop region wraps as well.
+		if player.loop == nil || player.wrap() != nil || !player.next() {
+			for _, event := range player.events {
+				event.finish()
+			}
+			player.end()
+			return false
+		}
+	}
+	if player.Audio != nil {
+		return true
+	}
+
+	frame := player.Video.FrameBuffer()
+	for _, event := range player.events {
+		if err := event.handle(player.Video, frame); err != nil {
+			player.eventErr = err
+			player.publish(StateEvent{Type: StateError, Err: err})
+		}
+	}
+
+	return true
+}
+
+// Reads the next frame or audio chunk. Audio chunks are cut at the end of the loop region.
+func (player *Player) next() bool {
+	if player.Audio == nil {
+		return player.Video.Read()
+	}
+	if !player.Audio.Read() {
+		return false
+	}
+	if player.loop != nil {
+		player.Audio.truncate(player.loop.end)
+	}
+	return true
+}
+
+// Returns the playback position in seconds, the end of the last frame or audio chunk read.
+func (player *Player) Position() float64 {
+	if player.Audio != nil {
+		return player.Audio.Position()
+	}
+	if player.steps != nil && player.steps.current >= 0 {
+		return frameTime(player.steps.current+1, player.Video.FPS())
+	}
+	return frameTime(int(player.Video.delivered.Load()), player.Video.FPS())
+}
+
+// Makes the next Read continue at the given position in seconds.
+func (player *Player) Seek(t float64) error {
+	player.mu.Lock()
+	defer player.mu.Unlock()
+	if player.closed {
+		return fmt.Errorf("vidio: player of %s is closed", player.FilePath)
+	}
+	if err := player.seek(t); err != nil {
+		player.publish(StateEvent{Type: StateError, Err: err})
+		return err
+	}
+	player.restartClock()
+	player.publish(StateEvent{Type: StateSeek})
+	return nil
+}
+
+// Seeks without restarting the clock or publishing events.
+func (player *Player) seek(t float64) error {
+	if player.Audio != nil {
+		return player.Audio.Seek(t)
+	}
+	if err := player.stopStepping(); err != nil {
+		return err
+	}
+	return player.Video.Seek(player.Video.StartTime() + t)
+}
+
+// Stops playback at the end of the file.
+func (player *Player) end() {
+	player.playing = false
+	player.publish(StateEvent{Type: StateEOF})
+}
+
+// Matches an integer verb such as "%d" or "%04d", which is not escaped as "%%d".
+var eventVerb = regexp.MustCompile(`(^|[^%])(%%)*%0?\d*d`)
+
+// Returns the output filename of the event with the given number. Filenames without an integer verb,
+// such as "100%.png", are used as they are.
+func eventOutput(output string, count int) string {
+	if !eventVerb.MatchString(output) {
+		return output
+	}
+	return fmt.Sprintf(output, count)
+}
+
+// Writes the frame to the clip in progress, or fires the event if the trigger returns true.
+func (event *playerEvent) handle(video *Video, frame []byte) error {
+	if event.writer != nil {
+		if err := event.writer.Write(frame); err != nil {
+			event.finish()
+			return err
+		}
+		event.remaining--
+		if event.remaining <= 0 {
+			event.finish()
+		}
+		return nil
+	}
+
+	now := time.Now()
+	if !event.last.IsZero() && now.Sub(event.last) < event.cooldown {
+		return nil
+	}
+	if !event.trigger() {
+		return nil
+	}
+
+	event.last = now
+	event.count++
+
+	output := eventOutput(event.action.Output, event.count)
+
+	if event.action.Clip <= 0 {
+		return Write(output, video.Width(), video.Height(), frame)
+	}
+
+	options := Options{}
+	if event.action.Options != nil {
+		options = *event.action.Options
+	}
+	if options.FPS == 0 {
+		options.FPS = video.FPS()
+	}
+
+	writer, err := NewVideoWriter(output, video.Width(), video.Height(), &options)
+	if err != nil {
+		return err
+	}
+	event.writer = writer
+	event.remaining = int(event.action.Clip * options.FPS)
+
+	return event.handle(video, frame)
+}
+
+// Finalizes the clip in progress, if any.
+func (event *playerEvent) finish() {
+	if event.writer != nil {
+		event.writer.Close()
+		event.writer = nil
+	}
+	event.remaining = 0
 }
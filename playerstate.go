@@ -0,0 +1,173 @@
+package vidio
+
+import (
+	"fmt"
+	"sync"
+	"time"
+)
+
+// Kind of a StateEvent.
+type StateEventType string
+
+const (
+	StatePlay  StateEventType = "play"  // Paced playback started with Player.Play.
+	StatePause StateEventType = "pause" // Playback was paused with Player.Pause.
+	StateSeek  StateEventType = "seek"  // The position changed with Player.Seek.
+	StateEOF   StateEventType = "eof"   // Player.Read reached the end of the file.
+	StateError StateEventType = "error" // Seeking or saving an event snapshot or clip failed.
+)
+
+// A change of the playback state of a Player.
+type StateEvent struct {
+	Type     StateEventType // Kind of change.
+	Time     time.Time      // Time the event was emitted.
+	Position float64        // Playback position in seconds.
+	Err      error          // Failure, for StateError.
+}
+
+// Number of state events buffered per subscriber. Subscribers which fall behind lose the oldest events.
+const stateBuffer = 16
+
+// Guards the subscribers of all players, which may subscribe from other goroutines than the one reading.
+var subscribersMu sync.Mutex
+
+// Returns a channel receiving the state changes of the player, so several components can follow one
+// shared player without polling. Events are never blocked on slow subscribers; once the buffer is full,
+// the oldest events are dropped. Use Unsubscribe to stop receiving events.
+func (player *Player) Subscribe() <-chan StateEvent {
+	subscribersMu.Lock()
+	defer subscribersMu.Unlock()
+	subscriber := make(chan StateEvent, stateBuffer)
+	player.subscribers = append(player.subscribers, subscriber)
+	return subscriber
+}
+
+// Removes a channel returned by Subscribe and closes it.
+func (player *Player) Unsubscribe(subscriber <-chan StateEvent) {
+	subscribersMu.Lock()
+	defer subscribersMu.Unlock()
+	for i, entry := range player.subscribers {
+		if entry == subscriber {
+			player.subscribers = append(player.subscribers[:i], player.subscribers[i+1:]...)
+			close(entry)
+			return
+		}
+	}
+}
+
+// Starts paced playback: Player.Read waits until each frame or audio chunk is due in real time.
+func (player *Player) Play() {
+	player.mu.Lock()
+	defer player.mu.Unlock()
+	if player.playing {
+		return
+	}
+	player.playing = true
+	player.restartClock()
+	player.publish(StateEvent{Type: StatePlay})
+}
+
+// Stops paced playback. Player.Read then returns frames as fast as they are decoded.
+func (player *Player) Pause() {
+	player.mu.Lock()
+	defer player.mu.Unlock()
+	player.pause()
+}
+
+// Stops paced playback. The caller must hold mu.
+func (player *Player) pause() {
+	if !player.playing {
+		return
+	}
+	player.playing = false
+	player.publish(StateEvent{Type: StatePause})
+}
+
+// Returns true if the player is playing, see Player.Play.
+func (player *Player) Playing() bool {
+	player.mu.Lock()
+	defer player.mu.Unlock()
+	return player.playing
+}
+
+// Range of speed factors supported by Player.SetSpeed.
+const (
+	minSpeed = 0.25
+	maxSpeed = 4
+)
+
+// Changes the playback speed by the given factor from 0.25 to 4, e.g. 0.5 plays at half speed. Paced
+// playback delivers frames and audio chunks faster or slower; the audio of audio files is time-stretched
+// with the ffmpeg atempo filter, keeping its pitch, and decoding restarts at the current position.
+func (player *Player) SetSpeed(factor float64) error {
+	if factor < minSpeed || factor > maxSpeed {
+		return fmt.Errorf("vidio: speed %g is not within %g to %g", factor, minSpeed, float64(maxSpeed))
+	}
+	player.mu.Lock()
+	defer player.mu.Unlock()
+	if player.Audio != nil {
+		player.Audio.setTempo(factor)
+	}
+	player.restartClock()
+	player.speed = factor
+	return nil
+}
+
+// Returns the playback speed factor, 1 unless changed with SetSpeed.
+func (player *Player) Speed() float64 {
+	player.mu.Lock()
+	defer player.mu.Unlock()
+	return player.speedFactor()
+}
+
+// Returns the playback speed factor. The caller must hold mu.
+func (player *Player) speedFactor() float64 {
+	if player.speed == 0 {
+		return 1
+	}
+	return player.speed
+}
+
+// Makes the current position due now.
+func (player *Player) restartClock() {
+	player.clock = time.Now()
+	player.origin = player.Position()
+}
+
+// Returns how long to wait until the current position is due, 0 if not playing.
+func (player *Player) wait() time.Duration {
+	if !player.playing {
+		return 0
+	}
+	return time.Until(player.due())
+}
+
+// Returns the time the current position is due at the playback speed.
+func (player *Player) due() time.Time {
+	return player.clock.Add(time.Duration((player.Position() - player.origin) / player.speedFactor() * float64(time.Second)))
+}
+
+// Sends the event to all subscribers.
+func (player *Player) publish(event StateEvent) {
+	if event.Time.IsZero() {
+		event.Time = time.Now()
+	}
+	event.Position = player.Position()
+
+	subscribersMu.Lock()
+	defer subscribersMu.Unlock()
+	for _, subscriber := range player.subscribers {
+		for delivered := false; !delivered; {
+			select {
+			case subscriber <- event:
+				delivered = true
+			default:
+				// Makes room, unless the subscriber received an event in the meantime.
+				select {
+				case <-subscriber:
+				default:
+				}
+			}
+		}
+	}
+}
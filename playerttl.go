@@ -0,0 +1,145 @@
+package vidio
+
+import (
+	"slices"
+	"time"
+)
+
+// Expiry of inactive players, guarded by playersMu.
+var (
+	playerTTL time.Duration // Inactivity after which players expire. 0 keeps them forever.
+	expiring  bool          // True while the goroutine expiring players is running.
+)
+
+// Makes players expire once they have not been accessed with GetPlayer or Player.Read for "ttl", e.g. to
+// clean up after abandoned browser sessions. Expired players are closed, which stops their ffmpeg processes,
+// and removed from Players, so the next GetPlayer with the same file and id opens a new player.
+// A ttl of 0, the default, keeps players until they are closed.
+func SetPlayerTTL(ttl time.Duration) {
+	playersMu.Lock()
+	defer playersMu.Unlock()
+	playerTTL = max(0, ttl)
+	if playerTTL > 0 && !expiring {
+		expiring = true
+		go expirePlayers()
+	}
+}
+
+// Periodically removes expired players until the TTL is disabled.
+func expirePlayers() {
+	for {
+		playersMu.Lock()
+		ttl := playerTTL
+		if ttl == 0 {
+			expiring = false
+			playersMu.Unlock()
+			return
+		}
+		playersMu.Unlock()
+
+		time.Sleep(max(10*time.Millisecond, ttl/4))
+		ExpirePlayers()
+	}
+}
+
+// Closes and removes the players which have not been accessed within the TTL set with SetPlayerTTL.
+// Returns the number of players removed. Called periodically once a TTL is set. Players being read are
+// closed once the read finishes, unless the read counts as an access within the TTL.
+func ExpirePlayers() int {
+	playersMu.Lock()
+	if playerTTL == 0 {
+		playersMu.Unlock()
+		return 0
+	}
+	deadline := time.Now().Add(-playerTTL).UnixNano()
+	stale := []Player{}
+	for _, player := range Players {
+		if player.accessed.Load() < deadline {
+			stale = append(stale, player)
+		}
+	}
+	playersMu.Unlock()
+
+	expired := 0
+	for _, player := range stale {
+		if player.expire(deadline) {
+			expired++
+		}
+	}
+	return expired
+}
+
+// Returns a copy of the registered players, which share the state of the players.
+func ListPlayers() []Player {
+	playersMu.Lock()
+	defer playersMu.Unlock()
+	return slices.Clone(Players)
+}
+
+// Closes the player unless it was accessed after the deadline, in Unix nanoseconds. Waits for reads in
+// progress. Returns true if the player was closed.
+func (player *Player) expire(deadline int64) bool {
+	player.mu.Lock()
+	if player.closed || player.accessed.Load() >= deadline {
+		player.mu.Unlock()
+		return false
+	}
+	player.close()
+	player.mu.Unlock()
+
+	playersMu.Lock()
+	defer playersMu.Unlock()
+	removePlayer(player)
+	return true
+}
+
+// Removes the player from Players and stops its ffmpeg processes, finishing clips being recorded.
+// Closes the channels returned by Subscribe. Waits for reads in progress.
+func (player *Player) Close() {
+	player.mu.Lock()
+	if !player.closed {
+		player.close()
+	}
+	player.mu.Unlock()
+
+	playersMu.Lock()
+	defer playersMu.Unlock()
+	removePlayer(player)
+}
+
+// Stops the ffmpeg processes of the player. Must be called with "mu" held.
+func (player *Player) close() {
+	player.closed = true
+	for _, event := range player.events {
+		event.finish()
+	}
+	if player.Audio != nil {
+		player.Audio.Close()
+	}
+	if player.Video != nil {
+		player.Video.Close()
+	}
+	player.playing = false
+
+	subscribersMu.Lock()
+	defer subscribersMu.Unlock()
+	for _, subscriber := range player.subscribers {
+		close(subscriber)
+	}
+	player.subscribers = nil
+}
+
+// Removes the player from Players. Must be called with playersMu held.
+func removePlayer(player *Player) {
+	for i, entry := range Players {
+		if entry.playerState == player.playerState {
+			Players = slices.Delete(Players, i, i+1)
+			return
+		}
+	}
+}
+
+// Marks the player as accessed now.
+func (player *Player) touch() {
+	player.accessed.Store(time.Now().UnixNano())
+}
@@ -0,0 +1,139 @@
+package vidio
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// Reads several videos back to back as one continuous stream of frames.
+type Playlist struct {
+	videos      []*Video                         // Videos in playback order.
+	current     int                              // Index of the video being read.
+	width       int                              // Width of all frames.
+	height      int                              // Height of all frames.
+	fps         float64                          // Frames per second of all frames.
+	framebuffer []byte                           // Frame shared by all videos.
+	onboundary  func(index int, filename string) // Called when a new file starts.
+	started     bool                             // Whether reading has started.
+}
+
+// Opens the given files as one playlist. Frames of all files have the size and frame rate of the first file.
+// Files with a different size are scaled to fit and padded with black, files with a different frame rate
+// are resampled. The options are passed to NewVideo for every file.
+func NewPlaylist(files []string, options ...Option) (*Playlist, error) {
+	if len(files) == 0 {
+		return nil, fmt.Errorf("vidio: no playlist files given")
+	}
+
+	playlist := &Playlist{}
+	for _, filename := range files {
+		video, err := NewVideo(filename, options...)
+		if err != nil {
+			playlist.Close()
+			return nil, err
+		}
+		playlist.videos = append(playlist.videos, video)
+	}
+
+	first := playlist.videos[0]
+	playlist.width, playlist.height, playlist.fps = first.width, first.height, first.fps
+	playlist.framebuffer = make([]byte, first.width*first.height*first.depth)
+	for _, video := range playlist.videos {
+		if filter := normalizeFilter(video, playlist.width, playlist.height, playlist.fps); filter != "" {
+			video.filter = strings.TrimPrefix(video.filter+","+filter, ",")
+		}
+		video.width, video.height = playlist.width, playlist.height
+		video.framebuffer = playlist.framebuffer
+	}
+
+	return playlist, nil
+}
+
+// Returns the filter converting frames of the video to the given size and frame rate,
+// or an empty string if they already match.
+func normalizeFilter(video *Video, width, height int, fps float64) string {
+	filter := ""
+	if video.width != width || video.height != height {
+		filter = fmt.Sprintf(
+			"scale=%d:%d:force_original_aspect_ratio=decrease,pad=%d:%d:(ow-iw)/2:(oh-ih)/2,setsar=1",
+			width, height, width, height,
+		)
+	}
+	if fps > 0 && math.Abs(video.fps-fps) > 0.001 {
+		if filter != "" {
+			filter += ","
+		}
+		filter += "fps=" + strconv.FormatFloat(fps, 'f', -1, 64)
+	}
+	return filter
+}
+
+func (playlist *Playlist) Width() int {
+	return playlist.width
+}
+
+func (playlist *Playlist) Height() int {
+	return playlist.height
+}
+
+// Frames per second of the playlist, taken from the first file.
+func (playlist *Playlist) FPS() float64 {
+	return playlist.fps
+}
+
+// Total duration of all files in seconds.
+func (playlist *Playlist) Duration() float64 {
+	duration := 0.0
+	for _, video := range playlist.videos {
+		duration += video.Duration()
+	}
+	return duration
+}
+
+// Videos of the playlist in playback order.
+func (playlist *Playlist) Videos() []*Video {
+	return playlist.videos
+}
+
+// Index of the file currently being read.
+func (playlist *Playlist) Current() int {
+	return playlist.current
+}
+
+func (playlist *Playlist) FrameBuffer() []byte {
+	return playlist.framebuffer
+}
+
+// Sets a function called with the index and filename of each file when reading it starts, including the first.
+func (playlist *Playlist) OnBoundary(callback func(index int, filename string)) {
+	playlist.onboundary = callback
+}
+
+// Reads the next frame of the playlist into the framebuffer, continuing with the next file when a file ends.
+// Returns false after the last frame of the last file.
+func (playlist *Playlist) Read() bool {
+	for playlist.current < len(playlist.videos) {
+		video := playlist.videos[playlist.current]
+		if !playlist.started {
+			playlist.started = true
+			if playlist.onboundary != nil {
+				playlist.onboundary(playlist.current, video.filename)
+			}
+		}
+		if video.Read() {
+			return true
+		}
+		playlist.current++
+		playlist.started = false
+	}
+	return false
+}
+
+// Stops reading and closes all files.
+func (playlist *Playlist) Close() {
+	for _, video := range playlist.videos {
+		video.Close()
+	}
+}
@@ -0,0 +1,103 @@
+package vidio
+
+import "fmt"
+
+// PreRoll is an in-memory circular buffer holding the most recent frames of a live source,
+// so event triggered recordings can include footage from before the trigger.
+type PreRoll struct {
+	width  int      // Frame width.
+	height int      // Frame height.
+	fps    float64  // Frames per second of the source.
+	frames [][]byte // Ring of frame buffers.
+	next   int      // Index of the slot the next frame is written to.
+	count  int      // Number of frames currently buffered.
+}
+
+// Creates a new PreRoll buffer holding "seconds" of RGBA frames at the given frame rate.
+func NewPreRoll(width, height int, fps, seconds float64) (*PreRoll, error) {
+	if fps <= 0 {
+		return nil, fmt.Errorf("vidio: pre-roll frame rate must be positive")
+	}
+	size := int(seconds * fps)
+	if size <= 0 {
+		return nil, fmt.Errorf("vidio: pre-roll duration must hold at least one frame")
+	}
+
+	return &PreRoll{
+		width:  width,
+		height: height,
+		fps:    fps,
+		frames: make([][]byte, size),
+	}, nil
+}
+
+// Number of frames currently buffered.
+func (preroll *PreRoll) Len() int {
+	return preroll.count
+}
+
+// Maximum number of frames the buffer holds.
+func (preroll *PreRoll) Cap() int {
+	return len(preroll.frames)
+}
+
+// Duration in seconds of the buffered footage.
+func (preroll *PreRoll) Duration() float64 {
+	return float64(preroll.count) / preroll.fps
+}
+
+// Copies the given frame into the buffer, overwriting the oldest frame if the buffer is full.
+func (preroll *PreRoll) Push(frame []byte) {
+	size := preroll.width * preroll.height * 4
+	slot := preroll.frames[preroll.next]
+	if len(slot) != size {
+		slot = make([]byte, size)
+		preroll.frames[preroll.next] = slot
+	}
+	copy(slot, frame)
+
+	preroll.next = (preroll.next + 1) % len(preroll.frames)
+	if preroll.count < len(preroll.frames) {
+		preroll.count++
+	}
+}
+
+// Returns the buffered frames, oldest first. The returned slices are only valid until the next Push.
+func (preroll *PreRoll) Frames() [][]byte {
+	frames := make([][]byte, 0, preroll.count)
+	start := (preroll.next - preroll.count + len(preroll.frames)) % len(preroll.frames)
+	for i := 0; i < preroll.count; i++ {
+		frames = append(frames, preroll.frames[(start+i)%len(preroll.frames)])
+	}
+	return frames
+}
+
+// Removes all buffered frames.
+func (preroll *PreRoll) Reset() {
+	preroll.next = 0
+	preroll.count = 0
+}
+
+// Writes the buffered frames to the given video file.
+// The returned VideoWriter is still open so the caller can keep appending live frames after the pre-roll.
+func (preroll *PreRoll) DumpPreRoll(output string, options *Options) (*VideoWriter, error) {
+	if options == nil {
+		options = &Options{}
+	}
+	copied := *options
+	copied.FPS = preroll.fps
+
+	writer, err := NewVideoWriter(output, preroll.width, preroll.height, &copied)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, frame := range preroll.Frames() {
+		if err := writer.Write(frame); err != nil {
+			writer.Close()
+			return nil, err
+		}
+	}
+
+	return writer, nil
+}
@@ -0,0 +1,106 @@
+package vidio
+
+import (
+	"fmt"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+// Curated encoding profile for VideoWriter. Each preset lists codec specific settings in order of preference,
+// and the first codec supported by the installed ffmpeg build is used.
+type Preset string
+
+const (
+	PresetWebHD    Preset = "web-hd"   // Efficient streaming quality for the web.
+	PresetArchival Preset = "archival" // Near-transparent quality for long term storage. Slow to encode.
+	PresetSocial   Preset = "social"   // Small files for sharing, encoded quickly.
+)
+
+// Encoder and its options for a preset.
+type presetProfile struct {
+	codec string
+	args  []string
+}
+
+// Profiles of each preset, in order of preference. The AV1 profiles use SVT-AV1, VP9 uses libvpx
+// in constant quality mode ("-b:v 0"), and libx264 is the fallback available in most builds.
+var presetProfiles = map[Preset][]presetProfile{
+	PresetWebHD: {
+		{"libsvtav1", []string{"-preset", "8", "-crf", "35", "-g", "240"}},
+		{"libvpx-vp9", []string{"-crf", "33", "-b:v", "0", "-deadline", "good", "-cpu-used", "2", "-row-mt", "1"}},
+		{"libx264", []string{"-preset", "medium", "-crf", "23"}},
+	},
+	PresetArchival: {
+		{"libsvtav1", []string{"-preset", "4", "-crf", "22", "-g", "240"}},
+		{"libvpx-vp9", []string{"-crf", "18", "-b:v", "0", "-deadline", "good", "-cpu-used", "0", "-row-mt", "1"}},
+		{"libx264", []string{"-preset", "veryslow", "-crf", "16"}},
+	},
+	PresetSocial: {
+		{"libsvtav1", []string{"-preset", "10", "-crf", "40", "-g", "120"}},
+		{"libvpx-vp9", []string{"-crf", "38", "-b:v", "0", "-deadline", "realtime", "-cpu-used", "6", "-row-mt", "1"}},
+		{"libx264", []string{"-preset", "fast", "-crf", "26"}},
+	},
+}
+
+// Returns the codec and its options for the preset, choosing the first codec among
+// the given encoders which can be stored in the container of "filename".
+func (preset Preset) resolve(encoders []string, filename string) (string, []string, error) {
+	profiles, ok := presetProfiles[preset]
+	if !ok {
+		return "", nil, fmt.Errorf("vidio: unknown preset: %s", preset)
+	}
+	webm := strings.ToLower(filepath.Ext(filename)) == ".webm"
+	for _, profile := range profiles {
+		// WebM only holds VP8, VP9 and AV1 video.
+		if webm && profile.codec == "libx264" {
+			continue
+		}
+		for _, encoder := range encoders {
+			if encoder == profile.codec {
+				return profile.codec, profile.args, nil
+			}
+		}
+	}
+	return "", nil, fmt.Errorf("vidio: ffmpeg has none of the encoders for preset %s", preset)
+}
+
+// Returns the codec and its options the preset uses with the installed ffmpeg build for the given output file.
+func (preset Preset) Resolve(filename string) (string, []string, error) {
+	encoders, err := Encoders()
+	if err != nil {
+		return "", nil, err
+	}
+	return preset.resolve(encoders, filename)
+}
+
+// Returns the names of the video encoders supported by the installed ffmpeg build.
+func Encoders() ([]string, error) {
+	if err := installed("ffmpeg"); err != nil {
+		return nil, err
+	}
+	output, err := exec.Command("ffmpeg", "-hide_banner", "-encoders").Output()
+	if err != nil {
+		return nil, fmt.Errorf("vidio: failed to list ffmpeg encoders: %w", err)
+	}
+	return parseEncoders(string(output)), nil
+}
+
+// Parses the video encoder names from the output of "ffmpeg -encoders".
+// Encoder lines start with capability flags, the first of which is "V" for video.
+func parseEncoders(output string) []string {
+	encoders := []string{}
+	listing := false
+	for _, line := range strings.Split(output, "\n") {
+		fields := strings.Fields(line)
+		// The legend ends with a separator line.
+		if len(fields) == 1 && fields[0] == "------" {
+			listing = true
+			continue
+		}
+		if listing && len(fields) >= 2 && len(fields[0]) == 6 && fields[0][0] == 'V' {
+			encoders = append(encoders, fields[1])
+		}
+	}
+	return encoders
+}
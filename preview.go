@@ -0,0 +1,78 @@
+package vidio
+
+import (
+	"fmt"
+	"math"
+	"path/filepath"
+	"strings"
+
+	"github.com/benitogf/Vidio/ffcmd"
+)
+
+// Width in pixels of preview clips.
+const previewWidth = 320
+
+// Creates a short hover preview of a video, like the previews shown on video sites. "n" clips of "clipLen"
+// seconds are sampled evenly across the video and concatenated without audio. The output format is
+// chosen by the extension of "output": ".gif" uses an optimized palette, other formats such as ".webm"
+// are encoded with VP9 or, for ".mp4", H.264. Clips are rotated and stretched to their display aspect ratio
+// unless WithoutAutoOrient is given.
+func PreviewClip(filename, output string, n int, clipLen float64, options ...Option) error {
+	if n < 1 || clipLen <= 0 {
+		return fmt.Errorf("vidio: preview needs at least one clip with a positive length")
+	}
+
+	video, err := NewVideo(filename, options...)
+	if err != nil {
+		return err
+	}
+	video.Close()
+	duration := video.Duration()
+	if duration <= 0 {
+		return fmt.Errorf("vidio: video %s has no duration", filename)
+	}
+	clipLen = math.Min(clipLen, duration/float64(n))
+
+	c := newConfig(options)
+	input, orient, _, _ := video.orientation(c)
+
+	builder := ffcmd.New().Global("-y")
+	concat := ""
+	for i, start := range previewStarts(duration, n, clipLen) {
+		// Seeking each input is faster than decoding the whole video and trimming it.
+		builder.Input(filename, append(input, "-ss", fmt.Sprintf("%.3f", start), "-t", fmt.Sprintf("%.3f", clipLen))...)
+		concat += fmt.Sprintf("[%d:v:%d]", i, video.Stream())
+	}
+	concat += fmt.Sprintf("concat=n=%d:v=1:a=0,", n)
+	if orient != "" {
+		concat += orient + ","
+	}
+	concat += fmt.Sprintf("scale=%d:-2", previewWidth)
+
+	var args []string
+	switch strings.ToLower(filepath.Ext(output)) {
+	case ".gif":
+		// A palette generated from the clips looks much better than the default gif palette.
+		builder.Filter(concat + ",fps=10,split[a][b];[a]palettegen[p];[b][p]paletteuse[out]")
+		args = []string{"-loop", "0"}
+	case ".mp4":
+		builder.Filter(concat + "[out]")
+		args = []string{"-c:v", "libx264", "-crf", "28", "-pix_fmt", "yuv420p", "-movflags", "+faststart"}
+	default:
+		builder.Filter(concat + "[out]")
+		args = []string{"-c:v", "libvpx-vp9", "-crf", "40", "-b:v", "0", "-deadline", "good", "-cpu-used", "4"}
+	}
+	builder.Map("[out]").Output(output, append(args, "-an")...)
+
+	return c.run(builder)
+}
+
+// Returns the start times of "n" clips of "clipLen" seconds centered in equal parts of the video.
+func previewStarts(duration float64, n int, clipLen float64) []float64 {
+	starts := make([]float64, n)
+	for i := range starts {
+		center := (float64(i) + 0.5) * duration / float64(n)
+		starts[i] = math.Max(0, math.Min(center-clipLen/2, duration-clipLen))
+	}
+	return starts
+}
@@ -0,0 +1,26 @@
+package vidio
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+)
+
+// There is no file mode creation mask on windows.
+var umask os.FileMode
+
+// Process groups are not used on windows. ffmpeg does not spawn child processes.
+func setProcessGroup(cmd *exec.Cmd) {}
+
+// Switching users is not supported on windows.
+func setCredential(cmd *exec.Cmd, uid, gid uint32) error {
+	return fmt.Errorf("vidio: sandbox users are not supported on windows")
+}
+
+// Kills the given started command.
+func killProcessGroup(cmd *exec.Cmd) error {
+	if cmd.Process == nil {
+		return nil
+	}
+	return cmd.Process.Kill()
+}
@@ -0,0 +1,81 @@
+package vidio
+
+import (
+	"bytes"
+	"strings"
+	"time"
+)
+
+// Progress of a running ffmpeg encode, parsed from the "-progress" output.
+type Progress struct {
+	Frame   int           // Number of frames encoded.
+	FPS     float64       // Encoding speed in frames per second.
+	Bitrate float64       // Output bitrate in kbit/s.
+	OutTime time.Duration // Timestamp of the output encoded so far.
+	Speed   float64       // Encoding speed relative to real time, e.g. 2 means twice as fast as playback.
+	Done    bool          // True for the last update, sent when ffmpeg finished.
+}
+
+// Makes operations report encoding progress to the given channel. Updates are sent without blocking
+// and dropped if the channel is full, so a slow consumer never stalls the encode.
+// Use a buffered channel to avoid missing the final update.
+func WithProgress(progress chan<- Progress) Option {
+	return func(c *config) {
+		c.progress = progress
+	}
+}
+
+// Parses the key=value lines written by "ffmpeg -progress" and sends a Progress update
+// to the channel at the end of every block.
+type progressParser struct {
+	progress chan<- Progress // Receives the updates. May be nil.
+	notify   func(Progress)  // Called with every update if not nil.
+	current  Progress        // Update being parsed.
+	buffer   []byte          // Incomplete line.
+}
+
+func (parser *progressParser) Write(p []byte) (int, error) {
+	parser.buffer = append(parser.buffer, p...)
+	for {
+		index := bytes.IndexByte(parser.buffer, '\n')
+		if index == -1 {
+			return len(p), nil
+		}
+		parser.line(strings.TrimSpace(string(parser.buffer[:index])))
+		parser.buffer = parser.buffer[index+1:]
+	}
+}
+
+// Parses a single key=value line.
+func (parser *progressParser) line(line string) {
+	key, value, ok := strings.Cut(line, "=")
+	if !ok {
+		return
+	}
+	value = strings.TrimSpace(value)
+	switch key {
+	case "frame":
+		parser.current.Frame = int(parse(value))
+	case "fps":
+		parser.current.FPS = parse(value)
+	case "bitrate":
+		parser.current.Bitrate = parse(strings.TrimSuffix(value, "kbits/s"))
+	case "out_time_us":
+		parser.current.OutTime = time.Duration(parse(value)) * time.Microsecond
+	case "speed":
+		parser.current.Speed = parse(strings.TrimSuffix(value, "x"))
+	case "progress":
+		parser.current.Done = value == "end"
+		if parser.notify != nil {
+			parser.notify(parser.current)
+		}
+		select {
+		case parser.progress <- parser.current:
+		default:
+		}
+	}
+}
+
+func (parser *progressParser) Close() error {
+	return nil
+}
@@ -0,0 +1,62 @@
+package vidio
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+)
+
+// View of 360 degree footage rendered with WithProjection. Angles are in degrees.
+type Projection struct {
+	Input  string  // ffmpeg v360 projection of the source, e.g. "e" (equirectangular), "c3x2" (cubemap) or "fisheye". Default "e".
+	Yaw    float64 // Horizontal viewing direction. Positive turns right.
+	Pitch  float64 // Vertical viewing direction. Positive looks up.
+	Roll   float64 // Rotation of the view. Positive turns clockwise.
+	FOV    float64 // Horizontal field of view. Default 90. The vertical field of view follows from the aspect ratio.
+	Width  int     // Width of the rendered frames. Default 1280.
+	Height int     // Height of the rendered frames. Default 720.
+}
+
+// Makes NewVideo and EncodeLadder render a flat view of 360 degree footage looking in the given direction,
+// e.g. to turn equirectangular recordings into normal previews. Frames have the size of the projection.
+func WithProjection(projection Projection) Option {
+	return func(c *config) {
+		c.view = &projection
+	}
+}
+
+// Returns the size of the rendered frames.
+func (projection *Projection) size() (int, int) {
+	width, height := projection.Width, projection.Height
+	if width <= 0 {
+		width = 1280
+	}
+	if height <= 0 {
+		height = 720
+	}
+	return width, height
+}
+
+// Returns the ffmpeg v360 filter rendering the view, or an error if the field of view is not below 180 degrees.
+func (projection *Projection) filter() (string, error) {
+	fov := projection.FOV
+	if fov == 0 {
+		fov = 90
+	}
+	if fov < 0 || fov >= 180 {
+		return "", fmt.Errorf("vidio: field of view %g is not between 0 and 180 degrees", fov)
+	}
+	input := projection.Input
+	if input == "" {
+		input = "e"
+	}
+	width, height := projection.size()
+	vertical := 2 * math.Atan(math.Tan(fov*math.Pi/360)*float64(height)/float64(width)) * 180 / math.Pi
+
+	format := func(v float64) string { return strconv.FormatFloat(v, 'f', -1, 64) }
+	return fmt.Sprintf(
+		"v360=input=%s:output=flat:yaw=%s:pitch=%s:roll=%s:h_fov=%s:v_fov=%s:w=%d:h=%d",
+		input, format(projection.Yaw), format(projection.Pitch), format(projection.Roll),
+		format(fov), format(math.Round(vertical*100)/100), width, height,
+	), nil
+}
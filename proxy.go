@@ -0,0 +1,68 @@
+package vidio
+
+import (
+	"fmt"
+	"strconv"
+
+	"github.com/benitogf/Vidio/ffcmd"
+)
+
+// Optional parameters for RecordWithProxy.
+type ProxyOptions struct {
+	Height     int // Height of the proxy frames. The width follows the aspect ratio. Default 360.
+	Bitrate    int // Video bitrate of the proxy in bits/s. Default 800000.
+	ArchiveCRF int // libx264 quality of the archive, lower is better. Default 16, visually lossless.
+}
+
+// Records a live source, such as an IP camera or SRT stream, at archival quality to "archive" and at the same
+// time as a low bitrate proxy to "proxy", so review can start on the proxy right away. The source is decoded
+// once and encoded twice with libx264 and AAC. Both outputs are fragmented MP4 files readable while they are
+// being written. Records until the source ends or the context given with WithContext is cancelled.
+func RecordWithProxy(source, archive, proxy string, settings *ProxyOptions, options ...Option) error {
+	if settings == nil {
+		settings = &ProxyOptions{}
+	}
+	height, bitrate, crf := settings.Height, settings.Bitrate, settings.ArchiveCRF
+	if height <= 0 {
+		height = 360
+	}
+	if bitrate <= 0 {
+		bitrate = 800000
+	}
+	if crf <= 0 {
+		crf = 16
+	}
+	if !isURL(source) && !exists(source) {
+		return fmt.Errorf("vidio: source %s does not exist", source)
+	}
+
+	fragmented := []string{"-movflags", "+frag_keyframe+empty_moov+default_base_moof"}
+	builder := ffcmd.New().
+		Global("-y").
+		Input(source, protocolArgs(source)...).
+		Filter("[0:v:0]split=2[archive][p]").
+		Filter(fmt.Sprintf("[p]scale=-2:%d[proxy]", height)).
+		Map("[archive]", "0:a:0?").
+		Output(archive, append([]string{
+			"-c:v", "libx264",
+			"-preset", "veryfast",
+			"-crf", strconv.Itoa(crf),
+			"-pix_fmt", "yuv420p",
+			"-c:a", "aac",
+			"-b:a", "192k",
+		}, fragmented...)...).
+		Map("[proxy]", "0:a:0?").
+		Output(proxy, append([]string{
+			"-c:v", "libx264",
+			"-preset", "veryfast",
+			"-b:v", strconv.Itoa(bitrate),
+			"-maxrate", strconv.Itoa(bitrate),
+			"-bufsize", strconv.Itoa(2 * bitrate),
+			"-g", "50",
+			"-pix_fmt", "yuv420p",
+			"-c:a", "aac",
+			"-b:a", "96k",
+		}, fragmented...)...)
+
+	return newConfig(options).run(builder)
+}
@@ -0,0 +1,56 @@
+package vidio
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/benitogf/Vidio/ffcmd"
+)
+
+// Writes a review copy of "input" with the frame index and the timecode of every frame burnt into a bar
+// at the bottom, so reviewers can refer to exact frames. The timecode starts at the embedded timecode
+// of the input or at 00:00:00:00. The video is encoded with libx264 at high quality, audio is copied.
+func ExportWithFrameNumbers(input, output string, options ...Option) error {
+	video, err := NewVideo(input, options...)
+	if err != nil {
+		return err
+	}
+	defer video.Close()
+
+	timecode, ok := video.Timecode()
+	if !ok {
+		timecode = Timecode{Rate: max(1, int(math.Round(video.FPS())))}
+	}
+	rate := video.metadata["r_frame_rate"]
+	if rate == "" || strings.HasSuffix(rate, "/0") {
+		rate = strconv.FormatFloat(video.FPS(), 'f', -1, 64)
+	}
+
+	builder := ffcmd.New().
+		Global("-y").
+		Input(input).
+		Map(fmt.Sprintf("0:v:%d", video.Stream()), "0:a?").
+		Output(output,
+			"-vf", frameNumberFilter(timecode, rate),
+			"-c:v", "libx264",
+			"-crf", "18",
+			"-pix_fmt", "yuv420p",
+			"-c:a", "copy",
+		)
+
+	return newConfig(options).run(builder)
+}
+
+// Returns the filter drawing the frame index on the left and the timecode on the right of a translucent bar.
+func frameNumberFilter(timecode Timecode, rate string) string {
+	escape := strings.NewReplacer(":", `\:`, ";", `\;`)
+	text := "fontcolor=white:fontsize=h/24:y=h-h/16+(h/16-th)/2"
+	return fmt.Sprintf(
+		"drawbox=x=0:y=ih-ih/16:w=iw:h=ih/16:color=black@0.6:t=fill,"+
+			"drawtext=text='%%{frame_num}':start_number=0:x=w/50:%s,"+
+			"drawtext=timecode='%s':rate=%s:x=w-tw-w/50:%s",
+		text, escape.Replace(timecode.String()), rate, text,
+	)
+}
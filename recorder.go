@@ -0,0 +1,297 @@
+package vidio
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/benitogf/Vidio/ffcmd"
+)
+
+// A single segment file written by a Recorder.
+type Segment struct {
+	Filename string    // Path of the segment file.
+	Start    time.Time // Wall clock time the first frame of the segment was written.
+	Duration float64   // Duration of the segment in seconds.
+}
+
+// End time of the segment.
+func (segment Segment) End() time.Time {
+	return segment.Start.Add(time.Duration(segment.Duration * float64(time.Second)))
+}
+
+// Optional parameters for Recorder.
+type RecorderOptions struct {
+	Segment   float64       // Duration of each segment file in seconds. Default 60.
+	Retention int           // Maximum number of segment files to keep. 0 keeps all segments.
+	MaxAge    time.Duration // Segments older than MaxAge are removed. 0 keeps all segments.
+	Extension string        // Segment file extension. Default ".mp4".
+	Writer    *Options      // Encoding options for the segment files. MaxDuration and MaxFileSize limit the whole recording.
+}
+
+// Recorder writes incoming frames into fixed duration segment files with automatic rollover.
+type Recorder struct {
+	dir      string           // Directory segment files are written to.
+	width    int              // Frame width.
+	height   int              // Frame height.
+	segment  float64          // Segment duration in seconds.
+	fps      float64          // Frames per second of the segments.
+	options  RecorderOptions  // Recorder options.
+	writer   *VideoWriter     // Writer for the current segment.
+	frames   int              // Number of frames written to the current segment.
+	count    int              // Total number of segments created.
+	segments []Segment        // Segments currently on disk, oldest first.
+	total    int              // Total number of frames recorded.
+	size     int64            // Total size in bytes of the finished segments, including removed ones.
+	status   CompletionStatus // Whether the recorder is open, or why it stopped.
+	span     Span             // Span of the current segment.
+}
+
+// Creates a new Recorder writing segments of "width" x "height" frames into "dir".
+func NewRecorder(dir string, width, height int, options *RecorderOptions) (*Recorder, error) {
+	if err := installed("ffmpeg"); err != nil {
+		return nil, err
+	}
+
+	if options == nil {
+		options = &RecorderOptions{}
+	}
+
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, err
+	}
+
+	recorder := &Recorder{
+		dir:     dir,
+		width:   width,
+		height:  height,
+		options: *options,
+	}
+
+	if options.Segment <= 0 {
+		recorder.segment = 60
+	} else {
+		recorder.segment = options.Segment
+	}
+
+	if options.Extension == "" {
+		recorder.options.Extension = ".mp4"
+	}
+
+	if options.Writer == nil {
+		recorder.options.Writer = &Options{}
+	}
+
+	if recorder.options.Writer.FPS == 0 {
+		recorder.fps = 25
+	} else {
+		recorder.fps = recorder.options.Writer.FPS
+	}
+
+	return recorder, nil
+}
+
+// Directory segment files are written to.
+func (recorder *Recorder) Dir() string {
+	return recorder.dir
+}
+
+// Segment duration in seconds.
+func (recorder *Recorder) SegmentDuration() float64 {
+	return recorder.segment
+}
+
+// Returns the segments currently kept on disk, oldest first.
+// The last segment may still be in progress.
+func (recorder *Recorder) Segments() []Segment {
+	segments := make([]Segment, len(recorder.segments))
+	copy(segments, recorder.segments)
+	return segments
+}
+
+// Returns whether the recorder is still recording, or why it was finalized.
+func (recorder *Recorder) Status() CompletionStatus {
+	return recorder.status
+}
+
+// Writes the given frame to the current segment, starting a new segment if the current one is full.
+// Once the MaxDuration or MaxFileSize limit of the whole recording is reached, the current segment
+// is finalized and ErrLimitReached is returned for all further frames.
+func (recorder *Recorder) Write(frame []byte) error {
+	if recorder.status != StatusWriting {
+		if recorder.status == StatusMaxDuration || recorder.status == StatusMaxFileSize {
+			return ErrLimitReached
+		}
+		return fmt.Errorf("vidio: recorder for %s is closed", recorder.dir)
+	}
+
+	if recorder.writer == nil || float64(recorder.frames) >= recorder.segment*recorder.fps {
+		if err := recorder.rollover(); err != nil {
+			return err
+		}
+	}
+
+	if err := recorder.writer.Write(frame); err != nil {
+		return err
+	}
+
+	recorder.frames++
+	recorder.total++
+	recorder.segments[len(recorder.segments)-1].Duration = float64(recorder.frames) / recorder.fps
+
+	if status := recorder.limit(); status != StatusWriting {
+		recorder.Close()
+		recorder.status = status
+	}
+
+	return nil
+}
+
+// Returns the status the recorder should be finalized with if one of its limits is reached.
+func (recorder *Recorder) limit() CompletionStatus {
+	limits := recorder.options.Writer
+	if limits.MaxDuration > 0 && float64(recorder.total)/recorder.fps >= limits.MaxDuration {
+		return StatusMaxDuration
+	}
+	if limits.MaxFileSize > 0 && recorder.frames%int(math.Max(1, recorder.fps)) == 0 {
+		size := recorder.size
+		if info, err := os.Stat(recorder.writer.FileName()); err == nil {
+			size += info.Size()
+		}
+		if size >= limits.MaxFileSize {
+			return StatusMaxFileSize
+		}
+	}
+	return StatusWriting
+}
+
+// Finalizes the current segment writer and adds its size to the recording total.
+func (recorder *Recorder) closeSegment() {
+	if recorder.writer == nil {
+		return
+	}
+	recorder.writer.Close()
+	if info, err := os.Stat(recorder.writer.FileName()); err == nil {
+		recorder.size += info.Size()
+	}
+	if n := len(recorder.segments); n > 0 {
+		segment := recorder.segments[n-1]
+		emit(Event{Type: EventSegment, Output: segment.Filename, Segment: &segment})
+		recorder.span.SetAttribute("vidio.duration", segment.Duration)
+	}
+	recorder.span.End(nil)
+	recorder.span = nil
+	recorder.writer = nil
+}
+
+// Finalizes the current segment and starts a new one.
+func (recorder *Recorder) rollover() error {
+	recorder.closeSegment()
+
+	now := time.Now()
+	filename := recorder.segmentName(now)
+
+	options := *recorder.options.Writer
+	options.FPS = recorder.fps
+	// Limits apply to the whole recording and are enforced by the recorder.
+	options.MaxDuration = 0
+	options.MaxFileSize = 0
+	writer, err := NewVideoWriter(filename, recorder.width, recorder.height, &options)
+	if err != nil {
+		return err
+	}
+
+	_, recorder.span = startSpan(context.Background(), SpanSegment)
+	recorder.span.SetAttribute("vidio.file", filename)
+	recorder.writer = writer
+	recorder.frames = 0
+	recorder.count++
+	recorder.segments = append(recorder.segments, Segment{Filename: filename, Start: now})
+
+	recorder.retain(now)
+
+	return nil
+}
+
+// Path of the next segment file, numbered by the segment count and named after its start time
+// so the files sort in recording order.
+func (recorder *Recorder) segmentName(start time.Time) string {
+	return filepath.Join(
+		recorder.dir,
+		fmt.Sprintf("%06d-%s%s", recorder.count, start.Format("20060102-150405"), recorder.options.Extension),
+	)
+}
+
+// Removes finished segments exceeding the retention policy.
+func (recorder *Recorder) retain(now time.Time) {
+	for len(recorder.segments) > 1 {
+		oldest := recorder.segments[0]
+		expired := recorder.options.MaxAge > 0 && now.Sub(oldest.End()) > recorder.options.MaxAge
+		excess := recorder.options.Retention > 0 && len(recorder.segments) > recorder.options.Retention
+		if !expired && !excess {
+			break
+		}
+		os.Remove(oldest.Filename)
+		recorder.segments = recorder.segments[1:]
+	}
+}
+
+// Joins the recorded footage between "start" and "end" into a single file using stream copy.
+// Finished segments only, the segment currently being written is not included.
+func (recorder *Recorder) Stitch(start, end time.Time, output string, options ...Option) error {
+	if !end.After(start) {
+		return fmt.Errorf("vidio: stitch end time must be after start time")
+	}
+
+	finished := recorder.segments
+	if recorder.writer != nil && len(finished) > 0 {
+		finished = finished[:len(finished)-1]
+	}
+
+	entries := []concatEntry{}
+	for _, segment := range finished {
+		if !segment.End().After(start) || !segment.Start.Before(end) {
+			continue
+		}
+		entry := concatEntry{filename: segment.Filename}
+		if start.After(segment.Start) {
+			entry.inpoint = start.Sub(segment.Start).Seconds()
+		}
+		if end.Before(segment.End()) {
+			entry.outpoint = end.Sub(segment.Start).Seconds()
+		}
+		entries = append(entries, entry)
+	}
+
+	if len(entries) == 0 {
+		return fmt.Errorf("vidio: no recorded segments between %s and %s", start, end)
+	}
+
+	list, err := writeConcatList(entries)
+	if err != nil {
+		return err
+	}
+	c := newConfig(options)
+	c.generated = append(c.generated, list)
+	if !c.dryRun() {
+		defer os.Remove(list)
+	}
+
+	builder := ffcmd.New().
+		Global("-y").
+		Input(list, "-f", "concat", "-safe", "0").
+		Output(output, "-c", "copy")
+
+	return c.run(builder)
+}
+
+// Finalizes the current segment.
+func (recorder *Recorder) Close() {
+	recorder.closeSegment()
+	if recorder.status == StatusWriting {
+		recorder.status = StatusClosed
+	}
+}
@@ -0,0 +1,77 @@
+package vidio
+
+import (
+	"fmt"
+	"image"
+	"strconv"
+
+	"github.com/benitogf/Vidio/ffcmd"
+)
+
+// Size in pixels of the blocks redacted regions are pixelated to.
+const redactBlock = 16
+
+// A rectangle of the frame during a time range.
+type TimedRect struct {
+	Rect  image.Rectangle // Region of the frame. Must lie within the frame.
+	Start float64         // Start time in seconds.
+	End   float64         // End time in seconds. 0 means until the end of the video.
+}
+
+// Transcodes "input" to "output" with the given regions pixelated into 16 pixel blocks during their time
+// ranges, e.g. to redact faces or license plates. The regions are cropped, pixelated and overlaid in a single
+// ffmpeg filtergraph, with each overlay only enabled during its time range. Audio is copied.
+func RedactRegions(input, output string, regions []TimedRect, options ...Option) error {
+	if len(regions) == 0 {
+		return fmt.Errorf("vidio: no regions to redact given")
+	}
+	if !isURL(input) && !exists(input) {
+		return fmt.Errorf("vidio: video file %s does not exist", input)
+	}
+
+	builder := ffcmd.New().Global("-y").Input(input)
+	split := fmt.Sprintf("[0:v]split=%d[base]", len(regions)+1)
+	for i := range regions {
+		split += fmt.Sprintf("[c%d]", i)
+	}
+	builder.Filter(split)
+
+	format := func(value float64) string {
+		return strconv.FormatFloat(value, 'f', -1, 64)
+	}
+	previous := "[base]"
+	for i, region := range regions {
+		rect := region.Rect.Canon()
+		rect.Min = rect.Min.Add(image.Point{max(0, -rect.Min.X), max(0, -rect.Min.Y)})
+		if rect.Empty() {
+			return fmt.Errorf("vidio: redacted region %d is empty", i)
+		}
+		if region.End != 0 && region.End <= region.Start {
+			return fmt.Errorf("vidio: redacted region %d ends before it starts", i)
+		}
+		enable := fmt.Sprintf("gte(t,%s)", format(region.Start))
+		if region.End != 0 {
+			enable = fmt.Sprintf("between(t,%s,%s)", format(region.Start), format(region.End))
+		}
+
+		w, h := rect.Dx(), rect.Dy()
+		builder.Filter(fmt.Sprintf(
+			"[c%d]crop=%d:%d:%d:%d,scale=%d:%d,scale=%d:%d:flags=neighbor[p%d]",
+			i, w, h, rect.Min.X, rect.Min.Y, max(1, w/redactBlock), max(1, h/redactBlock), w, h, i,
+		))
+		next := fmt.Sprintf("[r%d]", i)
+		if i == len(regions)-1 {
+			next = "[v]"
+		}
+		builder.Filter(fmt.Sprintf("%s[p%d]overlay=%d:%d:enable='%s'%s", previous, i, rect.Min.X, rect.Min.Y, enable, next))
+		previous = next
+	}
+
+	builder.Map("[v]", "0:a?").Output(output,
+		"-c:v", "libx264",
+		"-crf", "18",
+		"-pix_fmt", "yuv420p",
+		"-c:a", "copy",
+	)
+	return newConfig(options).run(builder)
+}
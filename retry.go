@@ -0,0 +1,100 @@
+package vidio
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Retry policy for opening and probing sources. Only transient failures are retried.
+type RetryPolicy struct {
+	Attempts   int           // Maximum number of attempts, including the first. 0 or 1 disables retrying.
+	Backoff    time.Duration // Delay before the first retry. Doubled after every retry. Default 500ms.
+	MaxBackoff time.Duration // Upper bound for the delay between retries. 0 means no bound.
+}
+
+// Makes probe and source open operations, such as NewVideo on a network URL, retry transient failures
+// (timeouts, refused or reset connections, server errors) with exponential backoff.
+func WithRetry(policy RetryPolicy) Option {
+	return func(c *config) {
+		c.retry = policy
+	}
+}
+
+// Error returned when ffprobe or ffmpeg fail to open a source.
+type OpenError struct {
+	Source string // File or URL that failed to open.
+	Output string // ffprobe/ffmpeg error output.
+	Err    error  // Underlying error, e.g. the process exit status.
+}
+
+func (err *OpenError) Error() string {
+	if err.Output == "" {
+		return fmt.Sprintf("vidio: failed to open %s: %s", err.Source, err.Err)
+	}
+	return fmt.Sprintf("vidio: failed to open %s: %s: %s", err.Source, err.Err, err.Output)
+}
+
+func (err *OpenError) Unwrap() error {
+	return err.Err
+}
+
+// Error output fragments of failures which may succeed when retried.
+var transientMessages = []string{
+	"timed out",
+	"timeout",
+	"connection refused",
+	"connection reset",
+	"broken pipe",
+	"network is unreachable",
+	"no route to host",
+	"temporary failure in name resolution",
+	"resource temporarily unavailable",
+	"server returned 5",
+	"end of file",
+}
+
+// Returns true if the open failure is transient (timeouts, connection resets, server errors)
+// rather than permanent (no such file, invalid data, no decoder, authentication failures).
+func (err *OpenError) Transient() bool {
+	output := strings.ToLower(err.Output)
+	for _, message := range transientMessages {
+		if strings.Contains(output, message) {
+			return true
+		}
+	}
+	return false
+}
+
+// Returns true if the given error is a transient failure to open a source.
+func IsTransient(err error) bool {
+	var openErr *OpenError
+	return errors.As(err, &openErr) && openErr.Transient()
+}
+
+// Calls "fn" until it succeeds, returns a permanent error, or the policy runs out of attempts.
+func (policy RetryPolicy) do(c *config, fn func() error) error {
+	backoff := policy.Backoff
+	if backoff <= 0 {
+		backoff = 500 * time.Millisecond
+	}
+
+	err := fn()
+	for attempt := 1; attempt < policy.Attempts && err != nil && IsTransient(err); attempt++ {
+		timer := time.NewTimer(backoff)
+		select {
+		case <-c.ctx.Done():
+			timer.Stop()
+			return c.ctx.Err()
+		case <-timer.C:
+		}
+
+		backoff *= 2
+		if policy.MaxBackoff > 0 && backoff > policy.MaxBackoff {
+			backoff = policy.MaxBackoff
+		}
+		err = fn()
+	}
+	return err
+}
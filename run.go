@@ -0,0 +1,193 @@
+package vidio
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/benitogf/Vidio/ffcmd"
+)
+
+// Option configures how ffmpeg operations, such as NewVideo or Recorder.Stitch, are executed.
+type Option func(*config)
+
+// Execution settings collected from the Options passed to an operation.
+type config struct {
+	ctx        context.Context    // Cancels the running ffmpeg process.
+	cleanup    bool               // Remove partial outputs if the operation fails or is cancelled.
+	dryrun     *[][]string        // If not nil, commands are appended here instead of being executed.
+	progress   chan<- Progress    // If not nil, receives encoding progress updates.
+	retry      RetryPolicy        // Retry policy for probing and opening sources.
+	pixfmt     PixelFormat        // Pixel format of decoded frames.
+	conceal    bool               // Keep decoding past damaged data.
+	ondamage   func(DamagedFrame) // Receives decoder errors when concealing.
+	live       time.Duration      // Idle time after which a growing file is finished. 0 if not live.
+	noorient   bool               // Keep the stored orientation and pixel aspect of thumbnails.
+	urlpolicy  *URLPolicy         // Sources operations may open. nil allows all sources.
+	generated  []string           // Inputs written by the operation itself, e.g. concat lists, exempt from the URL policy.
+	concatlist string             // Concat list written by NewConcatVideo, opened with the concat demuxer.
+	sandbox    *Sandbox           // Reduced privileges for the ffmpeg processes. nil runs them normally.
+	limits     *limits            // Decoding limits of videos. nil means no limits.
+	ivtc       bool               // Undo 3:2 pulldown while decoding.
+	denoise    DenoiseLevel       // Noise reduction applied while decoding. Empty for none.
+	lens       *lensCorrection    // Lens distortion corrected while decoding. nil for none.
+	view       *Projection        // Flat view rendered from 360 degree footage. nil keeps the frames.
+	eye        Eye                // View of stereoscopic 3D video to decode. Empty decodes whole frames.
+}
+
+// Makes operations build their ffmpeg command(s) and append them to "commands" without executing them,
+// so exactly what would run can be audited or logged. Each command starts with the program name.
+func WithDryRun(commands *[][]string) Option {
+	return func(c *config) {
+		c.dryrun = commands
+	}
+}
+
+// Makes operations stop when the given context is cancelled. The ffmpeg process is killed together
+// with its process group and the operation returns the context error. Since ffmpeg then runs in its own
+// process group, a Ctrl+C in the terminal no longer reaches it; use signal.NotifyContext to cancel the
+// context on interrupts. NewVideo stops decoding once the context is cancelled, so Read returns false.
+// VideoWriter takes no options and is stopped with Close instead.
+func WithContext(ctx context.Context) Option {
+	return func(c *config) {
+		c.ctx = ctx
+	}
+}
+
+// Makes operations remove their partially written output files if they fail or are cancelled.
+func WithCleanup() Option {
+	return func(c *config) {
+		c.cleanup = true
+	}
+}
+
+// Collects the given options into a config.
+func newConfig(options []Option) *config {
+	c := &config{ctx: context.Background()}
+	for _, option := range options {
+		if option != nil {
+			option(c)
+		}
+	}
+	return c
+}
+
+// Returns true if operations should only record their commands.
+func (c *config) dryRun() bool {
+	return c.dryrun != nil
+}
+
+// Runs the ffmpeg command assembled with the given builder and waits for it to finish.
+// If ffmpeg fails, the returned error contains the ffmpeg error output.
+func (c *config) run(builder *ffcmd.Builder) error {
+	return c.execute(builder, nil)
+}
+
+// Runs the ffmpeg command assembled with the given builder and returns what it wrote to stdout,
+// for analysis commands whose results are printed by filters. Progress is not reported.
+// Returns no output on dry runs.
+func (c *config) output(builder *ffcmd.Builder) ([]byte, error) {
+	stdout := &bytes.Buffer{}
+	err := c.execute(builder, stdout)
+	return stdout.Bytes(), err
+}
+
+// Runs the ffmpeg command, writing its stdout to "stdout" if it is not nil, or to the progress parser.
+func (c *config) execute(builder *ffcmd.Builder, stdout io.Writer) error {
+	command := []string{"ffmpeg", "-hide_banner", "-loglevel", "error"}
+	// Progress is also parsed for event handlers.
+	events := listening()
+	if (c.progress != nil || events) && stdout == nil {
+		command = append(command, "-progress", "pipe:1", "-nostats")
+	}
+	command = append(command, builder.Args()...)
+
+	if err := c.checkSources(builder.InputPaths()...); err != nil {
+		return err
+	}
+	if c.dryRun() {
+		*c.dryrun = append(*c.dryrun, command)
+		return nil
+	}
+
+	if err := c.ctx.Err(); err != nil {
+		return err
+	}
+
+	source, output := endpoints(builder)
+	_, span := startSpan(c.ctx, SpanTranscode)
+	span.SetAttribute("vidio.file", source)
+	span.SetAttribute("vidio.output", output)
+
+	cmd, err := c.sandbox.command(c.ctx, command[0], command[1:]...)
+	if err != nil {
+		span.End(err)
+		return err
+	}
+	// Without a cancellable context, ffmpeg stays in the process group of the program,
+	// so a Ctrl+C in the terminal stops it as well.
+	if c.ctx.Done() != nil {
+		setProcessGroup(cmd)
+		cmd.Cancel = func() error {
+			return killProcessGroup(cmd)
+		}
+	}
+	if stdout != nil {
+		cmd.Stdout = stdout
+	} else if c.progress != nil || events {
+		parser := &progressParser{progress: c.progress}
+		if events {
+			parser.notify = func(progress Progress) {
+				emit(Event{Type: EventProgress, Source: source, Output: output, Progress: &progress})
+			}
+		}
+		cmd.Stdout = parser
+	}
+
+	stderr := bytes.Buffer{}
+	cmd.Stderr = &stderr
+
+	err = cmd.Run()
+	if cmd.ProcessState != nil {
+		span.SetAttribute("vidio.exit_status", cmd.ProcessState.ExitCode())
+	}
+	if err != nil {
+		err = c.failed(builder, err, stderr.String())
+		emit(Event{Type: EventError, Source: source, Output: output, Err: err})
+	}
+	span.End(err)
+
+	return err
+}
+
+// Cleans up after a failed command and returns the error including the ffmpeg error output.
+func (c *config) failed(builder *ffcmd.Builder, err error, stderr string) error {
+	if c.cleanup {
+		for _, output := range builder.Outputs() {
+			if !isURL(output) {
+				os.Remove(output)
+			}
+		}
+	}
+	if ctxErr := c.ctx.Err(); ctxErr != nil {
+		return ctxErr
+	}
+	message := strings.TrimSpace(stderr)
+	if message == "" {
+		return fmt.Errorf("vidio: ffmpeg failed: %w", err)
+	}
+	return fmt.Errorf("vidio: ffmpeg failed: %w: %s", err, message)
+}
+
+// Runs the ffmpeg command assembled with the given builder and waits for it to finish.
+// "-hide_banner" and "-loglevel error" are added so failures are reported with the ffmpeg error output.
+func Run(builder *ffcmd.Builder, options ...Option) error {
+	if err := installed("ffmpeg"); err != nil {
+		return err
+	}
+	return newConfig(options).run(builder)
+}
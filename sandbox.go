@@ -0,0 +1,97 @@
+package vidio
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"os/user"
+	"strconv"
+)
+
+// Reduced privileges for the ffmpeg and ffprobe processes of a service processing untrusted files.
+// The user running the processes must be able to read the inputs and write the outputs.
+type Sandbox struct {
+	User    string   // User name or uid to run as. Unix only and requires privileges. Empty keeps the current user.
+	Group   string   // Group name or gid to run as. Default the primary group of User.
+	Env     []string // Environment of the processes as "KEY=value" pairs. nil only passes PATH.
+	Wrapper []string // Command the processes are started through, e.g. {"firejail", "--quiet", "--net=none"} or a seccomp launcher.
+	Dir     string   // Working directory of the processes. Default the current directory.
+}
+
+// Runs the ffmpeg and ffprobe processes of operations and videos in the given sandbox.
+func WithSandbox(sandbox Sandbox) Option {
+	return func(c *config) {
+		c.sandbox = &sandbox
+	}
+}
+
+// Creates the command running "name" with the given arguments in the sandbox. A nil sandbox creates a
+// plain command. A nil context creates a command which is not killed by a context.
+func (sandbox *Sandbox) command(ctx context.Context, name string, args ...string) (*exec.Cmd, error) {
+	if sandbox != nil && len(sandbox.Wrapper) > 0 {
+		args = append(append(append([]string{}, sandbox.Wrapper[1:]...), name), args...)
+		name = sandbox.Wrapper[0]
+	}
+	var cmd *exec.Cmd
+	if ctx != nil {
+		cmd = exec.CommandContext(ctx, name, args...)
+	} else {
+		cmd = exec.Command(name, args...)
+	}
+	if sandbox == nil {
+		return cmd, nil
+	}
+
+	cmd.Dir = sandbox.Dir
+	cmd.Env = sandbox.Env
+	if cmd.Env == nil {
+		cmd.Env = []string{"PATH=" + os.Getenv("PATH")}
+	}
+
+	if sandbox.User != "" || sandbox.Group != "" {
+		uid, gid, err := sandbox.credentials()
+		if err != nil {
+			return nil, err
+		}
+		if err := setCredential(cmd, uid, gid); err != nil {
+			return nil, err
+		}
+	}
+	return cmd, nil
+}
+
+// Resolves the user and group of the sandbox to numeric ids.
+func (sandbox *Sandbox) credentials() (uint32, uint32, error) {
+	uid, gid := uint32(os.Getuid()), uint32(os.Getgid())
+	if sandbox.User != "" {
+		account, err := user.Lookup(sandbox.User)
+		if err != nil {
+			if account, err = user.LookupId(sandbox.User); err != nil {
+				return 0, 0, fmt.Errorf("vidio: unknown sandbox user %s", sandbox.User)
+			}
+		}
+		id, err := strconv.ParseUint(account.Uid, 10, 32)
+		if err != nil {
+			return 0, 0, fmt.Errorf("vidio: sandbox user %s has no numeric uid", sandbox.User)
+		}
+		uid = uint32(id)
+		if id, err := strconv.ParseUint(account.Gid, 10, 32); err == nil {
+			gid = uint32(id)
+		}
+	}
+	if sandbox.Group != "" {
+		group, err := user.LookupGroup(sandbox.Group)
+		if err != nil {
+			if group, err = user.LookupGroupId(sandbox.Group); err != nil {
+				return 0, 0, fmt.Errorf("vidio: unknown sandbox group %s", sandbox.Group)
+			}
+		}
+		id, err := strconv.ParseUint(group.Gid, 10, 32)
+		if err != nil {
+			return 0, 0, fmt.Errorf("vidio: sandbox group %s has no numeric gid", sandbox.Group)
+		}
+		gid = uint32(id)
+	}
+	return uid, gid, nil
+}
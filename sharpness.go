@@ -0,0 +1,76 @@
+package vidio
+
+import (
+	"fmt"
+	"image"
+	"math"
+)
+
+// Number of candidate frames sampled by BestThumbnailFrame.
+const thumbnailCandidates = 24
+
+// Returns the focus score of an RGBA frame as the variance of the Laplacian of its luma.
+// Sharp frames have many strong edges and score high, blurry or flat frames score close to 0.
+// Scores depend on the content, so they are only comparable between frames of the same video.
+func Sharpness(frame []byte, w, h int) float64 {
+	if w < 3 || h < 3 || len(frame) < w*h*4 {
+		return 0
+	}
+
+	luma := make([]float64, w*h)
+	for i := range luma {
+		luma[i] = 0.299*float64(frame[i*4]) + 0.587*float64(frame[i*4+1]) + 0.114*float64(frame[i*4+2])
+	}
+
+	sum, squares := 0.0, 0.0
+	for y := 1; y < h-1; y++ {
+		for x := 1; x < w-1; x++ {
+			i := y*w + x
+			laplacian := luma[i-w] + luma[i+w] + luma[i-1] + luma[i+1] - 4*luma[i]
+			sum += laplacian
+			squares += laplacian * laplacian
+		}
+	}
+	n := float64((w - 2) * (h - 2))
+	mean := sum / n
+	return squares/n - mean*mean
+}
+
+// Picks a frame of the video suitable as a thumbnail: sharp, well exposed and not black.
+// Frames spread across the video are scored by their sharpness, weighted by how close their
+// mean brightness is to mid gray. Returns the index of the chosen frame and the frame.
+// The frame is rotated and stretched to its display aspect ratio unless WithoutAutoOrient is given.
+func BestThumbnailFrame(filename string, options ...Option) (int, *image.RGBA, error) {
+	video, err := NewVideo(filename, options...)
+	if err != nil {
+		return 0, nil, err
+	}
+	defer video.Close()
+
+	frames, indexes, err := sampleFrames(video, thumbnailCandidates, newConfig(options))
+	if err != nil {
+		return 0, nil, err
+	}
+
+	best, score := -1, 0.0
+	for i, frame := range frames {
+		if s := thumbnailScore(frame); s > score {
+			best, score = i, s
+		}
+	}
+	if best < 0 {
+		return 0, nil, fmt.Errorf("vidio: no usable thumbnail frame found in %s", filename)
+	}
+	return indexes[best], frames[best], nil
+}
+
+// Scores a frame as a thumbnail. Black, white and flat frames score 0.
+func thumbnailScore(frame *image.RGBA) float64 {
+	w, h := frame.Rect.Dx(), frame.Rect.Dy()
+	luma := meanLuma(frame.Pix)
+	if luma < 20 || luma > 235 {
+		return 0
+	}
+	exposure := 1 - math.Abs(luma-128)/128
+	return Sharpness(frame.Pix, w, h) * exposure
+}
@@ -0,0 +1,115 @@
+package vidio
+
+import (
+	"fmt"
+	"strconv"
+
+	"github.com/benitogf/Vidio/ffcmd"
+)
+
+// Optional parameters for Slideshow.
+type SlideshowOptions struct {
+	Width              int     // Frame width. Images are scaled to fit and padded with black. Default 1920.
+	Height             int     // Frame height. Default 1080.
+	FPS                float64 // Frames per second. Default 25.
+	Transition         string  // ffmpeg xfade transition between images, e.g. "fade", "wipeleft" or "circleopen". "none" cuts. Default "fade".
+	TransitionDuration float64 // Duration of each transition in seconds. Default 1.
+	Codec              string  // Codec for video. Default libx264.
+}
+
+// Creates a video showing each image for "perImage" seconds, with transitions between the images.
+// If "audio" is not empty, it is played in the background, looped if it is shorter than the slideshow,
+// and faded out at the end.
+func Slideshow(images []string, perImage float64, audio string, transitions *SlideshowOptions, output string, options ...Option) error {
+	if len(images) == 0 {
+		return fmt.Errorf("vidio: no slideshow images given")
+	}
+	for _, filename := range append(append([]string{}, images...), audio) {
+		if filename != "" && !isURL(filename) && !exists(filename) {
+			return fmt.Errorf("vidio: file %s does not exist", filename)
+		}
+	}
+
+	settings := SlideshowOptions{}
+	if transitions != nil {
+		settings = *transitions
+	}
+	if settings.Width <= 0 || settings.Height <= 0 {
+		settings.Width, settings.Height = 1920, 1080
+	}
+	if settings.FPS <= 0 {
+		settings.FPS = 25
+	}
+	if settings.Transition == "" {
+		settings.Transition = "fade"
+	}
+	if settings.TransitionDuration <= 0 {
+		settings.TransitionDuration = 1
+	}
+	if settings.Codec == "" {
+		settings.Codec = "libx264"
+	}
+	fade := settings.TransitionDuration
+	if settings.Transition == "none" || len(images) == 1 {
+		fade = 0
+	}
+	if perImage <= fade {
+		return fmt.Errorf("vidio: images must be shown longer than the %gs transitions", fade)
+	}
+
+	format := func(value float64) string {
+		return strconv.FormatFloat(value, 'f', -1, 64)
+	}
+
+	builder := ffcmd.New().Global("-y")
+	for i, filename := range images {
+		// Images overlap the next one by the transition duration.
+		length := perImage
+		if i < len(images)-1 {
+			length += fade
+		}
+		builder.Input(filename, "-loop", "1", "-t", format(length))
+		builder.Filter(fmt.Sprintf(
+			"[%d:v]scale=%d:%d:force_original_aspect_ratio=decrease,pad=%d:%d:(ow-iw)/2:(oh-ih)/2,setsar=1,fps=%s,format=yuv420p[s%d]",
+			i, settings.Width, settings.Height, settings.Width, settings.Height, format(settings.FPS), i,
+		))
+	}
+
+	if fade == 0 {
+		streams := ""
+		for i := range images {
+			streams += fmt.Sprintf("[s%d]", i)
+		}
+		builder.Filter(fmt.Sprintf("%sconcat=n=%d:v=1:a=0[v]", streams, len(images)))
+	} else {
+		previous := "[s0]"
+		for i := 1; i < len(images); i++ {
+			next := fmt.Sprintf("[x%d]", i)
+			if i == len(images)-1 {
+				next = "[v]"
+			}
+			builder.Filter(fmt.Sprintf(
+				"%s[s%d]xfade=transition=%s:duration=%s:offset=%s%s",
+				previous, i, settings.Transition, format(fade), format(float64(i)*perImage), next,
+			))
+			previous = next
+		}
+	}
+
+	total := float64(len(images)) * perImage
+	outputOptions := []string{"-c:v", settings.Codec, "-pix_fmt", "yuv420p", "-r", format(settings.FPS)}
+	builder.Map("[v]")
+	if audio != "" {
+		builder.Input(audio, "-stream_loop", "-1")
+		fadeOut := min(2, total/2)
+		builder.Filter(fmt.Sprintf(
+			"[%d:a]atrim=0:%s,afade=t=out:st=%s:d=%s[a]",
+			len(images), format(total), format(total-fadeOut), format(fadeOut),
+		))
+		builder.Map("[a]")
+		outputOptions = append(outputOptions, "-c:a", "aac")
+	}
+	builder.Output(output, outputOptions...)
+
+	return newConfig(options).run(builder)
+}
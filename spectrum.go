@@ -0,0 +1,45 @@
+package vidio
+
+import (
+	"fmt"
+	"image"
+
+	"github.com/benitogf/Vidio/ffcmd"
+)
+
+// Renders a spectrogram of the first audio stream of "input" as a "w" x "h" image: time runs from left
+// to right, frequency from bottom to top, and the color shows the loudness on a logarithmic scale.
+// Rendered with the ffmpeg showspectrumpic filter, without axes or legend.
+func Spectrogram(input string, w, h int, options ...Option) (image.Image, error) {
+	if w <= 0 || h <= 0 {
+		return nil, fmt.Errorf("vidio: spectrogram size must be positive")
+	}
+	if !isURL(input) && !exists(input) {
+		return nil, fmt.Errorf("vidio: file %s does not exist", input)
+	}
+
+	builder := ffcmd.New().
+		Input(input).
+		Filter(fmt.Sprintf("[0:a:0]showspectrumpic=s=%dx%d:legend=0:mode=combined:color=intensity:scale=log[v]", w, h)).
+		Map("[v]").
+		Output("-",
+			"-frames:v", "1",
+			"-f", "rawvideo",
+			"-pix_fmt", "rgba",
+		)
+
+	c := newConfig(options)
+	data, err := c.output(builder)
+	if err != nil {
+		return nil, err
+	}
+	img := image.NewRGBA(image.Rect(0, 0, w, h))
+	if c.dryRun() {
+		return img, nil
+	}
+	if len(data) < len(img.Pix) {
+		return nil, fmt.Errorf("vidio: no spectrogram rendered for %s", input)
+	}
+	copy(img.Pix, data)
+	return img, nil
+}
@@ -0,0 +1,120 @@
+package vidio
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+
+	"github.com/benitogf/Vidio/ffcmd"
+)
+
+const (
+	speechRate   = 16000 // Sample rate used for speech, as expected by most speech to text services.
+	speechWindow = 0.03  // Length of the analysis windows in seconds.
+	speechGap    = 0.3   // Pauses shorter than this many seconds do not split segments.
+	speechMin    = 0.25  // Voiced segments shorter than this many seconds are dropped.
+	speechPad    = 0.1   // Seconds added before and after each segment so words are not cut off.
+)
+
+// A stretch of audio containing speech.
+type SpeechSegment struct {
+	Start float64 // Start time in seconds.
+	End   float64 // End time in seconds.
+}
+
+// Returns the voiced intervals of the first audio stream, e.g. to send only speech to a speech to text
+// service. Windows are voiced when their energy is well above the noise floor of the recording.
+// Short pauses are bridged, short noises dropped, and segments are padded by a tenth of a second.
+func SpeechSegments(filename string) ([]SpeechSegment, error) {
+	samples, err := readPCM(filename, speechRate)
+	if err != nil {
+		return nil, err
+	}
+	return speechSegments(samples, speechRate), nil
+}
+
+// Returns the voiced intervals of the given mono samples.
+func speechSegments(samples []float32, rate int) []SpeechSegment {
+	window := int(speechWindow * float64(rate))
+	rms := energy(samples, window)
+	segments := []SpeechSegment{}
+	if len(rms) == 0 {
+		return segments
+	}
+
+	// The noise floor is the level of the quietest tenth of the windows. The threshold is capped,
+	// so recordings with hardly any pauses are still split at their pauses.
+	sorted := append([]float64{}, rms...)
+	sort.Float64s(sorted)
+	threshold := min(max(3*sorted[len(sorted)/10], 0.01), 0.05)
+
+	duration := float64(len(samples)) / float64(rate)
+	var current *SpeechSegment
+	for i, e := range rms {
+		if e < threshold {
+			continue
+		}
+		start, end := float64(i)*speechWindow, float64(i+1)*speechWindow
+		if current != nil && start-current.End < speechGap {
+			current.End = end
+			continue
+		}
+		if current != nil && current.End-current.Start >= speechMin {
+			segments = append(segments, *current)
+		}
+		current = &SpeechSegment{Start: start, End: end}
+	}
+	if current != nil && current.End-current.Start >= speechMin {
+		segments = append(segments, *current)
+	}
+
+	for i := range segments {
+		segments[i].Start = max(0, segments[i].Start-speechPad)
+		segments[i].End = min(duration, segments[i].End+speechPad)
+	}
+	return segments
+}
+
+// Writes the audio of each segment to a 16 kHz mono WAV file "segment-0001.wav", "segment-0002.wav", ...
+// in "dir", ready to be sent to a speech to text service, and returns the filenames. The first audio
+// stream is decoded once for all segments.
+func ExtractSegmentsAudio(filename string, segments []SpeechSegment, dir string, options ...Option) ([]string, error) {
+	if len(segments) == 0 {
+		return nil, fmt.Errorf("vidio: no segments given")
+	}
+	if !isURL(filename) && !exists(filename) {
+		return nil, fmt.Errorf("vidio: file %s does not exist", filename)
+	}
+	c := newConfig(options)
+	if !c.dryRun() {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			return nil, err
+		}
+	}
+
+	format := func(value float64) string {
+		return strconv.FormatFloat(value, 'f', -1, 64)
+	}
+	builder := ffcmd.New().Global("-y").Input(filename)
+	files := make([]string, len(segments))
+	for i, segment := range segments {
+		if segment.End <= segment.Start {
+			return nil, fmt.Errorf("vidio: segment %d ends before it starts", i)
+		}
+		files[i] = filepath.Join(dir, fmt.Sprintf("segment-%04d.wav", i+1))
+		builder.Map("0:a:0").Output(files[i],
+			"-ss", format(segment.Start),
+			"-to", format(segment.End),
+			"-ac", "1",
+			"-ar", strconv.Itoa(speechRate),
+			"-c:a", "pcm_s16le",
+		)
+	}
+
+	if err := c.run(builder); err != nil {
+		return nil, err
+	}
+	return files, nil
+}
@@ -0,0 +1,58 @@
+package vidio
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// Optional parameters for SRT connections.
+type SRTOptions struct {
+	Passphrase string        // Encryption passphrase. Must be between 10 and 79 characters. Empty disables encryption.
+	Latency    time.Duration // Receiver latency buffer. Default is the ffmpeg default (120ms).
+	StreamID   string        // Stream ID used by servers to select the stream.
+	Mode       string        // Connection mode: "caller", "listener" or "rendezvous". Default "caller".
+}
+
+// Returns the given srt:// URL with the SRT options added as query parameters.
+// The result can be passed to NewVideo or NewIPCamera for reading, or to NewVideoWriter
+// for writing, in which case the stream is sent as MPEG-TS.
+func SRTURL(address string, options *SRTOptions) (string, error) {
+	parsed, err := url.Parse(address)
+	if err != nil {
+		return "", fmt.Errorf("vidio: invalid srt url %s: %w", address, err)
+	}
+	if strings.ToLower(parsed.Scheme) != "srt" {
+		return "", fmt.Errorf("vidio: url %s is not an srt:// url", address)
+	}
+
+	if options == nil {
+		return parsed.String(), nil
+	}
+
+	query := parsed.Query()
+	if options.Passphrase != "" {
+		if len(options.Passphrase) < 10 || len(options.Passphrase) > 79 {
+			return "", fmt.Errorf("vidio: srt passphrase must be between 10 and 79 characters")
+		}
+		query.Set("passphrase", options.Passphrase)
+	}
+	if options.Latency > 0 {
+		// ffmpeg expects the latency in microseconds.
+		query.Set("latency", fmt.Sprintf("%d", options.Latency.Microseconds()))
+	}
+	if options.StreamID != "" {
+		query.Set("streamid", options.StreamID)
+	}
+	switch options.Mode {
+	case "":
+	case "caller", "listener", "rendezvous":
+		query.Set("mode", options.Mode)
+	default:
+		return "", fmt.Errorf("vidio: unsupported srt mode: %s", options.Mode)
+	}
+	parsed.RawQuery = query.Encode()
+
+	return parsed.String(), nil
+}
@@ -0,0 +1,145 @@
+package vidio
+
+import "fmt"
+
+// Number of blocks in a frame stamp: 2 guard blocks, 24 bits for the frame number and 8 checksum bits.
+const stampBlocks = 2 + 24 + 8
+
+// Returns the side length in pixels of a single stamp block for frames of the given width.
+func stampBlockSize(width int) int {
+	size := width / stampBlocks
+	if size > 8 {
+		size = 8
+	}
+	return size
+}
+
+// Checksum stored alongside the stamped frame number to reject misreads.
+func stampChecksum(n int) int {
+	return (n ^ (n >> 8) ^ (n >> 16) ^ 0xA5) & 0xFF
+}
+
+// Draws a machine-readable marker encoding the frame number "n" into the top left corner
+// of the given RGBA frame. The marker is a row of black and white blocks which survive
+// lossy encoding well enough to be read back with ReadStamp after decoding.
+// Useful for measuring latency and sync in streaming pipelines.
+func StampFrame(frame []byte, width, height, n int) error {
+	size := stampBlockSize(width)
+	if size < 2 || height < size {
+		return fmt.Errorf("vidio: frame size %dx%d is too small to stamp", width, height)
+	}
+	if len(frame) < width*height*4 {
+		return fmt.Errorf("vidio: buffer size %d is smaller than frame size %d", len(frame), width*height*4)
+	}
+	if n < 0 || n >= 1<<24 {
+		return fmt.Errorf("vidio: frame number %d can not be stamped", n)
+	}
+
+	bits := make([]bool, 0, stampBlocks)
+	bits = append(bits, true, false) // Guard blocks: white, black.
+	for i := 23; i >= 0; i-- {
+		bits = append(bits, (n>>i)&1 == 1)
+	}
+	checksum := stampChecksum(n)
+	for i := 7; i >= 0; i-- {
+		bits = append(bits, (checksum>>i)&1 == 1)
+	}
+
+	for b, bit := range bits {
+		value := byte(0)
+		if bit {
+			value = 255
+		}
+		for y := 0; y < size; y++ {
+			for x := b * size; x < (b+1)*size; x++ {
+				index := (y*width + x) * 4
+				frame[index+0] = value
+				frame[index+1] = value
+				frame[index+2] = value
+				frame[index+3] = 255
+			}
+		}
+	}
+
+	return nil
+}
+
+// Reads the frame number stamped by StampFrame from the given RGBA frame.
+// Returns an error if no valid stamp is found.
+func ReadStamp(frame []byte, width, height int) (int, error) {
+	size := stampBlockSize(width)
+	if size < 2 || height < size || len(frame) < width*height*4 {
+		return 0, fmt.Errorf("vidio: frame size %dx%d is too small to contain a stamp", width, height)
+	}
+
+	// Samples the average luma of the center of each block.
+	bits := make([]bool, stampBlocks)
+	for b := range bits {
+		total, count := 0, 0
+		for y := size / 4; y < size-size/4; y++ {
+			for x := b*size + size/4; x < (b+1)*size-size/4; x++ {
+				index := (y*width + x) * 4
+				total += (int(frame[index]) + int(frame[index+1]) + int(frame[index+2])) / 3
+				count++
+			}
+		}
+		bits[b] = total/count > 127
+	}
+
+	if !bits[0] || bits[1] {
+		return 0, fmt.Errorf("vidio: no frame stamp found")
+	}
+
+	n := 0
+	for _, bit := range bits[2:26] {
+		n <<= 1
+		if bit {
+			n |= 1
+		}
+	}
+	checksum := 0
+	for _, bit := range bits[26:] {
+		checksum <<= 1
+		if bit {
+			checksum |= 1
+		}
+	}
+	if checksum != stampChecksum(n) {
+		return 0, fmt.Errorf("vidio: frame stamp checksum mismatch")
+	}
+
+	return n, nil
+}
+
+// A stage of a streaming pipeline processing a single RGBA frame, e.g. an encode/decode round trip
+// or a network hop. Returns the frame which came out of the stage.
+type FrameTransform func(frame []byte) ([]byte, error)
+
+// Wraps "transform" for frames of "width" x "height" so that a copy of every frame passed in is stamped
+// with its sequence number before the transform, and the stamp read from the result is verified.
+// The wrapped transform fails if a stamp is lost or belongs to another frame, e.g. after drops or reordering.
+func StampFrames(width, height int, transform FrameTransform) FrameTransform {
+	n := 0
+	return func(frame []byte) ([]byte, error) {
+		stamped := make([]byte, len(frame))
+		copy(stamped, frame)
+		if err := StampFrame(stamped, width, height, n); err != nil {
+			return nil, err
+		}
+		expected := n
+		n++
+
+		result, err := transform(stamped)
+		if err != nil {
+			return nil, err
+		}
+		stamp, err := ReadStamp(result, width, height)
+		if err != nil {
+			return result, fmt.Errorf("vidio: frame %d lost its stamp: %w", expected, err)
+		}
+		if stamp != expected {
+			return result, fmt.Errorf("vidio: expected frame %d, got frame %d", expected, stamp)
+		}
+		return result, nil
+	}
+}
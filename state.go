@@ -0,0 +1,76 @@
+package vidio
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+)
+
+// Checkpoint of the decoding position of a Video, for resuming long batch jobs after a crash.
+// Can be stored as JSON.
+type VideoState struct {
+	Filename string  `json:"filename"` // Video filename.
+	Stream   int     `json:"stream"`   // Video stream index.
+	Frame    int     `json:"frame"`    // Index of the next frame Read returns.
+	Time     float64 `json:"time"`     // Timestamp of the next frame in seconds.
+}
+
+// Returns the current decoding position. Frame is the index of the frame the next Read returns.
+func (video *Video) SaveState() VideoState {
+	frame := int(video.delivered.Load())
+	return VideoState{
+		Filename: video.filename,
+		Stream:   video.stream,
+		Frame:    frame,
+		Time:     frameTime(frame, video.fps),
+	}
+}
+
+// Makes the next Read continue at the position of the given state, seeking instead of decoding the
+// frames before it. Stops the current decode. The state must have been saved for the same video and stream.
+func (video *Video) ResumeFrom(state VideoState) error {
+	if state.Filename != video.filename || state.Stream != video.stream {
+		return fmt.Errorf("vidio: state of %s stream %d does not belong to %s stream %d", state.Filename, state.Stream, video.filename, video.stream)
+	}
+	if state.Frame < 0 || video.frames > 0 && state.Frame > video.frames {
+		return fmt.Errorf("vidio: provided frame index %d is not in frame count range", state.Frame)
+	}
+	if video.cmd != nil {
+		video.Reset()
+	}
+	video.start = state.Frame
+	video.delivered.Store(int64(state.Frame))
+	return nil
+}
+
+// Returns the presentation time in seconds of the frame with the given index, including the start time of the video.
+func (video *Video) TimeAt(frame int) float64 {
+	return video.StartTime() + frameTime(frame, video.fps)
+}
+
+// Makes the next Read continue at the frame shown at the given presentation time in seconds, which includes
+// the start time of the video like the timestamps reported by ffmpeg. Stops the current decode.
+func (video *Video) Seek(t float64) error {
+	if video.fps <= 0 {
+		return fmt.Errorf("vidio: video %s has no frame rate to seek by", video.filename)
+	}
+	frame := max(0, int(math.Round((t-video.StartTime())*video.fps)))
+	return video.ResumeFrom(VideoState{Filename: video.filename, Stream: video.stream, Frame: frame})
+}
+
+// Returns the timestamp in seconds of the frame with the given index.
+func frameTime(frame int, fps float64) float64 {
+	if fps <= 0 {
+		return 0
+	}
+	return float64(frame) / fps
+}
+
+// Returns the input options seeking to the frame with the given index. Seeks half a frame early,
+// so rounding never skips the frame itself.
+func seekArgs(frame int, fps float64) []string {
+	if frame <= 0 || fps <= 0 {
+		return nil
+	}
+	return []string{"-ss", strconv.FormatFloat((float64(frame)-0.5)/fps, 'f', 6, 64)}
+}
@@ -0,0 +1,83 @@
+package vidio
+
+import (
+	"io"
+	"time"
+)
+
+// Snapshot of the delivery statistics of a live source.
+type Stats struct {
+	Frames     int           // Number of frames delivered so far.
+	FPS        float64       // Measured delivery rate in frames per second.
+	Latency    time.Duration // Estimated capture to delivery latency of the last frame.
+	AvgLatency time.Duration // Average estimated capture to delivery latency.
+	MaxLatency time.Duration // Largest estimated capture to delivery latency.
+	QueueDepth int           // Number of decoded frames waiting in the pipe to be read.
+	Throughput float64       // Bytes per second read from the pipe while waiting for frames.
+}
+
+// Tracks frame delivery timing for a source producing frames at a nominal frame rate.
+// The capture time of frame n is estimated as start + n/fps, where start is the time
+// the first frame was delivered. Latency is how far delivery falls behind that schedule.
+type frameClock struct {
+	fps     float64
+	start   time.Time
+	last    time.Time
+	frames  int
+	latency time.Duration
+	total   time.Duration
+	max     time.Duration
+	queue   int
+	bytes   int64         // Total bytes read.
+	reading time.Duration // Total time spent waiting for frame data.
+}
+
+// Records the delivery of a frame of "size" bytes read from "pipe", which took "wait" to read.
+func (clock *frameClock) tick(pipe io.Reader, size int, wait time.Duration) {
+	now := time.Now()
+	if clock.frames == 0 {
+		clock.start = now
+	}
+
+	latency := time.Duration(0)
+	if clock.fps > 0 {
+		expected := clock.start.Add(time.Duration(float64(clock.frames) / clock.fps * float64(time.Second)))
+		if now.After(expected) {
+			latency = now.Sub(expected)
+		}
+	}
+
+	clock.latency = latency
+	clock.total += latency
+	if latency > clock.max {
+		clock.max = latency
+	}
+	clock.last = now
+	clock.frames++
+	clock.bytes += int64(size)
+	clock.reading += wait
+
+	if size > 0 {
+		clock.queue = pipeBuffered(pipe) / size
+	}
+}
+
+// Returns a snapshot of the current statistics.
+func (clock *frameClock) stats() Stats {
+	stats := Stats{
+		Frames:     clock.frames,
+		Latency:    clock.latency,
+		MaxLatency: clock.max,
+		QueueDepth: clock.queue,
+	}
+	if clock.frames > 0 {
+		stats.AvgLatency = clock.total / time.Duration(clock.frames)
+	}
+	if clock.reading > 0 {
+		stats.Throughput = float64(clock.bytes) / clock.reading.Seconds()
+	}
+	if elapsed := clock.last.Sub(clock.start).Seconds(); clock.frames > 1 && elapsed > 0 {
+		stats.FPS = float64(clock.frames-1) / elapsed
+	}
+	return stats
+}
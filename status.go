@@ -0,0 +1,61 @@
+package vidio
+
+import (
+	"errors"
+	"fmt"
+)
+
+// Returned by writers once a MaxDuration or MaxFileSize limit has been reached and the output was finalized.
+var ErrLimitReached = errors.New("vidio: writer limit reached")
+
+// Describes whether a writer is still writing, or why it stopped.
+type CompletionStatus int
+
+const (
+	StatusWriting     CompletionStatus = iota // The writer accepts frames.
+	StatusClosed                              // The writer was closed by the user.
+	StatusMaxDuration                         // The output reached Options.MaxDuration.
+	StatusMaxFileSize                         // The output reached Options.MaxFileSize.
+	StatusFailed                              // Writing failed, e.g. the disk is full or ffmpeg exited.
+)
+
+func (status CompletionStatus) String() string {
+	switch status {
+	case StatusWriting:
+		return "writing"
+	case StatusClosed:
+		return "closed"
+	case StatusMaxDuration:
+		return "max duration reached"
+	case StatusMaxFileSize:
+		return "max file size reached"
+	case StatusFailed:
+		return "failed"
+	default:
+		return "unknown"
+	}
+}
+
+var (
+	ErrNoSpace    = errors.New("vidio: no space left on device")
+	ErrBrokenPipe = errors.New("vidio: ffmpeg stopped accepting frames")
+)
+
+// Returned by writers when writing fails. The output was finalized as far as possible:
+// with Options.Fragmented, mp4 files remain playable up to roughly Timestamp.
+type WriteError struct {
+	Err       error   // ErrNoSpace, ErrBrokenPipe or the underlying error.
+	Timestamp float64 // Time in seconds of the end of the last frame handed to ffmpeg before the failure.
+	Output    string  // Last lines of the ffmpeg error output.
+}
+
+func (err *WriteError) Error() string {
+	if err.Output == "" {
+		return fmt.Sprintf("%s (last frame at %.3fs)", err.Err, err.Timestamp)
+	}
+	return fmt.Sprintf("%s (last frame at %.3fs): %s", err.Err, err.Timestamp, err.Output)
+}
+
+func (err *WriteError) Unwrap() error {
+	return err.Err
+}
@@ -0,0 +1,148 @@
+package vidio
+
+import (
+	"fmt"
+	"slices"
+)
+
+// Default number of frames kept decoded before and after the current frame while stepping.
+const defaultStepWindow = 8
+
+// Frames decoded around the current frame by Player.StepForward and Player.StepBackward.
+type stepWindow struct {
+	behind  int            // Number of frames kept before the current frame.
+	ahead   int            // Number of frames decoded after the current frame.
+	frames  map[int][]byte // Decoded frames by index.
+	current int            // Index of the current frame. -1 if not stepping.
+	ended   bool           // True if decoding reached the end of the video.
+}
+
+// Sets the number of frames kept decoded before and after the current frame while stepping, 8 each by
+// default. Steps within the window are instant, steps outside it seek. Every frame takes
+// width*height*depth bytes of memory.
+func (player *Player) SetStepWindow(behind, ahead int) error {
+	if behind < 0 || ahead < 0 {
+		return fmt.Errorf("vidio: step window must not be negative")
+	}
+	player.mu.Lock()
+	defer player.mu.Unlock()
+	window := player.stepWindow()
+	window.behind, window.ahead = behind, ahead
+	window.evict()
+	return nil
+}
+
+// Pauses playback and moves "n" frames forward, storing the frame in the frame buffer of the video.
+// Frames within the step window are decoded ahead of time, so stepping through them is instant.
+func (player *Player) StepForward(n int) error {
+	return player.step(n)
+}
+
+// Pauses playback and moves "n" frames back, storing the frame in the frame buffer of the video.
+// Frames within the step window are kept, so stepping back through them is instant.
+func (player *Player) StepBackward(n int) error {
+	return player.step(-n)
+}
+
+// Moves the current frame by "delta" frames.
+func (player *Player) step(delta int) error {
+	if player.Audio != nil {
+		return fmt.Errorf("vidio: frame stepping is not supported for audio files")
+	}
+	player.mu.Lock()
+	defer player.mu.Unlock()
+	if player.closed {
+		return fmt.Errorf("vidio: player of %s is closed", player.FilePath)
+	}
+	player.touch()
+	player.pause()
+
+	video := player.Video
+	window := player.stepWindow()
+	current := window.current
+	if current < 0 {
+		// Stepping starts at the last frame read.
+		current = int(video.delivered.Load()) - 1
+	}
+	target := current + delta
+	if target < 0 || video.frames > 0 && target >= video.frames {
+		return fmt.Errorf("vidio: frame %d is not in frame count range", target)
+	}
+
+	if _, ok := window.frames[target]; !ok {
+		next := int(video.delivered.Load())
+		if target < next || target >= next+window.ahead {
+			// Outside the window, the video seeks and decodes the frames behind the target as well.
+			state := VideoState{Filename: video.filename, Stream: video.stream, Frame: max(0, target-window.behind)}
+			if err := video.ResumeFrom(state); err != nil {
+				return err
+			}
+			clear(window.frames)
+			window.ended = false
+		}
+		window.decode(video, target)
+		if _, ok := window.frames[target]; !ok {
+			return fmt.Errorf("vidio: frame %d is beyond the end of %s", target, video.filename)
+		}
+	}
+
+	window.current = target
+	window.decode(video, target+window.ahead)
+	window.evict()
+	copy(video.framebuffer, window.frames[target])
+	player.publish(StateEvent{Type: StateSeek})
+	return nil
+}
+
+// Returns the step window of the player, creating it with the default size.
+func (player *Player) stepWindow() *stepWindow {
+	if player.steps == nil {
+		player.steps = &stepWindow{
+			behind:  defaultStepWindow,
+			ahead:   defaultStepWindow,
+			frames:  map[int][]byte{},
+			current: -1,
+		}
+	}
+	return player.steps
+}
+
+// Ends stepping before reading or seeking, so reading continues after the current frame.
+func (player *Player) stopStepping() error {
+	window := player.steps
+	if window == nil || window.current < 0 {
+		return nil
+	}
+	next := window.current + 1
+	window.current = -1
+	clear(window.frames)
+	window.ended = false
+	if int(player.Video.delivered.Load()) == next {
+		return nil
+	}
+	return player.Video.ResumeFrom(VideoState{Filename: player.Video.filename, Stream: player.Video.stream, Frame: next})
+}
+
+// Decodes frames up to and including the given index, unless the video ends first.
+func (window *stepWindow) decode(video *Video, last int) {
+	for !window.ended && int(video.delivered.Load()) <= last {
+		index := int(video.delivered.Load())
+		if !video.Read() {
+			window.ended = true
+			return
+		}
+		window.frames[index] = slices.Clone(video.FrameBuffer())
+	}
+}
+
+// Drops the frames outside the window around the current frame.
+func (window *stepWindow) evict() {
+	if window.current < 0 {
+		return
+	}
+	for index := range window.frames {
+		if index < window.current-window.behind || index > window.current+window.ahead {
+			delete(window.frames, index)
+		}
+	}
+}
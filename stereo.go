@@ -0,0 +1,105 @@
+package vidio
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Arrangement of the two views of stereoscopic 3D video within a frame.
+type StereoLayout int
+
+const (
+	StereoNone       StereoLayout = iota // Not stereoscopic, or packed in an unsupported way.
+	StereoSideBySide                     // Views next to each other, e.g. 3D Blu-ray rips and VR180 cameras.
+	StereoTopBottom                      // Views above each other, e.g. stereo 360 footage.
+)
+
+func (layout StereoLayout) String() string {
+	switch layout {
+	case StereoSideBySide:
+		return "side by side"
+	case StereoTopBottom:
+		return "top and bottom"
+	default:
+		return "none"
+	}
+}
+
+// View of stereoscopic 3D video decoded with ExtractEye.
+type Eye string
+
+const (
+	EyeLeft  Eye = "left"
+	EyeRight Eye = "right"
+)
+
+// Makes NewVideo decode only the view of the given eye of stereoscopic 3D video. The layout is read from the
+// stereo 3D metadata of the stream, NewVideo fails for videos without it. Frames are half the width of side by
+// side videos or half the height of top and bottom videos. Applied before WithProjection, so single views of
+// stereo 360 footage can be reprojected.
+func ExtractEye(eye Eye) Option {
+	return func(c *config) {
+		c.eye = eye
+	}
+}
+
+// Returns the stereo layout from the stream metadata, either the stereo 3D side data or the Matroska stereo mode,
+// and whether the right view comes first.
+func stereoLayout(data map[string]string) (StereoLayout, bool) {
+	if data["side_data_type"] == "Stereo 3D" || data["side_data_type"] == "Stereoscopic 3D" {
+		inverted := data["inverted"] == "1"
+		switch data["type"] {
+		case "side by side":
+			return StereoSideBySide, inverted
+		case "top and bottom":
+			return StereoTopBottom, inverted
+		}
+	}
+	switch data["tag:stereo_mode"] {
+	case "left_right":
+		return StereoSideBySide, false
+	case "right_left":
+		return StereoSideBySide, true
+	case "top_bottom":
+		return StereoTopBottom, false
+	case "bottom_top":
+		return StereoTopBottom, true
+	}
+	return StereoNone, false
+}
+
+// Stereo 3D layout of the video from the stream metadata. StereoNone if the video is not stereoscopic.
+func (video *Video) Stereo() StereoLayout {
+	layout, _ := stereoLayout(video.metadata)
+	return layout
+}
+
+// Crops frames to the view of the given eye before the other decoding filters.
+func (video *Video) extractEye(eye Eye) error {
+	if eye != EyeLeft && eye != EyeRight {
+		return fmt.Errorf("vidio: unknown eye: %s", eye)
+	}
+	layout, inverted := stereoLayout(video.metadata)
+	// Whether the view is the right or bottom half.
+	second := (eye == EyeRight) != inverted
+
+	var crop string
+	switch layout {
+	case StereoSideBySide:
+		crop = "crop=iw/2:ih:0:0"
+		if second {
+			crop = "crop=iw/2:ih:iw/2:0"
+		}
+		video.width /= 2
+	case StereoTopBottom:
+		crop = "crop=iw:ih/2:0:0"
+		if second {
+			crop = "crop=iw:ih/2:0:ih/2"
+		}
+		video.height /= 2
+	default:
+		return fmt.Errorf("vidio: %s has no stereo 3D metadata", video.filename)
+	}
+	video.filter = strings.TrimSuffix(crop+","+video.filter, ",")
+	return nil
+}
@@ -0,0 +1,77 @@
+package vidio
+
+import (
+	"fmt"
+	"strconv"
+)
+
+// Description of a stream of a media file, for presenting audio and subtitle tracks in player UIs.
+type StreamInfo struct {
+	Index           int    // Index of the stream in the file.
+	Specifier       string // Stream specifier by type, e.g. "a:1" for the second audio stream. Usable with EditStreams.
+	Type            string // "video", "audio", "subtitle", "data" or "attachment".
+	Codec           string // Codec name, e.g. "aac" or "subrip".
+	Language        string // Language tag, usually an ISO 639-2 code such as "eng". Empty if not tagged.
+	Title           string // Title tag, e.g. "Director's commentary". Empty if not tagged.
+	Default         bool   // The stream is played by default.
+	Forced          bool   // The subtitle stream should be shown even if subtitles are off, e.g. for foreign dialogue.
+	HearingImpaired bool   // The stream is meant for hearing impaired viewers, e.g. subtitles with sound descriptions.
+	Commentary      bool   // The stream is a commentary track.
+}
+
+// Returns information about all streams of the file, in the order they are stored.
+func Streams(filename string) ([]StreamInfo, error) {
+	if !isURL(filename) && !exists(filename) {
+		return nil, fmt.Errorf("vidio: video file %s does not exist", filename)
+	}
+	if err := installed("ffprobe"); err != nil {
+		return nil, err
+	}
+
+	data, err := ffprobe(filename, "")
+	if err != nil {
+		return nil, err
+	}
+	return parseStreamInfo(data), nil
+}
+
+// Builds the stream descriptions from the ffprobe stream data.
+func parseStreamInfo(data []map[string]string) []StreamInfo {
+	specifiers := map[string]string{
+		"video":      "v",
+		"audio":      "a",
+		"subtitle":   "s",
+		"data":       "d",
+		"attachment": "t",
+	}
+	counts := map[string]int{}
+
+	streams := make([]StreamInfo, len(data))
+	for i, stream := range data {
+		index, err := strconv.Atoi(stream["index"])
+		if err != nil {
+			index = i
+		}
+		kind := stream["codec_type"]
+		info := StreamInfo{
+			Index:           index,
+			Type:            kind,
+			Codec:           stream["codec_name"],
+			Language:        stream["tag:language"],
+			Title:           stream["tag:title"],
+			Default:         stream["disposition:default"] == "1",
+			Forced:          stream["disposition:forced"] == "1",
+			HearingImpaired: stream["disposition:hearing_impaired"] == "1",
+			Commentary:      stream["disposition:comment"] == "1",
+		}
+		if info.Language == "und" {
+			info.Language = ""
+		}
+		if letter, ok := specifiers[kind]; ok {
+			info.Specifier = fmt.Sprintf("%s:%d", letter, counts[kind])
+			counts[kind]++
+		}
+		streams[i] = info
+	}
+	return streams
+}
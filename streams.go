@@ -0,0 +1,105 @@
+package vidio
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/benitogf/Vidio/ffcmd"
+)
+
+// Kind of a StreamOp.
+type streamOpKind int
+
+const (
+	streamKeep streamOpKind = iota
+	streamDrop
+	streamLanguage
+	streamDefault
+)
+
+// Edit applied to the streams of a file by EditStreams. Create StreamOps with
+// KeepStream, DropStream, SetLanguage and SetDefault.
+type StreamOp struct {
+	kind   streamOpKind // What the op does.
+	stream string       // ffmpeg stream specifier, e.g. "a:1".
+	value  string       // Language code for SetLanguage.
+}
+
+// Keeps the input streams matching the stream specifier, e.g. "v:0", "a:1" or "s".
+// If any streams are kept, only kept streams are written, in the order of the KeepStream ops,
+// which allows reordering streams. Otherwise all streams are written in their input order.
+func KeepStream(stream string) StreamOp {
+	return StreamOp{kind: streamKeep, stream: stream}
+}
+
+// Removes the input streams matching the stream specifier, e.g. "a:2" or "d" for all data streams.
+func DropStream(stream string) StreamOp {
+	return StreamOp{kind: streamDrop, stream: stream}
+}
+
+// Sets the language tag, e.g. "eng" or "spa", of the output streams matching the stream specifier.
+func SetLanguage(stream, language string) StreamOp {
+	return StreamOp{kind: streamLanguage, stream: stream, value: language}
+}
+
+// Makes the output stream matching the specifier, e.g. "a:1", the default stream of its type,
+// and clears the default flag of all other streams of that type.
+func SetDefault(stream string) StreamOp {
+	return StreamOp{kind: streamDefault, stream: stream}
+}
+
+// Copies the input to the output with the given stream edits, without re-encoding. Keep and drop ops use
+// specifiers of the input streams, language and default ops use specifiers of the output streams,
+// i.e. after streams were dropped or reordered.
+func EditStreams(input, output string, ops []StreamOp, options ...Option) error {
+	if !exists(input) {
+		return fmt.Errorf("vidio: video file %s does not exist", input)
+	}
+
+	builder := ffcmd.New().Global("-y").Input(input)
+	args, err := streamArgs(builder, ops)
+	if err != nil {
+		return err
+	}
+	builder.Output(output, append(args, "-c", "copy")...)
+
+	return newConfig(options).run(builder)
+}
+
+// Adds the stream maps of the ops to the builder and returns the output options setting tags and dispositions.
+func streamArgs(builder *ffcmd.Builder, ops []StreamOp) ([]string, error) {
+	kept := false
+	for _, op := range ops {
+		if op.stream == "" {
+			return nil, fmt.Errorf("vidio: stream op without a stream specifier")
+		}
+		if op.kind == streamKeep {
+			builder.Map("0:" + op.stream)
+			kept = true
+		}
+	}
+	if !kept {
+		builder.Map("0")
+	}
+
+	args := []string{}
+	cleared := map[string]bool{}
+	for _, op := range ops {
+		switch op.kind {
+		case streamDrop:
+			// Negative maps remove streams selected by the maps before them.
+			builder.Map("-0:" + op.stream)
+		case streamLanguage:
+			args = append(args, "-metadata:s:"+op.stream, "language="+op.value)
+		case streamDefault:
+			// The last matching disposition wins, so the type is cleared before the stream is set.
+			kind := strings.SplitN(op.stream, ":", 2)[0]
+			if !cleared[kind] {
+				args = append(args, "-disposition:"+kind, "0")
+				cleared[kind] = true
+			}
+			args = append(args, "-disposition:"+op.stream, "default")
+		}
+	}
+	return args, nil
+}
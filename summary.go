@@ -0,0 +1,194 @@
+package vidio
+
+import (
+	"fmt"
+	"math"
+	"sort"
+
+	"github.com/benitogf/Vidio/ffcmd"
+)
+
+// A stretch of a video selected for a summary or highlight clip.
+type clip struct {
+	start float64 // Start time in seconds.
+	end   float64 // End time in seconds.
+}
+
+// Creates a summary of about "targetLen" seconds of "input", made of its most representative segments in their
+// original order. The video is split into shots at scene changes, and segments of a few seconds are scored by
+// their motion and audio energy. Segments are picked by score, preferring shots not in the summary yet, so the
+// summary covers the whole video instead of its busiest scene. Segments are cut at shot boundaries and encoded
+// with libx264 and AAC.
+func Summarize(input, output string, targetLen float64, options ...Option) error {
+	if targetLen <= 0 {
+		return fmt.Errorf("vidio: summary length must be positive")
+	}
+	if err := newConfig(options).checkSources(input); err != nil {
+		return err
+	}
+	video, err := NewVideo(input)
+	if err != nil {
+		return err
+	}
+	video.Close()
+	duration := video.Duration()
+	if duration <= 0 {
+		return fmt.Errorf("vidio: video %s has no duration", input)
+	}
+
+	// The scene score of every frame measures its change from the previous frame.
+	frames, err := detectScenes(input, -1, options...)
+	if err != nil {
+		return err
+	}
+	motion := make([]float64, int(math.Ceil(duration)))
+	counts := make([]int, len(motion))
+	cuts := []float64{}
+	for _, frame := range frames {
+		// Filter timestamps include the start time of the video.
+		frame.time -= video.StartTime()
+		second := clampInt(int(frame.time), 0, len(motion)-1)
+		motion[second] += frame.score
+		counts[second]++
+		if frame.score > sceneThreshold {
+			cuts = append(cuts, frame.time)
+		}
+	}
+	for i, count := range counts {
+		motion[i] /= float64(max(1, count))
+	}
+
+	// Videos without audio are scored by motion only.
+	loudness := []float64{}
+	audio, err := ffprobe(input, "a")
+	if err != nil {
+		return err
+	}
+	if len(audio) > 0 {
+		samples, err := readPCM(input, 8000)
+		if err != nil {
+			return err
+		}
+		// Energy of every second.
+		loudness = energy(samples, 8000)
+	}
+
+	clips := summaryClips(motion, loudness, cuts, duration, targetLen)
+	return encodeClips(input, output, clips, video.Stream(), len(audio) > 0, options...)
+}
+
+// Selects segments with a total length of about "targetLen" seconds from the motion and audio energy of every
+// second and the times of the shot boundaries. Returns the segments in their original order.
+func summaryClips(motion, loudness, cuts []float64, duration, targetLen float64) []clip {
+	if targetLen >= duration {
+		return []clip{{0, duration}}
+	}
+	length := math.Max(2, math.Min(6, targetLen/6))
+	motion, loudness = normalizeScores(motion), normalizeScores(loudness)
+	score := func(c clip) float64 {
+		sum, n := 0.0, 0
+		for second := int(c.start); second < int(math.Ceil(c.end)); second++ {
+			if second < len(motion) {
+				sum += motion[second]
+			}
+			if second < len(loudness) {
+				sum += loudness[second]
+			}
+			n++
+		}
+		return sum / float64(max(1, n))
+	}
+
+	// Candidate segments of "length" seconds within every shot.
+	type candidate struct {
+		clip
+		shot  int
+		score float64
+	}
+	sort.Float64s(cuts)
+	bounds := append(append([]float64{0}, cuts...), duration)
+	candidates := []candidate{}
+	for shot := 0; shot+1 < len(bounds); shot++ {
+		for start := bounds[shot]; bounds[shot+1]-start >= 1; start += length {
+			c := clip{start, math.Min(start+length, bounds[shot+1])}
+			candidates = append(candidates, candidate{c, shot, score(c)})
+		}
+	}
+
+	// Greedy selection, halving the score of candidates for every segment already picked from their shot.
+	picked := map[int]int{}
+	weighted := func(c candidate) float64 { return c.score * math.Pow(0.5, float64(picked[c.shot])) }
+	clips := []clip{}
+	total := 0.0
+	for total < targetLen && len(candidates) > 0 {
+		best := 0
+		for i, c := range candidates {
+			if weighted(c) > weighted(candidates[best]) {
+				best = i
+			}
+		}
+		c := candidates[best]
+		candidates = append(candidates[:best], candidates[best+1:]...)
+		c.end = math.Min(c.end, c.start+targetLen-total)
+		if c.end-c.start < 0.5 {
+			break
+		}
+		clips = append(clips, c.clip)
+		picked[c.shot]++
+		total += c.end - c.start
+	}
+	sort.Slice(clips, func(i, j int) bool { return clips[i].start < clips[j].start })
+	return clips
+}
+
+// Scales the scores to between 0 and 1.
+func normalizeScores(scores []float64) []float64 {
+	highest := 0.0
+	for _, score := range scores {
+		highest = math.Max(highest, score)
+	}
+	normalized := make([]float64, len(scores))
+	for i, score := range scores {
+		if highest > 0 {
+			normalized[i] = score / highest
+		}
+	}
+	return normalized
+}
+
+// Concatenates the clips of "input" into "output", encoded with libx264 and, if the video has audio, AAC.
+func encodeClips(input, output string, clips []clip, stream int, audio bool, options ...Option) error {
+	if len(clips) == 0 {
+		return fmt.Errorf("vidio: no clips selected from %s", input)
+	}
+	builder := ffcmd.New().Global("-y")
+	args := []string{"-c:v", "libx264", "-crf", "20", "-pix_fmt", "yuv420p"}
+	if len(clips) == 1 {
+		// A single clip needs no concatenation. Audio is mapped optionally, so videos without audio work too.
+		builder.Input(input, "-ss", fmt.Sprintf("%.3f", clips[0].start), "-t", fmt.Sprintf("%.3f", clips[0].end-clips[0].start))
+		builder.Map(fmt.Sprintf("0:v:%d", stream))
+		if audio {
+			builder.Map("0:a:0?")
+			args = append(args, "-c:a", "aac", "-b:a", "160k")
+		} else {
+			args = append(args, "-an")
+		}
+		return newConfig(options).run(builder.Output(output, args...))
+	}
+	concat := ""
+	for i, c := range clips {
+		// Seeking each input is faster than decoding the whole video and trimming it.
+		builder.Input(input, "-ss", fmt.Sprintf("%.3f", c.start), "-t", fmt.Sprintf("%.3f", c.end-c.start))
+		concat += fmt.Sprintf("[%d:v:%d]", i, stream)
+		if audio {
+			concat += fmt.Sprintf("[%d:a:0]", i)
+		}
+	}
+	if audio {
+		builder.Filter(fmt.Sprintf("%sconcat=n=%d:v=1:a=1[v][a]", concat, len(clips))).Map("[v]", "[a]")
+		args = append(args, "-c:a", "aac", "-b:a", "160k")
+	} else {
+		builder.Filter(fmt.Sprintf("%sconcat=n=%d:v=1:a=0[v]", concat, len(clips))).Map("[v]")
+	}
+	return newConfig(options).run(builder.Output(output, args...))
+}
@@ -0,0 +1,89 @@
+package vidio
+
+import (
+	"fmt"
+	"math"
+	"sort"
+)
+
+// Result of an audio/video sync measurement.
+type SyncReport struct {
+	Offset  float64   // Median audio offset in seconds. Positive values mean the audio lags behind the video.
+	Flashes []float64 // Times in seconds of the detected flashes in the video.
+	Beeps   []float64 // Times in seconds of the detected beeps in the audio.
+	Matches int       // Number of flash/beep pairs used to compute the offset.
+}
+
+// Measures the audio/video offset of a recording of a flash/beep test pattern.
+// Flashes are detected as sudden increases in frame brightness, beeps as sudden
+// increases in audio energy. Each flash is paired with the closest beep within one second.
+func MeasureSync(filename string) (*SyncReport, error) {
+	video, err := NewVideo(filename)
+	if err != nil {
+		return nil, err
+	}
+	defer video.Close()
+
+	lumas := []float64{}
+	for video.Read() {
+		lumas = append(lumas, meanLuma(video.FrameBuffer()))
+	}
+
+	rate := 8000
+	samples, err := readPCM(filename, rate)
+	if err != nil {
+		return nil, err
+	}
+
+	report := &SyncReport{
+		Flashes: detectFlashes(lumas, video.FPS(), 0.5),
+		Beeps:   detectOnsets(samples, rate, 0.5),
+	}
+
+	offsets := matchEvents(report.Flashes, report.Beeps, 1)
+	if len(offsets) == 0 {
+		return report, fmt.Errorf("vidio: no matching flash and beep events found in %s", filename)
+	}
+
+	sort.Float64s(offsets)
+	report.Matches = len(offsets)
+	report.Offset = offsets[len(offsets)/2]
+
+	return report, nil
+}
+
+// Returns the times in seconds of frames which are much brighter than the previous frame.
+// Flashes closer together than "gap" seconds are merged.
+func detectFlashes(lumas []float64, fps, gap float64) []float64 {
+	flashes := []float64{}
+	if fps <= 0 {
+		return flashes
+	}
+	last := math.Inf(-1)
+	for i := 1; i < len(lumas); i++ {
+		t := float64(i) / fps
+		if lumas[i]-lumas[i-1] > 64 && t-last >= gap {
+			flashes = append(flashes, t)
+			last = t
+		}
+	}
+	return flashes
+}
+
+// Pairs each reference event with the closest candidate event within "window" seconds
+// and returns the candidate - reference differences.
+func matchEvents(reference, candidates []float64, window float64) []float64 {
+	offsets := []float64{}
+	for _, r := range reference {
+		best := math.Inf(1)
+		for _, c := range candidates {
+			if math.Abs(c-r) < math.Abs(best) {
+				best = c - r
+			}
+		}
+		if math.Abs(best) <= window {
+			offsets = append(offsets, best)
+		}
+	}
+	return offsets
+}
@@ -0,0 +1,59 @@
+package vidio
+
+import (
+	"sort"
+	"strings"
+)
+
+// Additional output of a VideoWriter, written by the ffmpeg tee muxer from the same encode.
+type TeeOutput struct {
+	Filename string            // Output filename or URL, e.g. "rtmp://live.example.com/app/key" or "hls/index.m3u8".
+	Format   string            // Container format. Default guessed from the filename, e.g. flv for rtmp:// URLs.
+	Options  map[string]string // Muxer options for this output only, e.g. {"hls_time": "4"}.
+	Optional bool              // Keep writing the other outputs if this one fails.
+}
+
+// Escapes characters with a special meaning in the tee muxer output list.
+func teeEscape(value string) string {
+	return strings.NewReplacer(
+		`\`, `\\`,
+		`:`, `\:`,
+		`|`, `\|`,
+		`[`, `\[`,
+		`]`, `\]`,
+		`'`, `\'`,
+	).Replace(value)
+}
+
+// Returns the tee muxer output list for the given outputs, e.g. "[f=flv:onfail=ignore]rtmp\://host/app|out.mp4".
+func teeSpec(outputs []TeeOutput) string {
+	entries := make([]string, len(outputs))
+	for i, output := range outputs {
+		options := []string{}
+		format := output.Format
+		if format == "" {
+			format = outputFormat(output.Filename)
+		}
+		if format != "" {
+			options = append(options, "f="+teeEscape(format))
+		}
+		if output.Optional {
+			options = append(options, "onfail=ignore")
+		}
+		keys := make([]string, 0, len(output.Options))
+		for key := range output.Options {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			options = append(options, teeEscape(key)+"="+teeEscape(output.Options[key]))
+		}
+
+		entry := teeEscape(output.Filename)
+		if len(options) > 0 {
+			entry = "[" + strings.Join(options, ":") + "]" + entry
+		}
+		entries[i] = entry
+	}
+	return strings.Join(entries, "|")
+}
@@ -0,0 +1,102 @@
+package vidio
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/benitogf/Vidio/ffcmd"
+)
+
+// How the frames of a video were scanned.
+type ScanType int
+
+const (
+	ScanProgressive ScanType = iota // Progressive frames.
+	ScanInterlaced                  // Interlaced video, e.g. recorded by a broadcast camera.
+	ScanTelecine                    // Film converted to interlaced video with 3:2 pulldown, e.g. NTSC DVDs.
+)
+
+func (scan ScanType) String() string {
+	switch scan {
+	case ScanInterlaced:
+		return "interlaced"
+	case ScanTelecine:
+		return "telecine"
+	default:
+		return "progressive"
+	}
+}
+
+// Number of frames analyzed by DetectTelecine.
+const telecineFrames = 1000
+
+// Filters undoing 3:2 pulldown. Fields are matched back into the original film frames,
+// leftover combed frames are deinterlaced and the duplicate frame of every 5 is dropped.
+const ivtcFilter = "fieldmatch,yadif=deint=interlaced,decimate"
+
+// Per-frame results of the ffmpeg interlace detection.
+type ScanReport struct {
+	Type        ScanType // Detected scan type.
+	Frames      int      // Number of frames analyzed.
+	Progressive int      // Frames detected as progressive.
+	Interlaced  int      // Frames detected as interlaced, top or bottom field first.
+	Repeated    int      // Frames repeating a field of the previous frame, as produced by pulldown.
+}
+
+// Makes NewVideo and EncodeLadder undo 3:2 pulldown, restoring the original 23.976 frames per second
+// of telecined film. The frame rate and number of frames of videos are reduced by a fifth, and
+// ReadFrame, ReadFrames and MapFrames index the restored frames.
+// Use DetectTelecine to find out whether a source needs it.
+func WithInverseTelecine() Option {
+	return func(c *config) {
+		c.ivtc = true
+	}
+}
+
+// Analyzes the first frames of the first video stream with the ffmpeg interlace detection and reports
+// whether the video is progressive, interlaced or telecined. In 3:2 pulldown, 2 of every 5 frames repeat
+// a field, so telecine is detected from the share of repeated fields.
+func DetectTelecine(filename string, options ...Option) (*ScanReport, error) {
+	builder := ffcmd.New().
+		Input(filename).
+		Map("0:v:0").
+		Output("-",
+			"-vf", "idet,metadata=print:file=-",
+			"-frames:v", strconv.Itoa(telecineFrames),
+			"-an", "-f", "null",
+		)
+
+	output, err := newConfig(options).output(builder)
+	if err != nil {
+		return nil, err
+	}
+	return parseScanReport(string(output)), nil
+}
+
+// Parses the idet metadata printed for every frame and classifies the video.
+func parseScanReport(output string) *ScanReport {
+	report := &ScanReport{}
+	for _, line := range strings.Split(output, "\n") {
+		line = strings.TrimSpace(line)
+		if value, ok := strings.CutPrefix(line, "lavfi.idet.multiple.current_frame="); ok {
+			report.Frames++
+			switch value {
+			case "progressive":
+				report.Progressive++
+			case "tff", "bff":
+				report.Interlaced++
+			}
+		} else if value, ok := strings.CutPrefix(line, "lavfi.idet.repeated.current_frame="); ok && value != "neither" {
+			report.Repeated++
+		}
+	}
+
+	determined := report.Progressive + report.Interlaced
+	switch {
+	case report.Frames > 0 && float64(report.Repeated)/float64(report.Frames) >= 0.2:
+		report.Type = ScanTelecine
+	case determined > 0 && float64(report.Interlaced)/float64(determined) >= 0.5:
+		report.Type = ScanInterlaced
+	}
+	return report
+}
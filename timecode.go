@@ -0,0 +1,151 @@
+package vidio
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// A SMPTE timecode such as 01:00:00:00. Drop frame timecodes, written with a semicolon as 01:00:00;00,
+// skip frame numbers at the start of each minute except every tenth, so they follow the wall clock at
+// 29.97 and 59.94 frames per second.
+type Timecode struct {
+	Hours     int
+	Minutes   int
+	Seconds   int
+	Frames    int
+	Rate      int  // Nominal frames per second, e.g. 30 for 29.97.
+	DropFrame bool // Whether frame numbers are dropped.
+}
+
+// Parses a timecode for a video with the given frame rate. A semicolon or period before the frames
+// marks a drop frame timecode, which is only valid for multiples of 29.97 frames per second.
+func ParseTimecode(value string, fps float64) (Timecode, error) {
+	rate := int(math.Round(fps))
+	if rate <= 0 {
+		return Timecode{}, fmt.Errorf("vidio: invalid timecode frame rate %g", fps)
+	}
+
+	value = strings.TrimSpace(value)
+	timecode := Timecode{Rate: rate}
+	if index := strings.LastIndexAny(value, ";."); index >= 0 {
+		timecode.DropFrame = true
+		value = value[:index] + ":" + value[index+1:]
+	}
+	fields := strings.Split(value, ":")
+	if len(fields) != 4 {
+		return Timecode{}, fmt.Errorf("vidio: invalid timecode %q", value)
+	}
+	parts := []*int{&timecode.Hours, &timecode.Minutes, &timecode.Seconds, &timecode.Frames}
+	for i, field := range fields {
+		number, err := strconv.Atoi(field)
+		if err != nil {
+			return Timecode{}, fmt.Errorf("vidio: invalid timecode %q", value)
+		}
+		*parts[i] = number
+	}
+
+	if timecode.Hours < 0 || timecode.Hours > 23 || timecode.Minutes < 0 || timecode.Minutes > 59 ||
+		timecode.Seconds < 0 || timecode.Seconds > 59 || timecode.Frames < 0 || timecode.Frames >= rate {
+		return Timecode{}, fmt.Errorf("vidio: invalid timecode %q", value)
+	}
+	if timecode.DropFrame {
+		if rate%30 != 0 || fps == float64(rate) {
+			return Timecode{}, fmt.Errorf("vidio: drop frame timecodes are not valid at %g frames per second", fps)
+		}
+		if timecode.Seconds == 0 && timecode.Minutes%10 != 0 && timecode.Frames < timecode.dropped() {
+			return Timecode{}, fmt.Errorf("vidio: timecode %q is dropped", value)
+		}
+	}
+	return timecode, nil
+}
+
+// Number of frame numbers dropped at the start of a minute.
+func (timecode Timecode) dropped() int {
+	if !timecode.DropFrame {
+		return 0
+	}
+	return timecode.Rate / 15
+}
+
+// Returns the timecode as HH:MM:SS:FF, or HH:MM:SS;FF for drop frame timecodes.
+func (timecode Timecode) String() string {
+	separator := ":"
+	if timecode.DropFrame {
+		separator = ";"
+	}
+	return fmt.Sprintf("%02d:%02d:%02d%s%02d", timecode.Hours, timecode.Minutes, timecode.Seconds, separator, timecode.Frames)
+}
+
+// Returns the number of frames since 00:00:00:00.
+func (timecode Timecode) FrameNumber() int {
+	minutes := timecode.Hours*60 + timecode.Minutes
+	frames := ((minutes*60)+timecode.Seconds)*timecode.Rate + timecode.Frames
+	return frames - timecode.dropped()*(minutes-minutes/10)
+}
+
+// Returns the timecode "frames" frames later, or earlier if negative. Timecodes wrap around at 24 hours.
+func (timecode Timecode) Add(frames int) Timecode {
+	drop := timecode.dropped()
+	perMinute := timecode.Rate*60 - drop
+	perTenMinutes := perMinute*10 + drop
+	perDay := perTenMinutes * 6 * 24
+
+	frame := (timecode.FrameNumber() + frames) % perDay
+	if frame < 0 {
+		frame += perDay
+	}
+	// Adds the dropped frame numbers back to get the displayed number.
+	if drop > 0 {
+		tens, rest := frame/perTenMinutes, frame%perTenMinutes
+		frame += drop * 9 * tens
+		if rest > drop {
+			frame += drop * ((rest - drop) / perMinute)
+		}
+	}
+
+	result := Timecode{Rate: timecode.Rate, DropFrame: timecode.DropFrame}
+	result.Frames = frame % timecode.Rate
+	result.Seconds = frame / timecode.Rate % 60
+	result.Minutes = frame / (timecode.Rate * 60) % 60
+	result.Hours = frame / (timecode.Rate * 3600)
+	return result
+}
+
+// Returns the timecode of the first frame, read from the timecode tag of the video stream,
+// a timecode (tmcd) track or the container. False if the video has no timecode.
+func (video *Video) Timecode() (Timecode, bool) {
+	value, ok := video.metadata["tag:timecode"]
+	if !ok && !isURL(video.filename) {
+		// Timecode tracks are data streams, some containers such as MXF store it globally.
+		for _, probe := range [][2]string{{"d", "stream_tags"}, {"", "format_tags"}} {
+			data, err := ffprobeEntries(video.filename, probe[0], probe[1], "timecode")
+			if err != nil {
+				break
+			}
+			for _, entry := range data {
+				if value, ok = entry["tag:timecode"]; ok {
+					break
+				}
+			}
+			if ok {
+				break
+			}
+		}
+	}
+	if !ok {
+		return Timecode{}, false
+	}
+	timecode, err := ParseTimecode(value, video.fps)
+	return timecode, err == nil
+}
+
+// Returns the timecode of the n-th frame, counted from 0. False if the video has no timecode.
+func (video *Video) FrameTimecode(n int) (Timecode, bool) {
+	timecode, ok := video.Timecode()
+	if !ok {
+		return Timecode{}, false
+	}
+	return timecode.Add(n), true
+}
@@ -0,0 +1,251 @@
+package vidio
+
+import (
+	"fmt"
+	"os"
+	"sync"
+	"time"
+)
+
+// Duration in seconds of the segments spooled by TimeShift. Playback returns to live within this delay.
+const timeShiftSegment = 2
+
+// A live source of frames, such as a Camera or a Video reading a stream.
+type LiveSource interface {
+	Read() bool
+	FrameBuffer() []byte
+	Width() int
+	Height() int
+	FPS() float64
+	Close()
+}
+
+// Reads a live source with DVR-style time shifting: the source is recorded into short segments in a
+// temporary directory, so playback can be paused or moved back by up to the time shift window while the
+// source keeps being recorded. Read, Seek, Pause and Live must be called from the same goroutine.
+type TimeShift struct {
+	source      LiveSource    // Source being recorded.
+	recorder    *Recorder     // Spools the source into segments, guarded by mu.
+	mu          sync.Mutex    // Guards the recorder.
+	window      time.Duration // How far back playback can seek.
+	live        chan []byte   // Latest live frame. Closed when the source ends.
+	framebuffer []byte        // Frame returned by the last Read. Sized on the first frame.
+	playback    *Video        // Segment being replayed. nil when playing live.
+	segment     Segment       // Segment being replayed.
+	frame       int           // Index of the next frame of the replayed segment.
+	position    time.Time     // Wall clock time the frame returned by the last Read was recorded.
+	paused      bool          // Whether playback continues at "position" instead of live.
+}
+
+// Starts recording the source into a temporary segment store keeping the last "window" of footage.
+// Reading starts live.
+func NewTimeShift(source LiveSource, window time.Duration) (*TimeShift, error) {
+	if window <= 0 {
+		return nil, fmt.Errorf("vidio: time shift window must be positive")
+	}
+	dir, err := os.MkdirTemp("", "vidio-timeshift-*")
+	if err != nil {
+		return nil, err
+	}
+	recorder, err := NewRecorder(dir, source.Width(), source.Height(), &RecorderOptions{
+		Segment: timeShiftSegment,
+		MaxAge:  window,
+		Writer:  &Options{FPS: source.FPS()},
+	})
+	if err != nil {
+		os.RemoveAll(dir)
+		return nil, err
+	}
+
+	shift := &TimeShift{
+		source:   source,
+		recorder: recorder,
+		window:   window,
+		live:     make(chan []byte, 1),
+		position: time.Now(),
+	}
+	go shift.record()
+	return shift, nil
+}
+
+// Records the source until it ends, passing the latest frame to live playback.
+func (shift *TimeShift) record() {
+	defer close(shift.live)
+	for shift.source.Read() {
+		frame := shift.source.FrameBuffer()
+		shift.mu.Lock()
+		err := shift.recorder.Write(frame)
+		shift.mu.Unlock()
+		if err != nil {
+			break
+		}
+		deliver(shift.live, append([]byte{}, frame...), DropOldest)
+	}
+	shift.mu.Lock()
+	shift.recorder.Close()
+	shift.mu.Unlock()
+}
+
+func (shift *TimeShift) Width() int {
+	return shift.source.Width()
+}
+
+func (shift *TimeShift) Height() int {
+	return shift.source.Height()
+}
+
+func (shift *TimeShift) FPS() float64 {
+	return shift.source.FPS()
+}
+
+func (shift *TimeShift) FrameBuffer() []byte {
+	return shift.framebuffer
+}
+
+// How far back playback can seek.
+func (shift *TimeShift) Window() time.Duration {
+	return shift.window
+}
+
+// How far playback is behind the live source. 0 when playing live.
+func (shift *TimeShift) Delay() time.Duration {
+	if shift.playback == nil && !shift.paused {
+		return 0
+	}
+	return time.Since(shift.position)
+}
+
+// Reads the next frame into the framebuffer, from the recorded segments when time shifted, otherwise the
+// latest live frame. Playback returns to live when it reaches the segment currently being recorded.
+// Returns false once the live source has ended and playback is live.
+func (shift *TimeShift) Read() bool {
+	if shift.paused {
+		shift.paused = false
+		if err := shift.seekTo(shift.position); err != nil {
+			shift.Live()
+		}
+	}
+	if shift.playback != nil && shift.readPlayback() {
+		return true
+	}
+	frame, ok := <-shift.live
+	if !ok {
+		return false
+	}
+	shift.show(frame)
+	shift.position = time.Now()
+	return true
+}
+
+// Copies the frame into the framebuffer. Sources allocate their framebuffer on the first Read,
+// so its size is only known once a frame arrived.
+func (shift *TimeShift) show(frame []byte) {
+	if len(shift.framebuffer) != len(frame) {
+		shift.framebuffer = make([]byte, len(frame))
+	}
+	copy(shift.framebuffer, frame)
+}
+
+// Reads the next recorded frame, continuing with the next segment when a segment ends.
+// Returns false and plays live once no finished segment is left.
+func (shift *TimeShift) readPlayback() bool {
+	for {
+		if shift.playback.Read() {
+			shift.show(shift.playback.FrameBuffer())
+			shift.position = shift.segment.Start.Add(time.Duration(float64(shift.frame) / shift.playback.FPS() * float64(time.Second)))
+			shift.frame++
+			return true
+		}
+		next, _, ok := shiftSegment(shift.finished(), shift.segment.End())
+		if !ok || !next.Start.After(shift.segment.Start) || shift.open(next, 0) != nil {
+			shift.Live()
+			return false
+		}
+	}
+}
+
+// Returns the finished segments, oldest first.
+func (shift *TimeShift) finished() []Segment {
+	shift.mu.Lock()
+	defer shift.mu.Unlock()
+	segments := shift.recorder.Segments()
+	if shift.recorder.writer != nil && len(segments) > 0 {
+		segments = segments[:len(segments)-1]
+	}
+	return segments
+}
+
+// Returns the finished segment holding the footage recorded at "t" and the offset of "t" into it. If "t"
+// is older than the oldest segment, the oldest segment is returned. False if "t" is not recorded yet.
+func shiftSegment(segments []Segment, t time.Time) (Segment, time.Duration, bool) {
+	for _, segment := range segments {
+		if segment.End().After(t) {
+			return segment, max(0, t.Sub(segment.Start)), true
+		}
+	}
+	return Segment{}, 0, false
+}
+
+// Starts replaying the segment at the given frame.
+func (shift *TimeShift) open(segment Segment, frame int) error {
+	video, err := NewVideo(segment.Filename)
+	if err != nil {
+		return err
+	}
+	if frame > 0 {
+		if err := video.ResumeFrom(VideoState{Filename: video.filename, Stream: video.stream, Frame: frame}); err != nil {
+			video.Close()
+			return err
+		}
+	}
+	if shift.playback != nil {
+		shift.playback.Close()
+	}
+	shift.playback, shift.segment, shift.frame = video, segment, frame
+	return nil
+}
+
+// Continues playback from the footage recorded at "t", or live if it is not recorded yet.
+func (shift *TimeShift) seekTo(t time.Time) error {
+	segment, offset, ok := shiftSegment(shift.finished(), t)
+	if !ok {
+		shift.Live()
+		return nil
+	}
+	return shift.open(segment, int(offset.Seconds()*shift.source.FPS()))
+}
+
+// Moves playback to the footage recorded "back" before now, clamped to the time shift window.
+// Seeking back less than a segment plays live.
+func (shift *TimeShift) Seek(back time.Duration) error {
+	shift.paused = false
+	if back <= 0 {
+		shift.Live()
+		return nil
+	}
+	return shift.seekTo(time.Now().Add(-min(back, shift.window)))
+}
+
+// Pauses playback. The next Read continues with the frame after the last frame read, from the recording.
+func (shift *TimeShift) Pause() {
+	shift.paused = true
+}
+
+// Returns playback to the live source.
+func (shift *TimeShift) Live() {
+	shift.paused = false
+	if shift.playback != nil {
+		shift.playback.Close()
+		shift.playback = nil
+	}
+}
+
+// Stops reading the source, closes it and removes the recorded segments.
+func (shift *TimeShift) Close() {
+	shift.Live()
+	shift.source.Close()
+	// Waits until recording has stopped.
+	for range shift.live {
+	}
+	os.RemoveAll(shift.recorder.Dir())
+}
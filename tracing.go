@@ -0,0 +1,68 @@
+package vidio
+
+import (
+	"context"
+	"sync"
+)
+
+// Creates spans for traced operations. Implement it with an adapter for a tracing library,
+// e.g. OpenTelemetry, to get spans for probing, decoding, transcoding and recording segments.
+type Tracer interface {
+	// Starts a span with the given name as a child of the span in the context.
+	Start(ctx context.Context, name string) (context.Context, Span)
+}
+
+// A span started by a Tracer.
+type Span interface {
+	// Sets an attribute of the span. Values are strings, ints, float64s or bools.
+	SetAttribute(key string, value any)
+	// Ends the span. "err" is the error the operation failed with, or nil.
+	End(err error)
+}
+
+// Span names of the traced operations.
+const (
+	SpanProbe      = "vidio.probe"       // Probing a source with ffprobe.
+	SpanDecodeInit = "vidio.decode.init" // Starting the ffmpeg process decoding a video.
+	SpanTranscode  = "vidio.transcode"   // Running an ffmpeg operation to completion.
+	SpanSegment    = "vidio.segment"     // Writing a single Recorder segment.
+)
+
+var (
+	tracerMu sync.RWMutex
+	tracer   Tracer = noopTracer{}
+)
+
+// Sets the tracer used for all operations. Pass nil to disable tracing, which is the default.
+func SetTracer(t Tracer) {
+	tracerMu.Lock()
+	defer tracerMu.Unlock()
+	if t == nil {
+		t = noopTracer{}
+	}
+	tracer = t
+}
+
+// Starts a span with the current tracer.
+func startSpan(ctx context.Context, name string) (context.Context, Span) {
+	tracerMu.RLock()
+	t := tracer
+	tracerMu.RUnlock()
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	return t.Start(ctx, name)
+}
+
+// Tracer used when tracing is disabled.
+type noopTracer struct{}
+
+func (noopTracer) Start(ctx context.Context, name string) (context.Context, Span) {
+	return ctx, noopSpan{}
+}
+
+type noopSpan struct{}
+
+func (noopSpan) SetAttribute(key string, value any) {}
+
+func (noopSpan) End(err error) {}
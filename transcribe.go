@@ -0,0 +1,129 @@
+package vidio
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"math"
+	"strings"
+)
+
+// Mono audio passed to a Transcriber.
+type AudioChunk struct {
+	Start      float64   // Time of the first sample in the source in seconds.
+	SampleRate int       // Sample rate in Hz.
+	Samples    []float32 // Samples between -1 and 1.
+}
+
+// Timed text, such as a subtitle cue.
+type Caption struct {
+	Start float64 // Start time in seconds.
+	End   float64 // End time in seconds.
+	Text  string  // Text shown during the caption.
+}
+
+// Converts speech to text, e.g. with Whisper or a cloud speech to text service. Transcribe is called
+// for each chunk of a recording in order and returns captions with times relative to the chunk start.
+type Transcriber interface {
+	Transcribe(ctx context.Context, chunk AudioChunk) ([]Caption, error)
+}
+
+// Optional parameters for Transcribe.
+type TranscribeOptions struct {
+	SampleRate int     // Sample rate of the chunks in Hz. Default 16000.
+	MaxChunk   float64 // Maximum length of a chunk in seconds. Default 30, the window of Whisper models.
+}
+
+// Transcribes the first audio stream of "filename" with the transcriber and returns the captions with times
+// in the source. The audio is resampled to mono and split at pauses into chunks of at most MaxChunk seconds,
+// so silence is not sent and words are rarely cut. The context passed with WithContext is given to the
+// transcriber. Use WriteSRT or WriteVTT to write the captions as subtitles.
+func Transcribe(filename string, transcriber Transcriber, options *TranscribeOptions, opts ...Option) ([]Caption, error) {
+	settings := TranscribeOptions{}
+	if options != nil {
+		settings = *options
+	}
+	if settings.SampleRate <= 0 {
+		settings.SampleRate = speechRate
+	}
+	if settings.MaxChunk <= 0 {
+		settings.MaxChunk = 30
+	}
+
+	c := newConfig(opts)
+	if err := c.checkSources(filename); err != nil {
+		return nil, err
+	}
+	samples, err := readPCM(filename, settings.SampleRate)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx := c.ctx
+	captions := []Caption{}
+	for _, chunk := range transcriptionChunks(speechSegments(samples, settings.SampleRate), settings.MaxChunk) {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		start := int(chunk.Start * float64(settings.SampleRate))
+		end := min(len(samples), int(math.Ceil(chunk.End*float64(settings.SampleRate))))
+		texts, err := transcriber.Transcribe(ctx, AudioChunk{
+			Start:      chunk.Start,
+			SampleRate: settings.SampleRate,
+			Samples:    samples[start:end],
+		})
+		if err != nil {
+			return nil, fmt.Errorf("vidio: failed to transcribe %.2fs to %.2fs: %w", chunk.Start, chunk.End, err)
+		}
+		for _, text := range texts {
+			text.Start += chunk.Start
+			text.End += chunk.Start
+			captions = append(captions, text)
+		}
+	}
+	return captions, nil
+}
+
+// Groups consecutive speech segments into chunks of at most "maxLen" seconds. Segments longer than that are split.
+func transcriptionChunks(segments []SpeechSegment, maxLen float64) []SpeechSegment {
+	chunks := []SpeechSegment{}
+	for _, segment := range segments {
+		for segment.End-segment.Start > maxLen {
+			chunks = append(chunks, SpeechSegment{Start: segment.Start, End: segment.Start + maxLen})
+			segment.Start += maxLen
+		}
+		if last := len(chunks) - 1; last >= 0 && segment.End-chunks[last].Start <= maxLen {
+			chunks[last].End = segment.End
+		} else {
+			chunks = append(chunks, segment)
+		}
+	}
+	return chunks
+}
+
+// Writes the captions as SubRip (.srt) subtitles.
+func WriteSRT(w io.Writer, captions []Caption) error {
+	builder := strings.Builder{}
+	for i, caption := range captions {
+		fmt.Fprintf(&builder, "%d\n%s --> %s\n%s\n\n", i+1, captionTime(caption.Start, ","), captionTime(caption.End, ","), strings.TrimSpace(caption.Text))
+	}
+	_, err := io.WriteString(w, builder.String())
+	return err
+}
+
+// Writes the captions as WebVTT (.vtt) subtitles.
+func WriteVTT(w io.Writer, captions []Caption) error {
+	builder := strings.Builder{}
+	builder.WriteString("WEBVTT\n\n")
+	for _, caption := range captions {
+		fmt.Fprintf(&builder, "%s --> %s\n%s\n\n", captionTime(caption.Start, "."), captionTime(caption.End, "."), strings.TrimSpace(caption.Text))
+	}
+	_, err := io.WriteString(w, builder.String())
+	return err
+}
+
+// Formats seconds as HH:MM:SS followed by the separator and milliseconds.
+func captionTime(seconds float64, separator string) string {
+	millis := int64(math.Round(max(0, seconds) * 1000))
+	return fmt.Sprintf("%02d:%02d:%02d%s%03d", millis/3600000, millis/60000%60, millis/1000%60, separator, millis%1000)
+}
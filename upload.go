@@ -0,0 +1,157 @@
+package vidio
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+// Limits an untrusted upload must satisfy. Zero values mean no limit.
+type Policy struct {
+	MaxSize         int64    // Maximum file size in bytes.
+	MaxDuration     float64  // Maximum duration in seconds.
+	MaxWidth        int      // Maximum frame width of video streams.
+	MaxHeight       int      // Maximum frame height of video streams.
+	Formats         []string // Allowed container formats as named by ffprobe, e.g. "mov" or "matroska". Empty allows all.
+	VideoCodecs     []string // Allowed video codecs, e.g. "h264". Empty allows all.
+	AudioCodecs     []string // Allowed audio codecs, e.g. "aac". Empty allows all.
+	MaxVideoStreams int      // Maximum number of video streams.
+	MaxAudioStreams int      // Maximum number of audio streams.
+	MaxStreams      int      // Maximum total number of streams.
+	RequireVideo    bool     // Reject files without a video stream.
+}
+
+// Probed properties of an upload.
+type ProbeResult struct {
+	Format   string       // Container format names, e.g. "mov,mp4,m4a,3gp,3g2,mj2".
+	Duration float64      // Duration in seconds.
+	Size     int64        // File size in bytes.
+	Width    int          // Largest frame width of the video streams.
+	Height   int          // Largest frame height of the video streams.
+	Streams  []StreamInfo // All streams in the order they are stored.
+}
+
+// Reads an untrusted upload and checks it against the policy before it enters a processing pipeline.
+// The upload is read into a temporary file, stopping as soon as it exceeds MaxSize, and probed there with
+// ffprobe restricted to local file access. Returns the probe result, also if the policy is violated
+// so the violation can be logged, and an error describing the first violation.
+func ValidateUpload(r io.Reader, policy Policy) (*ProbeResult, error) {
+	if err := installed("ffprobe"); err != nil {
+		return nil, err
+	}
+
+	file, err := os.CreateTemp("", "vidio-upload-*")
+	if err != nil {
+		return nil, err
+	}
+	defer os.Remove(file.Name())
+	defer file.Close()
+
+	reader := r
+	if policy.MaxSize > 0 {
+		reader = io.LimitReader(r, policy.MaxSize+1)
+	}
+	size, err := io.Copy(file, reader)
+	if err != nil {
+		return nil, fmt.Errorf("vidio: failed to read upload: %w", err)
+	}
+	if policy.MaxSize > 0 && size > policy.MaxSize {
+		return &ProbeResult{Size: size}, fmt.Errorf("vidio: upload rejected: larger than %d bytes", policy.MaxSize)
+	}
+	if err := file.Close(); err != nil {
+		return nil, err
+	}
+
+	format, err := ffprobeEntries(file.Name(), "0", "format", "format_name,duration")
+	if err != nil {
+		return nil, fmt.Errorf("vidio: upload rejected: not a media file: %w", err)
+	}
+	streams, err := ffprobe(file.Name(), "")
+	if err != nil {
+		return nil, fmt.Errorf("vidio: upload rejected: not a media file: %w", err)
+	}
+	if len(format) == 0 {
+		format = []map[string]string{{}}
+	}
+
+	result := probeResult(format[0], streams)
+	result.Size = size
+	return result, policy.check(result)
+}
+
+// Builds the probe result from the ffprobe format and stream data.
+func probeResult(format map[string]string, streams []map[string]string) *ProbeResult {
+	result := &ProbeResult{
+		Format:   format["format_name"],
+		Duration: parse(format["duration"]),
+		Streams:  parseStreamInfo(streams),
+	}
+	for _, stream := range streams {
+		if stream["codec_type"] != "video" {
+			continue
+		}
+		result.Width = max(result.Width, int(parse(stream["width"])))
+		result.Height = max(result.Height, int(parse(stream["height"])))
+	}
+	return result
+}
+
+// Returns an error describing the first violation of the policy.
+func (policy Policy) check(result *ProbeResult) error {
+	reject := func(format string, args ...any) error {
+		return fmt.Errorf("vidio: upload rejected: "+format, args...)
+	}
+
+	if len(result.Streams) == 0 {
+		return reject("no streams found")
+	}
+	if len(policy.Formats) > 0 && !anyFold(policy.Formats, strings.Split(result.Format, ",")) {
+		return reject("container format %s is not allowed", result.Format)
+	}
+	if policy.MaxDuration > 0 && result.Duration > policy.MaxDuration {
+		return reject("duration %gs exceeds %gs", result.Duration, policy.MaxDuration)
+	}
+	if policy.MaxWidth > 0 && result.Width > policy.MaxWidth || policy.MaxHeight > 0 && result.Height > policy.MaxHeight {
+		return reject("resolution %dx%d exceeds %dx%d", result.Width, result.Height, policy.MaxWidth, policy.MaxHeight)
+	}
+	if policy.MaxStreams > 0 && len(result.Streams) > policy.MaxStreams {
+		return reject("%d streams exceed %d", len(result.Streams), policy.MaxStreams)
+	}
+
+	videos, audios := 0, 0
+	for _, stream := range result.Streams {
+		switch stream.Type {
+		case "video":
+			videos++
+			if len(policy.VideoCodecs) > 0 && !containsFold(policy.VideoCodecs, stream.Codec) {
+				return reject("video codec %s is not allowed", stream.Codec)
+			}
+		case "audio":
+			audios++
+			if len(policy.AudioCodecs) > 0 && !containsFold(policy.AudioCodecs, stream.Codec) {
+				return reject("audio codec %s is not allowed", stream.Codec)
+			}
+		}
+	}
+	if policy.RequireVideo && videos == 0 {
+		return reject("no video stream found")
+	}
+	if policy.MaxVideoStreams > 0 && videos > policy.MaxVideoStreams {
+		return reject("%d video streams exceed %d", videos, policy.MaxVideoStreams)
+	}
+	if policy.MaxAudioStreams > 0 && audios > policy.MaxAudioStreams {
+		return reject("%d audio streams exceed %d", audios, policy.MaxAudioStreams)
+	}
+	return nil
+}
+
+// Returns true if any of "candidates" is in "values", ignoring case.
+func anyFold(values, candidates []string) bool {
+	for _, candidate := range candidates {
+		if containsFold(values, candidate) {
+			return true
+		}
+	}
+	return false
+}
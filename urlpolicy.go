@@ -0,0 +1,158 @@
+package vidio
+
+import (
+	"fmt"
+	"net"
+	"net/url"
+	"slices"
+	"strings"
+)
+
+// Restricts which sources operations may open, so services passing user supplied sources to ffmpeg
+// can not simply be pointed at internal hosts or local files (SSRF). The policy is checked before ffmpeg
+// starts: it does not follow what ffmpeg does afterwards. ffmpeg resolves host names again, so a host
+// changing its DNS records in between (DNS rebinding) is not caught, and ffmpeg follows HTTP redirects,
+// which may lead to private addresses. Services exposed to untrusted users should also restrict the
+// network access of ffmpeg, e.g. with a Sandbox wrapper or an egress firewall.
+type URLPolicy struct {
+	Schemes      []string // Allowed URL schemes. Default http and https.
+	AllowHosts   []string // If not empty, only these hosts are allowed. ".example.com" also allows all subdomains.
+	DenyHosts    []string // Hosts which are never allowed, with the same matching as AllowHosts.
+	AllowPrivate bool     // Allow hosts resolving to loopback, private, link-local or unspecified addresses.
+	AllowFiles   bool     // Allow local file paths.
+}
+
+// Makes operations check their sources against the policy before probing or opening them. Violations are
+// returned as errors. Functions without options, such as Streams, can not take a policy; check their
+// sources with URLPolicy.Check first.
+func WithURLPolicy(policy URLPolicy) Option {
+	return func(c *config) {
+		c.urlpolicy = &policy
+	}
+}
+
+// Returns an error if the source is not allowed by the policy. Host names are resolved, and all of their
+// addresses must be public unless AllowPrivate is set. The addresses are only checked at this time, see
+// URLPolicy. Media which references other sources, such as HLS playlists, is further restricted by the
+// protocol whitelist passed to ffmpeg.
+func (policy URLPolicy) Check(source string) error {
+	if !isURL(source) {
+		if !policy.AllowFiles {
+			return fmt.Errorf("vidio: local file %s is not allowed", source)
+		}
+		return nil
+	}
+
+	parsed, err := url.Parse(source)
+	if err != nil {
+		return fmt.Errorf("vidio: invalid source url: %w", err)
+	}
+	schemes := policy.Schemes
+	if len(schemes) == 0 {
+		schemes = []string{"http", "https"}
+	}
+	if !containsFold(schemes, parsed.Scheme) {
+		return fmt.Errorf("vidio: url scheme %s is not allowed", parsed.Scheme)
+	}
+
+	host := strings.TrimSuffix(strings.ToLower(parsed.Hostname()), ".")
+	if host == "" {
+		return fmt.Errorf("vidio: url %s has no host", parsed.Redacted())
+	}
+	if matchHost(policy.DenyHosts, host) {
+		return fmt.Errorf("vidio: host %s is not allowed", host)
+	}
+	if len(policy.AllowHosts) > 0 && !matchHost(policy.AllowHosts, host) {
+		return fmt.Errorf("vidio: host %s is not allowed", host)
+	}
+	if policy.AllowPrivate {
+		return nil
+	}
+
+	ips := []net.IP{net.ParseIP(host)}
+	if ips[0] == nil {
+		if ips, err = net.LookupIP(host); err != nil {
+			return fmt.Errorf("vidio: failed to resolve host %s: %w", host, err)
+		}
+	}
+	for _, ip := range ips {
+		if privateIP(ip) {
+			return fmt.Errorf("vidio: host %s resolves to the private address %s", host, ip)
+		}
+	}
+	return nil
+}
+
+// Returns an error if one of the sources is not allowed by the URL policy of the operation.
+// Inputs generated by the operation itself are not checked.
+func (c *config) checkSources(sources ...string) error {
+	if c.urlpolicy == nil {
+		return nil
+	}
+	for _, source := range sources {
+		if slices.Contains(c.generated, source) {
+			continue
+		}
+		if err := c.urlpolicy.Check(source); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Returns true if one of the patterns matches the host. Patterns starting with a dot match subdomains.
+func matchHost(patterns []string, host string) bool {
+	for _, pattern := range patterns {
+		pattern = strings.ToLower(pattern)
+		if host == pattern || strings.HasPrefix(pattern, ".") && (strings.HasSuffix(host, pattern) || host == pattern[1:]) {
+			return true
+		}
+	}
+	return false
+}
+
+// Returns true if "ip" is not reachable from the public internet.
+func privateIP(ip net.IP) bool {
+	return ip.IsLoopback() || ip.IsPrivate() || ip.IsUnspecified() ||
+		ip.IsLinkLocalUnicast() || ip.IsLinkLocalMulticast() || ip.IsInterfaceLocalMulticast()
+}
+
+// Returns true if "values" contains "value", ignoring case.
+func containsFold(values []string, value string) bool {
+	for _, v := range values {
+		if strings.EqualFold(v, value) {
+			return true
+		}
+	}
+	return false
+}
+
+// Protocols ffmpeg needs for each source scheme, including the transports they are built on.
+var schemeProtocols = map[string]string{
+	"http":  "http,tcp,crypto",
+	"https": "http,https,tcp,tls,crypto",
+	"rtmp":  "rtmp,tcp",
+	"rtmps": "rtmps,rtmp,tcp,tls",
+	"rtsp":  "rtsp,rtp,srtp,tcp,udp",
+	"srt":   "srt,udp",
+	"udp":   "udp",
+	"rtp":   "rtp,udp",
+}
+
+// Returns the ffmpeg "-protocol_whitelist" for a source. Local files may only reference other local files,
+// so an uploaded playlist can not make ffmpeg fetch URLs. Network sources may only use their own protocol.
+func protocolWhitelist(source string) string {
+	if !isURL(source) {
+		return "file,crypto,data"
+	}
+	scheme := strings.ToLower(source[:strings.Index(source, "://")])
+	if protocols, ok := schemeProtocols[scheme]; ok {
+		return protocols
+	}
+	return scheme
+}
+
+// Returns the input options restricting the protocols ffmpeg may use for the source.
+func protocolArgs(source string) []string {
+	return []string{"-protocol_whitelist", protocolWhitelist(source)}
+}
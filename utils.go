@@ -26,6 +26,39 @@ func exists(filename string) bool {
 	return false
 }
 
+// Returns true if the given filename is a network URL such as rtsp:// or srt:// rather than a local file.
+func isURL(filename string) bool {
+	index := strings.Index(filename, "://")
+	if index <= 1 {
+		// A single letter scheme is a windows drive letter.
+		return false
+	}
+	for _, c := range filename[:index] {
+		if !(c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' || c == '+' || c == '-' || c == '.') {
+			return false
+		}
+	}
+	return true
+}
+
+// Returns the ffmpeg output format required for the given network output URL,
+// or an empty string if ffmpeg can infer the format from the filename.
+func outputFormat(filename string) string {
+	if !isURL(filename) {
+		return ""
+	}
+	switch strings.ToLower(filename[:strings.Index(filename, "://")]) {
+	case "srt", "udp", "rtp", "tcp":
+		return "mpegts"
+	case "rtmp", "rtmps":
+		return "flv"
+	case "rtsp":
+		return "rtsp"
+	default:
+		return ""
+	}
+}
+
 // Checks if the given program is installed.
 func installed(program string) error {
 	cmd := exec.Command(program, "-version")
@@ -39,23 +72,36 @@ func installed(program string) error {
 
 // Runs ffprobe on the given file and returns a map of the metadata.
 func ffprobe(filename, stype string) ([]map[string]string, error) {
-	// "stype" is stream stype. "v" for video, "a" for audio.
-	// Extract video information with ffprobe.
-	cmd := exec.Command(
-		"ffprobe",
+	return probeStreams(nil, filename, stype)
+}
 
-		"-show_streams",
-		"-select_streams", stype,
-		"-print_format", "compact",
-		"-loglevel", "quiet",
-		filename,
-	)
+// Runs ffprobe in the given sandbox, which may be nil, and returns a map of the metadata per stream.
+// The input options are placed before the source.
+func probeStreams(sandbox *Sandbox, filename, stype string, input ...string) ([]map[string]string, error) {
+	// "stype" is stream stype. "v" for video, "a" for audio, "" for all streams.
+	// Extract video information with ffprobe.
+	args := []string{"-show_streams"}
+	if stype != "" {
+		args = append(args, "-select_streams", stype)
+	}
+	args = append(args, "-print_format", "compact", "-loglevel", "error")
+	args = append(args, input...)
+	args = append(args, protocolArgs(filename)...)
+	args = append(args, filename)
+	cmd, err := sandbox.command(nil, "ffprobe", args...)
+	if err != nil {
+		return nil, err
+	}
 
 	pipe, err := cmd.StdoutPipe()
 	if err != nil {
 		return nil, err
 	}
 
+	// Errors are kept to tell transient from permanent failures.
+	stderr := &tailBuffer{size: 4096}
+	cmd.Stderr = stderr
+
 	if err := cmd.Start(); err != nil {
 		return nil, err
 	}
@@ -73,7 +119,7 @@ func ffprobe(filename, stype string) ([]map[string]string, error) {
 
 	// Wait for ffprobe command to complete.
 	if err := cmd.Wait(); err != nil {
-		return nil, err
+		return nil, &OpenError{Source: filename, Output: strings.TrimSpace(stderr.String()), Err: err}
 	}
 
 	// Parse ffprobe output to fill in video data.
@@ -256,3 +302,35 @@ func buildSelectExpression(n ...int) (string, error) {
 
 	return sb.String(), nil
 }
+
+// Keeps the last "size" bytes written to it. Used to capture the end of the ffmpeg error output.
+type tailBuffer struct {
+	size int
+	data []byte
+}
+
+func (tail *tailBuffer) Write(p []byte) (int, error) {
+	tail.data = append(tail.data, p...)
+	if len(tail.data) > tail.size {
+		tail.data = tail.data[len(tail.data)-tail.size:]
+	}
+	return len(p), nil
+}
+
+func (tail *tailBuffer) String() string {
+	return string(tail.data)
+}
+
+// Number of frames the pipe buffer between ffmpeg and the reader should hold.
+const pipeFrames = 4
+
+// Fills the given buffers with consecutive data from the pipe, one buffer at a time.
+// Returns the number of buffers filled completely.
+func readBuffersFull(pipe io.Reader, buffers [][]byte) (int, error) {
+	for i, buffer := range buffers {
+		if _, err := io.ReadFull(pipe, buffer); err != nil {
+			return i, err
+		}
+	}
+	return len(buffers), nil
+}
@@ -1,32 +1,51 @@
 package vidio
 
 import (
+	"context"
 	"fmt"
 	"image"
 	"io"
+	"math"
 	"os"
 	"os/exec"
 	"os/signal"
 	"strings"
+	"sync/atomic"
 	"syscall"
+	"time"
 )
 
 type Video struct {
-	filename    string            // Video Filename.
-	width       int               // Width of frames.
-	height      int               // Height of frames.
-	depth       int               // Depth of frames.
-	bitrate     int               // Bitrate for video encoding.
-	frames      int               // Total number of frames.
-	stream      int               // Stream Index.
-	duration    float64           // Duration of video in seconds.
-	fps         float64           // Frames per second.
-	codec       string            // Codec used for video encoding.
-	hasstreams  bool              // Flag storing whether file has additional data streams.
-	framebuffer []byte            // Raw frame data.
-	metadata    map[string]string // Video metadata.
-	pipe        io.ReadCloser     // Stdout pipe for ffmpeg process.
-	cmd         *exec.Cmd         // ffmpeg command.
+	filename    string             // Video Filename.
+	width       int                // Width of frames.
+	height      int                // Height of frames.
+	depth       int                // Depth of frames.
+	bitrate     int                // Bitrate for video encoding.
+	frames      int                // Total number of frames.
+	stream      int                // Stream Index.
+	duration    float64            // Duration of video in seconds.
+	fps         float64            // Frames per second.
+	codec       string             // Codec used for video encoding.
+	hasstreams  bool               // Flag storing whether file has additional data streams.
+	framebuffer []byte             // Raw frame data.
+	metadata    map[string]string  // Video metadata.
+	pipe        io.ReadCloser      // Stdout pipe for ffmpeg process.
+	cmd         *exec.Cmd          // ffmpeg command.
+	clock       frameClock         // Frame delivery statistics.
+	cache       *FrameCache        // On-disk cache for frames read with ReadFrame.
+	pixfmt      PixelFormat        // Pixel format of decoded frames. Default rgba.
+	conceal     bool               // Keep decoding past damaged data.
+	ondamage    func(DamagedFrame) // Receives decoder errors when concealing.
+	delivered   atomic.Int64       // Number of frames read from the pipe.
+	live        *liveFile          // State of a file which is still being written. nil if not live.
+	tracectx    context.Context    // Parent of the decoding spans. Cancelling it stops decoding.
+	rotation    int                // Clockwise rotation in degrees from the stream metadata.
+	sar         float64            // Sample aspect ratio. 1 for square pixels.
+	sandbox     *Sandbox           // Reduced privileges for the decoding processes. nil runs them normally.
+	limits      *limits            // Decoding limits. nil means no limits.
+	start       int                // Index of the first frame decoded, set by ResumeFrom.
+	filter      string             // Video filter applied while decoding, e.g. to normalize playlist files.
+	concatlist  string             // Temporary concat demuxer list created by NewConcatVideo.
 
 	closeCleanupChan chan struct{} // exit from cleanup goroutine to avoid chan and goroutine leak
 	cleanupClosed    bool
@@ -49,13 +68,29 @@ func (video *Video) Depth() int {
 	return video.depth
 }
 
+// Pixel format of decoded frames.
+func (video *Video) PixelFormat() PixelFormat {
+	return video.pixfmt
+}
+
+// Number of bytes per row of a frame. Rows are tightly packed, so this is Width()*Depth().
+// Pass as the pitch when copying frames into SDL textures or Win32/X11 surfaces.
+func (video *Video) Stride() int {
+	return video.width * video.depth
+}
+
 // Bitrate of video in bits/s.
 func (video *Video) Bitrate() int {
 	return video.bitrate
 }
 
-// Total number of frames in video.
+// Total number of frames in video. Grows while a live file is being written.
 func (video *Video) Frames() int {
+	if video.live != nil {
+		video.live.mu.Lock()
+		defer video.live.mu.Unlock()
+		return video.live.frames
+	}
 	return video.frames
 }
 
@@ -64,11 +99,26 @@ func (video *Video) Stream() int {
 	return video.stream
 }
 
-// Video duration in seconds.
+// Video duration in seconds. Grows while a live file is being written.
 func (video *Video) Duration() float64 {
+	if video.live != nil {
+		video.live.mu.Lock()
+		defer video.live.mu.Unlock()
+		return video.live.duration
+	}
 	return video.duration
 }
 
+// Presentation time in seconds of the first frame. Usually 0, but MPEG-TS files, stream recordings and cut
+// files often start later. Timestamps reported by ffmpeg, such as the PTS in ExportFrameIndex, include it.
+func (video *Video) StartTime() float64 {
+	start := probeTime(video.metadata["start_time"])
+	if math.IsNaN(start) {
+		return 0
+	}
+	return start
+}
+
 // Frames per second of video.
 func (video *Video) FPS() float64 {
 	return video.fps
@@ -92,6 +142,12 @@ func (video *Video) MetaData() map[string]string {
 	return video.metadata
 }
 
+// Returns a snapshot of the frame delivery statistics of the current Read() session.
+// Mostly useful for live stream sources.
+func (video *Video) Stats() Stats {
+	return video.clock.stats()
+}
+
 func (video *Video) SetFrameBuffer(buffer []byte) error {
 	size := video.width * video.height * video.depth
 	if len(buffer) < size {
@@ -101,8 +157,14 @@ func (video *Video) SetFrameBuffer(buffer []byte) error {
 	return nil
 }
 
-func NewVideo(filename string) (*Video, error) {
-	streams, err := NewVideoStreams(filename)
+// Makes ReadFrame look up frames in the given on-disk cache before decoding them,
+// and store decoded frames in it. Pass nil to disable caching.
+func (video *Video) SetFrameCache(cache *FrameCache) {
+	video.cache = cache
+}
+
+func NewVideo(filename string, options ...Option) (*Video, error) {
+	streams, err := NewVideoStreams(filename, options...)
 	if streams == nil {
 		return nil, err
 	}
@@ -111,8 +173,13 @@ func NewVideo(filename string) (*Video, error) {
 }
 
 // Read all video streams from the given file.
-func NewVideoStreams(filename string) ([]*Video, error) {
-	if !exists(filename) {
+func NewVideoStreams(filename string, options ...Option) ([]*Video, error) {
+	c := newConfig(options)
+	// Checked first, so disallowed sources are not even tested for existence.
+	if err := c.checkSources(filename); err != nil {
+		return nil, err
+	}
+	if !isURL(filename) && !exists(filename) {
 		return nil, fmt.Errorf("vidio: video file %s does not exist", filename)
 	}
 	// Check if ffmpeg and ffprobe are installed on the users machine.
@@ -123,20 +190,44 @@ func NewVideoStreams(filename string) ([]*Video, error) {
 		return nil, err
 	}
 
-	videoData, err := ffprobe(filename, "v")
+	pixfmt, err := c.pixelFormat()
+	if err != nil {
+		return nil, err
+	}
+	filter, err := c.videoFilter()
 	if err != nil {
 		return nil, err
 	}
 
-	if len(videoData) == 0 {
-		return nil, fmt.Errorf("vidio: no video data found in %s", filename)
+	_, span := startSpan(c.ctx, SpanProbe)
+	span.SetAttribute("vidio.file", filename)
+
+	var input []string
+	if c.concatlist != "" && filename == c.concatlist {
+		input = concatArgs
+	}
+
+	var videoData []map[string]string
+	err = c.retry.do(c, func() error {
+		var err error
+		videoData, err = probeStreams(c.sandbox, filename, "v", input...)
+		return err
+	})
+	if err == nil && len(videoData) == 0 {
+		err = fmt.Errorf("vidio: no video data found in %s", filename)
+	}
+	if err != nil {
+		emit(Event{Type: EventError, Source: filename, Err: err})
+		span.End(err)
+		return nil, err
 	}
 
 	// Loop over all stream types. a: Audio, s: Subtitle, d: Data, t: Attachments
 	hasstream := false
-	for _, c := range "asdt" {
-		data, err := ffprobe(filename, string(c))
+	for _, stype := range "asdt" {
+		data, err := probeStreams(c.sandbox, filename, string(stype), input...)
 		if err != nil {
+			span.End(err)
 			return nil, err
 		}
 		if len(data) > 0 {
@@ -149,22 +240,65 @@ func NewVideoStreams(filename string) ([]*Video, error) {
 	for i, data := range videoData {
 		video := &Video{
 			filename:   filename,
-			depth:      4,
+			depth:      pixfmt.Depth(),
+			pixfmt:     pixfmt,
 			stream:     i,
 			hasstreams: hasstream,
 			metadata:   data,
+			conceal:    c.conceal,
+			ondamage:   c.ondamage,
+			tracectx:   c.ctx,
+			sandbox:    c.sandbox,
+			limits:     c.limits,
+			filter:     filter,
 
 			closeCleanupChan: make(chan struct{}, 1),
 		}
 
 		video.addVideoData(data)
+		if err := c.decodeSize(video); err != nil {
+			span.End(err)
+			return nil, err
+		}
+		if c.live > 0 && !isURL(filename) {
+			video.live = newLiveFile(filename, c.live, video.duration, video.frames)
+		}
 
 		streams[i] = video
 	}
 
+	emit(Event{Type: EventProbe, Source: filename})
+	span.SetAttribute("vidio.codec", streams[0].codec)
+	span.SetAttribute("vidio.duration", streams[0].duration)
+	span.SetAttribute("vidio.streams", len(streams))
+	span.End(nil)
+
 	return streams, nil
 }
 
+// Checks the probed video against the limits and adjusts its frame size, rate and count
+// to the frames produced by the decoding filters.
+func (c *config) decodeSize(video *Video) error {
+	// The size limit applies to the decoded source, before views or eyes are cropped from it.
+	if err := c.limits.apply(video); err != nil {
+		return err
+	}
+	if c.ivtc {
+		// Every 5th frame is dropped.
+		video.fps *= 0.8
+		video.frames = video.frames * 4 / 5
+	}
+	if c.eye != "" {
+		if err := video.extractEye(c.eye); err != nil {
+			return err
+		}
+	}
+	if c.view != nil {
+		video.width, video.height = c.view.size()
+	}
+	return nil
+}
+
 // Adds Video data to the video struct from the ffprobe output.
 func (video *Video) addVideoData(data map[string]string) {
 	if width, ok := data["width"]; ok {
@@ -173,9 +307,11 @@ func (video *Video) addVideoData(data map[string]string) {
 	if height, ok := data["height"]; ok {
 		video.height = int(parse(height))
 	}
-	if rotation, ok := data["tag:rotate"]; ok && (rotation == "90" || rotation == "270") {
+	video.rotation = rotation(data)
+	if video.rotation == 90 || video.rotation == 270 {
 		video.width, video.height = video.height, video.width
 	}
+	video.sar = parseRatio(data["sample_aspect_ratio"])
 	if duration, ok := data["duration"]; ok {
 		video.duration = float64(parse(duration))
 	}
@@ -198,31 +334,75 @@ func (video *Video) addVideoData(data map[string]string) {
 
 // Once the user calls Read() for the first time on a Video struct,
 // the ffmpeg command which is used to read the video is started.
-func (video *Video) init() error {
+func (video *Video) init() (err error) {
 	// If user exits with Ctrl+C, stop ffmpeg process.
 	video.cleanup()
+	defer func() {
+		// Stop watching a live file if ffmpeg could not be started.
+		if err != nil && video.live != nil {
+			video.live.close()
+		}
+	}()
 	// ffmpeg command to pipe video data to stdout in 8-bit RGBA format.
-	cmd := exec.Command(
-		"ffmpeg",
-		"-i", video.filename,
+	args := []string{}
+	loglevel := logLevel()
+	if video.conceal {
+		args = append(args, concealInputArgs...)
+		// Decoder errors are reported as damaged frames.
+		loglevel = "error"
+	}
+	args = append(args, seekArgs(video.start, video.fps)...)
+	video.delivered.Store(int64(video.start))
+	if video.live != nil {
+		args = append(args, video.live.inputArgs(video.filename)...)
+		video.live.start(video)
+	} else {
+		args = append(args, video.inputArgs()...)
+		args = append(args, "-i", video.filename)
+	}
+	args = append(
+		args,
 		"-f", "image2pipe",
 		"-tune", "zerolatency",
 		"-preset", "ultrafast",
-		"-loglevel", "quiet",
-		"-pix_fmt", "rgba",
+		"-loglevel", loglevel,
+		"-pix_fmt", string(video.pixfmt),
 		"-vcodec", "rawvideo",
 		"-map", fmt.Sprintf("0:v:%d", video.stream),
-		"-",
 	)
+	if video.filter != "" {
+		args = append(args, "-vf", video.filter)
+	}
+	args = append(args, video.limits.outputArgs()...)
+	if video.conceal {
+		args = append(args, concealOutputArgs...)
+	}
+	cmd, err := video.sandbox.command(video.tracectx, "ffmpeg", append(args, "-")...)
+	if err != nil {
+		return fmt.Errorf("vidio: failed to create the ffmpeg command: %w", err)
+	}
+	if video.conceal {
+		cmd.Stderr = &damageReporter{video: video, report: video.ondamage}
+	}
+	logOutput(cmd, video.filename)
+
+	_, span := startSpan(video.tracectx, SpanDecodeInit)
+	span.SetAttribute("vidio.file", video.filename)
+	span.SetAttribute("vidio.codec", video.codec)
+	span.SetAttribute("vidio.pixel_format", string(video.pixfmt))
+	span.SetAttribute("vidio.stream", video.stream)
 
 	video.cmd = cmd
 	pipe, err := cmd.StdoutPipe()
 	if err != nil {
+		span.End(err)
 		return err
 	}
 	video.pipe = pipe
 
-	if err := cmd.Start(); err != nil {
+	err = cmd.Start()
+	span.End(err)
+	if err != nil {
 		return err
 	}
 
@@ -230,6 +410,10 @@ func (video *Video) init() error {
 		video.framebuffer = make([]byte, video.width*video.height*video.depth)
 	}
 
+	video.clock = frameClock{fps: video.fps}
+	// A larger pipe buffer lets ffmpeg run ahead by a few frames instead of blocking on every write.
+	setPipeSize(pipe, pipeFrames*video.width*video.height*video.depth)
+
 	return nil
 }
 
@@ -243,17 +427,60 @@ func (video *Video) Read() bool {
 		}
 	}
 
+	start := time.Now()
 	if _, err := io.ReadFull(video.pipe, video.framebuffer); err != nil {
-		video.Close()
+		video.stop()
 		return false
 	}
+	video.clock.tick(video.pipe, video.width*video.height*video.depth, time.Since(start))
+	video.delivered.Add(1)
 	return true
 }
 
+// Reads the next len(buffers) frames into the given buffers, using a single vectored read
+// per batch where supported (Linux). Each buffer must hold at least one frame.
+// Returns the number of frames read. If fewer frames than buffers were read, the video has ended
+// and is closed automatically.
+func (video *Video) ReadInto(buffers ...[]byte) int {
+	size := video.width * video.height * video.depth
+	frames := make([][]byte, len(buffers))
+	for i, buffer := range buffers {
+		if len(buffer) < size {
+			return 0
+		}
+		frames[i] = buffer[:size]
+	}
+	if len(frames) == 0 {
+		return 0
+	}
+
+	if video.cmd == nil {
+		if err := video.init(); err != nil {
+			return 0
+		}
+	}
+
+	start := time.Now()
+	n, err := readBuffers(video.pipe, frames)
+	wait := time.Since(start) / time.Duration(max(1, n))
+	for i := 0; i < n; i++ {
+		video.clock.tick(video.pipe, size, wait)
+	}
+	video.delivered.Add(int64(n))
+	if n > 0 {
+		copy(video.framebuffer, frames[n-1])
+	}
+	if err != nil {
+		video.stop()
+	}
+
+	return n
+}
+
 // Reads the N-th frame from the video and stores it in the framebuffer. If the index is out of range or
 // the operation failes, the function will return an error. The frames are indexed from 0.
 func (video *Video) ReadFrame(n int) error {
-	if n >= video.frames {
+	if n >= video.Frames() {
 		return fmt.Errorf("vidio: provided frame index %d is not in frame count range", n)
 	}
 
@@ -261,24 +488,40 @@ func (video *Video) ReadFrame(n int) error {
 		video.framebuffer = make([]byte, video.width*video.height*video.depth)
 	}
 
-	selectExpression, err := buildSelectExpression(n)
+	frame := video.framebuffer[:video.width*video.height*video.depth]
+	key := ""
+	if video.cache != nil {
+		key = video.cache.key(video, n)
+		if video.cache.get(key, frame) {
+			return nil
+		}
+	}
+
+	selectExpression, err := video.selectFilter(n)
 	if err != nil {
 		return fmt.Errorf("vidio: failed to parse the specified frame index: %w", err)
 	}
 
-	cmd := exec.Command(
+	args := append(video.inputArgs(), "-i", video.filename)
+	cmd, err := video.sandbox.command(
+		nil,
 		"ffmpeg",
-		"-i", video.filename,
-		"-f", "image2pipe",
-		"-loglevel", "quiet",
-		"-pix_fmt", "rgba",
-		"-vcodec", "rawvideo",
-		"-map", fmt.Sprintf("0:v:%d", video.stream),
-		"-vf", selectExpression,
-		"-vsync", "0",
-		"-",
+		append(args,
+			"-f", "image2pipe",
+			"-loglevel", logLevel(),
+			"-pix_fmt", string(video.pixfmt),
+			"-vcodec", "rawvideo",
+			"-map", fmt.Sprintf("0:v:%d", video.stream),
+			"-vf", selectExpression,
+			"-vsync", "0",
+			"-",
+		)...,
 	)
+	if err != nil {
+		return fmt.Errorf("vidio: failed to create the ffmpeg command: %w", err)
+	}
 
+	logOutput(cmd, video.filename)
 	stdoutPipe, err := cmd.StdoutPipe()
 	if err != nil {
 		return fmt.Errorf("vidio: failed to access the ffmpeg stdout pipe: %w", err)
@@ -309,44 +552,78 @@ func (video *Video) ReadFrame(n int) error {
 		return fmt.Errorf("vidio: failed to close the ffmpeg stdout pipe: %w", err)
 	}
 
-	if err := cmd.Wait(); err != nil {
+	if err := waitOutput(cmd); err != nil {
 		return fmt.Errorf("vidio: failed to free resources after the ffmpeg cmd: %w", err)
 	}
 
+	if video.cache != nil {
+		if err := video.cache.put(key, frame); err != nil {
+			return fmt.Errorf("vidio: failed to cache frame %d: %w", n, err)
+		}
+	}
+
 	return nil
 }
 
+// Returns the filter selecting the frames with the given indexes. The decoding filter runs first, so
+// the frames are processed, cropped and indexed the same way as when reading them with Read.
+func (video *Video) selectFilter(n ...int) (string, error) {
+	selectExpression, err := buildSelectExpression(n...)
+	if err != nil || video.filter == "" {
+		return selectExpression, err
+	}
+	return video.filter + "," + selectExpression, nil
+}
+
 // Read the N-amount of frames with the given indexes and return them as a slice of RGBA image pointers. If one of
 // the indexes is out of range, the function will return an error. The frames are indexes from 0.
+// Frames are always decoded as RGBA, regardless of the video pixel format.
 func (video *Video) ReadFrames(n ...int) ([]*image.RGBA, error) {
+	return video.readFrames(n, nil, "", video.width, video.height)
+}
+
+// Reads the frames with the given indexes as RGBA images of "width" x "height". The input options are placed
+// before the input and "filter" is applied after selecting the frames, e.g. to orient thumbnails.
+func (video *Video) readFrames(n []int, input []string, filter string, width, height int) ([]*image.RGBA, error) {
 	if len(n) == 0 {
 		return nil, fmt.Errorf("vidio: no frames indexes specified")
 	}
 
 	for _, nValue := range n {
-		if nValue >= video.frames {
+		if nValue >= video.Frames() {
 			return nil, fmt.Errorf("vidio: provided frame index %d is not in frame count range", nValue)
 		}
 	}
 
-	selectExpression, err := buildSelectExpression(n...)
+	selectExpression, err := video.selectFilter(n...)
 	if err != nil {
 		return nil, fmt.Errorf("vidio: failed to parse the specified frame index: %w", err)
 	}
 
-	cmd := exec.Command(
+	if filter != "" {
+		selectExpression += "," + filter
+	}
+
+	args := append(append(video.inputArgs(), input...), "-i", video.filename)
+	cmd, err := video.sandbox.command(
+		nil,
 		"ffmpeg",
-		"-i", video.filename,
-		"-f", "image2pipe",
-		"-loglevel", "quiet",
-		"-pix_fmt", "rgba",
-		"-vcodec", "rawvideo",
-		"-map", fmt.Sprintf("0:v:%d", video.stream),
-		"-vf", selectExpression,
-		"-vsync", "0",
-		"-",
+		append(args,
+			"-f", "image2pipe",
+			"-loglevel", logLevel(),
+			"-pix_fmt", "rgba",
+			"-vcodec", "rawvideo",
+			"-map", fmt.Sprintf("0:v:%d", video.stream),
+			"-vf", selectExpression,
+			"-vsync", "0",
+			"-",
+		)...,
 	)
+	if err != nil {
+		return nil, fmt.Errorf("vidio: failed to create the ffmpeg command: %w", err)
+	}
 
+	logOutput(cmd, video.filename)
 	stdoutPipe, err := cmd.StdoutPipe()
 	if err != nil {
 		return nil, fmt.Errorf("vidio: failed to access the ffmpeg stdout pipe: %w", err)
@@ -371,7 +648,7 @@ func (video *Video) ReadFrames(n ...int) ([]*image.RGBA, error) {
 
 	frames := make([]*image.RGBA, len(n))
 	for frameIndex := range frames {
-		frames[frameIndex] = image.NewRGBA(image.Rect(0, 0, video.width, video.height))
+		frames[frameIndex] = image.NewRGBA(image.Rect(0, 0, width, height))
 
 		if _, err := io.ReadFull(stdoutPipe, frames[frameIndex].Pix); err != nil {
 			return nil, fmt.Errorf("vidio: failed to read the ffmpeg cmd result to the image buffer: %w", err)
@@ -382,25 +659,38 @@ func (video *Video) ReadFrames(n ...int) ([]*image.RGBA, error) {
 		return nil, fmt.Errorf("vidio: failed to close the ffmpeg stdout pipe: %w", err)
 	}
 
-	if err := cmd.Wait(); err != nil {
+	if err := waitOutput(cmd); err != nil {
 		return nil, fmt.Errorf("vidio: failed to free resources after the ffmpeg cmd: %w", err)
 	}
 
 	return frames, nil
 }
 
-// Closes the pipe and stops the ffmpeg process.
+// Closes the pipe and stops the ffmpeg process. The concat list of a video opened with
+// NewConcatVideo is removed, so the video can not be read again.
 func (video *Video) Close() {
+	video.stop()
+	if video.concatlist != "" {
+		os.Remove(video.concatlist)
+	}
+}
+
+// Closes the pipe and stops the ffmpeg process once the video has been read to the end.
+// Unlike Close, generated files are kept so the video can still be reset.
+func (video *Video) stop() {
 	if !video.cleanupClosed {
 		video.cleanupClosed = true
 		video.closeCleanupChan <- struct{}{}
 		close(video.closeCleanupChan)
 	}
+	if video.live != nil {
+		video.live.close()
+	}
 	if video.pipe != nil {
 		video.pipe.Close()
 	}
 	if video.cmd != nil {
-		video.cmd.Wait()
+		waitOutput(video.cmd)
 	}
 }
 
@@ -410,11 +700,14 @@ func (video *Video) Reset() {
 		video.closeCleanupChan <- struct{}{}
 		close(video.closeCleanupChan)
 	}
+	if video.live != nil {
+		video.live.close()
+	}
 	if video.pipe != nil {
 		video.pipe.Close()
 	}
 	if video.cmd != nil {
-		video.cmd.Wait()
+		waitOutput(video.cmd)
 	}
 	video.cmd = nil
 }
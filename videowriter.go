@@ -1,30 +1,61 @@
 package vidio
 
 import (
+	"errors"
 	"fmt"
 	"io"
 	"math"
 	"os"
 	"os/exec"
 	"os/signal"
+	"path/filepath"
+	"runtime"
 	"strings"
 	"syscall"
+	"time"
 )
 
 type VideoWriter struct {
-	filename   string         // Output filename.
-	streamfile string         // Extra stream data filename.
-	width      int            // Frame width.
-	height     int            // Frame height.
-	bitrate    int            // Output video bitrate.
-	loop       int            // Number of times for GIF to loop.
-	delay      int            // Delay of final frame of GIF. Default -1 (same delay as previous frame).
-	macro      int            // Macroblock size for determining how to resize frames for codecs.
-	fps        float64        // Frames per second for output video. Default 25.
-	quality    float64        // Used if bitrate not given. Default 0.5.
-	codec      string         // Codec to encode video with. Default libx264.
-	pipe       io.WriteCloser // Stdout pipe of ffmpeg process.
-	cmd        *exec.Cmd      // ffmpeg command.
+	filename   string           // Output filename.
+	streamfile string           // Extra stream data filename.
+	width      int              // Frame width.
+	height     int              // Frame height.
+	bitrate    int              // Output video bitrate.
+	loop       int              // Number of times for GIF to loop.
+	delay      int              // Delay of final frame of GIF. Default -1 (same delay as previous frame).
+	macro      int              // Macroblock size for determining how to resize frames for codecs.
+	fps        float64          // Frames per second for output video. Default 25.
+	quality    float64          // Used if bitrate not given. Default 0.5.
+	codec      string           // Codec to encode video with. Default libx264.
+	pipe       io.WriteCloser   // Stdout pipe of ffmpeg process.
+	cmd        *exec.Cmd        // ffmpeg command.
+	output     []string         // Extra output options placed before the filename.
+	stdout     io.WriteCloser   // Receives the ffmpeg stdout when writing to "pipe:1". Closed after ffmpeg exits.
+	maxlength  float64          // Maximum duration of the output in seconds. 0 means no limit.
+	maxsize    int64            // Maximum size of the output file in bytes. 0 means no limit.
+	frames     int              // Number of frames written.
+	status     CompletionStatus // Whether the writer is open, or why it stopped.
+	fragmented bool             // Write fragmented mp4 so the file stays playable if writing fails.
+	stderr     *tailBuffer      // End of the ffmpeg error output.
+	err        *WriteError      // Error that stopped the writer, if any.
+	finisherr  error            // Error finishing the output when the writer stopped, e.g. a failed rename.
+	atomic     bool             // Write to a temporary file and rename it to the filename on success.
+	tempfile   string           // Temporary file written to when writing atomically.
+	progress   chan<- Progress  // Receives encoding progress updates.
+	bitexact   bool             // Produce byte-reproducible output.
+	timestamps bool             // Frames are written with explicit timestamps using WriteAt.
+	pts        time.Duration    // Timestamp of the last written frame.
+	framesize  int              // Size in bytes of the input frames, before any resizing for the codec.
+	vfr        bool             // Keep the frame timestamps instead of resampling to a constant frame rate.
+	audio      *AudioWriter     // Audio muxed into the output, if any.
+	audiopipe  *os.File         // Read end of the audio pipe, passed to ffmpeg as pipe:3.
+	tee        []TeeOutput      // Additional outputs written from the same encode.
+	twopass    bool             // Encode in two passes.
+	passdir    string           // Temporary directory holding the pass log and the intermediate file.
+	pass2      []string         // ffmpeg arguments of the second pass, run when the writer is closed.
+	preset     Preset           // Encoding preset, if any.
+	presetargs []string         // Codec options of the preset, used instead of the quality settings.
+	timecode   string           // Timecode of the first frame, written as a timecode track.
 }
 
 // Optional parameters for VideoWriter.
@@ -37,6 +68,29 @@ type Options struct {
 	Quality    float64 // If bitrate not given, use quality instead. Must be between 0 and 1. 0:best, 1:worst.
 	Codec      string  // Codec for video.
 	StreamFile string  // File path for extra stream data.
+
+	MaxDuration float64 // Maximum duration of the output in seconds. The writer is finalized when reached.
+	MaxFileSize int64   // Approximate maximum size of the output file in bytes. The writer is finalized when reached.
+	Fragmented  bool    // For mp4/mov only. Write a fragmented file which stays playable if writing fails midway.
+	AtomicWrite bool    // Write to a temporary file which is renamed to the filename once the video is finished.
+	// Encode single-threaded with -bitexact and without version metadata, so the same frames always produce the same bytes.
+	Deterministic bool
+	// Keep the timestamps passed to WriteAt in the output instead of resampling to a constant FPS.
+	VariableFrameRate bool
+	// Mux PCM audio written to Audio() into the output. Not supported on windows or together with StreamFile.
+	Audio *AudioOptions
+	// Write the same encode to these outputs as well, using the ffmpeg tee muxer.
+	Tee []TeeOutput
+	// Encode in two passes for better quality at the given Bitrate. Frames are analyzed while writing and
+	// the output is encoded from a lossless intermediate file when the writer is closed.
+	TwoPass bool
+	// Encoding profile choosing the codec and its settings. Can not be combined with Codec or Bitrate.
+	Preset Preset
+	// Timecode of the first frame, e.g. "01:00:00:00" or "00:59:58;00" for drop frame. Written as a
+	// timecode (tmcd) track in mp4 and mov files, and as the timecode of the stream in other containers.
+	Timecode string
+
+	Progress chan<- Progress // Receives encoding progress updates. Updates are dropped if the channel is full.
 }
 
 func (writer *VideoWriter) FileName() string {
@@ -90,6 +144,85 @@ func (writer *VideoWriter) Codec() string {
 	return writer.codec
 }
 
+// Maximum duration of the output in seconds. 0 means no limit.
+func (writer *VideoWriter) MaxDuration() float64 {
+	return writer.maxlength
+}
+
+// Maximum size of the output file in bytes. 0 means no limit.
+func (writer *VideoWriter) MaxFileSize() int64 {
+	return writer.maxsize
+}
+
+// Number of frames written so far.
+func (writer *VideoWriter) FramesWritten() int {
+	return writer.frames
+}
+
+// Returns whether the writer is still writing, or why it was finalized.
+func (writer *VideoWriter) Status() CompletionStatus {
+	return writer.status
+}
+
+// Returns true if the output is written as fragmented mp4.
+func (writer *VideoWriter) Fragmented() bool {
+	return writer.fragmented
+}
+
+// Returns true if the output is written to a temporary file first and renamed on success.
+func (writer *VideoWriter) AtomicWrite() bool {
+	return writer.atomic
+}
+
+// Returns true if the output is encoded to be byte-reproducible.
+func (writer *VideoWriter) Deterministic() bool {
+	return writer.bitexact
+}
+
+// Returns true if the output keeps the frame timestamps instead of having a constant frame rate.
+func (writer *VideoWriter) VariableFrameRate() bool {
+	return writer.vfr
+}
+
+// Returns the writer for the audio muxed into the output, or nil if Options.Audio was not set.
+// Audio and video are read by ffmpeg concurrently, so write them from separate goroutines
+// or interleave them in small chunks.
+func (writer *VideoWriter) Audio() *AudioWriter {
+	return writer.audio
+}
+
+// Additional outputs written from the same encode.
+func (writer *VideoWriter) Tee() []TeeOutput {
+	return writer.tee
+}
+
+// Returns true if the output is encoded in two passes.
+func (writer *VideoWriter) TwoPass() bool {
+	return writer.twopass
+}
+
+// Encoding preset of the video, or "" if none is used.
+func (writer *VideoWriter) Preset() Preset {
+	return writer.preset
+}
+
+// Returns the file ffmpeg is writing to.
+func (writer *VideoWriter) outfile() string {
+	if writer.tempfile != "" {
+		return writer.tempfile
+	}
+	return writer.filename
+}
+
+// Returns the error that stopped the writer, or the error finishing the output once it was closed,
+// e.g. when ffmpeg failed or the temporary file of an atomic write could not be renamed. Returns nil otherwise.
+func (writer *VideoWriter) Err() error {
+	if writer.err != nil {
+		return writer.err
+	}
+	return writer.finisherr
+}
+
 // Creates a new VideoWriter struct with default values from the Options struct.
 func NewVideoWriter(filename string, width, height int, options *Options) (*VideoWriter, error) {
 	// Check if ffmpeg is installed on the users machine.
@@ -108,6 +241,31 @@ func NewVideoWriter(filename string, width, height int, options *Options) (*Vide
 		bitrate:  options.Bitrate,
 	}
 
+	if options.MaxDuration < 0 || options.MaxFileSize < 0 {
+		return nil, fmt.Errorf("vidio: writer limits must not be negative")
+	}
+	writer.maxlength = options.MaxDuration
+	writer.maxsize = options.MaxFileSize
+	writer.fragmented = options.Fragmented
+	writer.atomic = options.AtomicWrite && !isURL(filename) && filename != "pipe:1"
+	writer.progress = options.Progress
+	writer.bitexact = options.Deterministic
+	writer.vfr = options.VariableFrameRate
+	writer.tee = options.Tee
+	writer.twopass = options.TwoPass
+
+	if options.Timecode != "" {
+		fps := options.FPS
+		if fps == 0 {
+			fps = 25
+		}
+		timecode, err := ParseTimecode(options.Timecode, fps)
+		if err != nil {
+			return nil, err
+		}
+		writer.timecode = timecode.String()
+	}
+
 	// Default Parameter options logic from:
 	// https://github.com/imageio/imageio-ffmpeg/blob/master/imageio_ffmpeg/_io.py#L268.
 
@@ -156,6 +314,43 @@ func NewVideoWriter(filename string, width, height int, options *Options) (*Vide
 		writer.streamfile = options.StreamFile
 	}
 
+	if options.Preset != "" {
+		if options.Codec != "" || options.Bitrate != 0 {
+			return nil, fmt.Errorf("vidio: preset %s can not be combined with a codec or bitrate", options.Preset)
+		}
+		if writer.codec == "gif" {
+			return nil, fmt.Errorf("vidio: presets are not supported for gif output")
+		}
+		codec, args, err := options.Preset.Resolve(filename)
+		if err != nil {
+			return nil, err
+		}
+		writer.preset = options.Preset
+		writer.codec = codec
+		writer.presetargs = args
+	}
+
+	if writer.twopass {
+		if writer.bitrate == 0 {
+			return nil, fmt.Errorf("vidio: two pass encoding requires a bitrate")
+		}
+		if options.Audio != nil || writer.codec == "gif" || filename == "pipe:1" {
+			return nil, fmt.Errorf("vidio: two pass encoding is not supported for %s", filename)
+		}
+	}
+
+	if options.Audio != nil {
+		if writer.streamfile != "" {
+			return nil, fmt.Errorf("vidio: audio can not be muxed together with a stream file")
+		}
+		audio, pipe, err := newMuxedAudioWriter(filename, options.Audio)
+		if err != nil {
+			return nil, err
+		}
+		writer.audio = audio
+		writer.audiopipe = pipe
+	}
+
 	return writer, nil
 }
 
@@ -167,13 +362,30 @@ func (writer *VideoWriter) init() error {
 	// ffmpeg command to write to video file. Takes in bytes from Stdin and encodes them.
 	command := []string{
 		"-y", // overwrite output file if it exists.
-		"-loglevel", "quiet",
-		"-f", "rawvideo",
-		"-vcodec", "rawvideo",
-		"-s", fmt.Sprintf("%dx%d", writer.width, writer.height), // frame w x h.
-		"-pix_fmt", "rgba",
-		"-r", fmt.Sprintf("%.02f", writer.fps), // frames per second.
-		"-i", "-", // The input comes from stdin.
+		// Errors are captured to explain write failures.
+		"-loglevel", "error",
+	}
+	if writer.timestamps {
+		// Timestamped frames arrive as a matroska stream, see mkv.go.
+		command = append(command, "-f", "matroska")
+	} else {
+		command = append(
+			command,
+			"-f", "rawvideo",
+			"-vcodec", "rawvideo",
+			"-s", fmt.Sprintf("%dx%d", writer.width, writer.height), // frame w x h.
+			"-pix_fmt", "rgba",
+			"-r", fmt.Sprintf("%.02f", writer.fps), // frames per second.
+		)
+	}
+	command = append(command, "-i", "-") // The input comes from stdin.
+	input := len(command)
+
+	// Muxed audio is read from the first extra file descriptor.
+	if writer.audio != nil {
+		command = append(command, writer.audio.inputArgs()...)
+		command = append(command, "-i", "pipe:3", "-map", "0:v:0", "-map", "1:a:0")
+		command = append(command, writer.audio.outputArgs()...)
 	}
 
 	gif := strings.HasSuffix(strings.ToLower(writer.filename), ".gif")
@@ -197,6 +409,7 @@ func (writer *VideoWriter) init() error {
 		)
 	}
 
+	encode := len(command)
 	command = append(
 		command,
 		"-vcodec", writer.codec,
@@ -206,7 +419,9 @@ func (writer *VideoWriter) init() error {
 	// Code from the imageio-ffmpeg project.
 	// https://github.com/imageio/imageio-ffmpeg/blob/master/imageio_ffmpeg/_io.py#L399.
 	// If bitrate not given, use a default.
-	if writer.bitrate == 0 {
+	if writer.presetargs != nil {
+		command = append(command, writer.presetargs...)
+	} else if writer.bitrate == 0 {
 		if writer.codec == "libx264" {
 			// Quality between 0 an 51. 51 is worst.
 			command = append(command, "-crf", fmt.Sprintf("%d", int(writer.quality*51)))
@@ -249,10 +464,95 @@ func (writer *VideoWriter) init() error {
 		}
 	}
 
-	command = append(command, writer.filename)
+	// Timestamped frames are either passed through as they are, or resampled to a constant
+	// frame rate by duplicating or dropping frames.
+	if writer.timestamps && writer.vfr {
+		command = append(command, "-vsync", "vfr")
+		// The default mp4 timescale is derived from the frame rate and would round the timestamps.
+		if ext := strings.ToLower(filepath.Ext(writer.filename)); ext == ".mp4" || ext == ".mov" || ext == ".m4v" {
+			command = append(command, "-video_track_timescale", "90000")
+		}
+	} else if writer.timestamps {
+		command = append(command, "-vsync", "cfr", "-r", fmt.Sprintf("%.02f", writer.fps))
+	}
+
+	// Encoder threads split work non-deterministically and muxers embed the ffmpeg version,
+	// both of which change the output bytes between runs or machines.
+	if writer.bitexact {
+		command = append(
+			command,
+			"-threads", "1",
+			"-flags:v", "+bitexact",
+			"-flags:a", "+bitexact",
+			"-fflags", "+bitexact",
+			"-map_metadata", "-1",
+		)
+	}
+
+	if writer.timecode != "" {
+		command = append(command, "-timecode", writer.timecode)
+	}
+
+	mux := len(command)
+
+	// Fragmented mp4 keeps everything up to the last fragment playable if ffmpeg dies.
+	// With tee outputs, muxer options are set per output below.
+	if writer.fragmented && len(writer.tee) == 0 {
+		command = append(command, "-movflags", "+frag_keyframe+empty_moov+default_base_moof")
+	}
+
+	// Network outputs such as srt:// need an explicit container format.
+	if format := outputFormat(writer.filename); format != "" && len(writer.tee) == 0 {
+		command = append(command, "-f", format)
+	}
+
+	// The temporary file keeps the extension so ffmpeg picks the same container format.
+	if writer.atomic {
+		dir, base := filepath.Split(writer.filename)
+		ext := filepath.Ext(base)
+		temp, err := os.CreateTemp(dir, "."+strings.TrimSuffix(base, ext)+"-*"+ext)
+		if err != nil {
+			return err
+		}
+		temp.Close()
+		writer.tempfile = temp.Name()
+	}
+
+	// Progress uses stdout, which is only free if the video is not written to it.
+	progress := writer.progress != nil && writer.stdout == nil
+	if progress {
+		command = append(command, "-progress", "pipe:1", "-nostats")
+	}
+
+	command = append(command, writer.output...)
+	if len(writer.tee) > 0 {
+		command = append(command, writer.teeArgs()...)
+	} else {
+		command = append(command, writer.outfile())
+	}
+
+	if writer.twopass {
+		first, err := writer.passes(command, input, encode, mux)
+		if err != nil {
+			return err
+		}
+		command = first
+	}
+
 	cmd := exec.Command("ffmpeg", command...)
 	writer.cmd = cmd
 
+	if writer.stdout != nil {
+		cmd.Stdout = writer.stdout
+	} else if progress {
+		cmd.Stdout = &progressParser{progress: writer.progress}
+	}
+	writer.stderr = &tailBuffer{size: 4096}
+	cmd.Stderr = writer.stderr
+	if writer.audiopipe != nil {
+		cmd.ExtraFiles = []*os.File{writer.audiopipe}
+	}
+
 	pipe, err := cmd.StdinPipe()
 	if err != nil {
 		return err
@@ -262,12 +562,142 @@ func (writer *VideoWriter) init() error {
 	if err := cmd.Start(); err != nil {
 		return err
 	}
+	// ffmpeg holds its own copy of the read end.
+	if writer.audiopipe != nil {
+		writer.audiopipe.Close()
+		writer.audiopipe = nil
+	}
+
+	return nil
+}
+
+// Splits the single pass command into the first pass, run while frames are written, and the second pass,
+// stored to run when the writer is closed. The command is divided at the given indices into the stdin input,
+// extra inputs, encoding options and output options. The first pass analyzes the frames for the pass log
+// and stores them in a lossless intermediate file, from which the second pass encodes the output.
+func (writer *VideoWriter) passes(command []string, input, encode, mux int) ([]string, error) {
+	dir, err := os.MkdirTemp("", "vidio-2pass-")
+	if err != nil {
+		return nil, err
+	}
+	writer.passdir = dir
+	log := filepath.Join(dir, "pass")
+	intermediate := filepath.Join(dir, "intermediate.mkv")
+
+	// The analysis output is discarded, which needs the platform specific null device.
+	null := "/dev/null"
+	if runtime.GOOS == "windows" {
+		null = "NUL"
+	}
+
+	first := append([]string{}, command[:input]...)
+	first = append(first, "-map", "0:v:0")
+	first = append(first, command[encode:mux]...)
+	first = append(first, "-pass", "1", "-passlogfile", log, "-an", "-f", "null", null)
+	first = append(first, "-map", "0:v:0", "-c:v", "ffv1", "-f", "matroska", intermediate)
+
+	second := []string{"-y", "-loglevel", "error", "-i", intermediate}
+	second = append(second, command[input:mux]...)
+	second = append(second, "-pass", "2", "-passlogfile", log)
+	writer.pass2 = append(second, command[mux:]...)
+
+	return first, nil
+}
 
+// Runs the second pass of a two pass encode.
+func (writer *VideoWriter) secondPass() error {
+	cmd := exec.Command("ffmpeg", writer.pass2...)
+	if writer.stdout != nil {
+		cmd.Stdout = writer.stdout
+	} else if writer.progress != nil {
+		cmd.Stdout = &progressParser{progress: writer.progress}
+	}
+	cmd.Stderr = writer.stderr
+	writer.cmd = cmd
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("vidio: second pass failed to write %s: %w", writer.filename, err)
+	}
 	return nil
 }
 
-// Writes the given frame to the video file.
+// Returns the output options writing the encode to the filename and all tee outputs.
+func (writer *VideoWriter) teeArgs() []string {
+	primary := TeeOutput{Filename: writer.outfile(), Format: outputFormat(writer.filename)}
+	if writer.fragmented {
+		primary.Options = map[string]string{"movflags": "+frag_keyframe+empty_moov+default_base_moof"}
+	}
+	outputs := append([]TeeOutput{primary}, writer.tee...)
+
+	// The tee muxer needs explicitly mapped streams, and containers such as mp4 and flv
+	// need the codec headers out of band since the encoder can not configure each muxer.
+	args := []string{"-f", "tee", "-flags", "+global_header"}
+	if writer.audio == nil && writer.streamfile == "" {
+		args = append(args, "-map", "0:v:0")
+	}
+	return append(args, teeSpec(outputs))
+}
+
+// Writes the given frame to the video file. Once a MaxDuration or MaxFileSize limit is reached,
+// the file is finalized and ErrLimitReached is returned for all further frames.
+// If the writer was started with WriteAt, the frame is timestamped one frame after the previous one.
 func (writer *VideoWriter) Write(frame []byte) error {
+	if writer.vfr && writer.frames == 0 && writer.cmd == nil {
+		return writer.WriteAt(frame, 0)
+	}
+	if writer.timestamps {
+		return writer.WriteAt(frame, writer.pts+time.Duration(float64(time.Second)/writer.fps))
+	}
+	return writer.write(frame, nil)
+}
+
+// Writes the given frame with an explicit presentation timestamp, for sources producing frames
+// at irregular intervals such as screen captures or sensors. Timestamps must increase.
+// The first call must happen before any frame is written with Write. Unless VariableFrameRate
+// is set, the output is written at the constant FPS of the writer, duplicating or dropping frames as needed.
+func (writer *VideoWriter) WriteAt(frame []byte, pts time.Duration) error {
+	if writer.cmd == nil && writer.frames == 0 {
+		writer.timestamps = true
+	}
+	if !writer.timestamps {
+		return fmt.Errorf("vidio: WriteAt can not be used after frames were written with Write")
+	}
+	if writer.frames > 0 && pts <= writer.pts {
+		return fmt.Errorf("vidio: timestamp %s is not after the previous timestamp %s", pts, writer.pts)
+	}
+	if pts < 0 {
+		return fmt.Errorf("vidio: timestamp %s must not be negative", pts)
+	}
+
+	// The stream header is sent with the first frame. It uses the input frame size,
+	// since init pads the width and height to a multiple of the macroblock size.
+	var header []byte
+	if writer.cmd == nil {
+		writer.framesize = writer.width * writer.height * 4
+		header = mkvHeader(writer.width, writer.height, writer.fps)
+	}
+	if len(frame) < writer.framesize {
+		return fmt.Errorf("vidio: frame size %d is smaller than %d", len(frame), writer.framesize)
+	}
+	header = append(header, mkvFrameHeader(pts, writer.framesize)...)
+	if err := writer.write(frame[:writer.framesize], header); err != nil {
+		return err
+	}
+	writer.pts = pts
+	return nil
+}
+
+// Writes the header bytes and the frame to ffmpeg, starting it on the first frame.
+func (writer *VideoWriter) write(frame, header []byte) error {
+	if writer.status != StatusWriting {
+		if writer.status == StatusMaxDuration || writer.status == StatusMaxFileSize {
+			return ErrLimitReached
+		}
+		if writer.err != nil {
+			return writer.err
+		}
+		return fmt.Errorf("vidio: writer for %s is closed", writer.filename)
+	}
+
 	// If cmd is nil, video writing has not been set up.
 	if writer.cmd == nil {
 		if err := writer.init(); err != nil {
@@ -275,26 +705,124 @@ func (writer *VideoWriter) Write(frame []byte) error {
 		}
 	}
 
-	total := 0
-	for total < len(frame) {
-		n, err := writer.pipe.Write(frame[total:])
-		if err != nil {
-			return err
+	for _, data := range [][]byte{header, frame} {
+		total := 0
+		for total < len(data) {
+			n, err := writer.pipe.Write(data[total:])
+			if err != nil {
+				return writer.fail(err)
+			}
+			total += n
 		}
-		total += n
+	}
+	writer.frames++
+
+	if status := writer.limit(); status != StatusWriting {
+		return writer.finalize(status)
 	}
 
 	return nil
 }
 
+// Returns the status the writer should be finalized with if one of its limits is reached.
+func (writer *VideoWriter) limit() CompletionStatus {
+	if writer.maxlength > 0 && writer.elapsed() >= writer.maxlength {
+		return StatusMaxDuration
+	}
+	// The file size lags behind the written frames due to encoder buffering,
+	// so it is checked at most once per second of video.
+	if writer.maxsize > 0 && writer.frames%int(math.Max(1, writer.fps)) == 0 && !isURL(writer.filename) {
+		if info, err := os.Stat(writer.outfile()); err == nil && info.Size() >= writer.maxsize {
+			return StatusMaxFileSize
+		}
+	}
+	return StatusWriting
+}
+
+// Returns the duration in seconds of the video written so far.
+func (writer *VideoWriter) elapsed() float64 {
+	if writer.timestamps {
+		return writer.pts.Seconds() + 1/writer.fps
+	}
+	return float64(writer.frames) / writer.fps
+}
+
+// Finalizes the writer after a failed write and returns a WriteError describing the failure.
+func (writer *VideoWriter) fail(err error) error {
+	writer.finalize(StatusFailed)
+
+	output := strings.TrimSpace(writer.stderr.String())
+	cause := err
+	if errors.Is(err, syscall.ENOSPC) || strings.Contains(output, "No space left on device") {
+		cause = ErrNoSpace
+	} else if errors.Is(err, syscall.EPIPE) || errors.Is(err, os.ErrClosed) {
+		cause = ErrBrokenPipe
+	}
+
+	writer.err = &WriteError{
+		Err:       cause,
+		Timestamp: writer.elapsed(),
+		Output:    output,
+	}
+	return writer.err
+}
+
 // Closes the pipe and stops the ffmpeg process.
+// Use Err to check whether the output could be finished.
 func (writer *VideoWriter) Close() {
+	if writer.status == StatusWriting {
+		writer.finalize(StatusClosed)
+	}
+}
+
+// Closes the pipe, waits for ffmpeg to finish the file and records why the writer stopped.
+// When writing atomically, the temporary file is renamed to the filename if ffmpeg succeeded
+// and removed otherwise.
+func (writer *VideoWriter) finalize(status CompletionStatus) error {
+	writer.status = status
 	if writer.pipe != nil {
 		writer.pipe.Close()
 	}
+	// ffmpeg only finishes once all of its inputs are closed.
+	if writer.audio != nil {
+		writer.audio.Close()
+	}
+	if writer.audiopipe != nil {
+		writer.audiopipe.Close()
+	}
+	var err error
 	if writer.cmd != nil {
-		writer.cmd.Wait()
+		if werr := writer.cmd.Wait(); werr != nil {
+			err = fmt.Errorf("vidio: ffmpeg failed to write %s: %w", writer.filename, werr)
+		}
+	}
+	if writer.pass2 != nil {
+		if err == nil && status != StatusFailed {
+			err = writer.secondPass()
+		}
+		os.RemoveAll(writer.passdir)
+		writer.pass2 = nil
+	}
+	if writer.stdout != nil {
+		writer.stdout.Close()
+	}
+
+	if writer.tempfile != "" {
+		// Temporary files are private, the output gets the mode of files created normally.
+		if err == nil && status != StatusFailed {
+			err = os.Chmod(writer.tempfile, 0666&^umask)
+		}
+		if err == nil && status != StatusFailed {
+			err = os.Rename(writer.tempfile, writer.filename)
+		}
+		if err != nil || status == StatusFailed {
+			os.Remove(writer.tempfile)
+		}
+		writer.tempfile = ""
 	}
+
+	writer.finisherr = err
+	return err
 }
 
 // Stops the "cmd" process running when the user presses Ctrl+C.
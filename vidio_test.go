@@ -1,10 +1,29 @@
 package vidio
 
 import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
 	"image"
+	"image/color"
+	"image/draw"
 	"image/png"
+	"io"
+	"math"
 	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"strings"
+	"syscall"
 	"testing"
+	"time"
+
+	"github.com/benitogf/Vidio/ffcmd"
 )
 
 func assertEquals(t *testing.T, actual, expected interface{}) {
@@ -401,3 +420,2746 @@ func TestReadFramesShouldReturnCorrectFrames(t *testing.T) {
 		}
 	}
 }
+
+func TestFrameStamp(t *testing.T) {
+	w, h, img, err := Read("test/bananas.jpg")
+	if err != nil {
+		t.Errorf("Failed to read image: %s", err)
+	}
+
+	if _, err := ReadStamp(img, w, h); err == nil {
+		t.Error("Error was expected to not be nil")
+	}
+
+	if err := StampFrame(img, w, h, 12345); err != nil {
+		t.Errorf("Failed to stamp frame: %s", err)
+	}
+
+	n, err := ReadStamp(img, w, h)
+	if err != nil {
+		t.Errorf("Failed to read frame stamp: %s", err)
+	}
+	assertEquals(t, n, 12345)
+
+	// The wrapped transform is frozen on the first frame.
+	var first []byte
+	transform := StampFrames(w, h, func(frame []byte) ([]byte, error) {
+		if first == nil {
+			first = frame
+		}
+		return first, nil
+	})
+	frame := make([]byte, w*h*4)
+	result, err := transform(frame)
+	assertEquals(t, err, nil)
+	stamp, _ := ReadStamp(result, w, h)
+	assertEquals(t, stamp, 0)
+	// The frame passed in is not modified.
+	_, err = ReadStamp(frame, w, h)
+	assertEquals(t, err != nil, true)
+	_, err = transform(frame)
+	assertEquals(t, err != nil && strings.Contains(err.Error(), "expected frame 1, got frame 0"), true)
+	_, err = StampFrames(w, h, func(frame []byte) ([]byte, error) {
+		return make([]byte, len(frame)), nil
+	})(frame)
+	assertEquals(t, err != nil && strings.Contains(err.Error(), "lost its stamp"), true)
+}
+
+func TestSyncEventMatching(t *testing.T) {
+	lumas := make([]float64, 60)
+	lumas[30] = 255
+	flashes := detectFlashes(lumas, 30, 0.5)
+	assertEquals(t, len(flashes), 1)
+	assertEquals(t, flashes[0], float64(1))
+
+	rate := 1000
+	samples := make([]float32, 2*rate)
+	for i := 1100; i < 1200; i++ {
+		samples[i] = 0.5
+	}
+	beeps := detectOnsets(samples, rate, 0.5)
+	assertEquals(t, len(beeps), 1)
+
+	offsets := matchEvents(flashes, beeps, 1)
+	assertEquals(t, len(offsets), 1)
+	if math.Abs(offsets[0]-0.1) > 1e-9 {
+		t.Errorf("Expected offset 0.1, got %v", offsets[0])
+	}
+}
+
+func TestConcatList(t *testing.T) {
+	assertEquals(t, concatQuote("it's.mp4"), `'it'\''s.mp4'`)
+
+	list, err := writeConcatList([]concatEntry{
+		{filename: "/tmp/a.mp4", inpoint: 1.5},
+		{filename: "/tmp/b.mp4", outpoint: 2},
+	})
+	if err != nil {
+		t.Errorf("Failed to write concat list: %s", err)
+	}
+	defer os.Remove(list)
+
+	data, err := os.ReadFile(list)
+	if err != nil {
+		t.Errorf("Failed to read concat list: %s", err)
+	}
+	assertEquals(
+		t,
+		string(data),
+		"ffconcat version 1.0\nfile '/tmp/a.mp4'\ninpoint 1.500000\nfile '/tmp/b.mp4'\noutpoint 2.000000\n",
+	)
+}
+
+func TestPreRoll(t *testing.T) {
+	preroll, err := NewPreRoll(1, 1, 2, 1.5)
+	if err != nil {
+		t.Errorf("Failed to create the pre-roll: %s", err)
+	}
+	assertEquals(t, preroll.Cap(), 3)
+
+	for i := 0; i < 5; i++ {
+		preroll.Push([]byte{byte(i), 0, 0, 255})
+	}
+
+	frames := preroll.Frames()
+	assertEquals(t, len(frames), 3)
+	assertEquals(t, frames[0][0], uint8(2))
+	assertEquals(t, frames[2][0], uint8(4))
+	assertEquals(t, preroll.Duration(), 1.5)
+}
+
+func TestSRTURL(t *testing.T) {
+	address, err := SRTURL("srt://127.0.0.1:9000", &SRTOptions{
+		Passphrase: "0123456789",
+		Latency:    200 * time.Millisecond,
+		Mode:       "listener",
+	})
+	if err != nil {
+		t.Errorf("Failed to build srt url: %s", err)
+	}
+	assertEquals(t, address, "srt://127.0.0.1:9000?latency=200000&mode=listener&passphrase=0123456789")
+
+	if _, err := SRTURL("rtmp://127.0.0.1", nil); err == nil {
+		t.Error("Error was expected to not be nil")
+	}
+
+	assertEquals(t, isURL("srt://127.0.0.1:9000"), true)
+	assertEquals(t, isURL(`C:\videos\koala.mp4`), false)
+	assertEquals(t, isURL("test/koala.mp4"), false)
+	assertEquals(t, outputFormat("srt://127.0.0.1:9000"), "mpegts")
+	assertEquals(t, outputFormat("test/koala.mp4"), "")
+}
+
+type testSink struct {
+	units  [][]byte
+	closed bool
+}
+
+func (sink *testSink) WriteVideo(data []byte, duration time.Duration) error {
+	sink.units = append(sink.units, data)
+	return nil
+}
+
+func (sink *testSink) Close() error {
+	sink.closed = true
+	return nil
+}
+
+func TestAccessUnitSplitter(t *testing.T) {
+	sink := &testSink{}
+	splitter := &accessUnitSplitter{sink: sink, duration: time.Second / 25}
+
+	stream := []byte{
+		0, 0, 0, 1, 9, 0xF0, 0, 0, 0, 1, 0x65, 1, 2, 3,
+		0, 0, 0, 1, 9, 0xF0, 0, 0, 1, 0x41, 4, 5,
+	}
+	// Write in small chunks to cover access units spanning several writes.
+	for i := 0; i < len(stream); i += 3 {
+		end := i + 3
+		if end > len(stream) {
+			end = len(stream)
+		}
+		splitter.Write(stream[i:end])
+	}
+	splitter.Close()
+
+	assertEquals(t, len(sink.units), 2)
+	assertEquals(t, len(sink.units[0]), 14)
+	assertEquals(t, len(sink.units[1]), 12)
+	assertEquals(t, sink.closed, true)
+
+	// Sinks carry no audio.
+	if _, err := NewSinkWriter(sink, 4, 4, &Options{Audio: &AudioOptions{}}); err == nil {
+		t.Error("Error was expected to not be nil")
+	}
+}
+
+func TestDryRun(t *testing.T) {
+	var commands [][]string
+	c := newConfig([]Option{WithDryRun(&commands)})
+	if err := c.run(ffcmd.New().Input("input.mp4").Output("output.mp4")); err != nil {
+		t.Errorf("Dry run failed: %s", err)
+	}
+
+	assertEquals(t, len(commands), 1)
+	assertEquals(t, commands[0][0], "ffmpeg")
+	assertEquals(t, commands[0][len(commands[0])-1], "output.mp4")
+}
+
+func TestProgressParsing(t *testing.T) {
+	progress := make(chan Progress, 2)
+	parser := &progressParser{progress: progress}
+	parser.Write([]byte("frame=50\nfps=25.0\nbitrate= 812.3kbits/s\nout_time_us=2000000\nspe"))
+	parser.Write([]byte("ed=1.5x\nprogress=continue\nframe=101\nprogress=end\n"))
+
+	update := <-progress
+	assertEquals(t, update.Frame, 50)
+	assertEquals(t, update.FPS, float64(25))
+	assertEquals(t, update.Bitrate, 812.3)
+	assertEquals(t, update.OutTime, 2*time.Second)
+	assertEquals(t, update.Speed, 1.5)
+	assertEquals(t, update.Done, false)
+
+	update = <-progress
+	assertEquals(t, update.Frame, 101)
+	assertEquals(t, update.Done, true)
+}
+
+func TestRetryPolicy(t *testing.T) {
+	transient := &OpenError{Source: "rtsp://camera", Output: "Connection refused", Err: errors.New("exit status 1")}
+	permanent := &OpenError{Source: "missing.mp4", Output: "No such file or directory", Err: errors.New("exit status 1")}
+	assertEquals(t, IsTransient(transient), true)
+	assertEquals(t, IsTransient(permanent), false)
+	assertEquals(t, IsTransient(fmt.Errorf("wrapped: %w", transient)), true)
+
+	attempts := 0
+	policy := RetryPolicy{Attempts: 3, Backoff: time.Millisecond}
+	err := policy.do(newConfig(nil), func() error {
+		attempts++
+		return transient
+	})
+	assertEquals(t, err, error(transient))
+	assertEquals(t, attempts, 3)
+
+	attempts = 0
+	policy.do(newConfig(nil), func() error {
+		attempts++
+		return permanent
+	})
+	assertEquals(t, attempts, 1)
+}
+
+func TestFrameCache(t *testing.T) {
+	dir, err := os.MkdirTemp("", "vidio-cache")
+	if err != nil {
+		t.Errorf("Failed to create cache directory: %s", err)
+	}
+	defer os.RemoveAll(dir)
+
+	cache, err := NewFrameCache(dir, 8)
+	if err != nil {
+		t.Errorf("Failed to create the frame cache: %s", err)
+	}
+
+	cache.put("a", []byte{1, 2, 3, 4})
+	cache.put("b", []byte{5, 6, 7, 8})
+	buffer := make([]byte, 4)
+	assertEquals(t, cache.get("a", buffer), true)
+	assertEquals(t, buffer[0], uint8(1))
+
+	// "b" is the least recently used frame and gets evicted.
+	cache.put("c", []byte{9, 10, 11, 12})
+	assertEquals(t, cache.get("b", buffer), false)
+	assertEquals(t, cache.Size(), int64(8))
+
+	// Reopening the cache keeps the stored frames.
+	cache, err = NewFrameCache(dir, 8)
+	if err != nil {
+		t.Errorf("Failed to reopen the frame cache: %s", err)
+	}
+	assertEquals(t, cache.get("c", buffer), true)
+	assertEquals(t, buffer[0], uint8(9))
+
+	// Frames decoded with another filter or size get another key.
+	video := &Video{filename: "input.mp4", width: 4, height: 4, depth: 4, filter: "hflip"}
+	key := cache.key(video, 0)
+	video.filter = "vflip"
+	assertEquals(t, cache.key(video, 0) == key, false)
+	video.filter, video.width = "hflip", 8
+	assertEquals(t, cache.key(video, 0) == key, false)
+
+	if _, err := NewFrameCache(dir, -1); err == nil {
+		t.Error("Error was expected to not be nil")
+	}
+}
+
+func TestVectoredRead(t *testing.T) {
+	reader, writer, err := os.Pipe()
+	if err != nil {
+		t.Errorf("Failed to create pipe: %s", err)
+	}
+	defer reader.Close()
+
+	go func() {
+		writer.Write([]byte{1, 2, 3})
+		writer.Write([]byte{4, 5, 6, 7, 8, 9})
+		writer.Close()
+	}()
+
+	buffers := [][]byte{make([]byte, 4), make([]byte, 4), make([]byte, 4)}
+	n, err := readBuffers(reader, buffers)
+	if err == nil {
+		t.Error("Error was expected to not be nil")
+	}
+	assertEquals(t, n, 2)
+	assertEquals(t, buffers[0][3], uint8(4))
+	assertEquals(t, buffers[1][3], uint8(8))
+}
+
+func TestPixelConversions(t *testing.T) {
+	rgba := []byte{1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11, 12, 13, 14, 15, 16, 17, 18, 19, 20}
+	bgra := make([]byte, len(rgba))
+	if err := SwapRB(bgra, rgba); err != nil {
+		t.Errorf("Failed to swap channels: %s", err)
+	}
+	assertEquals(t, bgra[0], uint8(3))
+	assertEquals(t, bgra[2], uint8(1))
+	assertEquals(t, bgra[3], uint8(4))
+	assertEquals(t, bgra[16], uint8(19))
+
+	rgb := []byte{1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11, 12, 13, 14, 15}
+	converted := make([]byte, 20)
+	if err := RGBToBGRA(converted, rgb); err != nil {
+		t.Errorf("Failed to convert rgb: %s", err)
+	}
+	back := make([]byte, 15)
+	if err := BGRAToRGB(back, converted); err != nil {
+		t.Errorf("Failed to convert bgra: %s", err)
+	}
+	for i := range rgb {
+		assertEquals(t, back[i], rgb[i])
+	}
+	assertEquals(t, converted[3], uint8(255))
+
+	// Limited range white and black.
+	dst := make([]byte, 2*2*4)
+	YUV420ToRGBA(dst, []byte{235, 235, 16, 16}, []byte{128}, []byte{128}, 2, 2)
+	assertEquals(t, dst[0], uint8(255))
+	assertEquals(t, dst[8], uint8(0))
+}
+
+func BenchmarkSwapRB(b *testing.B) {
+	frame := make([]byte, 3840*2160*4)
+	b.SetBytes(int64(len(frame)))
+	for i := 0; i < b.N; i++ {
+		SwapRB(frame, frame)
+	}
+}
+
+func BenchmarkRGBToBGRA(b *testing.B) {
+	src := make([]byte, 3840*2160*3)
+	dst := make([]byte, 3840*2160*4)
+	b.SetBytes(int64(len(src)))
+	for i := 0; i < b.N; i++ {
+		RGBToBGRA(dst, src)
+	}
+}
+
+func BenchmarkYUV420ToRGBA(b *testing.B) {
+	w, h := 3840, 2160
+	y := make([]byte, w*h)
+	u := make([]byte, w*h/4)
+	v := make([]byte, w*h/4)
+	dst := make([]byte, w*h*4)
+	b.SetBytes(int64(len(dst)))
+	for i := 0; i < b.N; i++ {
+		YUV420ToRGBA(dst, y, u, v, w, h)
+	}
+}
+
+func TestFrameImage(t *testing.T) {
+	frame := make([]byte, 4*2*4)
+	img, err := FrameImage(frame, 4, 2, PixelBGRA)
+	if err != nil {
+		t.Errorf("Failed to wrap frame: %s", err)
+	}
+	draw.Draw(img, image.Rect(1, 1, 3, 2), &image.Uniform{color.RGBA{255, 128, 0, 255}}, image.Point{}, draw.Src)
+
+	index := (1*4 + 1) * 4
+	assertEquals(t, frame[index+0], uint8(0))
+	assertEquals(t, frame[index+1], uint8(128))
+	assertEquals(t, frame[index+2], uint8(255))
+	assertEquals(t, frame[0], uint8(0))
+	assertEquals(t, img.At(2, 1), color.Color(color.RGBA{255, 128, 0, 255}))
+
+	rgba, err := FrameImage(frame, 4, 2, PixelRGBA)
+	if err != nil {
+		t.Errorf("Failed to wrap frame: %s", err)
+	}
+	if _, ok := rgba.(*image.RGBA); !ok {
+		t.Errorf("Expected *image.RGBA for rgba frames")
+	}
+
+	if _, err := FrameImage(frame, 8, 8, PixelRGBA); err == nil {
+		t.Errorf("Expected error for undersized buffer")
+	}
+}
+
+func TestFrameDiff(t *testing.T) {
+	width, height := 8, 4
+	a := make([]byte, width*height*4)
+	b := make([]byte, width*height*4)
+
+	changed, bbox, err := FrameDiff(a, b, width, height)
+	if err != nil {
+		t.Errorf("Failed to diff frames: %s", err)
+	}
+	assertEquals(t, changed, 0)
+	assertEquals(t, bbox.Empty(), true)
+
+	b[(1*width+2)*4] = 10
+	b[(3*width+5)*4+1] = 200
+	changed, bbox, _ = FrameDiff(a, b, width, height)
+	assertEquals(t, changed, 2)
+	assertEquals(t, bbox, image.Rect(2, 1, 6, 4))
+
+	changed, _, _ = FrameDiffThreshold(a, b, width, height, 20)
+	assertEquals(t, changed, 1)
+
+	region, err := ExtractRegion(b, width, bbox)
+	if err != nil {
+		t.Errorf("Failed to extract region: %s", err)
+	}
+	assertEquals(t, len(region), 4*3*4)
+	if err := ApplyRegion(a, width, bbox, region); err != nil {
+		t.Errorf("Failed to apply region: %s", err)
+	}
+	changed, _, _ = FrameDiff(a, b, width, height)
+	assertEquals(t, changed, 0)
+}
+
+func TestDeterministicWriting(t *testing.T) {
+	write := func(output string) []byte {
+		writer, err := NewVideoWriter(output, 64, 48, &Options{Deterministic: true})
+		if err != nil {
+			t.Errorf("Failed to create the video writer: %s", err)
+			return nil
+		}
+		frame := make([]byte, 64*48*4)
+		for i := 0; i < 10; i++ {
+			StampFrame(frame, 64, 48, i)
+			if err := writer.Write(frame); err != nil {
+				t.Errorf("Failed to write frame: %s", err)
+			}
+		}
+		writer.Close()
+		data, _ := os.ReadFile(output)
+		os.Remove(output)
+		return data
+	}
+
+	first := write("test/deterministic-1.mp4")
+	second := write("test/deterministic-2.mp4")
+	if len(first) == 0 || string(first) != string(second) {
+		t.Errorf("Expected identical outputs")
+	}
+}
+
+func TestMatroskaFraming(t *testing.T) {
+	assertEquals(t, fmt.Sprintf("%x", mkvSize(5)), "85")
+	assertEquals(t, fmt.Sprintf("%x", mkvSize(127)), "407f")
+	assertEquals(t, fmt.Sprintf("%x", mkvSize(1000)), "43e8")
+	assertEquals(t, fmt.Sprintf("%x", mkvUint(mkvTrackNumber, 1)), "d78101")
+
+	header := mkvHeader(640, 480, 30)
+	assertEquals(t, fmt.Sprintf("%x", header[:4]), "1a45dfa3")
+
+	// Cluster ID, 3 byte size, timestamp element, block ID, 3 byte size and the block header.
+	frame := mkvFrameHeader(1500*time.Millisecond, 640*480*4)
+	assertEquals(t, fmt.Sprintf("%x", frame[:4]), "1f43b675")
+	assertEquals(t, fmt.Sprintf("%x", frame[7:12]), "e78316e360")
+	assertEquals(t, len(frame), 4+3+5+1+3+4)
+}
+
+func TestVariableFrameRateWriting(t *testing.T) {
+	output := "test/vfr.mp4"
+	writer, err := NewVideoWriter(output, 64, 48, &Options{VariableFrameRate: true})
+	if err != nil {
+		t.Errorf("Failed to create the video writer: %s", err)
+		return
+	}
+	defer os.Remove(output)
+
+	frame := make([]byte, 64*48*4)
+	for _, pts := range []time.Duration{0, 100 * time.Millisecond, 150 * time.Millisecond, 900 * time.Millisecond} {
+		if err := writer.WriteAt(frame, pts); err != nil {
+			t.Errorf("Failed to write frame: %s", err)
+		}
+	}
+	if err := writer.WriteAt(frame, 500*time.Millisecond); err == nil {
+		t.Errorf("Expected error for decreasing timestamp")
+	}
+	writer.Close()
+
+	video, err := NewVideo(output)
+	if err != nil {
+		t.Errorf("Failed to open the video: %s", err)
+		return
+	}
+	defer video.Close()
+	frames := 0
+	for video.Read() {
+		frames++
+	}
+	assertEquals(t, frames, 4)
+}
+
+func TestAudioWriting(t *testing.T) {
+	assertEquals(t, audioCodec("out.webm"), "libopus")
+	assertEquals(t, audioCodec("out.m4a"), "aac")
+
+	output := "test/tone.wav"
+	writer, err := NewAudioWriter(output, &AudioOptions{SampleRate: 8000, Channels: 1, Format: SampleF32})
+	if err != nil {
+		t.Errorf("Failed to create the audio writer: %s", err)
+		return
+	}
+	defer os.Remove(output)
+
+	samples := make([]float32, 8000)
+	for i := range samples {
+		samples[i] = float32(0.5 * math.Sin(2*math.Pi*440*float64(i)/8000))
+	}
+	if err := writer.WriteInt16([]int16{0}); err == nil {
+		t.Errorf("Expected error for mismatched sample format")
+	}
+	if err := writer.WriteFloat32(samples); err != nil {
+		t.Errorf("Failed to write samples: %s", err)
+	}
+	assertEquals(t, writer.Duration(), 1.0)
+	if err := writer.Close(); err != nil {
+		t.Errorf("Failed to close the audio writer: %s", err)
+	}
+
+	pcm, err := readPCM(output, 8000)
+	if err != nil {
+		t.Errorf("Failed to read the audio: %s", err)
+	}
+	assertEquals(t, len(pcm), 8000)
+}
+
+func TestTeeSpec(t *testing.T) {
+	spec := teeSpec([]TeeOutput{
+		{Filename: "archive.mp4"},
+		{Filename: "rtmp://live.example.com/app/key", Optional: true},
+		{Filename: "hls/index.m3u8", Format: "hls", Options: map[string]string{"hls_time": "4", "hls_list_size": "6"}},
+	})
+	expected := `archive.mp4|[f=flv:onfail=ignore]rtmp\://live.example.com/app/key|[f=hls:hls_list_size=6:hls_time=4]hls/index.m3u8`
+	assertEquals(t, spec, expected)
+}
+
+func TestEncodeLadder(t *testing.T) {
+	commands := [][]string{}
+	err := EncodeLadder("test/koala.mp4", []Rendition{
+		{Output: "test/koala-270.mp4", Height: 270, Bitrate: 400000},
+		{Output: "test/koala-135.mp4", Width: 240, Height: 136, Bitrate: 150000},
+	}, WithDryRun(&commands))
+	if err != nil {
+		t.Errorf("Failed to build ladder: %s", err)
+	}
+	assertEquals(t, len(commands), 1)
+
+	command := fmt.Sprint(commands[0])
+	for _, part := range []string{
+		"-filter_complex [0:v]split=2[s0][s1];[s0]scale=-2:270[v0];[s1]scale=240:136[v1]",
+		"-map [v0] -map 0:a:0? -c:v libx264 -b:v 400000",
+		"-map [v1] -map 0:a:0? -c:v libx264 -b:v 150000",
+	} {
+		if !strings.Contains(command, part) {
+			t.Errorf("Expected %q in %s", part, command)
+		}
+	}
+}
+
+func TestTwoPassCommands(t *testing.T) {
+	writer := &VideoWriter{filename: "out.mp4", twopass: true}
+	command := []string{"-y", "-loglevel", "error", "-f", "rawvideo", "-i", "-", "-i", "audio.m4a", "-vcodec", "libx264", "-b:v", "1000", "out.mp4"}
+	first, err := writer.passes(command, 7, 9, 13)
+	if err != nil {
+		t.Errorf("Failed to split the passes: %s", err)
+	}
+	defer os.RemoveAll(writer.passdir)
+
+	log := writer.passdir + string(os.PathSeparator) + "pass"
+	intermediate := writer.passdir + string(os.PathSeparator) + "intermediate.mkv"
+	assertEquals(t, strings.Join(first, " "), "-y -loglevel error -f rawvideo -i - -map 0:v:0 -vcodec libx264 -b:v 1000 "+
+		"-pass 1 -passlogfile "+log+" -an -f null /dev/null -map 0:v:0 -c:v ffv1 -f matroska "+intermediate)
+	assertEquals(t, strings.Join(writer.pass2, " "), "-y -loglevel error -i "+intermediate+" -i audio.m4a -vcodec libx264 -b:v 1000 "+
+		"-pass 2 -passlogfile "+log+" out.mp4")
+}
+
+func TestPresets(t *testing.T) {
+	output := ` V..... = Video
+ A..... = Audio
+ ------
+ V....D libx264              libx264 H.264 / AVC / MPEG-4 AVC / MPEG-4 part 10 (codec h264)
+ V....D libvpx-vp9           libvpx VP9 (codec vp9)
+ A....D aac                  AAC (Advanced Audio Coding)
+`
+	encoders := parseEncoders(output)
+	assertEquals(t, strings.Join(encoders, ","), "libx264,libvpx-vp9")
+
+	codec, args, err := PresetWebHD.resolve(encoders, "out.mp4")
+	if err != nil {
+		t.Errorf("Failed to resolve preset: %s", err)
+	}
+	assertEquals(t, codec, "libvpx-vp9")
+	assertEquals(t, args[0], "-crf")
+
+	codec, _, _ = PresetArchival.resolve(append(encoders, "libsvtav1"), "out.mkv")
+	assertEquals(t, codec, "libsvtav1")
+
+	if _, _, err := PresetSocial.resolve([]string{"libx264"}, "out.webm"); err == nil {
+		t.Errorf("Expected error for h264 in webm")
+	}
+	if _, _, err := Preset("unknown").resolve(encoders, "out.mp4"); err == nil {
+		t.Errorf("Expected error for unknown preset")
+	}
+}
+
+func TestPreviewClip(t *testing.T) {
+	starts := previewStarts(100, 4, 2)
+	assertEquals(t, fmt.Sprint(starts), "[11.5 36.5 61.5 86.5]")
+	assertEquals(t, previewStarts(1, 1, 1)[0], 0.0)
+
+	commands := [][]string{}
+	if err := PreviewClip("test/koala.mp4", "test/koala-preview.gif", 3, 0.5, WithDryRun(&commands)); err != nil {
+		t.Errorf("Failed to build preview: %s", err)
+		return
+	}
+	command := strings.Join(commands[0], " ")
+	if !strings.Contains(command, "[0:v:0][1:v:0][2:v:0]concat=n=3:v=1:a=0") || !strings.Contains(command, "paletteuse") {
+		t.Errorf("Unexpected preview command: %s", command)
+	}
+}
+
+func TestKMeans(t *testing.T) {
+	samples := [][3]float64{}
+	for i := 0; i < 30; i++ {
+		samples = append(samples, [3]float64{250, 10, 10})
+	}
+	for i := 0; i < 10; i++ {
+		samples = append(samples, [3]float64{10, 10, 240}, [3]float64{12, 8, 244})
+	}
+
+	colors := kmeans(samples, 2, 20)
+	assertEquals(t, len(colors), 2)
+	assertEquals(t, colors[0], color.RGBA{250, 10, 10, 255})
+	assertEquals(t, colors[1], color.RGBA{11, 9, 242, 255})
+
+	assertEquals(t, len(kmeans(samples[:1], 3, 20)), 1)
+}
+
+func TestSharpness(t *testing.T) {
+	w, h := 16, 16
+	flat := make([]byte, w*h*4)
+	checker := make([]byte, w*h*4)
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			i := (y*w + x) * 4
+			flat[i], flat[i+1], flat[i+2], flat[i+3] = 128, 128, 128, 255
+			value := byte(40)
+			if (x+y)%2 == 0 {
+				value = 200
+			}
+			checker[i], checker[i+1], checker[i+2], checker[i+3] = value, value, value, 255
+		}
+	}
+
+	assertEquals(t, Sharpness(flat, w, h), 0.0)
+	if Sharpness(checker, w, h) <= 0 {
+		t.Errorf("Expected checkerboard to be sharp")
+	}
+
+	img := &image.RGBA{Pix: checker, Stride: w * 4, Rect: image.Rect(0, 0, w, h)}
+	black := &image.RGBA{Pix: make([]byte, w*h*4), Stride: w * 4, Rect: image.Rect(0, 0, w, h)}
+	if thumbnailScore(img) <= thumbnailScore(black) {
+		t.Errorf("Expected sharp frame to score higher than a black frame")
+	}
+}
+
+func TestAutoChapters(t *testing.T) {
+	output := "frame:0    pts:96      pts_time:4\nlavfi.scene_score=0.410000\n" +
+		"frame:1    pts:120     pts_time:5\nlavfi.scene_score=0.900000\n" +
+		"frame:2    pts:600     pts_time:25\nlavfi.scene_score=0.350000\n" +
+		"frame:3    pts:700     pts_time:29\nlavfi.scene_score=0.800000\n"
+	cuts := parseScenes(output)
+	assertEquals(t, len(cuts), 4)
+	assertEquals(t, cuts[1].time, 5.0)
+	assertEquals(t, cuts[1].score, 0.9)
+
+	// The cuts at 4s and 5s are too early and the cut at 29s is too close to the end.
+	chapters := chaptersFromCuts(cuts, 36, 10)
+	assertEquals(t, fmt.Sprint(chapters), "[{0 25 Chapter 1} {25 36 Chapter 2}]")
+
+	// The stronger cut at 5s wins over the cut at 4s.
+	chapters = chaptersFromCuts(cuts, 36, 4)
+	assertEquals(t, fmt.Sprint(chapters), "[{0 5 Chapter 1} {5 25 Chapter 2} {25 29 Chapter 3} {29 36 Chapter 4}]")
+
+	metadata, err := writeFFMetadata(nil, []Chapter{{Start: 0, End: 1.5, Title: "Intro; a=b"}})
+	if err != nil {
+		t.Errorf("Failed to write metadata: %s", err)
+	}
+	defer os.Remove(metadata)
+	data, _ := os.ReadFile(metadata)
+	assertEquals(t, string(data), ";FFMETADATA1\n[CHAPTER]\nTIMEBASE=1/1000\nSTART=0\nEND=1500\ntitle=Intro\\; a\\=b\n")
+}
+
+func TestSetMetadata(t *testing.T) {
+	metadata, err := writeFFMetadata(map[string]string{"title": "Koala", "artist": "Zoo #1"}, nil)
+	if err != nil {
+		t.Errorf("Failed to write metadata: %s", err)
+	}
+	defer os.Remove(metadata)
+	data, _ := os.ReadFile(metadata)
+	assertEquals(t, string(data), ";FFMETADATA1\nartist=Zoo \\#1\ntitle=Koala\n")
+
+	commands := [][]string{}
+	if err := SetMetadata("test/koala.mp4", "test/koala-tagged.mp4", map[string]string{"title": "Koala"}, nil, WithDryRun(&commands)); err != nil {
+		t.Errorf("Failed to build command: %s", err)
+	}
+	command := strings.Join(commands[0], " ")
+	if !strings.Contains(command, "-map 0 -map_metadata 1 -c copy test/koala-tagged.mp4") {
+		t.Errorf("Unexpected metadata command: %s", command)
+	}
+	// Dry runs keep the metadata file so the command can be inspected.
+	for i, arg := range commands[0] {
+		if arg == "ffmetadata" {
+			os.Remove(commands[0][i+2])
+		}
+	}
+}
+
+func TestEditStreams(t *testing.T) {
+	builder := ffcmd.New().Input("in.mkv")
+	args, err := streamArgs(builder, []StreamOp{
+		KeepStream("v:0"),
+		KeepStream("a:1"),
+		KeepStream("a:0"),
+		KeepStream("s"),
+		DropStream("s:2"),
+		SetLanguage("a:0", "spa"),
+		SetDefault("a:0"),
+	})
+	if err != nil {
+		t.Errorf("Failed to build stream args: %s", err)
+	}
+	builder.Output("out.mkv", args...)
+	assertEquals(t, strings.Join(builder.Args(), " "), "-i in.mkv -map 0:v:0 -map 0:a:1 -map 0:a:0 -map 0:s -map -0:s:2 "+
+		"-metadata:s:a:0 language=spa -disposition:a 0 -disposition:a:0 default out.mkv")
+
+	builder = ffcmd.New().Input("in.mkv")
+	streamArgs(builder, []StreamOp{DropStream("a")})
+	builder.Output("out.mkv")
+	assertEquals(t, strings.Join(builder.Args(), " "), "-i in.mkv -map 0 -map -0:a out.mkv")
+
+	if _, err := streamArgs(ffcmd.New(), []StreamOp{KeepStream("")}); err == nil {
+		t.Errorf("Expected error for missing stream specifier")
+	}
+}
+
+func TestStreamInfo(t *testing.T) {
+	streams := parseStreamInfo([]map[string]string{
+		{"index": "0", "codec_type": "video", "codec_name": "h264", "disposition:default": "1"},
+		{"index": "1", "codec_type": "audio", "codec_name": "aac", "tag:language": "eng", "disposition:default": "1"},
+		{"index": "2", "codec_type": "audio", "codec_name": "ac3", "tag:language": "und", "tag:title": "Commentary", "disposition:comment": "1"},
+		{"index": "3", "codec_type": "subtitle", "codec_name": "subrip", "tag:language": "spa", "disposition:forced": "1"},
+	})
+	assertEquals(t, len(streams), 4)
+	assertEquals(t, streams[1].Specifier, "a:0")
+	assertEquals(t, streams[1].Language, "eng")
+	assertEquals(t, streams[1].Default, true)
+	assertEquals(t, streams[2].Specifier, "a:1")
+	assertEquals(t, streams[2].Language, "")
+	assertEquals(t, streams[2].Title, "Commentary")
+	assertEquals(t, streams[2].Commentary, true)
+	assertEquals(t, streams[3].Specifier, "s:0")
+	assertEquals(t, streams[3].Forced, true)
+}
+
+func TestBitrateTimeline(t *testing.T) {
+	packets := parsePackets([]map[string]string{
+		{"pts_time": "0.000000", "dts_time": "-0.040000", "size": "1000", "flags": "K__"},
+		{"pts_time": "0.500000", "dts_time": "0.000000", "size": "250", "flags": "___"},
+		{"pts_time": "1.200000", "dts_time": "1.100000", "size": "500", "flags": "___"},
+		{"pts_time": "N/A", "dts_time": "N/A", "size": "10", "flags": "___"},
+		{"pts_time": "2.100000", "dts_time": "2.000000", "size": "125", "flags": "K__"},
+	})
+	assertEquals(t, packets[0].keyframe, true)
+	assertEquals(t, math.IsNaN(packets[3].pts), true)
+
+	points := bitrateTimeline(packets, 1)
+	assertEquals(t, len(points), 3)
+	assertEquals(t, points[0].Bitrate, 10000.0)
+	assertEquals(t, points[0].Keyframes, 1)
+	assertEquals(t, points[1].Bytes, 500)
+	assertEquals(t, points[2].Time, 2.0)
+}
+
+func TestGOPStats(t *testing.T) {
+	// Decoding order I P B B | I B P: the B frame after the second keyframe is shown before it.
+	pts := []float64{0, 0.12, 0.04, 0.08, 0.2, 0.16, 0.24}
+	keys := []bool{true, false, false, false, true, false, false}
+	packets := make([]packet, len(pts))
+	for i := range pts {
+		packets[i] = packet{pts: pts[i], keyframe: keys[i]}
+	}
+
+	report := gopReport(packets, []string{"I", "B", "B", "P", "B", "I", "P"})
+	assertEquals(t, len(report.GOPs), 2)
+	assertEquals(t, report.GOPs[0].Frames, 4)
+	assertEquals(t, report.GOPs[0].Open, false)
+	assertEquals(t, report.GOPs[1].Open, true)
+	assertEquals(t, report.OpenGOPs, 1)
+	assertEquals(t, report.MinLength, 4)
+	assertEquals(t, report.MaxLength, 4)
+	assertEquals(t, math.Round(report.GOPs[0].Duration*100)/100, 0.2)
+	assertEquals(t, report.IFrames, 2)
+	assertEquals(t, report.BFrames, 3)
+}
+
+func TestDamageReporter(t *testing.T) {
+	video := &Video{}
+	video.delivered.Store(12)
+
+	damaged := []DamagedFrame{}
+	reporter := &damageReporter{video: video, report: func(damage DamagedFrame) {
+		damaged = append(damaged, damage)
+	}}
+
+	// Lines may be split across writes.
+	reporter.Write([]byte("[h264 @ 0x1] error while decoding MB 3 4\n[h264 @ 0x1] concealing "))
+	reporter.Write([]byte("120 DC errors\n\n"))
+	reporter.Write([]byte("partial"))
+
+	assertEquals(t, len(damaged), 2)
+	assertEquals(t, damaged[0].Frame, 12)
+	assertEquals(t, damaged[0].Message, "[h264 @ 0x1] error while decoding MB 3 4")
+	assertEquals(t, damaged[1].Message, "[h264 @ 0x1] concealing 120 DC errors")
+
+	c := newConfig([]Option{WithErrorConcealment(nil)})
+	assertEquals(t, c.conceal, true)
+}
+
+func TestLiveFile(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "recording.mkv")
+	if err := os.WriteFile(filename, []byte("header"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	live := newLiveFile(filename, time.Second, 2, 0)
+	start := time.Now()
+	assertEquals(t, live.poll(filename, start), false)
+	assertEquals(t, live.growing(start.Add(2*time.Second)), false)
+
+	if err := os.WriteFile(filename, []byte("header and frames"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	grown := start.Add(3 * time.Second)
+	assertEquals(t, live.poll(filename, grown), true)
+	assertEquals(t, live.growing(grown.Add(500*time.Millisecond)), true)
+
+	// Frame counts are estimated from the duration if the container has none.
+	live.update(4, 25, 0)
+	assertEquals(t, live.duration, 4.0)
+	assertEquals(t, live.frames, 100)
+	live.update(3, 25, 0)
+	assertEquals(t, live.duration, 4.0)
+
+	args := live.inputArgs(filename)
+	assertEquals(t, strings.Join(args[:4], " "), "-follow 1 -rw_timeout 1000000")
+	assertEquals(t, args[5], "file:"+filename)
+
+	live.close()
+	assertEquals(t, live.growing(grown), false)
+}
+
+func TestWatch(t *testing.T) {
+	inbox := t.TempDir()
+	done := filepath.Join(t.TempDir(), "done")
+	for _, name := range []string{"a.mp4", "b.mp4", "notes.txt"} {
+		if err := os.WriteFile(filepath.Join(inbox, name), []byte(name), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	watch := func() []string {
+		ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+		defer cancel()
+		processed := []string{}
+		err := Watch(ctx, inbox, &WatchOptions{
+			Pipeline: []WatchStep{func(ctx context.Context, job *WatchJob) error {
+				processed = append(processed, job.Name)
+				return nil
+			}},
+			Interval:   10 * time.Millisecond,
+			Extensions: []string{".mp4"},
+		})
+		assertEquals(t, errors.Is(err, context.DeadlineExceeded), true)
+		return processed
+	}
+
+	assertEquals(t, strings.Join(watch(), " "), "a.mp4 b.mp4")
+	// The journal prevents reprocessing after a restart.
+	assertEquals(t, len(watch()), 0)
+
+	// Changed files are processed again.
+	if err := os.WriteFile(filepath.Join(inbox, "a.mp4"), []byte("new take"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	assertEquals(t, strings.Join(watch(), " "), "a.mp4")
+
+	job := &WatchJob{Filename: filepath.Join(inbox, "b.mp4"), Name: "b.mp4"}
+	if err := MoveTo(done)(context.Background(), job); err != nil {
+		t.Fatal(err)
+	}
+	assertEquals(t, job.Filename, filepath.Join(done, "b.mp4"))
+	assertEquals(t, exists(job.Filename), true)
+}
+
+func TestEvents(t *testing.T) {
+	events := []Event{}
+	remove := OnEvent(func(event Event) {
+		events = append(events, event)
+	})
+	assertEquals(t, listening(), true)
+
+	emit(Event{Type: EventSegment, Output: "segment.mp4"})
+	parser := &progressParser{notify: func(progress Progress) {
+		emit(Event{Type: EventProgress, Progress: &progress})
+	}}
+	parser.Write([]byte("frame=10\nprogress=continue\nframe=20\nprogress=end\n"))
+
+	remove()
+	assertEquals(t, listening(), false)
+	emit(Event{Type: EventError})
+
+	assertEquals(t, len(events), 3)
+	assertEquals(t, events[0].Type, EventSegment)
+	assertEquals(t, events[0].Time.IsZero(), false)
+	assertEquals(t, events[1].Progress.Frame, 10)
+	assertEquals(t, events[2].Progress.Done, true)
+
+	source, output := endpoints(ffcmd.New().Input("in.mp4").Output("out.mp4"))
+	assertEquals(t, source, "in.mp4")
+	assertEquals(t, output, "out.mp4")
+}
+
+type testSpan struct {
+	name       string
+	attributes map[string]any
+	err        error
+	ended      bool
+}
+
+func (span *testSpan) SetAttribute(key string, value any) {
+	span.attributes[key] = value
+}
+
+func (span *testSpan) End(err error) {
+	span.err = err
+	span.ended = true
+}
+
+type testTracer struct {
+	spans []*testSpan
+}
+
+func (tracer *testTracer) Start(ctx context.Context, name string) (context.Context, Span) {
+	span := &testSpan{name: name, attributes: map[string]any{}}
+	tracer.spans = append(tracer.spans, span)
+	return ctx, span
+}
+
+func TestTracing(t *testing.T) {
+	tracer := &testTracer{}
+	SetTracer(tracer)
+	defer SetTracer(nil)
+
+	// Dry runs are not traced.
+	commands := [][]string{}
+	builder := ffcmd.New().Input("missing.mp4").Output("out.mp4")
+	newConfig([]Option{WithDryRun(&commands)}).run(builder)
+	assertEquals(t, len(tracer.spans), 0)
+
+	// Fails without ffmpeg or with the missing input.
+	err := newConfig(nil).run(builder)
+	assertEquals(t, len(tracer.spans), 1)
+	span := tracer.spans[0]
+	assertEquals(t, span.name, SpanTranscode)
+	assertEquals(t, span.ended, true)
+	assertEquals(t, span.err, err)
+	assertEquals(t, span.attributes["vidio.file"], "missing.mp4")
+	assertEquals(t, span.attributes["vidio.output"], "out.mp4")
+}
+
+func TestPipeline(t *testing.T) {
+	definition := `{
+		"sources": [{"path": "{input}", "start": 5}, {"path": "logo.png"}],
+		"filters": ["[0:v][1:v]overlay=10:10[out]"],
+		"outputs": [{
+			"path": "out/{base}.mp4",
+			"map": ["[out]", "0:a:0?"],
+			"codec": "libx264",
+			"bitrate": 2000000,
+			"audio_codec": "aac"
+		}],
+		"limits": {"duration": 60, "timeout": "10m"}
+	}`
+	pipeline, err := ParsePipeline([]byte(definition))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	commands := [][]string{}
+	if err := pipeline.Run("inbox/clip.mov", WithDryRun(&commands)); err != nil {
+		t.Fatal(err)
+	}
+	expected := "ffmpeg -hide_banner -loglevel error -y -ss 5 -i inbox/clip.mov -i logo.png " +
+		"-filter_complex [0:v][1:v]overlay=10:10[out] -map [out] -map 0:a:0? " +
+		"-c:v libx264 -b:v 2000000 -c:a aac -t 60 out/clip.mp4"
+	assertEquals(t, strings.Join(commands[0], " "), expected)
+
+	_, err = ParsePipeline([]byte(`{"sources": [{"path": "in.mp4"}], "outputs": [{"path": "out.mp4", "codek": "vp9"}]}`))
+	assertEquals(t, err != nil, true)
+	_, err = ParsePipeline([]byte(`{"sources": [{"path": "in.mp4"}], "outputs": []}`))
+	assertEquals(t, err != nil, true)
+	_, err = ParsePipeline([]byte(`{"sources": [{"path": "in.mp4"}], "outputs": [{"path": "out.mp4"}], "limits": {"timeout": "soon"}}`))
+	assertEquals(t, err != nil, true)
+}
+
+func TestKenBurns(t *testing.T) {
+	from, to := image.Rect(0, 0, 8, 4), image.Rect(4, 2, 8, 4)
+	assertEquals(t, panRect(from, to, 0), [4]float64{0, 0, 8, 4})
+	assertEquals(t, panRect(from, to, 1), [4]float64{4, 2, 4, 2})
+	// Eased: the midpoint is halfway, the first quarter moves less than a quarter of the way.
+	assertEquals(t, panRect(from, to, 0.5), [4]float64{2, 1, 6, 3})
+	assertEquals(t, panRect(from, to, 0.25)[0] < 1, true)
+
+	src := image.NewRGBA(image.Rect(0, 0, 8, 4))
+	for y := 0; y < 4; y++ {
+		for x := 0; x < 8; x++ {
+			src.Set(x, y, color.RGBA{uint8(x * 30), uint8(y * 60), 0, 255})
+		}
+	}
+
+	// The full region reproduces the image.
+	dst := image.NewRGBA(image.Rect(0, 0, 8, 4))
+	panFrame(dst, src, panRect(from, to, 0))
+	assertEquals(t, bytes.Equal(dst.Pix, src.Pix), true)
+
+	// Zoomed in 2x on the bottom right quarter. The first pixel center maps to x=3.75.
+	panFrame(dst, src, panRect(from, to, 1))
+	assertEquals(t, dst.RGBAAt(0, 0).R, uint8(113))
+	assertEquals(t, dst.RGBAAt(7, 3), src.RGBAAt(7, 3))
+
+	err := KenBurns(src, 1, from, image.Rect(4, 2, 12, 6), "out.mp4", nil)
+	assertEquals(t, err != nil, true)
+}
+
+func TestSlideshow(t *testing.T) {
+	dir := t.TempDir()
+	images := []string{}
+	for _, name := range []string{"a.png", "b.png", "c.png", "music.mp3"} {
+		filename := filepath.Join(dir, name)
+		if err := os.WriteFile(filename, nil, 0644); err != nil {
+			t.Fatal(err)
+		}
+		images = append(images, filename)
+	}
+	audio := images[3]
+	images = images[:3]
+
+	commands := [][]string{}
+	options := &SlideshowOptions{Width: 640, Height: 360, Transition: "wipeleft"}
+	if err := Slideshow(images, 3, audio, options, "show.mp4", WithDryRun(&commands)); err != nil {
+		t.Fatal(err)
+	}
+	command := strings.Join(commands[0], " ")
+	assertEquals(t, strings.Contains(command, "-loop 1 -t 4 -i "+images[0]), true)
+	assertEquals(t, strings.Contains(command, "-loop 1 -t 3 -i "+images[2]), true)
+	assertEquals(t, strings.Contains(command, "[0:v]scale=640:360:force_original_aspect_ratio=decrease,pad=640:360"), true)
+	assertEquals(t, strings.Contains(command, "[s0][s1]xfade=transition=wipeleft:duration=1:offset=3[x1]"), true)
+	assertEquals(t, strings.Contains(command, "[x1][s2]xfade=transition=wipeleft:duration=1:offset=6[v]"), true)
+	assertEquals(t, strings.Contains(command, "[3:a]atrim=0:9,afade=t=out:st=7:d=2[a]"), true)
+	assertEquals(t, strings.HasSuffix(command, "-map [v] -map [a] -c:v libx264 -pix_fmt yuv420p -r 25 -c:a aac show.mp4"), true)
+
+	commands = [][]string{}
+	if err := Slideshow(images, 2, "", &SlideshowOptions{Transition: "none"}, "show.mp4", WithDryRun(&commands)); err != nil {
+		t.Fatal(err)
+	}
+	assertEquals(t, strings.Contains(strings.Join(commands[0], " "), "[s0][s1][s2]concat=n=3:v=1:a=0[v]"), true)
+
+	assertEquals(t, Slideshow(images, 1, "", nil, "show.mp4", WithDryRun(&commands)) != nil, true)
+}
+
+func TestBurst(t *testing.T) {
+	assertEquals(t, burstStart(10, 5, 0.5), 9.0)
+	assertEquals(t, burstStart(10, 1, 0.5), 10.0)
+	// Bursts near the start begin at the first frame.
+	assertEquals(t, burstStart(0.5, 5, 0.5), 0.0)
+
+	_, err := Burst("video.mp4", 10, 0, 0.5)
+	assertEquals(t, err != nil, true)
+}
+
+func TestOrientation(t *testing.T) {
+	assertEquals(t, rotation(map[string]string{"tag:rotate": "90"}), 90)
+	assertEquals(t, rotation(map[string]string{"rotation": "-90"}), 270)
+	assertEquals(t, rotation(map[string]string{}), 0)
+	assertEquals(t, parseRatio("4:3"), 4.0/3)
+	assertEquals(t, parseRatio("0:1"), 1.0)
+	assertEquals(t, parseRatio("N/A"), 1.0)
+
+	// A portrait phone clip stored as 1920x1080 with a rotation of 90 degrees.
+	video := &Video{}
+	video.addVideoData(map[string]string{"width": "1920", "height": "1080", "rotation": "-90", "sample_aspect_ratio": "1:1"})
+	assertEquals(t, video.Width(), 1080)
+
+	input, filter, width, height := video.orientation(newConfig(nil))
+	assertEquals(t, len(input), 0)
+	assertEquals(t, filter, "")
+	assertEquals(t, [2]int{width, height}, [2]int{1080, 1920})
+
+	input, _, width, height = video.orientation(newConfig([]Option{WithoutAutoOrient()}))
+	assertEquals(t, input[0], "-noautorotate")
+	assertEquals(t, [2]int{width, height}, [2]int{1920, 1080})
+
+	// Anamorphic DV: 720x480 with 32:27 pixels displays as 853x480, rounded to an even 852.
+	video = &Video{}
+	video.addVideoData(map[string]string{"width": "720", "height": "480", "sample_aspect_ratio": "32:27"})
+	_, filter, width, height = video.orientation(newConfig(nil))
+	assertEquals(t, filter, sarFilter)
+	assertEquals(t, [2]int{width, height}, [2]int{852, 480})
+}
+
+func TestURLPolicy(t *testing.T) {
+	policy := URLPolicy{AllowHosts: []string{".example.com", "127.0.0.1"}}
+	assertEquals(t, policy.Check("https://93.184.216.34/video.mp4") != nil, true)
+	assertEquals(t, policy.Check("ftp://cdn.example.com/video.mp4") != nil, true)
+	assertEquals(t, policy.Check("concat:a.mp4|b.mp4") != nil, true)
+	assertEquals(t, policy.Check("/etc/passwd") != nil, true)
+	// Allowed hosts must still be public.
+	assertEquals(t, policy.Check("http://127.0.0.1:8080/admin") != nil, true)
+
+	policy = URLPolicy{AllowPrivate: true, AllowFiles: true, DenyHosts: []string{"metadata.internal"}}
+	assertEquals(t, policy.Check("http://127.0.0.1:8080/video.mp4"), nil)
+	assertEquals(t, policy.Check("video.mp4"), nil)
+	assertEquals(t, policy.Check("http://metadata.internal/latest") != nil, true)
+
+	assertEquals(t, URLPolicy{}.Check("http://10.0.0.1/video.mp4") != nil, true)
+	assertEquals(t, URLPolicy{}.Check("http://[::1]/video.mp4") != nil, true)
+	assertEquals(t, URLPolicy{}.Check("http://169.254.169.254/latest/meta-data") != nil, true)
+	assertEquals(t, URLPolicy{}.Check("https://93.184.216.34/video.mp4"), nil)
+
+	assertEquals(t, protocolWhitelist("upload.m3u8"), "file,crypto,data")
+	assertEquals(t, protocolWhitelist("HTTPS://example.com/live.m3u8"), "http,https,tcp,tls,crypto")
+	assertEquals(t, protocolWhitelist("srt://example.com:9000"), "srt,udp")
+
+	_, err := NewVideo("/etc/passwd", WithURLPolicy(URLPolicy{}))
+	assertEquals(t, err != nil && strings.Contains(err.Error(), "not allowed"), true)
+
+	// The policy applies to every ffmpeg input, except files the operation writes itself.
+	var commands [][]string
+	err = SetMetadata("test/koala.mp4", "out.mp4", map[string]string{"title": "Koala"}, nil,
+		WithURLPolicy(URLPolicy{}), WithDryRun(&commands))
+	assertEquals(t, err != nil && strings.Contains(err.Error(), "not allowed"), true)
+	assertEquals(t, len(commands), 0)
+	err = SetMetadata("test/koala.mp4", "out.mp4", map[string]string{"title": "Koala"}, nil,
+		WithURLPolicy(URLPolicy{AllowFiles: true}), WithDryRun(&commands))
+	assertEquals(t, err, nil)
+	assertEquals(t, len(commands), 1)
+
+	// Sources are checked before they are probed.
+	policy = URLPolicy{}
+	private := func(err error) bool {
+		return err != nil && strings.Contains(err.Error(), "private address")
+	}
+	_, err = Burst("http://169.254.169.254/latest", 1, 3, 0.5, WithURLPolicy(policy))
+	assertEquals(t, private(err), true)
+	assertEquals(t, private(PreviewClip("http://169.254.169.254/latest", "preview.gif", 3, 1, WithURLPolicy(policy))), true)
+	_, _, err = BestThumbnailFrame("http://169.254.169.254/latest", WithURLPolicy(policy))
+	assertEquals(t, private(err), true)
+	_, err = AutoChapters("http://169.254.169.254/latest", 10, WithURLPolicy(policy))
+	assertEquals(t, private(err), true)
+}
+
+func TestSandbox(t *testing.T) {
+	var none *Sandbox
+	cmd, err := none.command(nil, "ffmpeg", "-version")
+	assertEquals(t, err, nil)
+	assertEquals(t, cmd.Env == nil, true)
+
+	sandbox := &Sandbox{Wrapper: []string{"firejail", "--quiet", "--net=none"}, Dir: os.TempDir()}
+	cmd, err = sandbox.command(context.Background(), "ffmpeg", "-i", "upload.mp4")
+	assertEquals(t, err, nil)
+	assertEquals(t, strings.Join(cmd.Args, " "), "firejail --quiet --net=none ffmpeg -i upload.mp4")
+	assertEquals(t, cmd.Dir, os.TempDir())
+	// Only PATH is passed by default.
+	assertEquals(t, len(cmd.Env), 1)
+	assertEquals(t, strings.HasPrefix(cmd.Env[0], "PATH="), true)
+
+	sandbox = &Sandbox{User: fmt.Sprint(os.Getuid())}
+	uid, _, err := sandbox.credentials()
+	if err == nil {
+		assertEquals(t, int(uid), os.Getuid())
+	}
+	_, err = (&Sandbox{User: "no-such-vidio-user"}).command(nil, "ffmpeg")
+	assertEquals(t, err != nil, true)
+
+	// Failing to create the ffmpeg command is reported instead of starting a nil command.
+	video := &Video{
+		filename:         "test/koala.mp4",
+		width:            4,
+		height:           4,
+		depth:            4,
+		frames:           10,
+		pixfmt:           PixelRGBA,
+		sandbox:          &Sandbox{User: "no-such-vidio-user"},
+		live:             newLiveFile("test/koala.mp4", time.Second, 1, 10),
+		closeCleanupChan: make(chan struct{}),
+	}
+	assertEquals(t, video.ReadFrame(1) != nil, true)
+	_, err = video.ReadFrames(1, 2)
+	assertEquals(t, err != nil, true)
+	assertEquals(t, video.Read(), false)
+	// The live file is no longer watched.
+	assertEquals(t, video.live.stopped, true)
+	close(video.closeCleanupChan)
+}
+
+func TestUploadPolicy(t *testing.T) {
+	result := probeResult(
+		map[string]string{"format_name": "mov,mp4,m4a,3gp,3g2,mj2", "duration": "12.5"},
+		[]map[string]string{
+			{"index": "0", "codec_type": "video", "codec_name": "h264", "width": "1920", "height": "1080"},
+			{"index": "1", "codec_type": "audio", "codec_name": "aac"},
+		},
+	)
+	assertEquals(t, result.Width, 1920)
+	assertEquals(t, result.Duration, 12.5)
+	assertEquals(t, len(result.Streams), 2)
+
+	policy := Policy{
+		MaxDuration:     60,
+		MaxWidth:        3840,
+		MaxHeight:       2160,
+		Formats:         []string{"mp4", "webm"},
+		VideoCodecs:     []string{"h264", "vp9"},
+		AudioCodecs:     []string{"aac", "opus"},
+		MaxAudioStreams: 1,
+		RequireVideo:    true,
+	}
+	assertEquals(t, policy.check(result), nil)
+
+	rejects := func(policy Policy) bool {
+		err := policy.check(result)
+		return err != nil && strings.Contains(err.Error(), "upload rejected")
+	}
+	assertEquals(t, rejects(Policy{MaxDuration: 10}), true)
+	assertEquals(t, rejects(Policy{MaxWidth: 1280}), true)
+	assertEquals(t, rejects(Policy{Formats: []string{"matroska"}}), true)
+	assertEquals(t, rejects(Policy{VideoCodecs: []string{"vp9"}}), true)
+	assertEquals(t, rejects(Policy{AudioCodecs: []string{"opus"}}), true)
+	assertEquals(t, rejects(Policy{MaxStreams: 1}), true)
+	assertEquals(t, rejects(Policy{MaxVideoStreams: 1}), false)
+	assertEquals(t, (Policy{}).check(&ProbeResult{}) != nil, true)
+}
+
+func TestLimits(t *testing.T) {
+	c := newConfig([]Option{WithLimits(3840, 2160, 1000, 60)})
+
+	video := &Video{filename: "bomb.mkv", width: 65536, height: 65536}
+	assertEquals(t, c.limits.apply(video) != nil, true)
+	video = &Video{filename: "unknown.mkv"}
+	assertEquals(t, c.limits.apply(video) != nil, true)
+
+	video = &Video{filename: "long.mp4", width: 1920, height: 1080, frames: 90000, duration: 3600}
+	assertEquals(t, c.limits.apply(video), nil)
+	assertEquals(t, video.Frames(), 1000)
+	assertEquals(t, video.Duration(), 60.0)
+	assertEquals(t, strings.Join(c.limits.outputArgs(), " "), "-frames:v 1000 -t 60")
+
+	var none *limits
+	assertEquals(t, none.apply(video), nil)
+	assertEquals(t, len(none.outputArgs()), 0)
+}
+
+func TestTeeDelivery(t *testing.T) {
+	frame := func(n byte) []byte {
+		return []byte{n}
+	}
+
+	latest := make(chan []byte, 2)
+	for n := byte(1); n <= 4; n++ {
+		deliver(latest, frame(n), DropOldest)
+	}
+	assertEquals(t, (<-latest)[0], byte(3))
+	assertEquals(t, (<-latest)[0], byte(4))
+
+	first := make(chan []byte, 2)
+	for n := byte(1); n <= 4; n++ {
+		deliver(first, frame(n), DropNewest)
+	}
+	assertEquals(t, (<-first)[0], byte(1))
+	assertEquals(t, (<-first)[0], byte(2))
+	assertEquals(t, len(first), 0)
+
+	blocking := make(chan []byte)
+	go deliver(blocking, frame(7), DropNone)
+	assertEquals(t, (<-blocking)[0], byte(7))
+}
+
+func TestVideoTee(t *testing.T) {
+	video, err := NewVideo("test/koala.mp4")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	frames := video.Tee(2, TeePolicy{Buffer: 4}, TeePolicy{Buffer: 1, Drop: DropOldest})
+	counts := make(chan int)
+	for _, channel := range frames {
+		go func(channel <-chan []byte) {
+			count := 0
+			for frame := range channel {
+				assertEquals(t, len(frame), video.Width()*video.Height()*4)
+				count++
+			}
+			counts <- count
+		}(channel)
+	}
+
+	total := <-counts + <-counts
+	assertEquals(t, total > video.Frames(), true)
+	assertEquals(t, total <= 2*video.Frames(), true)
+}
+
+func TestVideoState(t *testing.T) {
+	video := &Video{filename: "batch.mp4", fps: 25, frames: 1000}
+	video.delivered.Store(250)
+
+	state := video.SaveState()
+	assertEquals(t, state, VideoState{Filename: "batch.mp4", Frame: 250, Time: 10})
+
+	resumed := &Video{filename: "batch.mp4", fps: 25, frames: 1000}
+	assertEquals(t, resumed.ResumeFrom(state), nil)
+	assertEquals(t, resumed.SaveState().Frame, 250)
+	assertEquals(t, strings.Join(seekArgs(resumed.start, resumed.fps), " "), "-ss 9.980000")
+	assertEquals(t, len(seekArgs(0, 25)), 0)
+
+	assertEquals(t, resumed.ResumeFrom(VideoState{Filename: "other.mp4"}) != nil, true)
+	assertEquals(t, resumed.ResumeFrom(VideoState{Filename: "batch.mp4", Frame: 2000}) != nil, true)
+}
+
+func TestPlaylistNormalize(t *testing.T) {
+	_, err := NewPlaylist(nil)
+	assertEquals(t, err != nil, true)
+
+	video := &Video{width: 1280, height: 720, fps: 25}
+	assertEquals(t, normalizeFilter(video, 1280, 720, 25), "")
+	assertEquals(t, normalizeFilter(video, 1920, 1080, 25),
+		"scale=1920:1080:force_original_aspect_ratio=decrease,pad=1920:1080:(ow-iw)/2:(oh-ih)/2,setsar=1")
+	assertEquals(t, normalizeFilter(video, 1280, 720, 29.97), "fps=29.97")
+	assertEquals(t, normalizeFilter(video, 640, 360, 30),
+		"scale=640:360:force_original_aspect_ratio=decrease,pad=640:360:(ow-iw)/2:(oh-ih)/2,setsar=1,fps=30")
+}
+
+func TestConcatVideo(t *testing.T) {
+	// Only the list created by NewConcatVideo is read with "-safe 0".
+	assertEquals(t, strings.Join(protocolArgs("chapters.ffconcat"), " "), "-protocol_whitelist file,crypto,data")
+	list, err := writeConcatList([]concatEntry{{filename: "test/koala.mp4"}})
+	assertEquals(t, err, nil)
+	defer os.Remove(list)
+	video := &Video{filename: list, concatlist: list, width: 2, height: 2, depth: 4, frames: 1,
+		framebuffer: make([]byte, 16), pipe: io.NopCloser(bytes.NewReader(make([]byte, 16))), cmd: &exec.Cmd{},
+		closeCleanupChan: make(chan struct{}, 1)}
+	assertEquals(t, strings.Join(video.inputArgs(), " "), "-f concat -safe 0 -protocol_whitelist file,crypto,data")
+	assertEquals(t, strings.Join((&Video{filename: list}).inputArgs(), " "), "-protocol_whitelist file,crypto,data")
+
+	// The list outlives reading to the end, so the video can be reset.
+	assertEquals(t, video.Read(), true)
+	assertEquals(t, video.Read(), false)
+	video.Reset()
+	assertEquals(t, exists(list), true)
+	assertEquals(t, strings.Join(video.inputArgs(), " "), "-f concat -safe 0 -protocol_whitelist file,crypto,data")
+
+	// Closing the video removes the list.
+	video.Close()
+	assertEquals(t, exists(list), false)
+
+	_, err = NewConcatVideo(nil)
+	assertEquals(t, err != nil, true)
+	_, err = NewConcatVideo([]string{"https://example.com/a.mp4"})
+	assertEquals(t, err != nil, true)
+}
+
+func TestChapteredSet(t *testing.T) {
+	dir := t.TempDir()
+	create := func(name string, size int64) {
+		file, err := os.Create(filepath.Join(dir, name))
+		if err != nil {
+			t.Fatal(err)
+		}
+		file.Close()
+		// Sparse, so split sized files take no space.
+		if err := os.Truncate(file.Name(), size); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for _, name := range []string{"GH020042.MP4", "GH010042.MP4", "GH010043.MP4", "GX010042.MP4", "GH010042.LRV", "GOPR0007.MP4", "GP010007.MP4", "GP020007.MP4", "notes.txt"} {
+		create(name, 1)
+	}
+	create("DJI_0001.MP4", djiSplitSize)
+	create("DJI_0002.MP4", djiSplitSize)
+	create("DJI_0003.MP4", 10)
+	create("DJI_0004.MP4", 10)
+
+	detect := func(name string) string {
+		files, err := DetectChapteredSet(filepath.Join(dir, name))
+		if err != nil {
+			t.Fatal(err)
+		}
+		names := []string{}
+		for _, file := range files {
+			names = append(names, filepath.Base(file))
+		}
+		return strings.Join(names, " ")
+	}
+	assertEquals(t, detect("GH020042.MP4"), "GH010042.MP4 GH020042.MP4")
+	assertEquals(t, detect("GH010043.MP4"), "GH010043.MP4")
+	assertEquals(t, detect("GP010007.MP4"), "GOPR0007.MP4 GP010007.MP4 GP020007.MP4")
+	assertEquals(t, detect("DJI_0002.MP4"), "DJI_0001.MP4 DJI_0002.MP4 DJI_0003.MP4")
+	assertEquals(t, detect("DJI_0004.MP4"), "DJI_0004.MP4")
+	assertEquals(t, detect("notes.txt"), "notes.txt")
+
+	_, err := DetectChapteredSet(filepath.Join(dir, "missing.MP4"))
+	assertEquals(t, err != nil, true)
+
+	commands := [][]string{}
+	assertEquals(t, JoinChapters(filepath.Join(dir, "GH010042.MP4"), "joined.mp4", WithDryRun(&commands)), nil)
+	assertEquals(t, len(commands), 1)
+	defer os.Remove(commands[0][10])
+	assertEquals(t, strings.Join(commands[0][:10], " "), "ffmpeg -hide_banner -loglevel error -y -f concat -safe 0 -i")
+	assertEquals(t, strings.Join(commands[0][11:], " "), "-map 0:v -map 0:a? -c copy joined.mp4")
+}
+
+func TestTimecode(t *testing.T) {
+	timecode, err := ParseTimecode("01:00:00:00", 25)
+	assertEquals(t, err, nil)
+	assertEquals(t, timecode.FrameNumber(), 90000)
+	assertEquals(t, timecode.Add(24).String(), "01:00:00:24")
+	assertEquals(t, timecode.Add(25).String(), "01:00:01:00")
+	assertEquals(t, timecode.Add(-1).String(), "00:59:59:24")
+	assertEquals(t, timecode.Add(23*3600*25).String(), "00:00:00:00")
+
+	drop, err := ParseTimecode("00:00:59;29", 30000.0/1001)
+	assertEquals(t, err, nil)
+	assertEquals(t, drop.Rate, 30)
+	assertEquals(t, drop.Add(1).String(), "00:01:00;02")
+	assertEquals(t, drop.Add(1).FrameNumber(), drop.FrameNumber()+1)
+	assertEquals(t, drop.Add(17982).String(), "00:10:59;29")
+	tenth, _ := ParseTimecode("00:09:59;29", 29.97)
+	assertEquals(t, tenth.Add(1).String(), "00:10:00;00")
+
+	for _, value := range []string{"01:00:00", "01:00:00:25", "24:00:00:00", "aa:00:00:00"} {
+		_, err := ParseTimecode(value, 25)
+		assertEquals(t, err != nil, true)
+	}
+	_, err = ParseTimecode("00:00:00;00", 25)
+	assertEquals(t, err != nil, true)
+	_, err = ParseTimecode("00:01:00;01", 29.97)
+	assertEquals(t, err != nil, true)
+
+	video := &Video{fps: 25, metadata: map[string]string{"tag:timecode": "10:00:00:00"}}
+	frame, ok := video.FrameTimecode(50)
+	assertEquals(t, ok, true)
+	assertEquals(t, frame.String(), "10:00:02:00")
+}
+
+func TestFrameNumberFilter(t *testing.T) {
+	timecode, _ := ParseTimecode("00:59:58;00", 29.97)
+	filter := frameNumberFilter(timecode, "30000/1001")
+	assertEquals(t, strings.Contains(filter, `drawtext=timecode='00\:59\:58\;00':rate=30000/1001:`), true)
+	assertEquals(t, strings.Contains(filter, "drawtext=text='%{frame_num}':start_number=0:"), true)
+	assertEquals(t, strings.HasPrefix(filter, "drawbox="), true)
+}
+
+func TestTelecine(t *testing.T) {
+	frames := func(pattern ...string) string {
+		output := ""
+		for i := 0; i < 100; i++ {
+			fields := strings.Split(pattern[i%len(pattern)], "/")
+			output += fmt.Sprintf("frame:%d pts:%d pts_time:%d\n", i, i, i)
+			output += "lavfi.idet.multiple.current_frame=" + fields[0] + "\n"
+			output += "lavfi.idet.repeated.current_frame=" + fields[1] + "\n"
+		}
+		return output
+	}
+
+	report := parseScanReport(frames("progressive/neither", "progressive/neither", "tff/top", "tff/bottom", "progressive/neither"))
+	assertEquals(t, *report, ScanReport{Type: ScanTelecine, Frames: 100, Progressive: 60, Interlaced: 40, Repeated: 40})
+	assertEquals(t, parseScanReport(frames("tff/neither", "undetermined/neither")).Type, ScanInterlaced)
+	assertEquals(t, parseScanReport(frames("progressive/neither", "undetermined/neither")).Type, ScanProgressive)
+	assertEquals(t, ScanTelecine.String(), "telecine")
+
+	commands := [][]string{}
+	err := EncodeLadder("test/koala.mp4", []Rendition{{Output: "out.mp4", Bitrate: 1000000}}, WithInverseTelecine(), WithDryRun(&commands))
+	assertEquals(t, err, nil)
+	assertEquals(t, strings.Contains(strings.Join(commands[0], " "), "[0:v]"+ivtcFilter+",split=1[s0]"), true)
+}
+
+func TestDenoise(t *testing.T) {
+	filter, err := newConfig([]Option{WithDenoise(DenoiseMedium)}).videoFilter()
+	assertEquals(t, err, nil)
+	assertEquals(t, filter, "hqdn3d=4:3:6:4.5")
+
+	filter, _ = newConfig([]Option{WithInverseTelecine(), WithDenoise(DenoiseHeavy)}).videoFilter()
+	assertEquals(t, filter, ivtcFilter+",nlmeans=s=6:p=7:r=15")
+
+	// Frames read by index are selected from the filtered, decimated stream.
+	video := &Video{filename: "film.mkv", frames: 1000, filter: filter}
+	c := newConfig([]Option{WithInverseTelecine()})
+	assertEquals(t, c.decodeSize(video), nil)
+	assertEquals(t, video.Frames(), 800)
+	selection, err := video.selectFilter(799)
+	assertEquals(t, err, nil)
+	assertEquals(t, selection, ivtcFilter+",nlmeans=s=6:p=7:r=15,select='eq(n\\,799)'")
+
+	_, err = newConfig([]Option{WithDenoise("extreme")}).videoFilter()
+	assertEquals(t, err != nil, true)
+	assertEquals(t, EncodeLadder("test/koala.mp4", []Rendition{{Output: "out.mp4", Bitrate: 1000000}}, WithDenoise("extreme")) != nil, true)
+}
+
+func TestSpectrogram(t *testing.T) {
+	commands := [][]string{}
+	img, err := Spectrogram("test/koala.mp4", 640, 240, WithDryRun(&commands))
+	assertEquals(t, err, nil)
+	assertEquals(t, img.Bounds(), image.Rect(0, 0, 640, 240))
+	assertEquals(t, strings.Join(commands[0][4:], " "),
+		"-i test/koala.mp4 -filter_complex [0:a:0]showspectrumpic=s=640x240:legend=0:mode=combined:color=intensity:scale=log[v] -map [v] -frames:v 1 -f rawvideo -pix_fmt rgba -")
+
+	_, err = Spectrogram("test/koala.mp4", 0, 240)
+	assertEquals(t, err != nil, true)
+}
+
+func TestWaveform(t *testing.T) {
+	// Half a second of silence, then a full scale square wave.
+	samples := make([]float32, 200)
+	for i := 100; i < 200; i++ {
+		samples[i] = float32(1 - 2*(i%2))
+	}
+	img := drawWaveform(samples, 10, 21, color.RGBA{0, 128, 255, 255})
+	assertEquals(t, img.RGBAAt(2, 10), color.RGBA{0, 128, 255, 255})
+	assertEquals(t, img.RGBAAt(2, 9), color.RGBA{})
+	assertEquals(t, img.RGBAAt(7, 0), color.RGBA{0, 128, 255, 255})
+	assertEquals(t, img.RGBAAt(7, 20), color.RGBA{0, 128, 255, 255})
+
+	assertEquals(t, peakValue(1), 127)
+	assertEquals(t, peakValue(-1), -128)
+	assertEquals(t, peakValue(0.5), 64)
+
+	data, err := json.Marshal(&Peaks{Version: 2, Channels: 1, SampleRate: 22050, SamplesPerPixel: 256, Bits: 8, Length: 1, Data: []int{-3, 5}})
+	assertEquals(t, err, nil)
+	assertEquals(t, string(data), `{"version":2,"channels":1,"sample_rate":22050,"samples_per_pixel":256,"bits":8,"length":1,"data":[-3,5]}`)
+
+	_, err = WaveformPeaks("test/koala.mp4", 0)
+	assertEquals(t, err != nil, true)
+}
+
+func TestDetectBeats(t *testing.T) {
+	// Clicks at 120 BPM, starting at 0.25s, over quiet noise.
+	rate := 22050
+	samples := make([]float32, rate*8)
+	for i := range samples {
+		samples[i] = float32(0.01 * math.Sin(float64(i)*0.37))
+	}
+	for start := rate / 4; start < len(samples); start += rate / 2 {
+		for i := start; i < start+rate/50 && i < len(samples); i++ {
+			samples[i] = float32(0.8 * math.Sin(float64(i-start)*0.5))
+		}
+	}
+
+	beats := detectBeats(samples, rate)
+	assertEquals(t, len(beats) >= 14 && len(beats) <= 16, true)
+	for i, beat := range beats {
+		expected := 0.25 + 0.5*float64(i)
+		if math.Abs(beat-expected) > 0.03 {
+			t.Errorf("Expected beat %d at %v, got %v", i, expected, beat)
+		}
+	}
+	assertEquals(t, len(detectBeats(make([]float32, rate), rate)), 0)
+}
+
+func TestSpeechSegments(t *testing.T) {
+	// Speech from 1s to 2s with a short pause at 1.5s, a click at 3s and speech from 4s to 5s.
+	rate := 16000
+	samples := make([]float32, rate*6)
+	for i := range samples {
+		t := float64(i) / float64(rate)
+		samples[i] = float32(0.002 * math.Sin(float64(i)*1.3))
+		if t >= 1 && t < 2 && (t < 1.5 || t >= 1.6) || t >= 3 && t < 3.05 || t >= 4 && t < 5 {
+			samples[i] = float32(0.3 * math.Sin(float64(i)*0.2))
+		}
+	}
+
+	segments := speechSegments(samples, rate)
+	assertEquals(t, len(segments), 2)
+	for i, expected := range []SpeechSegment{{0.9, 2.1}, {3.9, 5.1}} {
+		if math.Abs(segments[i].Start-expected.Start) > 0.04 || math.Abs(segments[i].End-expected.End) > 0.04 {
+			t.Errorf("Expected segment %v, got %v", expected, segments[i])
+		}
+	}
+	assertEquals(t, len(speechSegments(make([]float32, rate), rate)), 0)
+
+	commands := [][]string{}
+	files, err := ExtractSegmentsAudio("test/koala.mp4", []SpeechSegment{{0.5, 1.25}, {2, 3}}, "segments", WithDryRun(&commands))
+	assertEquals(t, err, nil)
+	assertEquals(t, strings.Join(files, " "), filepath.Join("segments", "segment-0001.wav")+" "+filepath.Join("segments", "segment-0002.wav"))
+	assertEquals(t, strings.Contains(strings.Join(commands[0], " "), "-map 0:a:0 -ss 0.5 -to 1.25 -ac 1 -ar 16000 -c:a pcm_s16le "+files[0]), true)
+}
+
+func TestTranscribe(t *testing.T) {
+	chunks := transcriptionChunks([]SpeechSegment{{0, 10}, {12, 25}, {26, 40}, {41, 100}}, 30)
+	assertEquals(t, fmt.Sprint(chunks), "[{0 25} {26 40} {41 71} {71 100}]")
+
+	captions := []Caption{{Start: 1.5, End: 3.25, Text: "Hello there. "}, {Start: 3661.001, End: 3662, Text: "Bye"}}
+	srt := bytes.Buffer{}
+	assertEquals(t, WriteSRT(&srt, captions), nil)
+	assertEquals(t, srt.String(), "1\n00:00:01,500 --> 00:00:03,250\nHello there.\n\n2\n01:01:01,001 --> 01:01:02,000\nBye\n\n")
+	vtt := bytes.Buffer{}
+	assertEquals(t, WriteVTT(&vtt, captions[:1]), nil)
+	assertEquals(t, vtt.String(), "WEBVTT\n\n00:00:01.500 --> 00:00:03.250\nHello there.\n\n")
+}
+
+func TestCanvas(t *testing.T) {
+	frame := make([]byte, 20*20*4)
+	canvas, err := NewCanvas(frame, 20, 20, PixelRGBA)
+	assertEquals(t, err, nil)
+	img := &image.RGBA{Pix: frame, Stride: 80, Rect: image.Rect(0, 0, 20, 20)}
+
+	red := color.RGBA{255, 0, 0, 255}
+	canvas.Rect(image.Rect(2, 2, 12, 12), red, 2)
+	assertEquals(t, img.RGBAAt(2, 2), red)
+	assertEquals(t, img.RGBAAt(3, 7), red)
+	assertEquals(t, img.RGBAAt(4, 7), color.RGBA{})
+	assertEquals(t, img.RGBAAt(11, 11), red)
+	assertEquals(t, img.RGBAAt(12, 12), color.RGBA{})
+
+	// Half transparent white over black, drawn once where the diagonal segments overlap.
+	canvas.Polyline([]image.Point{{14, 0}, {19, 5}, {14, 10}}, color.RGBA{128, 128, 128, 128}, 3, false)
+	assertEquals(t, img.RGBAAt(19, 5), color.RGBA{128, 128, 128, 128})
+	assertEquals(t, img.RGBAAt(16, 2), color.RGBA{128, 128, 128, 128})
+
+	canvas.SetClip(image.Rect(0, 15, 20, 20))
+	canvas.FillRect(image.Rect(-5, 10, 30, 30), color.RGBA{0, 0, 255, 255})
+	assertEquals(t, img.RGBAAt(0, 14), color.RGBA{})
+	assertEquals(t, img.RGBAAt(0, 15), color.RGBA{0, 0, 255, 255})
+
+	assertEquals(t, LabelSize("car 97%", LabelStyle{}), image.Point{86, 18})
+	rgb := make([]byte, 40*12*3)
+	canvas, _ = NewCanvas(rgb, 40, 12, PixelRGB24)
+	rect := canvas.Label(image.Point{1, 1}, "1", LabelStyle{Scale: 1, Padding: 1, Background: color.Black, Color: color.RGBA{0, 255, 0, 255}})
+	assertEquals(t, rect, image.Rect(1, 1, 8, 10))
+	// The stem of the "1" is the third column of the glyph.
+	assertEquals(t, fmt.Sprint(rgb[(4*40+4)*3:(4*40+4)*3+3]), "[0 255 0]")
+	assertEquals(t, fmt.Sprint(rgb[(4*40+3)*3:(4*40+3)*3+3]), "[0 0 0]")
+
+	_, err = NewCanvas(make([]byte, 10), 20, 20, PixelRGBA)
+	assertEquals(t, err != nil, true)
+}
+
+func TestAnnotations(t *testing.T) {
+	frame := make([]byte, 64*48*4)
+	canvas, _ := NewCanvas(frame, 64, 48, PixelRGBA)
+	img := &image.RGBA{Pix: frame, Stride: 64 * 4, Rect: image.Rect(0, 0, 64, 48)}
+	annotations := []Annotation{
+		{ID: 1, Label: "car", Score: 0.97, Box: image.Rect(10, 20, 40, 40), Track: []image.Point{{0, 47}, {25, 30}}},
+		{Label: "person", Box: image.Rect(50, 0, 60, 10), Color: color.RGBA{255, 255, 255, 255}},
+	}
+	renderAnnotations(canvas, annotations, 2, LabelStyle{Scale: 1})
+
+	assertEquals(t, img.RGBAAt(10, 20), annotationPalette[1])
+	assertEquals(t, img.RGBAAt(25, 30), annotationPalette[1])
+	// The label is above the first box and inside the second one, which touches the top of the frame.
+	assertEquals(t, img.RGBAAt(10, 19), annotationPalette[1])
+	assertEquals(t, img.RGBAAt(10, 10), color.RGBA{})
+	assertEquals(t, img.RGBAAt(55, 1), color.RGBA{255, 255, 255, 255})
+
+	data, err := json.Marshal(annotationFrame(50, 25, annotations))
+	assertEquals(t, err, nil)
+	assertEquals(t, string(data), `{"frame":50,"time":2,"annotations":[`+
+		`{"id":1,"label":"car","score":0.97,"box":[10,20,40,40],"track":[[0,47],[25,30]]},`+
+		`{"label":"person","box":[50,0,60,10]}]}`)
+}
+
+func TestRedactRegions(t *testing.T) {
+	commands := [][]string{}
+	err := RedactRegions("test/koala.mp4", "redacted.mp4", []TimedRect{
+		{Rect: image.Rect(10, 20, 74, 52), Start: 1, End: 2.5},
+		{Rect: image.Rect(-8, 0, 8, 8), Start: 3},
+	}, WithDryRun(&commands))
+	assertEquals(t, err, nil)
+	command := strings.Join(commands[0], " ")
+	assertEquals(t, strings.Contains(command, "-filter_complex [0:v]split=3[base][c0][c1];"+
+		"[c0]crop=64:32:10:20,scale=4:2,scale=64:32:flags=neighbor[p0];"+
+		"[base][p0]overlay=10:20:enable='between(t,1,2.5)'[r0];"+
+		"[c1]crop=8:8:0:0,scale=1:1,scale=8:8:flags=neighbor[p1];"+
+		"[r0][p1]overlay=0:0:enable='gte(t,3)'[v] -map [v] -map 0:a?"), true)
+
+	assertEquals(t, RedactRegions("test/koala.mp4", "redacted.mp4", []TimedRect{{Rect: image.Rect(0, 0, 8, 8), Start: 2, End: 1}}) != nil, true)
+	assertEquals(t, RedactRegions("test/koala.mp4", "redacted.mp4", nil) != nil, true)
+}
+
+func TestChromaKey(t *testing.T) {
+	frame := []byte{
+		0, 255, 0, 255, // Key color.
+		200, 30, 40, 255, // Far from the key.
+		60, 200, 60, 255, // Close to the key, within the blend range.
+	}
+	assertEquals(t, KeyFrame(frame, 3, 1, color.RGBA{0, 255, 0, 255}, 0.1, 0.3), nil)
+	assertEquals(t, fmt.Sprint(frame[:8]), "[0 0 0 0 200 30 40 255]")
+	assertEquals(t, frame[11] > 0 && frame[11] < 255, true)
+	assertEquals(t, keyAlpha(0.05, 0.1, 0), 0.0)
+	assertEquals(t, keyAlpha(0.15, 0.1, 0), 1.0)
+
+	commands := [][]string{}
+	err := ChromaKey("test/koala.mp4", "test/bananas.jpg", "keyed.mp4", color.RGBA{0, 255, 0, 255}, 0.1, 0.05, WithDryRun(&commands))
+	assertEquals(t, err, nil)
+	assertEquals(t, strings.Join(commands[0][4:], " "), "-y -i test/koala.mp4 -loop 1 -i test/bananas.jpg "+
+		"-filter_complex [1:v][0:v]scale2ref[bg][fg];[fg]chromakey=0x00FF00:0.1:0.05[keyed];[bg][keyed]overlay=shortest=1,format=yuv420p[v] "+
+		"-map [v] -map 0:a? -c:v libx264 -crf 18 -c:a copy keyed.mp4")
+}
+
+func TestMatte(t *testing.T) {
+	commands := [][]string{}
+	assertEquals(t, ExtractAlpha("test/koala.mp4", "matte.mp4", WithDryRun(&commands)), nil)
+	assertEquals(t, strings.Join(commands[0][4:], " "), "-y -i test/koala.mp4 -map 0:v:0 -vf alphaextract,format=gray -c:v libx264 -crf 12 -pix_fmt yuv420p matte.mp4")
+
+	assertEquals(t, ApplyMatte("test/koala.mp4", "test/koala-noaudio.mp4", "keyed.webm", WithDryRun(&commands)), nil)
+	assertEquals(t, strings.Join(commands[1][4:], " "), "-y -i test/koala.mp4 -i test/koala-noaudio.mp4 "+
+		"-filter_complex [1:v][0:v]scale2ref[m][v];[m]format=gray[a];[v][a]alphamerge[out] "+
+		"-map [out] -map 0:a? -c:v libvpx-vp9 -pix_fmt yuva420p -c:a libopus -shortest keyed.webm")
+	assertEquals(t, ApplyMatte("test/koala.mp4", "test/koala-noaudio.mp4", "keyed.mp4") != nil, true)
+}
+
+func TestMultiVideo(t *testing.T) {
+	angles := func(metadata ...map[string]string) []*Video {
+		videos := []*Video{}
+		for _, data := range metadata {
+			videos = append(videos, &Video{filename: "http://camera", fps: 25, metadata: data})
+		}
+		return videos
+	}
+
+	videos := angles(
+		map[string]string{"tag:timecode": "10:00:01:00", "tag:creation_time": "2024-05-01T10:00:00.000000Z"},
+		map[string]string{"tag:timecode": "10:00:00:05"},
+	)
+	assertEquals(t, fmt.Sprint(captureOffsets(videos)), "[800ms 0s]")
+
+	videos = angles(
+		map[string]string{"tag:creation_time": "2024-05-01T10:00:00.000000Z"},
+		map[string]string{"tag:creation_time": "2024-05-01T10:00:02.500000Z"},
+	)
+	assertEquals(t, fmt.Sprint(captureOffsets(videos)), "[0s 2.5s]")
+	assertEquals(t, fmt.Sprint(captureOffsets(angles(map[string]string{}, map[string]string{}))), "[0s 0s]")
+
+	multi := &MultiVideo{videos: videos, fps: 25}
+	assertEquals(t, multi.SetOffsets([]time.Duration{0, 2500 * time.Millisecond}), nil)
+	assertEquals(t, videos[0].start, 63)
+	assertEquals(t, videos[1].start, 0)
+	assertEquals(t, multi.SetOffsets([]time.Duration{0}) != nil, true)
+}
+
+func TestAlignByAudio(t *testing.T) {
+	rate := 8000
+	// Irregular clicks, recorded by a second camera started 1.25s later and a third started 0.4s earlier.
+	event := make([]float32, 12*rate)
+	for _, at := range []float64{0.5, 1.1, 2.6, 3.0, 4.7, 5.2, 6.9, 8.4, 8.8, 10.3} {
+		for i := 0; i < rate/50; i++ {
+			event[int(at*float64(rate))+i] = float32(math.Sin(float64(i) * 0.7))
+		}
+	}
+	second := event[int(1.25*float64(rate)):]
+	third := append(make([]float32, int(0.4*float64(rate))), event[:10*rate]...)
+
+	offsets := alignAudio([][]float32{event, second, third}, rate)
+	assertEquals(t, fmt.Sprint(offsets), "[400ms 1.65s 0s]")
+
+	assertEquals(t, correlationLag([]float64{0, 0, 1, 0, 0, 2, 0}, []float64{1, 0, 0, 2}), 2)
+	assertEquals(t, correlationLag([]float64{1, 0, 0, 2}, []float64{0, 0, 1, 0, 0, 2, 0}), -2)
+	_, err := AlignByAudio(nil)
+	assertEquals(t, err != nil, true)
+}
+
+func TestProjection(t *testing.T) {
+	filter, err := newConfig([]Option{WithProjection(Projection{Yaw: 45, Pitch: -10})}).videoFilter()
+	assertEquals(t, err, nil)
+	assertEquals(t, filter, "v360=input=e:output=flat:yaw=45:pitch=-10:roll=0:h_fov=90:v_fov=58.72:w=1280:h=720")
+
+	filter, _ = newConfig([]Option{WithDenoise(DenoiseLight), WithProjection(Projection{Input: "fisheye", FOV: 120, Width: 640, Height: 640})}).videoFilter()
+	assertEquals(t, filter, "hqdn3d=2:1.5:3:2.25,v360=input=fisheye:output=flat:yaw=0:pitch=0:roll=0:h_fov=120:v_fov=120:w=640:h=640")
+
+	_, err = newConfig([]Option{WithProjection(Projection{FOV: 180})}).videoFilter()
+	assertEquals(t, err != nil, true)
+}
+
+func TestProjectionDecoding(t *testing.T) {
+	// Oversized sources are rejected even if the rendered view is small.
+	c := newConfig([]Option{WithLimits(3840, 2160, 0, 0), WithProjection(Projection{})})
+	video := &Video{filename: "360.mp4", width: 7680, height: 3840}
+	assertEquals(t, c.decodeSize(video) != nil, true)
+	video = &Video{filename: "360.mp4", width: 3840, height: 1920}
+	assertEquals(t, c.decodeSize(video), nil)
+	assertEquals(t, fmt.Sprint(video.width, video.height), "1280 720")
+
+	if _, err := exec.LookPath("sh"); err != nil {
+		t.Skip("the test decoder is a shell script")
+	}
+	// The wrapper records its arguments and outputs two frames of 2x1 RGBA pixels instead of running ffmpeg.
+	dir := t.TempDir()
+	args := filepath.Join(dir, "args")
+	script := `for last; do :; done; echo "$@" >> "` + args + `"; ` +
+		`if [ "$last" = - ]; then printf AAAAaaaaBBBBbbbb; else printf AAAAaaaaBBBBbbbb > "$last"; fi`
+	video = &Video{filename: "360.mp4", width: 2, height: 1, depth: 4, pixfmt: PixelRGBA, frames: 2,
+		filter: "v360=input=e:output=flat", sandbox: &Sandbox{Wrapper: []string{"sh", "-c", script, "sh"}}}
+	assertEquals(t, video.ReadFrame(1), nil)
+	assertEquals(t, string(video.FrameBuffer()), "AAAAaaaa")
+	frames, err := video.ReadFrames(0, 1)
+	assertEquals(t, err, nil)
+	assertEquals(t, string(frames[1].Pix), "BBBBbbbb")
+	mapped, err := video.MapFrames(dir)
+	assertEquals(t, err, nil)
+	assertEquals(t, mapped.Wait(), nil)
+	mapped.Close()
+
+	// Every decode path applies the view before selecting frames.
+	data, err := os.ReadFile(args)
+	assertEquals(t, err, nil)
+	commands := strings.Split(strings.TrimSpace(string(data)), "\n")
+	assertEquals(t, len(commands), 3)
+	assertEquals(t, strings.Contains(commands[0], ` -vf v360=input=e:output=flat,select='eq(n\,1)' `), true)
+	assertEquals(t, strings.Contains(commands[1], ` -vf v360=input=e:output=flat,select='eq(n\,0)+eq(n\,1)' `), true)
+	assertEquals(t, strings.Contains(commands[2], " -vf v360=input=e:output=flat "), true)
+}
+
+func TestStereo(t *testing.T) {
+	video := &Video{filename: "vr180.mp4", width: 3840, height: 1920, filter: "hqdn3d=2:1.5:3:2.25",
+		metadata: map[string]string{"side_data_type": "Stereo 3D", "type": "side by side", "inverted": "0"}}
+	assertEquals(t, video.Stereo(), StereoSideBySide)
+	assertEquals(t, video.extractEye(EyeRight), nil)
+	assertEquals(t, video.filter, "crop=iw/2:ih:iw/2:0,hqdn3d=2:1.5:3:2.25")
+	assertEquals(t, video.width, 1920)
+
+	video = &Video{filename: "stereo.mkv", width: 4096, height: 4096, metadata: map[string]string{"tag:stereo_mode": "bottom_top"}}
+	assertEquals(t, video.Stereo().String(), "top and bottom")
+	assertEquals(t, video.extractEye(EyeLeft), nil)
+	assertEquals(t, video.filter, "crop=iw:ih/2:0:ih/2")
+	assertEquals(t, video.height, 2048)
+
+	video = &Video{filename: "flat.mp4", metadata: map[string]string{}}
+	assertEquals(t, video.Stereo(), StereoNone)
+	assertEquals(t, video.extractEye(EyeLeft) != nil, true)
+}
+
+func TestStereoReadFrame(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "stereo.mkv")
+	// Marks the koala video as side by side stereo, so each eye is one half of the frame.
+	cmd := exec.Command("ffmpeg", "-y", "-loglevel", "error", "-i", "test/koala.mp4", "-an", "-c:v", "copy",
+		"-metadata:s:v:0", "stereo_mode=left_right", path)
+	if err := cmd.Run(); err != nil {
+		t.Fatalf("Failed to arrange the test: %s", err)
+	}
+
+	video, err := NewVideo(path, ExtractEye(EyeRight))
+	if err != nil {
+		t.Fatalf("Failed to create the video: %s", err)
+	}
+	defer video.Close()
+	for i := 0; i <= 3; i++ {
+		if !video.Read() {
+			t.Fatalf("Failed to read frame %d", i)
+		}
+	}
+	expected := append([]byte{}, video.FrameBuffer()...)
+
+	random, err := NewVideo(path, ExtractEye(EyeRight))
+	if err != nil {
+		t.Fatalf("Failed to create the video: %s", err)
+	}
+	if err := random.ReadFrame(3); err != nil {
+		t.Fatalf("Failed to read the given frame: %s", err)
+	}
+	assertEquals(t, len(random.FrameBuffer()), len(expected))
+	assertEquals(t, bytes.Equal(random.FrameBuffer(), expected), true)
+
+	frames, err := random.ReadFrames(3)
+	if err != nil {
+		t.Fatalf("Failed to read the given frames: %s", err)
+	}
+	assertEquals(t, bytes.Equal(frames[0].Pix, expected), true)
+}
+
+func TestLensCorrection(t *testing.T) {
+	filter, err := newConfig([]Option{WithLensCorrection(-0.25, 0.05)}).videoFilter()
+	assertEquals(t, err, nil)
+	assertEquals(t, filter, "lenscorrection=k1=-0.25:k2=0.05")
+
+	filter, _ = newConfig([]Option{WithDenoise(DenoiseLight), WithLensPreset(LensGoProWide)}).videoFilter()
+	assertEquals(t, filter, "hqdn3d=2:1.5:3:2.25,lenscorrection=k1=-0.32:k2=0.1")
+
+	_, err = newConfig([]Option{WithLensPreset("fisheye")}).videoFilter()
+	assertEquals(t, err != nil, true)
+}
+
+func TestOverlayText(t *testing.T) {
+	assertEquals(t, overlayFilter(image.Rect(10, 20, 330, 60), 0.5), "fps=2,crop=320:40:10:20")
+	assertEquals(t, overlayFilter(image.Rect(0, 0, 200, 30), 0), "crop=200:30:0:0")
+
+	want := time.Date(2024, 5, 1, 13, 45, 7, 0, time.UTC)
+	for _, text := range []string{
+		"CAM 03  2024-05-01 13:45:07",
+		"2024/05/01 WED 13:45:07",
+		"05/01/2024 01:45:07PM",
+		"01 May 2024 13:45:07",
+		"May 01, 2024 13:45:07 Entrance",
+		"2O24-O5-0l l3:45:O7",
+	} {
+		stamp, err := ParseBurnedTimestamp(text, nil)
+		assertEquals(t, err, nil)
+		assertEquals(t, stamp, want)
+	}
+	_, err := ParseBurnedTimestamp("CAM 03", nil)
+	assertEquals(t, err != nil, true)
+}
+
+func TestFramesArchive(t *testing.T) {
+	data := &bytes.Buffer{}
+	archive, err := newFrameArchive(data, ArchiveTar)
+	assertEquals(t, err, nil)
+	assertEquals(t, archive.add("frames/frame-000000.jpg", []byte("first")), nil)
+	assertEquals(t, archive.add("frames/frame-000005.jpg", []byte("second")), nil)
+	assertEquals(t, archive.Close(), nil)
+
+	reader := tar.NewReader(data)
+	header, err := reader.Next()
+	assertEquals(t, err, nil)
+	assertEquals(t, header.Name, "frames/frame-000000.jpg")
+	header, _ = reader.Next()
+	content, _ := io.ReadAll(reader)
+	assertEquals(t, header.Name, "frames/frame-000005.jpg")
+	assertEquals(t, string(content), "second")
+
+	data.Reset()
+	archive, _ = newFrameArchive(data, ArchiveZip)
+	assertEquals(t, archive.add("frame-000000.png", []byte("frame")), nil)
+	assertEquals(t, archive.Close(), nil)
+	zipped, err := zip.NewReader(bytes.NewReader(data.Bytes()), int64(data.Len()))
+	assertEquals(t, err, nil)
+	assertEquals(t, len(zipped.File), 1)
+	assertEquals(t, zipped.File[0].Method, zip.Store)
+
+	_, err = newFrameArchive(data, ArchiveFormat(7))
+	assertEquals(t, err != nil, true)
+	assertEquals(t, ExportFramesArchive("test/koala.mp4", data, ArchiveTar, &ArchiveOptions{Image: "gif"}) != nil, true)
+}
+
+func TestFrameIndex(t *testing.T) {
+	frames := []map[string]string{
+		{"pts_time": "0.000000", "key_frame": "1", "pkt_size": "4210"},
+		{"pts_time": "N/A", "best_effort_timestamp_time": "0.040000", "key_frame": "0", "pkt_size": "812"},
+		{"pts_time": "N/A", "key_frame": "0", "pkt_size": "640"},
+	}
+	luma := parseLuma("frame:0    pts:0       pts_time:0\nlavfi.signalstats.YAVG=112.5\nframe:1    pts:1       pts_time:0.04\nlavfi.signalstats.YAVG=113\n")
+	records := frameRecords(frames, luma)
+
+	data := &bytes.Buffer{}
+	assertEquals(t, writeFrameIndex(data, records, false), nil)
+	assertEquals(t, data.String(), "frame,pts,keyframe,size,luma\n0,0,true,4210,112.5\n1,0.04,false,812,113\n2,,false,640,0\n")
+
+	data.Reset()
+	assertEquals(t, writeFrameIndex(data, records[1:], true), nil)
+	assertEquals(t, data.String(), `{"frame":1,"pts":0.04,"keyframe":false,"size":812,"luma":113}`+"\n"+
+		`{"frame":2,"pts":null,"keyframe":false,"size":640,"luma":0}`+"\n")
+	assertEquals(t, ExportFrameIndex("test/koala.mp4", "index.parquet") != nil, true)
+}
+
+func TestSummarize(t *testing.T) {
+	// A quiet first shot, a busy and loud second shot and a calm third shot.
+	motion := make([]float64, 60)
+	loudness := make([]float64, 60)
+	for i := range motion {
+		motion[i], loudness[i] = 0.05, 0.1
+		if i >= 20 && i < 40 {
+			motion[i], loudness[i] = 0.4, 0.8
+		}
+		if i >= 40 {
+			motion[i] = 0.15
+		}
+	}
+	clips := summaryClips(motion, loudness, []float64{20, 40}, 60, 12)
+	// The busy shot is picked from until its halved score drops to that of the other shots.
+	assertEquals(t, fmt.Sprint(clips), "[{0 2} {20 22} {22 24} {24 26} {26 28} {40 42}]")
+	total := 0.0
+	for _, c := range clips {
+		total += c.end - c.start
+	}
+	assertEquals(t, total, 12.0)
+	assertEquals(t, fmt.Sprint(summaryClips(motion, nil, nil, 10, 20)), "[{0 10}]")
+
+	commands := [][]string{}
+	assertEquals(t, encodeClips("test/koala.mp4", "summary.mp4", clips[1:3], 0, true, WithDryRun(&commands)), nil)
+	assertEquals(t, strings.Join(commands[0][4:], " "), "-y -ss 20.000 -t 2.000 -i test/koala.mp4 -ss 22.000 -t 2.000 -i test/koala.mp4 "+
+		"-filter_complex [0:v:0][0:a:0][1:v:0][1:a:0]concat=n=2:v=1:a=1[v][a] -map [v] -map [a] "+
+		"-c:v libx264 -crf 20 -pix_fmt yuv420p -c:a aac -b:a 160k summary.mp4")
+}
+
+func TestClipAroundPeaks(t *testing.T) {
+	rate := 8000
+	// Background noise with a loud cheer at 30s, a whistle at 12s and a quieter peak at 31s, too close to the cheer.
+	samples := make([]float32, 60*rate)
+	for i := range samples {
+		samples[i] = 0.01 * float32(math.Sin(float64(i)))
+	}
+	loud := func(at, length float64, level float32) {
+		for i := int(at * float64(rate)); i < int((at+length)*float64(rate)); i++ {
+			samples[i] = level * float32(math.Sin(float64(i)*0.3))
+		}
+	}
+	loud(29.5, 1, 0.9)
+	loud(31.5, 0.5, 0.6)
+	loud(11.8, 0.4, 0.5)
+
+	peaks := audioPeaks(samples, rate, 2, 10)
+	assertEquals(t, len(peaks), 2)
+	assertEquals(t, math.Abs(peaks[0]-12) < 0.3, true)
+	assertEquals(t, math.Abs(peaks[1]-30) < 0.3, true)
+	assertEquals(t, len(audioPeaks(make([]float32, rate), rate, 3, 1)), 0)
+
+	_, err := ClipAroundPeaks("test/koala.mp4", 0, 0, 3)
+	assertEquals(t, err != nil, true)
+}
+
+func TestFFmpegLogLevel(t *testing.T) {
+	assertEquals(t, logLevel(), "quiet")
+	assertEquals(t, SetFFmpegLogLevel("loud") != nil, true)
+	assertEquals(t, SetFFmpegLogLevel("warning"), nil)
+	defer SetFFmpegLogLevel("quiet")
+	assertEquals(t, logLevel(), "warning")
+
+	messages := []string{}
+	remove := OnEvent(func(event Event) {
+		if event.Type == EventLog {
+			messages = append(messages, event.Source+": "+event.Message)
+		}
+	})
+	defer remove()
+
+	cmd := exec.Command("ffmpeg")
+	logOutput(cmd, "input.mp4")
+	cmd.Stderr.Write([]byte("[h264 @ 0x5581] corrupt frame\n[h264 @ 0x5581] error while dec"))
+	cmd.Stderr.Write([]byte("oding MB 12 4\n"))
+	assertEquals(t, fmt.Sprint(messages), "[input.mp4: [h264 @ 0x5581] corrupt frame input.mp4: [h264 @ 0x5581] error while decoding MB 12 4]")
+
+	// The last message is emitted once the process exited, even without a trailing newline.
+	if _, err := exec.LookPath("sh"); err == nil {
+		messages = messages[:0]
+		cmd = exec.Command("sh", "-c", `printf "first\nlast" >&2`)
+		logOutput(cmd, "input.mp4")
+		assertEquals(t, cmd.Start(), nil)
+		assertEquals(t, waitOutput(cmd), nil)
+		assertEquals(t, fmt.Sprint(messages), "[input.mp4: first input.mp4: last]")
+	}
+}
+
+func TestDoctor(t *testing.T) {
+	assertEquals(t, parseVersion("ffmpeg version 6.1.1-3ubuntu5 Copyright (c) 2000-2023 the FFmpeg developers\nbuilt with gcc 13\n"), "6.1.1-3ubuntu5")
+	assertEquals(t, parseVersion("garbage"), "unknown")
+
+	codecs := parseCodecList("Encoders:\n V..... = Video\n A..... = Audio\n ------\n V....D libx264              libx264 H.264\n A....D aac                  AAC (Advanced Audio Coding)\n S..... srt                  SubRip subtitle\n")
+	assertEquals(t, fmt.Sprint(codecs), "[libx264 aac srt]")
+	assertEquals(t, fmt.Sprint(parseHWAccels("Hardware acceleration methods:\nvdpau\ncuda\n\n")), "[vdpau cuda]")
+
+	report, err := Doctor()
+	assertEquals(t, report != nil, true)
+	assertEquals(t, report.Healthy, err == nil)
+	if installed("ffmpeg") != nil {
+		assertEquals(t, report.Healthy, false)
+		assertEquals(t, report.FFmpeg.Path, "")
+	}
+}
+
+func TestStartTime(t *testing.T) {
+	video := &Video{filename: "recording.ts", fps: 25, frames: 250, metadata: map[string]string{"start_time": "1.400000"}}
+	assertEquals(t, video.StartTime(), 1.4)
+	assertEquals(t, video.TimeAt(50), 3.4)
+
+	assertEquals(t, video.Seek(3.4), nil)
+	assertEquals(t, video.start, 50)
+	assertEquals(t, video.SaveState().Frame, 50)
+	assertEquals(t, video.Seek(0.5), nil)
+	assertEquals(t, video.start, 0)
+	assertEquals(t, video.Seek(60) != nil, true)
+
+	video.metadata["start_time"] = "N/A"
+	assertEquals(t, video.StartTime(), 0.0)
+}
+
+func TestTimeShift(t *testing.T) {
+	var _ LiveSource = &Video{}
+	var _ LiveSource = &Camera{}
+
+	start := time.Date(2024, 5, 1, 20, 0, 0, 0, time.UTC)
+	segments := []Segment{
+		{Filename: "000000.mp4", Start: start, Duration: 2},
+		{Filename: "000001.mp4", Start: start.Add(2 * time.Second), Duration: 2},
+	}
+	segment, offset, ok := shiftSegment(segments, start.Add(2500*time.Millisecond))
+	assertEquals(t, ok, true)
+	assertEquals(t, segment.Filename, "000001.mp4")
+	assertEquals(t, offset, 500*time.Millisecond)
+
+	// Footage older than the window starts at the oldest segment.
+	segment, offset, _ = shiftSegment(segments, start.Add(-time.Minute))
+	assertEquals(t, segment.Filename, "000000.mp4")
+	assertEquals(t, offset, time.Duration(0))
+
+	_, _, ok = shiftSegment(segments, start.Add(4*time.Second))
+	assertEquals(t, ok, false)
+
+	_, err := NewTimeShift(&Video{}, 0)
+	assertEquals(t, err != nil, true)
+}
+
+// Live source producing "frames" stamped RGBA frames, with the framebuffer allocated on the first Read like Video and Camera.
+type testLiveSource struct {
+	width, height int
+	frames        int
+	read          int
+	framebuffer   []byte
+}
+
+func (source *testLiveSource) Read() bool {
+	if source.read == source.frames {
+		return false
+	}
+	if source.framebuffer == nil {
+		source.framebuffer = make([]byte, source.width*source.height*4)
+	}
+	StampFrame(source.framebuffer, source.width, source.height, source.read)
+	source.read++
+	return true
+}
+
+func (source *testLiveSource) FrameBuffer() []byte { return source.framebuffer }
+func (source *testLiveSource) Width() int          { return source.width }
+func (source *testLiveSource) Height() int         { return source.height }
+func (source *testLiveSource) FPS() float64        { return 25 }
+func (source *testLiveSource) Close()              {}
+
+func TestTimeShiftRead(t *testing.T) {
+	source := &testLiveSource{width: 64, height: 48, frames: 10}
+	shift, err := NewTimeShift(source, time.Minute)
+	if err != nil {
+		t.Fatalf("Failed to create the time shift: %s", err)
+	}
+	defer shift.Close()
+
+	// Live frames may be dropped, but every frame read is a complete frame of the source.
+	last, count := -1, 0
+	for shift.Read() {
+		assertEquals(t, len(shift.FrameBuffer()), 64*48*4)
+		n, err := ReadStamp(shift.FrameBuffer(), 64, 48)
+		assertEquals(t, err, nil)
+		assertEquals(t, n > last, true)
+		last = n
+		count++
+	}
+	assertEquals(t, count > 0, true)
+	assertEquals(t, last, 9)
+}
+
+func TestRecordWithProxy(t *testing.T) {
+	commands := [][]string{}
+	err := RecordWithProxy("rtsp://camera/stream", "archive.mp4", "proxy.mp4", &ProxyOptions{Height: 240}, WithDryRun(&commands))
+	assertEquals(t, err, nil)
+	command := strings.Join(commands[0][4:], " ")
+	assertEquals(t, strings.Contains(command, "-filter_complex [0:v:0]split=2[archive][p];[p]scale=-2:240[proxy]"), true)
+	assertEquals(t, strings.Contains(command, "-map [archive] -map 0:a:0? -c:v libx264 -preset veryfast -crf 16"), true)
+	assertEquals(t, strings.Contains(command, "-map [proxy] -map 0:a:0? -c:v libx264 -preset veryfast -b:v 800000"), true)
+	assertEquals(t, strings.HasSuffix(command, "+frag_keyframe+empty_moov+default_base_moof proxy.mp4"), true)
+}
+
+// Returns a player with the fields of the given one and a new state.
+func testPlayer(player Player) *Player {
+	player.playerState = &playerState{}
+	return &player
+}
+
+func TestAudioPlayer(t *testing.T) {
+	assertEquals(t, audioOnly(nil), true)
+	assertEquals(t, audioOnly([]map[string]string{{"codec_name": "mjpeg", "disposition:attached_pic": "1"}}), true)
+	assertEquals(t, audioOnly([]map[string]string{{"codec_name": "h264", "disposition:attached_pic": "0"}}), false)
+
+	audio := &AudioReader{filename: "song.flac", rate: 44100, channels: 2, duration: 180}
+	assertEquals(t, audio.Seek(90), nil)
+	assertEquals(t, audio.Position(), 90.0)
+	assertEquals(t, audio.Seek(200) != nil, true)
+
+	player := testPlayer(Player{FilePath: "song.flac", Audio: audio})
+	assertEquals(t, player.Seek(30), nil)
+	assertEquals(t, player.Position(), 30.0)
+	assertEquals(t, player.OnEvent(func() bool { return true }, SnapshotOrClip{Output: "out.png"}, 0) != nil, true)
+
+	_, err := NewAudioReader("missing.mp3")
+	assertEquals(t, err != nil, true)
+}
+
+func TestPlayerEventOutput(t *testing.T) {
+	assertEquals(t, eventOutput("event-%d.png", 3), "event-3.png")
+	assertEquals(t, eventOutput("clips/%04d.mp4", 12), "clips/0012.mp4")
+	assertEquals(t, eventOutput("100%.png", 3), "100%.png")
+	assertEquals(t, eventOutput("100%%d.png", 3), "100%%d.png")
+	assertEquals(t, eventOutput("100%%-%d.png", 3), "100%-3.png")
+
+	// Events may be registered while another session reads the player.
+	player := testPlayer(Player{FilePath: "clip.mp4", Video: &Video{filename: "clip.mp4"}})
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := 0; i < 100; i++ {
+			player.Err()
+		}
+	}()
+	for i := 0; i < 100; i++ {
+		player.OnEvent(func() bool { return false }, SnapshotOrClip{Output: "event.png"}, 0)
+	}
+	<-done
+	assertEquals(t, len(player.events), 100)
+}
+
+func TestPlayerSubscribe(t *testing.T) {
+	player := testPlayer(Player{FilePath: "song.flac", Audio: &AudioReader{filename: "song.flac", rate: 8000, channels: 1, duration: 60}})
+	first, second := player.Subscribe(), player.Subscribe()
+
+	player.Play()
+	player.Play()
+	assertEquals(t, player.Playing(), true)
+	assertEquals(t, player.Seek(30), nil)
+	assertEquals(t, player.Seek(90) != nil, true)
+	player.Pause()
+
+	for _, events := range []<-chan StateEvent{first, second} {
+		types := []StateEventType{}
+		for len(events) > 0 {
+			event := <-events
+			types = append(types, event.Type)
+			if event.Type == StateSeek {
+				assertEquals(t, event.Position, 30.0)
+			}
+		}
+		assertEquals(t, fmt.Sprint(types), "[play seek error pause]")
+	}
+
+	player.Unsubscribe(second)
+	_, open := <-second
+	assertEquals(t, open, false)
+	assertEquals(t, len(player.subscribers), 1)
+
+	// Subscribers falling behind lose the oldest events.
+	for i := 0; i < stateBuffer+4; i++ {
+		player.Seek(float64(i))
+	}
+	assertEquals(t, len(first), stateBuffer)
+	assertEquals(t, (<-first).Position, 4.0)
+}
+
+func TestPlayerTTL(t *testing.T) {
+	stale := testPlayer(Player{FilePath: "a.mp3", ID: "session-1", Audio: &AudioReader{rate: 8000, channels: 1}})
+	active := testPlayer(Player{FilePath: "a.mp3", ID: "session-2", Audio: &AudioReader{rate: 8000, channels: 1}})
+	stale.accessed.Store(time.Now().Add(-2 * time.Hour).UnixNano())
+	active.touch()
+	Players = []Player{*stale, *active}
+	defer func() { Players = nil }()
+	events := stale.Subscribe()
+
+	assertEquals(t, ExpirePlayers(), 0)
+	SetPlayerTTL(time.Hour)
+	defer SetPlayerTTL(0)
+	assertEquals(t, ExpirePlayers(), 1)
+	assertEquals(t, len(Players), 1)
+	assertEquals(t, ListPlayers()[0].playerState == active.playerState, true)
+	_, open := <-events
+	assertEquals(t, open, false)
+	assertEquals(t, stale.Read(), false)
+	assertEquals(t, stale.Seek(0) != nil, true)
+
+	// Reading keeps a player alive.
+	active.accessed.Store(0)
+	active.Read()
+	assertEquals(t, ExpirePlayers(), 0)
+
+	// Expiry waits for reads in progress and keeps players they accessed.
+	active.accessed.Store(0)
+	active.mu.Lock()
+	expired := make(chan int)
+	go func() { expired <- ExpirePlayers() }()
+	time.Sleep(10 * time.Millisecond)
+	active.touch()
+	active.mu.Unlock()
+	assertEquals(t, <-expired, 0)
+	assertEquals(t, len(ListPlayers()), 1)
+
+	active.Close()
+	assertEquals(t, len(Players), 0)
+}
+
+func TestPlayerMarkers(t *testing.T) {
+	player := testPlayer(Player{Audio: &AudioReader{rate: 8000, channels: 1, duration: 60}})
+	for _, marker := range []struct {
+		time  float64
+		label string
+	}{{30, "chorus"}, {5, "intro"}, {30, "drop"}} {
+		_, err := player.AddMarker(marker.time, marker.label)
+		assertEquals(t, err, nil)
+	}
+	_, err := player.AddMarker(75, "outro")
+	assertEquals(t, err != nil, true)
+	assertEquals(t, fmt.Sprint(player.Markers()), "[{2 5 intro} {1 30 chorus} {3 30 drop}]")
+
+	assertEquals(t, player.RemoveMarker(1), nil)
+	assertEquals(t, player.RemoveMarker(1) != nil, true)
+
+	filename := filepath.Join(t.TempDir(), "markers.json")
+	assertEquals(t, player.SetMarkerFile(filename), nil)
+	player.AddMarker(45, "bridge")
+
+	restored := testPlayer(Player{Audio: &AudioReader{rate: 8000, channels: 1, duration: 60}})
+	assertEquals(t, restored.SetMarkerFile(filename), nil)
+	assertEquals(t, fmt.Sprint(restored.Markers()), "[{2 5 intro} {3 30 drop} {4 45 bridge}]")
+	marker, _ := restored.AddMarker(10, "verse")
+	assertEquals(t, marker.ID, 5)
+}
+
+func TestPlayerLoopRegion(t *testing.T) {
+	player := testPlayer(Player{Audio: &AudioReader{rate: 8000, channels: 1, duration: 60}})
+	assertEquals(t, player.SetLoopRegion(20, 10) != nil, true)
+	assertEquals(t, player.SetLoopRegion(70, 80) != nil, true)
+	assertEquals(t, player.SetLoopRegion(10, 20), nil)
+	start, end, ok := player.LoopRegion()
+	assertEquals(t, fmt.Sprint(start, end, ok), "10 20 true")
+
+	// Positions before the region jump to its start.
+	assertEquals(t, player.enterLoop(), nil)
+	assertEquals(t, player.Position(), 10.0)
+
+	// Wrapping keeps the end of the region due when it was.
+	player.Audio.Seek(20)
+	clock := time.Now()
+	player.clock, player.origin = clock, 15
+	assertEquals(t, player.enterLoop(), nil)
+	assertEquals(t, player.Position(), 10.0)
+	assertEquals(t, player.origin, 10.0)
+	assertEquals(t, player.clock, clock.Add(5*time.Second))
+
+	// Chunks are cut at the end of the region.
+	player.Audio.samples = 8000*10 + 300
+	player.Audio.chunk = make([]byte, 1600)
+	player.Audio.truncate(20)
+	assertEquals(t, len(player.Audio.chunk), 1000)
+	assertEquals(t, player.Position(), 20.0)
+
+	player.ClearLoopRegion()
+	_, _, ok = player.LoopRegion()
+	assertEquals(t, ok, false)
+}
+
+func TestPlayerPacing(t *testing.T) {
+	video := &Video{filename: "clip.mp4", width: 2, height: 1, depth: 4, fps: 25, frames: 1, framebuffer: make([]byte, 8),
+		pipe: io.NopCloser(bytes.NewReader(make([]byte, 8))), cmd: &exec.Cmd{}, closeCleanupChan: make(chan struct{}, 1)}
+	player := testPlayer(Player{FilePath: "clip.mp4", Video: video})
+	player.Play()
+	// The first frame is due in 300ms.
+	player.clock = time.Now().Add(300 * time.Millisecond)
+
+	read := make(chan bool)
+	go func() {
+		read <- player.Read()
+	}()
+	time.Sleep(50 * time.Millisecond)
+	// The player is not locked while Read waits for the frame to be due.
+	start := time.Now()
+	assertEquals(t, player.Playing(), true)
+	assertEquals(t, player.Speed(), 1.0)
+	assertEquals(t, time.Since(start) < 100*time.Millisecond, true)
+	assertEquals(t, <-read, true)
+	assertEquals(t, time.Since(start) > 150*time.Millisecond, true)
+}
+
+func TestPlayerSpeed(t *testing.T) {
+	assertEquals(t, tempoFilter(1.5), "atempo=1.5")
+	assertEquals(t, tempoFilter(4), "atempo=2,atempo=2")
+	assertEquals(t, tempoFilter(0.25), "atempo=0.5,atempo=0.5")
+
+	player := testPlayer(Player{Audio: &AudioReader{rate: 8000, channels: 1, duration: 60}})
+	assertEquals(t, player.Speed(), 1.0)
+	assertEquals(t, player.SetSpeed(8) != nil, true)
+	assertEquals(t, player.SetSpeed(0.1) != nil, true)
+
+	player.Audio.Seek(10)
+	player.Audio.samples = 8000
+	assertEquals(t, player.SetSpeed(2), nil)
+	assertEquals(t, player.Speed(), 2.0)
+	assertEquals(t, player.Position(), 11.0)
+
+	// At double speed, every second of chunks covers two seconds of audio, due after one second.
+	player.Audio.samples = 8000
+	assertEquals(t, player.Position(), 13.0)
+	assertEquals(t, player.due(), player.clock.Add(time.Second))
+
+	player.Audio.chunk = make([]byte, 1600)
+	player.Audio.truncate(12.95)
+	assertEquals(t, len(player.Audio.chunk), 1200)
+	assertEquals(t, player.Position(), 12.95)
+}
+
+func TestPlayerStep(t *testing.T) {
+	// A video of 1x1 frames whose pixels hold the frame index.
+	data := []byte{}
+	for i := 0; i < 40; i++ {
+		data = append(data, byte(i), 0, 0, 255)
+	}
+	video := &Video{
+		filename:    "frames.mp4",
+		width:       1,
+		height:      1,
+		depth:       4,
+		fps:         25,
+		frames:      40,
+		framebuffer: make([]byte, 4),
+		pipe:        io.NopCloser(bytes.NewReader(data)),
+		cmd:         &exec.Cmd{},
+	}
+	player := testPlayer(Player{FilePath: "frames.mp4", Video: video})
+	assertEquals(t, player.SetStepWindow(-1, 3) != nil, true)
+	assertEquals(t, player.SetStepWindow(2, 3), nil)
+	events := player.Subscribe()
+
+	assertEquals(t, player.StepForward(1), nil)
+	assertEquals(t, video.FrameBuffer()[0], byte(0))
+	assertEquals(t, int(video.delivered.Load()), 4)
+
+	assertEquals(t, player.StepForward(2), nil)
+	assertEquals(t, video.FrameBuffer()[0], byte(2))
+	assertEquals(t, int(video.delivered.Load()), 6)
+	assertEquals(t, player.Position(), 3.0/25)
+
+	// Steps back within the window use the kept frames.
+	assertEquals(t, player.StepBackward(2), nil)
+	assertEquals(t, video.FrameBuffer()[0], byte(0))
+	assertEquals(t, len(player.steps.frames), 4)
+	assertEquals(t, player.StepBackward(1) != nil, true)
+	assertEquals(t, video.FrameBuffer()[0], byte(0))
+	assertEquals(t, (<-events).Type, StateSeek)
+
+	audio := testPlayer(Player{Audio: &AudioReader{rate: 8000, channels: 1}})
+	assertEquals(t, audio.StepForward(1) != nil, true)
+}
+
+func TestPlayerExportRegion(t *testing.T) {
+	player := testPlayer(Player{FilePath: "review.mp4", Video: &Video{filename: "review.mp4", stream: 1, fps: 25, duration: 30}})
+	assertEquals(t, player.ExportRegion("moment.mp4") != nil, true)
+
+	commands := [][]string{}
+	player.SetLoopRegion(12.5, 40)
+	assertEquals(t, player.ExportRegion("moment.mp4", WithDryRun(&commands)), nil)
+	assertEquals(t, strings.Join(commands[0][4:], " "),
+		"-y -ss 12.500 -t 17.500 -i review.mp4 -map 0:v:1 -map 0:a:0? -c:v libx264 -crf 20 -pix_fmt yuv420p -c:a aac -b:a 160k moment.mp4")
+
+	song := testPlayer(Player{FilePath: "song.flac", Audio: &AudioReader{filename: "song.flac", rate: 8000, channels: 1, duration: 60}})
+	song.SetLoopRegion(5, 10)
+	assertEquals(t, song.ExportRegion("hook.mp3", WithDryRun(&commands)), nil)
+	assertEquals(t, strings.Join(commands[1][4:], " "), "-y -ss 5.000 -t 5.000 -i song.flac -map 0:a:0 -vn hook.mp3")
+}
+
+func TestPlayerRegistry(t *testing.T) {
+	Players = []Player{*newPlayer("a.mp4", "1")}
+	defer func() { Players = nil }()
+	player, err := findPlayer("a.mp4", "1")
+	assertEquals(t, err, nil)
+
+	// Players found before the registry grows share the state of the registered player.
+	for i := 0; i < 10; i++ {
+		Players = append(Players, *newPlayer("b.mp4", fmt.Sprint(i)))
+	}
+	player.OnEvent(func() bool { return false }, SnapshotOrClip{Output: "event.png"}, 0)
+	again, _ := findPlayer("a.mp4", "1")
+	assertEquals(t, len(again.events), 1)
+	assertEquals(t, len(Players[0].events), 1)
+
+	_, err = findPlayer("a.mp4", "2")
+	assertEquals(t, err != nil, true)
+}
+
+// Pipe standing in for the stdin of ffmpeg, failing all writes with "err" if set.
+type testPipe struct {
+	bytes.Buffer
+	err error
+}
+
+func (pipe *testPipe) Write(data []byte) (int, error) {
+	if pipe.err != nil {
+		return 0, pipe.err
+	}
+	return pipe.Buffer.Write(data)
+}
+
+func (pipe *testPipe) Close() error {
+	return nil
+}
+
+func TestVideoWriterLimits(t *testing.T) {
+	writer := &VideoWriter{filename: "out.mp4", fps: 25, maxlength: 2}
+	writer.frames = 49
+	assertEquals(t, writer.limit(), StatusWriting)
+	writer.frames = 50
+	assertEquals(t, writer.limit(), StatusMaxDuration)
+	// Timestamped writers count the duration of the last frame.
+	writer = &VideoWriter{filename: "out.mp4", fps: 25, maxlength: 2, timestamps: true, pts: 1960 * time.Millisecond}
+	assertEquals(t, writer.limit(), StatusMaxDuration)
+
+	dir := t.TempDir()
+	filename := filepath.Join(dir, "out.mp4")
+	if err := os.WriteFile(filename, make([]byte, 1000), 0644); err != nil {
+		t.Fatal(err)
+	}
+	writer = &VideoWriter{filename: filename, fps: 25, maxsize: 1000, frames: 24}
+	// The size is only checked once per second of video.
+	assertEquals(t, writer.limit(), StatusWriting)
+	writer.frames = 25
+	assertEquals(t, writer.limit(), StatusMaxFileSize)
+	writer.maxsize = 1001
+	assertEquals(t, writer.limit(), StatusWriting)
+	writer = &VideoWriter{filename: "rtmp://example.com/live", fps: 25, maxsize: 1, frames: 25}
+	assertEquals(t, writer.limit(), StatusWriting)
+
+	// Reaching a limit finalizes the writer, which then rejects further frames.
+	writer = &VideoWriter{filename: "out.mp4", status: StatusWriting, fps: 25, maxlength: 0.08,
+		cmd: &exec.Cmd{}, pipe: &testPipe{}, stderr: &tailBuffer{size: 64}}
+	assertEquals(t, writer.Write([]byte{1}), nil)
+	writer.Write([]byte{2})
+	assertEquals(t, writer.Status(), StatusMaxDuration)
+	assertEquals(t, writer.Write([]byte{3}), ErrLimitReached)
+	assertEquals(t, writer.pipe.(*testPipe).String(), "\x01\x02")
+}
+
+func TestVideoWriterFail(t *testing.T) {
+	write := func(err error, output string) *WriteError {
+		stderr := &tailBuffer{size: 64}
+		stderr.Write([]byte(output))
+		writer := &VideoWriter{filename: "out.mp4", status: StatusWriting, fps: 25, frames: 50,
+			cmd: &exec.Cmd{}, pipe: &testPipe{err: err}, stderr: stderr}
+		var werr *WriteError
+		assertEquals(t, errors.As(writer.Write([]byte{1}), &werr), true)
+		assertEquals(t, writer.Status(), StatusFailed)
+		assertEquals(t, writer.Err(), error(werr))
+		return werr
+	}
+
+	werr := write(&os.PathError{Op: "write", Path: "|1", Err: syscall.ENOSPC}, "")
+	assertEquals(t, werr.Err, ErrNoSpace)
+	assertEquals(t, errors.Is(werr, ErrNoSpace), true)
+	assertEquals(t, werr.Timestamp, 2.0)
+	// ffmpeg reports a full disk of the output file on stderr, while the pipe breaks.
+	werr = write(syscall.EPIPE, "av_interleaved_write_frame(): No space left on device\n")
+	assertEquals(t, werr.Err, ErrNoSpace)
+	assertEquals(t, werr.Output, "av_interleaved_write_frame(): No space left on device")
+	werr = write(&os.PathError{Op: "write", Path: "|1", Err: syscall.EPIPE}, "")
+	assertEquals(t, werr.Err, ErrBrokenPipe)
+	werr = write(os.ErrClosed, "")
+	assertEquals(t, werr.Err, ErrBrokenPipe)
+	other := errors.New("write failed")
+	werr = write(other, "")
+	assertEquals(t, werr.Err, other)
+}
+
+func TestRecorderSegments(t *testing.T) {
+	dir := t.TempDir()
+	start := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	recorder := &Recorder{dir: dir, segment: 60, fps: 25, options: RecorderOptions{Retention: 2, Extension: ".mkv"}}
+	assertEquals(t, recorder.segmentName(start), filepath.Join(dir, "000000-20240301-120000.mkv"))
+	recorder.count = 12
+	assertEquals(t, recorder.segmentName(start.Add(time.Minute)), filepath.Join(dir, "000012-20240301-120100.mkv"))
+
+	segments := []Segment{}
+	for i := 0; i < 4; i++ {
+		segment := Segment{
+			Filename: recorder.segmentName(start.Add(time.Duration(i) * time.Minute)),
+			Start:    start.Add(time.Duration(i) * time.Minute),
+			Duration: 60,
+		}
+		if err := os.WriteFile(segment.Filename, nil, 0644); err != nil {
+			t.Fatal(err)
+		}
+		segments = append(segments, segment)
+		recorder.count++
+	}
+
+	// Only the newest segments are kept, counting the one being written.
+	recorder.segments = slices.Clone(segments)
+	recorder.retain(start.Add(4 * time.Minute))
+	assertEquals(t, len(recorder.segments), 2)
+	assertEquals(t, recorder.segments[0].Filename, segments[2].Filename)
+	assertEquals(t, exists(segments[1].Filename), false)
+	assertEquals(t, exists(segments[2].Filename), true)
+
+	// Segments which ended more than MaxAge ago are removed, but never the current one.
+	recorder.options = RecorderOptions{MaxAge: 90 * time.Second, Extension: ".mkv"}
+	recorder.retain(start.Add(10 * time.Minute))
+	assertEquals(t, len(recorder.segments), 1)
+	assertEquals(t, recorder.segments[0].Filename, segments[3].Filename)
+
+	// Stitching cuts the finished segments to the requested range.
+	recorder.segments = segments
+	var commands [][]string
+	err := recorder.Stitch(start.Add(30*time.Second), start.Add(90*time.Second), "clip.mkv", WithDryRun(&commands))
+	assertEquals(t, err, nil)
+	assertEquals(t, len(commands), 1)
+	args := strings.Join(commands[0], " ")
+	assertEquals(t, strings.Contains(args, "-f concat -safe 0 -i "), true)
+	filename := commands[0][len(commands[0])-4]
+	list, err := os.ReadFile(filename)
+	assertEquals(t, err, nil)
+	os.Remove(filename)
+	assertEquals(t, strings.Count(string(list), "file "), 2)
+	assertEquals(t, strings.Contains(string(list), "inpoint 30.000000\n"), true)
+	assertEquals(t, strings.Contains(string(list), "outpoint 30.000000\n"), true)
+	assertEquals(t, strings.HasSuffix(args, " -c copy clip.mkv"), true)
+}
+
+func TestCMAFWriter(t *testing.T) {
+	segment, chunk, err := (&CMAFOptions{}).durations()
+	assertEquals(t, err, nil)
+	assertEquals(t, segment, 4.0)
+	assertEquals(t, chunk, 0.5)
+	_, _, err = (&CMAFOptions{Segment: 1, Chunk: 2}).durations()
+	assertEquals(t, err != nil, true)
+	_, err = NewCMAFWriter(t.TempDir(), 640, 360, &CMAFOptions{Chunk: 5})
+	assertEquals(t, err != nil, true)
+
+	// Keyframes are placed exactly at the segment boundaries.
+	assertEquals(t, strings.Join(cmafArgs(2, 0.2, 30, 6), " "),
+		"-g 60 -keyint_min 60 -sc_threshold 0 -f dash -seg_duration 2.000000 -frag_type duration -frag_duration 0.200000 "+
+			"-streaming 1 -ldash 1 -use_template 1 -use_timeline 0 -hls_playlist 1 -lhls 1 -window_size 6 "+
+			"-init_seg_name init-$RepresentationID$.m4s -media_seg_name chunk-$RepresentationID$-$Number%05d$.m4s")
+	assertEquals(t, strings.Join(cmafArgs(4, 0.5, 29.97, 0)[:4], " "), "-g 120 -keyint_min 120")
+}
+
+func TestMapFrames(t *testing.T) {
+	if _, err := exec.LookPath("sh"); err != nil {
+		t.Skip("the test decoder is a shell script")
+	}
+	// The wrapper writes three and a half frames of 2x1 RGBA pixels instead of running ffmpeg,
+	// and records its arguments next to the frame file.
+	script := `for last; do :; done; echo "$@" > "$last.args"; printf AAAAaaaaBBBBbbbbCCCCccccDD > "$last"`
+	video := &Video{filename: "test/koala.mp4", width: 2, height: 1, depth: 4, pixfmt: PixelRGBA, stream: 1,
+		sandbox: &Sandbox{Wrapper: []string{"sh", "-c", script, "sh"}}}
+	dir := t.TempDir()
+	// The size of the frame file must be known.
+	_, err := video.MapFrames(dir)
+	assertEquals(t, err != nil, true)
+	video.frames = 4
+	frames, err := video.MapFrames(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer frames.Close()
+	assertEquals(t, frames.Size(), int64(32))
+	assertEquals(t, frames.Wait(), nil)
+
+	args, err := os.ReadFile(frames.file.Name() + ".args")
+	assertEquals(t, err, nil)
+	assertEquals(t, strings.TrimSpace(string(args)), "ffmpeg -y -loglevel "+logLevel()+" -protocol_whitelist file,crypto,data "+
+		"-i test/koala.mp4 -f rawvideo -pix_fmt rgba -vcodec rawvideo -map 0:v:1 "+frames.file.Name())
+
+	// Partially written frames are not available.
+	assertEquals(t, frames.Frames(), 3)
+	for n, expected := range []string{"AAAAaaaa", "BBBBbbbb", "CCCCcccc"} {
+		frame, err := frames.Frame(n)
+		assertEquals(t, err, nil)
+		assertEquals(t, string(frame), expected)
+	}
+	_, err = frames.Frame(3)
+	assertEquals(t, err != nil, true)
+	_, err = frames.Frame(-1)
+	assertEquals(t, err != nil, true)
+
+	assertEquals(t, frames.ReadFrame(1), nil)
+	assertEquals(t, string(video.FrameBuffer()), "BBBBbbbb")
+}
+
+func TestAtomicWriteMode(t *testing.T) {
+	dir := t.TempDir()
+	temp, err := os.CreateTemp(dir, ".out-*.mp4")
+	if err != nil {
+		t.Fatal(err)
+	}
+	temp.Close()
+	filename := filepath.Join(dir, "out.mp4")
+	writer := &VideoWriter{filename: filename, status: StatusWriting, tempfile: temp.Name(), stderr: &tailBuffer{size: 64}}
+	writer.Close()
+	assertEquals(t, writer.Err(), nil)
+
+	// The output has the mode of a file created without the temporary file.
+	info, err := os.Stat(filename)
+	assertEquals(t, err, nil)
+	plain, err := os.Create(filepath.Join(dir, "plain.mp4"))
+	assertEquals(t, err, nil)
+	plain.Close()
+	expected, err := os.Stat(plain.Name())
+	assertEquals(t, err, nil)
+	assertEquals(t, info.Mode(), expected.Mode())
+}
+
+func TestVideoWriterFinishError(t *testing.T) {
+	// ffmpeg failing to finish the output is reported by Err once the writer is closed.
+	writer := &VideoWriter{filename: "out.mp4", status: StatusWriting, fps: 25, cmd: &exec.Cmd{}, stderr: &tailBuffer{size: 64}}
+	assertEquals(t, writer.Err(), nil)
+	writer.Close()
+	assertEquals(t, writer.Status(), StatusClosed)
+	assertEquals(t, writer.Err() != nil, true)
+	writer.Close()
+	assertEquals(t, writer.Status(), StatusClosed)
+}
+
+func TestProcessGroup(t *testing.T) {
+	if _, err := os.Stat("/proc/self/stat"); err != nil {
+		t.Skip("process groups are read from /proc")
+	}
+	// The wrapper prints the pid of the process and its process group instead of running ffmpeg.
+	sandbox := Sandbox{Wrapper: []string{"sh", "-c", `echo $$ $(cut -d" " -f5 /proc/$$/stat)`, "sh"}}
+	group := func(options ...Option) bool {
+		output, err := newConfig(append(options, WithSandbox(sandbox))).output(ffcmd.New().Input("in.mp4").Output("-"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		fields := strings.Fields(string(output))
+		return len(fields) == 2 && fields[0] == fields[1]
+	}
+	assertEquals(t, group(), false)
+	assertEquals(t, group(WithContext(context.Background())), false)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	assertEquals(t, group(WithContext(ctx)), true)
+}
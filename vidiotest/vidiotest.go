@@ -0,0 +1,184 @@
+// Package vidiotest provides helpers for writing tests against decoded video frames:
+// tolerance-based frame comparison, golden-frame assertions and synthetic test sources.
+package vidiotest
+
+import (
+	"fmt"
+	"math"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+
+	vidio "github.com/benitogf/Vidio"
+	"github.com/benitogf/Vidio/ffcmd"
+)
+
+// Environment variable which, when set to a non-empty value, makes AssertGolden
+// (re)write golden files instead of comparing against them.
+const UpdateEnv = "VIDIO_UPDATE_GOLDEN"
+
+// Allowed difference between two frames. The zero value requires frames to be identical.
+type Tolerance struct {
+	MaxDelta int     // Largest allowed difference of any single channel value.
+	MinPSNR  float64 // Smallest allowed peak signal-to-noise ratio in dB. 0 disables the check.
+}
+
+// Difference between two frames.
+type Result struct {
+	MaxDelta int     // Largest difference of any single channel value.
+	PSNR     float64 // Peak signal-to-noise ratio in dB. +Inf if the frames are identical.
+	Changed  int     // Number of channel values that differ.
+}
+
+// Compares two frames byte by byte. Both frames must have the same length.
+func Compare(expected, actual []byte) (Result, error) {
+	if len(expected) != len(actual) {
+		return Result{}, fmt.Errorf("vidiotest: frame sizes differ: %d and %d", len(expected), len(actual))
+	}
+	if len(expected) == 0 {
+		return Result{PSNR: math.Inf(1)}, nil
+	}
+
+	result := Result{}
+	sum := 0.0
+	for i := range expected {
+		d := int(expected[i]) - int(actual[i])
+		if d < 0 {
+			d = -d
+		}
+		if d > 0 {
+			result.Changed++
+			if d > result.MaxDelta {
+				result.MaxDelta = d
+			}
+			sum += float64(d * d)
+		}
+	}
+
+	if sum == 0 {
+		result.PSNR = math.Inf(1)
+	} else {
+		mse := sum / float64(len(expected))
+		result.PSNR = 10 * math.Log10(255*255/mse)
+	}
+	return result, nil
+}
+
+// Returns an error describing how the result exceeds the tolerance, or nil if it does not.
+func (tolerance Tolerance) Check(result Result) error {
+	// Only a PSNR floor was given, so any single channel may differ.
+	psnrOnly := tolerance.MaxDelta == 0 && tolerance.MinPSNR > 0
+	if !psnrOnly && result.MaxDelta > tolerance.MaxDelta {
+		return fmt.Errorf("vidiotest: max channel delta %d exceeds %d (%d values differ)", result.MaxDelta, tolerance.MaxDelta, result.Changed)
+	}
+	if tolerance.MinPSNR > 0 && result.PSNR < tolerance.MinPSNR {
+		return fmt.Errorf("vidiotest: psnr %.2f dB is below %.2f dB", result.PSNR, tolerance.MinPSNR)
+	}
+	return nil
+}
+
+// Fails the test if the frames differ by more than the given tolerance.
+// A tolerance with only MinPSNR set accepts any per-channel delta.
+func AssertFrameEqual(t testing.TB, expected, actual []byte, tolerance Tolerance) {
+	t.Helper()
+	result, err := Compare(expected, actual)
+	if err == nil {
+		err = tolerance.Check(result)
+	}
+	if err != nil {
+		t.Error(err)
+	}
+}
+
+// Compares an RGBA frame against the png golden file at "path". If the UpdateEnv environment
+// variable is set, the frame is written to "path" instead. A missing golden file fails the test.
+func AssertGolden(t testing.TB, path string, width, height int, frame []byte, tolerance Tolerance) {
+	t.Helper()
+	if os.Getenv(UpdateEnv) != "" {
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := vidio.Write(path, width, height, frame); err != nil {
+			t.Fatal(err)
+		}
+		t.Logf("vidiotest: wrote golden frame %s", path)
+		return
+	}
+	if _, err := os.Stat(path); os.IsNotExist(err) {
+		t.Fatalf("vidiotest: golden frame %s does not exist, set %s=1 to write it", path, UpdateEnv)
+		return
+	}
+
+	w, h, golden, err := vidio.Read(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if w != width || h != height {
+		t.Errorf("vidiotest: golden frame %s is %dx%d, got %dx%d", path, w, h, width, height)
+		return
+	}
+	AssertFrameEqual(t, golden, frame[:width*height*4], tolerance)
+}
+
+// Synthetic test video generated with the ffmpeg lavfi sources.
+type Source struct {
+	Pattern  string  // lavfi video source, e.g. "testsrc2", "smptebars" or "color=c=red". Default "testsrc2".
+	Width    int     // Frame width. Default 320.
+	Height   int     // Frame height. Default 240.
+	FPS      float64 // Frames per second. Default 25.
+	Duration float64 // Duration in seconds. Default 1.
+	Audio    bool    // Add a 1 kHz sine wave audio stream.
+	Codec    string  // Video codec. Default libx264.
+}
+
+// Generates the test source video at "filename". The encode is deterministic, so regenerating
+// a fixture with the same settings yields the same frames.
+func Generate(filename string, source Source, options ...vidio.Option) error {
+	if source.Pattern == "" {
+		source.Pattern = "testsrc2"
+	}
+	if source.Width == 0 {
+		source.Width = 320
+	}
+	if source.Height == 0 {
+		source.Height = 240
+	}
+	if source.FPS == 0 {
+		source.FPS = 25
+	}
+	if source.Duration == 0 {
+		source.Duration = 1
+	}
+	if source.Codec == "" {
+		source.Codec = "libx264"
+	}
+
+	pattern := fmt.Sprintf("%s:size=%dx%d:rate=%g", source.Pattern, source.Width, source.Height, source.FPS)
+
+	builder := ffcmd.New().
+		Global("-y").
+		Input(pattern, "-f", "lavfi")
+	output := []string{"-t", fmt.Sprintf("%g", source.Duration), "-c:v", source.Codec, "-pix_fmt", "yuv420p", "-threads", "1", "-bitexact"}
+	if source.Audio {
+		builder.Input("sine=frequency=1000:sample_rate=48000", "-f", "lavfi")
+		output = append(output, "-c:a", "aac", "-shortest")
+	}
+	builder.Output(filename, output...)
+
+	return vidio.Run(builder, options...)
+}
+
+// Generates the test source in the test's temporary directory and returns its path.
+// Skips the test if ffmpeg is not installed.
+func Fixture(t testing.TB, source Source) string {
+	t.Helper()
+	if _, err := exec.LookPath("ffmpeg"); err != nil {
+		t.Skip("vidiotest: ffmpeg is not installed")
+	}
+	filename := filepath.Join(t.TempDir(), "fixture.mp4")
+	if err := Generate(filename, source); err != nil {
+		t.Fatal(err)
+	}
+	return filename
+}
@@ -0,0 +1,76 @@
+package vidiotest
+
+import (
+	"math"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestCompare(t *testing.T) {
+	expected := []byte{10, 20, 30, 255, 40, 50, 60, 255}
+	actual := []byte{12, 20, 30, 255, 40, 45, 60, 255}
+
+	result, err := Compare(expected, expected)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !math.IsInf(result.PSNR, 1) || result.MaxDelta != 0 {
+		t.Errorf("Expected identical frames, got %+v", result)
+	}
+
+	result, _ = Compare(expected, actual)
+	if result.MaxDelta != 5 || result.Changed != 2 {
+		t.Errorf("Expected max delta 5 with 2 changes, got %+v", result)
+	}
+
+	if err := (Tolerance{MaxDelta: 5}).Check(result); err != nil {
+		t.Errorf("Expected delta within tolerance: %s", err)
+	}
+	if err := (Tolerance{MaxDelta: 4}).Check(result); err == nil {
+		t.Errorf("Expected delta to exceed tolerance")
+	}
+	if err := (Tolerance{MinPSNR: 30}).Check(result); err != nil {
+		t.Errorf("Expected psnr within tolerance: %s", err)
+	}
+	if err := (Tolerance{MinPSNR: 60}).Check(result); err == nil {
+		t.Errorf("Expected psnr below floor")
+	}
+
+	if _, err := Compare(expected, actual[:4]); err == nil {
+		t.Errorf("Expected error for mismatched sizes")
+	}
+}
+
+func TestAssertGolden(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "golden", "frame.png")
+	frame := []byte{10, 20, 30, 255, 40, 50, 60, 255}
+
+	// A missing golden file fails instead of being written.
+	recorder := &recorder{TB: t}
+	AssertGolden(recorder, path, 2, 1, frame, Tolerance{})
+	if !recorder.failed {
+		t.Errorf("Expected missing golden file to fail")
+	}
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Errorf("Expected missing golden file to not be written")
+	}
+
+	// With the update variable set the golden file is written, afterwards it is compared against.
+	t.Setenv(UpdateEnv, "1")
+	AssertGolden(t, path, 2, 1, frame, Tolerance{})
+	t.Setenv(UpdateEnv, "")
+	AssertGolden(t, path, 2, 1, frame, Tolerance{})
+}
+
+// Records failures instead of failing the test.
+type recorder struct {
+	testing.TB
+	failed bool
+}
+
+func (r *recorder) Helper()                           {}
+func (r *recorder) Error(args ...any)                 { r.failed = true }
+func (r *recorder) Errorf(format string, args ...any) { r.failed = true }
+func (r *recorder) Fatal(args ...any)                 { r.failed = true }
+func (r *recorder) Fatalf(format string, args ...any) { r.failed = true }
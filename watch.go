@@ -0,0 +1,267 @@
+package vidio
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// A file from the inbox of Watch being processed by a pipeline.
+type WatchJob struct {
+	Filename string // Path of the file. Steps which move the file update it.
+	Name     string // File name in the inbox, as recorded in the journal.
+	video    *Video // Probed video, opened on first use.
+}
+
+// Returns the probed video of the file, opening it on first use.
+// The video is closed when the pipeline finishes.
+func (job *WatchJob) Video() (*Video, error) {
+	if job.video == nil {
+		video, err := NewVideo(job.Filename)
+		if err != nil {
+			return nil, err
+		}
+		job.video = video
+	}
+	return job.video, nil
+}
+
+// A single stage of a Watch pipeline, e.g. probing, transcoding, thumbnailing or moving the file.
+type WatchStep func(ctx context.Context, job *WatchJob) error
+
+// Optional parameters for Watch.
+type WatchOptions struct {
+	Pipeline    []WatchStep                    // Steps run in order for every new file. A failing step stops the pipeline.
+	Concurrency int                            // Maximum number of files processed at once. Default 1.
+	Journal     string                         // File recording processed files. Default ".vidio-journal" in the inbox.
+	Interval    time.Duration                  // Time between scans of the inbox. Default 1 second.
+	Extensions  []string                       // File extensions to process, e.g. ".mp4". Empty processes all files.
+	OnDone      func(job *WatchJob)            // Called after a file was processed. May be nil.
+	OnError     func(job *WatchJob, err error) // Called when a pipeline fails. The file is retried once it changes. May be nil.
+}
+
+// Returns a pipeline step moving the file into "dir".
+func MoveTo(dir string) WatchStep {
+	return func(ctx context.Context, job *WatchJob) error {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			return err
+		}
+		target := filepath.Join(dir, filepath.Base(job.Filename))
+		if err := os.Rename(job.Filename, target); err != nil {
+			return fmt.Errorf("vidio: failed to move %s: %w", job.Filename, err)
+		}
+		job.Filename = target
+		return nil
+	}
+}
+
+// Size and modification time of an inbox file, used to tell when it has been written completely
+// and whether it changed after processing.
+type watchState struct {
+	size    int64
+	modtime time.Time
+}
+
+// Key of the file in the journal.
+func (state watchState) key(name string) string {
+	return fmt.Sprintf("%s\t%d\t%d", name, state.size, state.modtime.UnixNano())
+}
+
+// Monitors the "inbox" directory and runs the pipeline on every new file until the context is cancelled.
+// A file is processed once its size and modification time are unchanged between two scans, so files are not
+// picked up while they are still being copied. Processed files are recorded in a journal and are
+// not processed again, also after restarts, unless they change. Waits for running pipelines before returning.
+func Watch(ctx context.Context, inbox string, options *WatchOptions) error {
+	if options == nil {
+		options = &WatchOptions{}
+	}
+	concurrency := options.Concurrency
+	if concurrency <= 0 {
+		concurrency = 1
+	}
+	interval := options.Interval
+	if interval <= 0 {
+		interval = time.Second
+	}
+	journalname := options.Journal
+	if journalname == "" {
+		journalname = filepath.Join(inbox, ".vidio-journal")
+	}
+
+	done, err := readJournal(journalname)
+	if err != nil {
+		return err
+	}
+	journal, err := os.OpenFile(journalname, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return fmt.Errorf("vidio: failed to open watch journal: %w", err)
+	}
+	defer journal.Close()
+
+	var (
+		mu      sync.Mutex
+		wg      sync.WaitGroup
+		seen    = map[string]watchState{} // State of each file at the last scan.
+		running = map[string]bool{}       // Journal keys of files being processed or failed.
+	)
+	slots := make(chan struct{}, concurrency)
+	defer wg.Wait()
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		states, err := scanInbox(inbox, journalname, options.Extensions)
+		if err != nil {
+			return err
+		}
+		previous := seen
+		// Forgets files which were removed from the inbox.
+		seen = states
+		for _, name := range sortedKeys(states) {
+			state := states[name]
+			last, ok := previous[name]
+			// Waits until the file stopped changing.
+			if !ok || last != state {
+				continue
+			}
+			key := state.key(name)
+			mu.Lock()
+			skip := done[key] || running[key]
+			if !skip {
+				running[key] = true
+			}
+			mu.Unlock()
+			if skip {
+				continue
+			}
+
+			select {
+			case slots <- struct{}{}:
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+			wg.Add(1)
+			go func(name, key string) {
+				defer wg.Done()
+				defer func() { <-slots }()
+
+				job := &WatchJob{Filename: filepath.Join(inbox, name), Name: name}
+				err := runPipeline(ctx, job, options.Pipeline)
+				mu.Lock()
+				defer mu.Unlock()
+				if err != nil {
+					// Failed files stay in "running" until they change, so they are not retried on every scan.
+					if options.OnError != nil {
+						options.OnError(job, err)
+					}
+					return
+				}
+				done[key] = true
+				delete(running, key)
+				if _, err := fmt.Fprintln(journal, key); err != nil && options.OnError != nil {
+					options.OnError(job, fmt.Errorf("vidio: failed to write watch journal: %w", err))
+				}
+				if options.OnDone != nil {
+					options.OnDone(job)
+				}
+			}(name, key)
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+// Runs the steps of a pipeline in order and closes the probed video afterwards.
+func runPipeline(ctx context.Context, job *WatchJob, pipeline []WatchStep) error {
+	defer func() {
+		if job.video != nil {
+			job.video.Close()
+		}
+	}()
+	for _, step := range pipeline {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if err := step(ctx, job); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Returns the state of the regular files in the inbox, skipping hidden files and the journal.
+func scanInbox(inbox, journal string, extensions []string) (map[string]watchState, error) {
+	entries, err := os.ReadDir(inbox)
+	if err != nil {
+		return nil, fmt.Errorf("vidio: failed to read inbox: %w", err)
+	}
+	states := map[string]watchState{}
+	for _, entry := range entries {
+		name := entry.Name()
+		if !entry.Type().IsRegular() || strings.HasPrefix(name, ".") || filepath.Join(inbox, name) == filepath.Clean(journal) {
+			continue
+		}
+		if len(extensions) > 0 && !hasExtension(name, extensions) {
+			continue
+		}
+		info, err := entry.Info()
+		if err != nil {
+			// Removed since the directory was read.
+			continue
+		}
+		states[name] = watchState{size: info.Size(), modtime: info.ModTime()}
+	}
+	return states, nil
+}
+
+// Returns true if the file name ends with one of the extensions, ignoring case.
+func hasExtension(name string, extensions []string) bool {
+	ext := filepath.Ext(name)
+	for _, extension := range extensions {
+		if strings.EqualFold(ext, extension) {
+			return true
+		}
+	}
+	return false
+}
+
+// Reads the keys of the files recorded in the journal. A missing journal is empty.
+func readJournal(filename string) (map[string]bool, error) {
+	done := map[string]bool{}
+	file, err := os.Open(filename)
+	if os.IsNotExist(err) {
+		return done, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("vidio: failed to read watch journal: %w", err)
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		if line := scanner.Text(); line != "" {
+			done[line] = true
+		}
+	}
+	return done, scanner.Err()
+}
+
+// Returns the keys of the map in sorted order, so files are processed in a stable order.
+func sortedKeys(states map[string]watchState) []string {
+	keys := make([]string, 0, len(states))
+	for key := range states {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,100 @@
+package vidio
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"math"
+)
+
+// Sample rate audio is decoded at for waveforms. High enough to keep the peaks of speech and music.
+const waveformRate = 22050
+
+// Audio peaks in the JSON format of the audiowaveform tool, as read by waveform viewers such as peaks.js.
+// "Data" holds a minimum and a maximum per pixel, scaled to 8 bits.
+type Peaks struct {
+	Version         int   `json:"version"`           // Format version, 2.
+	Channels        int   `json:"channels"`          // Number of channels, 1 as audio is mixed down to mono.
+	SampleRate      int   `json:"sample_rate"`       // Sample rate of the analyzed audio.
+	SamplesPerPixel int   `json:"samples_per_pixel"` // Number of samples summarized by each pair of values.
+	Bits            int   `json:"bits"`              // Resolution of the values, 8.
+	Length          int   `json:"length"`            // Number of minimum and maximum pairs.
+	Data            []int `json:"data"`              // Minimum and maximum of each pixel, between -128 and 127.
+}
+
+// Computes the peaks of the first audio stream of "input", one minimum and maximum per "samplesPerPixel"
+// samples at 22050 Hz. The result can be encoded with encoding/json for audiowaveform consumers.
+func WaveformPeaks(input string, samplesPerPixel int) (*Peaks, error) {
+	if samplesPerPixel <= 0 {
+		return nil, fmt.Errorf("vidio: samples per pixel must be positive")
+	}
+	samples, err := readPCM(input, waveformRate)
+	if err != nil {
+		return nil, err
+	}
+
+	data := []int{}
+	for start := 0; start < len(samples); start += samplesPerPixel {
+		low, high := peakRange(samples[start:min(start+samplesPerPixel, len(samples))])
+		data = append(data, peakValue(low), peakValue(high))
+	}
+	return &Peaks{
+		Version:         2,
+		Channels:        1,
+		SampleRate:      waveformRate,
+		SamplesPerPixel: samplesPerPixel,
+		Bits:            8,
+		Length:          len(data) / 2,
+		Data:            data,
+	}, nil
+}
+
+// Renders the waveform of the first audio stream of "input" as a "w" x "h" image with a transparent
+// background, as shown under audio tracks in editors. Each column shows the range of samples of its
+// part of the audio in the given color.
+func WaveformImage(input string, w, h int, c color.Color) (image.Image, error) {
+	if w <= 0 || h <= 0 {
+		return nil, fmt.Errorf("vidio: waveform size must be positive")
+	}
+	samples, err := readPCM(input, waveformRate)
+	if err != nil {
+		return nil, err
+	}
+	return drawWaveform(samples, w, h, c), nil
+}
+
+// Draws the sample range of each column of the image.
+func drawWaveform(samples []float32, w, h int, c color.Color) *image.RGBA {
+	img := image.NewRGBA(image.Rect(0, 0, w, h))
+	if len(samples) == 0 {
+		return img
+	}
+	middle := float64(h-1) / 2
+	for x := 0; x < w; x++ {
+		start, end := x*len(samples)/w, (x+1)*len(samples)/w
+		if end <= start {
+			end = min(start+1, len(samples))
+		}
+		low, high := peakRange(samples[start:end])
+		top := int(math.Round(middle - float64(high)*middle))
+		bottom := int(math.Round(middle - float64(low)*middle))
+		for y := clampInt(top, 0, h-1); y <= clampInt(bottom, 0, h-1); y++ {
+			img.Set(x, y, c)
+		}
+	}
+	return img
+}
+
+// Returns the smallest and largest sample, clamped to [-1, 1].
+func peakRange(samples []float32) (float32, float32) {
+	low, high := float32(0), float32(0)
+	for _, sample := range samples {
+		low, high = min(low, sample), max(high, sample)
+	}
+	return max(low, -1), min(high, 1)
+}
+
+// Scales a sample between -1 and 1 to 8 bits.
+func peakValue(sample float32) int {
+	return clampInt(int(math.Round(float64(sample)*128)), -128, 127)
+}
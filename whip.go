@@ -0,0 +1,124 @@
+package vidio
+
+import (
+	"bytes"
+	"fmt"
+	"time"
+)
+
+// MediaSink receives the encoded output of a writer created with NewSinkWriter.
+// Implementations typically forward the samples to a WHIP endpoint using a WebRTC
+// library such as pion, enabling sub-second browser previews of processed streams.
+type MediaSink interface {
+	// Receives a single H.264 access unit (all NAL units of one frame) in Annex B format.
+	WriteVideo(data []byte, duration time.Duration) error
+	// Called once the encoder has finished and all access units were delivered.
+	Close() error
+}
+
+// Creates a VideoWriter encoding frames to low latency H.264 and handing each encoded frame to the given sink
+// instead of writing a file. Audio is not supported: no Opus track is produced, and setting Options.Audio
+// returns an error. Sinks publishing to a WHIP endpoint should only negotiate a video track.
+func NewSinkWriter(sink MediaSink, width, height int, options *Options) (*VideoWriter, error) {
+	if sink == nil {
+		return nil, fmt.Errorf("vidio: media sink must not be nil")
+	}
+
+	if options == nil {
+		options = &Options{}
+	}
+	if options.Audio != nil {
+		return nil, fmt.Errorf("vidio: media sinks only carry video, audio is not supported")
+	}
+	copied := *options
+	copied.Codec = "libx264"
+	copied.StreamFile = ""
+
+	writer, err := NewVideoWriter("pipe:1", width, height, &copied)
+	if err != nil {
+		return nil, err
+	}
+
+	writer.output = []string{
+		"-preset", "ultrafast",
+		"-tune", "zerolatency",
+		"-profile:v", "baseline", // WebRTC clients universally support the baseline profile.
+		"-bf", "0",
+		"-g", fmt.Sprintf("%d", int(writer.fps)),
+		"-x264-params", "aud=1", // Access unit delimiters mark frame boundaries.
+		"-f", "h264",
+	}
+	writer.stdout = &accessUnitSplitter{
+		sink:     sink,
+		duration: time.Duration(float64(time.Second) / writer.fps),
+	}
+
+	return writer, nil
+}
+
+// Splits an Annex B H.264 byte stream into access units on access unit delimiter NAL units.
+type accessUnitSplitter struct {
+	sink     MediaSink     // Receives the access units.
+	duration time.Duration // Duration of a single frame.
+	buffer   []byte        // Bytes of the access unit currently being received.
+	err      error         // First error returned by the sink.
+}
+
+// Returns the index of the next access unit delimiter start code at or after "from", or -1.
+func nextAccessUnit(data []byte, from int) int {
+	for i := from; i+3 < len(data); i++ {
+		if data[i] == 0 && data[i+1] == 0 && data[i+2] == 1 && data[i+3]&0x1F == 9 {
+			// Include the leading zero of a four byte start code.
+			if i > 0 && data[i-1] == 0 {
+				return i - 1
+			}
+			return i
+		}
+	}
+	return -1
+}
+
+func (splitter *accessUnitSplitter) Write(p []byte) (int, error) {
+	if splitter.err != nil {
+		return 0, splitter.err
+	}
+	splitter.buffer = append(splitter.buffer, p...)
+
+	for {
+		start := nextAccessUnit(splitter.buffer, 0)
+		if start == -1 {
+			return len(p), nil
+		}
+		end := nextAccessUnit(splitter.buffer, start+5)
+		if end == -1 {
+			// Keep the incomplete access unit until the next one starts.
+			splitter.buffer = splitter.buffer[start:]
+			return len(p), nil
+		}
+		if err := splitter.emit(splitter.buffer[start:end]); err != nil {
+			return 0, err
+		}
+		splitter.buffer = splitter.buffer[end:]
+	}
+}
+
+// Hands a copy of the given access unit to the sink.
+func (splitter *accessUnitSplitter) emit(unit []byte) error {
+	if err := splitter.sink.WriteVideo(bytes.Clone(unit), splitter.duration); err != nil {
+		splitter.err = err
+		return err
+	}
+	return nil
+}
+
+// Flushes the last access unit and closes the sink.
+func (splitter *accessUnitSplitter) Close() error {
+	if splitter.err == nil && len(splitter.buffer) > 0 {
+		splitter.emit(splitter.buffer)
+		splitter.buffer = nil
+	}
+	if err := splitter.sink.Close(); err != nil {
+		return err
+	}
+	return splitter.err
+}